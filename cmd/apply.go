@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/github"
+	"vibe-git/internal/notify"
+	"vibe-git/internal/ui"
+)
+
+// runApply applies a saved change set for an issue and opens a PR without calling Claude
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+
+	var (
+		from   string
+		noPush bool
+	)
+	fs.StringVar(&from, "from", "", "File with a JSON array of file changes or a raw Claude response")
+	fs.BoolVar(&noPush, "no-push", false, "Commit on the local branch only; don't push or open a PR")
+
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("parsing flags: %w", err))
+	}
+
+	if from == "" {
+		printApplyUsage()
+		return withExitCode(ExitUsage, fmt.Errorf("--from is required"))
+	}
+	if fs.NArg() < 1 {
+		printApplyUsage()
+		return withExitCode(ExitUsage, fmt.Errorf("issue number required"))
+	}
+	issueNum, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || issueNum <= 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid issue number: %s", fs.Arg(0)))
+	}
+	if err := requireGitHubAuth(); err != nil {
+		return err
+	}
+	if repoOwner == "" || repoName == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("repository owner and name required (use --owner and --repo)"))
+	}
+
+	changes, err := loadChangeSet(from)
+	if err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle interrupt signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt, shutting down...")
+		cancel()
+	}()
+
+	issueClient := newGitHubClient(repoOwner, repoName)
+	githubClient := newGitHubClient(targetOwner, targetName)
+	gitClient := newGitClient()
+
+	if err := resolveBaseBranch(ctx, githubClient); err != nil {
+		return err
+	}
+	if err := validateBaseMap(ctx, githubClient); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== Applying %s to Issue #%d ===\n", from, issueNum)
+
+	issue, err := issueClient.GetIssue(ctx, issueNum)
+	if err != nil {
+		return fmt.Errorf("fetching issue: %w", err)
+	}
+	fmt.Printf("Title: %s\n", issue.Title)
+
+	branchName := issueBranchName(issueNum)
+	base := issueBase(issue)
+	changes, err = commitChangeSet(ctx, gitClient, issue, base, branchName, changes)
+	if err != nil {
+		return err
+	}
+
+	if noPush {
+		fmt.Printf("%s Committed %d file changes on %s (not pushed)\n", ui.Success(), len(changes), branchName)
+		return nil
+	}
+
+	fmt.Printf("Pushing branch %s...\n", branchName)
+	if err := gitClient.PushBranch(ctx, branchName); err != nil {
+		return fmt.Errorf("pushing branch: %w", err)
+	}
+
+	prTitle := fmt.Sprintf("Fix %s: %s", issueRef(issueNum), issue.Title)
+	prBody := buildPRBody(issueNum, issue.URL, "", diffSummary(ctx, gitClient, base, "  "))
+
+	prNumber, prURL, existing, err := openPullRequest(ctx, githubClient, base, branchName, prTitle, prBody, "")
+	if err != nil {
+		return err
+	}
+	if !existing {
+		sendNotification(ctx, notify.Event{Type: notify.EventPRCreated, IssueNumber: issueNum, IssueTitle: issue.Title, PRURL: prURL}, "  ")
+	}
+
+	labelPullRequest(ctx, githubClient, prNumber, "  ")
+	requestPullRequestReview(ctx, githubClient, prNumber, "  ")
+	assignPullRequest(ctx, githubClient, prNumber, "  ")
+	if !existing {
+		linkPullRequestOnIssue(ctx, issueClient, issueNum, prURL, "", "  ")
+	}
+
+	if err := runHook(ctx, "post-commit", postCommitHook, gitClient.Dir(), hookEnv(issue, branchName, prURL, nil), "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s %v\n", ui.Warn(), err)
+	}
+
+	return nil
+}
+
+// loadChangeSet reads the file changes saved at path. The file may hold a JSON
+// array of changes or a raw model response, e.g. from --dump-response.
+func loadChangeSet(path string) ([]claude.FileChange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading change set: %w", err)
+	}
+
+	var changes []claude.FileChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		if changes, err = claude.ParseChanges(string(data)); err != nil {
+			return nil, fmt.Errorf("parsing change set %s: %w", path, err)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("change set %s contains no changes", path)
+	}
+	return changes, nil
+}
+
+// commitChangeSet creates the issue branch from base, applies changes and
+// commits them, returning the changes committed
+func commitChangeSet(ctx context.Context, git *git.Client, issue *github.Issue, base, branchName string, changes []claude.FileChange) ([]claude.FileChange, error) {
+	scope, err := scopeIssue(issue, "")
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Creating branch: %s (from %s)\n", branchName, base)
+	if err := git.CreateBranch(ctx, base, branchName); err != nil {
+		return nil, fmt.Errorf("creating branch: %w", err)
+	}
+
+	if err := checkChangeSize(git, changes, ""); err != nil {
+		return nil, err
+	}
+	if err := scanGeneratedSecrets(git, changes, ""); err != nil {
+		return nil, err
+	}
+
+	if err := runHook(ctx, "pre-apply", preApplyHook, git.Dir(), hookEnv(issue, branchName, "", changes), ""); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Applying %d file changes...\n", len(changes))
+	changes, err = applyIssueChanges(ctx, git, scope, changes, "")
+	if err != nil {
+		return nil, fmt.Errorf("applying changes: %w", err)
+	}
+
+	// Without Claude, a conventional subject comes from the issue's labels and title
+	if err := commitIssueChanges(ctx, git, nil, issue, changes, ""); err != nil {
+		return nil, fmt.Errorf("committing changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+func printApplyUsage() {
+	fmt.Println(`vibe-git apply - Apply a saved change set without calling Claude
+
+Usage:
+  vibe-git [flags] apply --from <file> <issue-number>
+
+Flags:
+  -from string   File with a JSON array of file changes or a raw Claude response
+  -no-push       Commit on the local branch only; don't push or open a PR
+
+Examples:
+  # Reuse a response captured with --dump-response
+  vibe-git --owner myorg --repo myproject apply --from response.txt 42
+
+  # Try the apply logic locally
+  vibe-git --owner myorg --repo myproject apply --from changes.json --no-push 42`)
+}
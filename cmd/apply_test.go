@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/github"
+)
+
+// runGit runs git in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newApplyRepo returns a clone of a local origin with README.md and old.txt committed on main
+func newApplyRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	runGit(t, ".", "init", "-q", "--bare", "-b", "main", origin)
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, ".", "clone", "-q", origin, clone)
+	runGit(t, clone, "checkout", "-q", "-B", "main")
+	writeTestFile(t, filepath.Join(clone, "README.md"), "# demo\n")
+	writeTestFile(t, filepath.Join(clone, "old.txt"), "obsolete\n")
+	runGit(t, clone, "add", "-A")
+	runGit(t, clone, "commit", "-q", "-m", "initial")
+	runGit(t, clone, "push", "-q", "origin", "main")
+	return clone
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadChangeSet(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "changes.json")
+	writeTestFile(t, jsonPath, `[{"path":"a.go","operation":"create","content":"package a"}]`)
+	changes, err := loadChangeSet(jsonPath)
+	if err != nil || len(changes) != 1 || changes[0].Path != "a.go" {
+		t.Errorf("expected one change from JSON, got %+v (%v)", changes, err)
+	}
+
+	rawPath := filepath.Join(dir, "response.txt")
+	writeTestFile(t, rawPath, "=== Response (model: test-model) ===\nHere you go:\n```json\n[{\"path\":\"b.go\",\"operation\":\"delete\"}]\n```\n")
+	changes, err = loadChangeSet(rawPath)
+	if err != nil || len(changes) != 1 || changes[0].Operation != "delete" {
+		t.Errorf("expected one change from raw response, got %+v (%v)", changes, err)
+	}
+
+	emptyPath := filepath.Join(dir, "empty.json")
+	writeTestFile(t, emptyPath, `[]`)
+	if _, err := loadChangeSet(emptyPath); err == nil {
+		t.Error("expected an error for an empty change set")
+	}
+}
+
+func TestCommitChangeSetAppliesFixture(t *testing.T) {
+	clone := newApplyRepo(t)
+
+	origBase := baseBranch
+	t.Cleanup(func() { baseBranch = origBase })
+	baseBranch = "main"
+
+	fixture := filepath.Join(t.TempDir(), "changes.json")
+	writeTestFile(t, fixture, `[
+		{"path": "pkg/new.go", "operation": "create", "content": "package pkg\n"},
+		{"path": "README.md", "operation": "modify", "content": "# demo\n\nUpdated\n"},
+		{"path": "old.txt", "operation": "delete"}
+	]`)
+	changes, err := loadChangeSet(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gitClient := git.NewClient("owner", "repo", "")
+	gitClient.SetDir(clone)
+	gitClient.SetOutput(nil)
+
+	issue := &github.Issue{Number: 7, Title: "Add pkg", URL: "https://github.com/owner/repo/issues/7"}
+	captureStdout(t, func() {
+		_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+	})
+	if err != nil {
+		t.Fatalf("commitChangeSet: %v", err)
+	}
+
+	if branch := runGit(t, clone, "rev-parse", "--abbrev-ref", "HEAD"); branch != "vibe-git/issue-7" {
+		t.Errorf("expected to be on the issue branch, got %s", branch)
+	}
+	if msg := runGit(t, clone, "log", "-1", "--format=%s"); msg != "Fix issue #7: Add pkg" {
+		t.Errorf("unexpected commit message %q", msg)
+	}
+	if files := runGit(t, clone, "show", "--name-status", "--format=", "HEAD"); files != "M\tREADME.md\nD\told.txt\nA\tpkg/new.go" {
+		t.Errorf("unexpected committed files:\n%s", files)
+	}
+}
+
+func TestCommitChangeSetPerFile(t *testing.T) {
+	clone := newApplyRepo(t)
+	orig := commitPerFile
+	t.Cleanup(func() { commitPerFile = orig })
+	commitPerFile = true
+
+	gitClient := git.NewClient("owner", "repo", "")
+	gitClient.SetDir(clone)
+	gitClient.SetOutput(nil)
+
+	issue := &github.Issue{Number: 7, Title: "Add pkg", URL: "https://github.com/owner/repo/issues/7"}
+	changes := []claude.FileChange{
+		{Path: "pkg/new.go", Operation: "create", Content: "package pkg\n"},
+		{Path: "README.md", Operation: "modify", Content: "# demo v2\n"},
+		{Path: "old.txt", Operation: "delete"},
+	}
+
+	var err error
+	captureStdout(t, func() {
+		_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+	})
+	if err != nil {
+		t.Fatalf("commitChangeSet: %v", err)
+	}
+
+	if subjects := runGit(t, clone, "log", "--reverse", "--format=%s", "main..HEAD"); subjects != "Add pkg/new.go\nUpdate README.md\nDelete old.txt" {
+		t.Errorf("expected one commit per file in order, got:\n%s", subjects)
+	}
+	if body := runGit(t, clone, "log", "-1", "--format=%b"); body != "Part of issue #7: Add pkg\n\nhttps://github.com/owner/repo/issues/7" {
+		t.Errorf("unexpected commit body %q", body)
+	}
+	if files := runGit(t, clone, "show", "--name-only", "--format=", "HEAD~1"); files != "README.md" {
+		t.Errorf("expected the second commit to touch README.md only, got %q", files)
+	}
+}
+
+func TestCommitChangeSetRejectsOutOfScope(t *testing.T) {
+	clone := newApplyRepo(t)
+
+	gitClient := git.NewClient("owner", "repo", "")
+	gitClient.SetDir(clone)
+	gitClient.SetOutput(nil)
+
+	issue := &github.Issue{Number: 7, Title: "Add pkg", Body: "Only the package\n\n/path pkg\n"}
+	changes := []claude.FileChange{
+		{Path: "pkg/new.go", Operation: "create", Content: "package pkg\n"},
+		{Path: "README.md", Operation: "modify", Content: "# changed\n"},
+	}
+
+	var err error
+	captureStdout(t, func() {
+		_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+	})
+	if !errors.Is(err, git.ErrOutOfScope) {
+		t.Fatalf("expected ErrOutOfScope, got %v", err)
+	}
+	if status := runGit(t, clone, "status", "--porcelain"); status != "" {
+		t.Errorf("expected no files to be written, got:\n%s", status)
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/ui"
+)
+
+// --apply-mode values
+const (
+	applyStrict  = "strict"  // Fail the issue on the first change that can't be applied
+	applyLenient = "lenient" // Skip the changes that can't be applied and keep the rest
+)
+
+var applyMode = applyStrict // --apply-mode
+
+// applyIssueChanges writes changes confined to scope with git and returns the
+// ones it applied: all of them, or with --apply-mode=lenient those that could
+// be. Skipped changes are listed; the issue only fails if none could be applied.
+func applyIssueChanges(ctx context.Context, git *git.Client, scope string, changes []claude.FileChange, indent string) ([]claude.FileChange, error) {
+	if applyMode != applyLenient {
+		return changes, git.ApplyChangesInScope(ctx, scope, changes)
+	}
+
+	applied, failed, err := git.ApplyChangesLenient(ctx, scope, changes)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "%s%s Skipped %d of %d file changes:\n", indent, ui.Warn(), len(failed), len(changes))
+		for _, f := range failed {
+			fmt.Fprintf(os.Stderr, "%s  %v\n", indent, f)
+		}
+	}
+	if len(applied) == 0 {
+		return nil, fmt.Errorf("none of the %d file changes could be applied", len(changes))
+	}
+	return applied, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/github"
+)
+
+// withApplyMode sets --apply-mode for the test
+func withApplyMode(t *testing.T, mode string) {
+	t.Helper()
+	orig := applyMode
+	t.Cleanup(func() { applyMode = orig })
+	applyMode = mode
+}
+
+func TestCommitChangeSetApplyModes(t *testing.T) {
+	changes := []claude.FileChange{
+		{Path: "README.md", Operation: "modify", Content: "# demo v2\n"},
+		{Path: "README.md/nested.md", Operation: "create", Content: "# nested\n"},
+		{Path: "pkg/new.go", Operation: "create", Content: "package pkg\n"},
+	}
+	issue := &github.Issue{Number: 7, Title: "Add pkg"}
+
+	newClient := func(t *testing.T) (string, *git.Client) {
+		clone := newApplyRepo(t)
+		gitClient := git.NewClient("owner", "repo", "")
+		gitClient.SetDir(clone)
+		gitClient.SetOutput(nil)
+		return clone, gitClient
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		clone, gitClient := newClient(t)
+		withApplyMode(t, applyStrict)
+
+		var err error
+		captureStdout(t, func() {
+			_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+		})
+		if err == nil || !strings.Contains(err.Error(), "creating directory") {
+			t.Fatalf("expected README.md/nested.md to fail the change set, got %v", err)
+		}
+		if msg := runGit(t, clone, "log", "-1", "--format=%s"); msg != "initial" {
+			t.Errorf("nothing should be committed, got %q", msg)
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		clone, gitClient := newClient(t)
+		withApplyMode(t, applyLenient)
+
+		var (
+			committed []claude.FileChange
+			err       error
+		)
+		captureStdout(t, func() {
+			committed, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+		})
+		if err != nil {
+			t.Fatalf("commitChangeSet: %v", err)
+		}
+		if len(committed) != 2 {
+			t.Errorf("expected the 2 valid changes to be committed, got %+v", committed)
+		}
+		if files := runGit(t, clone, "show", "--name-status", "--format=", "HEAD"); files != "M\tREADME.md\nA\tpkg/new.go" {
+			t.Errorf("unexpected committed files:\n%s", files)
+		}
+		if status := runGit(t, clone, "status", "--porcelain"); status != "" {
+			t.Errorf("expected a clean tree after the commit, got:\n%s", status)
+		}
+	})
+
+	t.Run("lenient with nothing valid", func(t *testing.T) {
+		_, gitClient := newClient(t)
+		withApplyMode(t, applyLenient)
+
+		var err error
+		captureStdout(t, func() {
+			_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes[1:2])
+		})
+		if err == nil || !strings.Contains(err.Error(), "none of the 1 file changes could be applied") {
+			t.Errorf("expected the change set to fail, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/ui"
+)
+
+var (
+	maxChangedFiles int  // --max-changed-files; 0 = unlimited
+	maxChangedLines int  // --max-changed-lines; 0 = unlimited
+	force           bool // --force applies change sets over those limits anyway
+)
+
+// checkChangeSize refuses a change set over --max-changed-files or
+// --max-changed-lines, as when Claude reformats the whole repository instead of
+// fixing the issue; with --force it only warns. Lines are counted against the
+// files in git's working tree, so it must run before the changes are applied.
+func checkChangeSize(git *git.Client, changes []claude.FileChange, indent string) error {
+	over, err := changeSizeExcess(git, changes)
+	if err != nil || over == "" {
+		return err
+	}
+	if force {
+		fmt.Fprintf(os.Stderr, "%s%s Applying anyway (--force): %s\n", indent, ui.Warn(), over)
+		return nil
+	}
+	return withExitCode(ExitGeneration, fmt.Errorf("%s (use --force to apply it anyway)", over))
+}
+
+// changeSizeExcess describes the first limit changes exceed, or "" if none
+func changeSizeExcess(git *git.Client, changes []claude.FileChange) (string, error) {
+	if maxChangedFiles > 0 && len(changes) > maxChangedFiles {
+		return fmt.Sprintf("change set touches %d files, over --max-changed-files=%d", len(changes), maxChangedFiles), nil
+	}
+	if maxChangedLines <= 0 {
+		return "", nil
+	}
+	lines, err := git.ChangedLines(changes)
+	if err != nil {
+		return "", fmt.Errorf("measuring changes: %w", err)
+	}
+	if lines > maxChangedLines {
+		return fmt.Sprintf("change set changes %d lines, over --max-changed-lines=%d", lines, maxChangedLines), nil
+	}
+	return "", nil
+}
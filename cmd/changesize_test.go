@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+	"vibe-git/internal/github"
+)
+
+// withChangeLimits sets --max-changed-files, --max-changed-lines and --force for the test
+func withChangeLimits(t *testing.T, files, lines int, forced bool) {
+	t.Helper()
+	origFiles, origLines, origForce := maxChangedFiles, maxChangedLines, force
+	t.Cleanup(func() { maxChangedFiles, maxChangedLines, force = origFiles, origLines, origForce })
+	maxChangedFiles, maxChangedLines, force = files, lines, forced
+}
+
+func TestCommitChangeSetRejectsHugeChanges(t *testing.T) {
+	// 3 files; README.md loses 1 line and gains 3, old.txt loses 1, pkg/new.go gains 1
+	changes := []claude.FileChange{
+		{Path: "README.md", Operation: "modify", Content: "# demo v2\n\nReformatted\n"},
+		{Path: "old.txt", Operation: "delete"},
+		{Path: "pkg/new.go", Operation: "create", Content: "package pkg\n"},
+	}
+	issue := &github.Issue{Number: 7, Title: "Add pkg"}
+
+	tests := []struct {
+		name   string
+		files  int
+		lines  int
+		force  bool
+		reject string
+	}{
+		{name: "too many files", files: 2, reject: "touches 3 files, over --max-changed-files=2"},
+		{name: "too many lines", lines: 5, reject: "changes 6 lines, over --max-changed-lines=5"},
+		{name: "within limits", files: 3, lines: 6},
+		{name: "forced", files: 1, lines: 1, force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clone := newApplyRepo(t)
+			gitClient := git.NewClient("owner", "repo", "")
+			gitClient.SetDir(clone)
+			gitClient.SetOutput(nil)
+			withChangeLimits(t, tt.files, tt.lines, tt.force)
+
+			var err error
+			captureStdout(t, func() {
+				_, err = commitChangeSet(context.Background(), gitClient, issue, "main", "vibe-git/issue-7", changes)
+			})
+			if tt.reject == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.reject) {
+				t.Fatalf("expected the change set to be rejected with %q, got %v", tt.reject, err)
+			}
+			if code := ExitCode(err); code != ExitGeneration {
+				t.Errorf("expected exit code %d, got %d", ExitGeneration, code)
+			}
+			if status := runGit(t, clone, "status", "--porcelain"); status != "" {
+				t.Errorf("expected no files to be written, got:\n%s", status)
+			}
+		})
+	}
+}
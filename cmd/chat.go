@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/git"
+	"vibe-git/internal/ui"
+)
+
+// chatHelp lists the commands of a chat session
+const chatHelp = `Type an instruction to have Claude change the code, or a command:
+  /diff            Show every change kept since the session started or the last commit
+  /undo            Revert the last kept turn
+  /commit [msg]    Commit the kept changes
+  /help            Show this help
+  /quit            End the session; kept changes stay in the working tree`
+
+// runChat opens an interactive session that applies instructions to the
+// checkout in the current directory, one reviewed turn at a time
+func runChat(args []string) error {
+	fs := flag.NewFlagSet("chat", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("parsing flags: %w", err))
+	}
+	if claudeAPIKey == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)"))
+	}
+
+	// Ctrl-C ends the session like any REPL; kept changes stay staged
+	ctx := context.Background()
+
+	claudeClient := claude.NewClient(claudeAPIKey, os.Getenv("ANTHROPIC_BASE_URL"), model)
+	configureCodebase(claudeClient)
+	claudeClient.SetMaxResponseBytes(maxResponseSize)
+	if err := claudeClient.SetSampling(temperature, topP); err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	session, err := newChatSession(ctx, claudeClient, newGitClient())
+	if err != nil {
+		return err
+	}
+	fmt.Println(chatHelp)
+	return session.run(ctx)
+}
+
+// chatTurn is an instruction whose changes were kept
+type chatTurn struct {
+	instruction string
+	before      string // Snapshot of the index before the turn's changes
+}
+
+// chatSession applies instructions to a checkout. Kept changes are staged in
+// the index, so every turn starts from a snapshot it can be reverted to.
+type chatSession struct {
+	cl    *claude.Client
+	git   *git.Client
+	start string     // Snapshot at the start of the session or the last /commit
+	turns []chatTurn // Kept since start, oldest first
+}
+
+// newChatSession starts a session on the checkout of gc, which must be clean
+// so that reverting a turn can't touch the user's own edits
+func newChatSession(ctx context.Context, cl *claude.Client, gc *git.Client) (*chatSession, error) {
+	clean, err := gc.Clean(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("the working tree has uncommitted changes; commit or stash them before chatting")
+	}
+	start, err := gc.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &chatSession{cl: cl.WithRoot(gc.Dir()), git: gc, start: start}, nil
+}
+
+// parseChatInput splits a line into a command without its slash and the
+// argument after it. A line that isn't a command is an instruction, returned
+// as the argument with an empty command.
+func parseChatInput(line string) (command, arg string) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "/") {
+		return "", line
+	}
+	command, arg, _ = strings.Cut(line[1:], " ")
+	return strings.ToLower(command), strings.TrimSpace(arg)
+}
+
+// run reads instructions and commands until /quit or the end of input
+func (s *chatSession) run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		fmt.Print("vibe> ")
+		line, err := confirmInput.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if err != nil && strings.TrimSpace(line) == "" {
+			fmt.Println()
+			return nil
+		}
+
+		command, arg := parseChatInput(line)
+		switch command {
+		case "":
+			if arg == "" {
+				continue
+			}
+			err = s.turn(ctx, arg)
+		case "diff":
+			err = s.diff(ctx)
+		case "undo":
+			err = s.undo(ctx)
+		case "commit":
+			err = s.commit(ctx, arg)
+		case "help":
+			fmt.Println(chatHelp)
+		case "quit", "exit":
+			return nil
+		default:
+			fmt.Printf("Unknown command /%s; /help lists them\n", command)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", ui.Error(), err)
+		}
+	}
+	return ctx.Err()
+}
+
+// turn has Claude carry out instruction, shows the diff and keeps the changes
+// only if the user accepts them
+func (s *chatSession) turn(ctx context.Context, instruction string) error {
+	before, err := s.git.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	refs := ctxloader.ExtractFileReferencesIn(instruction, s.git.Dir())
+	fmt.Println("Generating code with Claude...")
+	changes, err := s.cl.GenerateCode(ctx, instruction, s.history(), loadReferencedFiles(refs, s.git.Dir(), ""))
+	if err != nil {
+		return fmt.Errorf("generating code: %w", err)
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes proposed")
+		return nil
+	}
+	if err := scanGeneratedSecrets(s.git, changes, ""); err != nil {
+		return err
+	}
+
+	if err := s.git.ApplyChanges(ctx, changes); err != nil {
+		if restoreErr := s.git.RestoreSnapshot(ctx, before); restoreErr != nil {
+			return errors.Join(fmt.Errorf("applying changes: %w", err), restoreErr)
+		}
+		return fmt.Errorf("applying changes: %w", err)
+	}
+	after, err := s.git.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	diff, err := s.git.DiffSnapshots(ctx, before, after)
+	if err != nil {
+		return err
+	}
+	fmt.Print(diff)
+
+	if !confirm("Keep these changes?") {
+		if err := s.git.RestoreSnapshot(ctx, before); err != nil {
+			return err
+		}
+		fmt.Println("Discarded")
+		return nil
+	}
+	s.turns = append(s.turns, chatTurn{instruction: instruction, before: before})
+	fmt.Printf("%s Kept %s\n", ui.Success(), plural(len(changes), "file change"))
+	return nil
+}
+
+// history describes the kept turns for the next prompt, since the model only
+// sees their result in the code
+func (s *chatSession) history() string {
+	if len(s.turns) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Earlier instructions in this session, already applied to the code:\n")
+	for i, t := range s.turns {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, t.instruction)
+	}
+	return sb.String()
+}
+
+// diff shows everything kept since the session started or the last commit
+func (s *chatSession) diff(ctx context.Context) error {
+	now, err := s.git.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	diff, err := s.git.DiffSnapshots(ctx, s.start, now)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Println("No changes kept yet")
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// undo reverts the last kept turn
+func (s *chatSession) undo(ctx context.Context) error {
+	if len(s.turns) == 0 {
+		fmt.Println("Nothing to undo")
+		return nil
+	}
+	last := s.turns[len(s.turns)-1]
+	if err := s.git.RestoreSnapshot(ctx, last.before); err != nil {
+		return err
+	}
+	s.turns = s.turns[:len(s.turns)-1]
+	fmt.Printf("Reverted %q\n", last.instruction)
+	return nil
+}
+
+// commit commits the kept changes with message, or one listing the
+// instructions. Turns before a commit can no longer be undone.
+func (s *chatSession) commit(ctx context.Context, message string) error {
+	if len(s.turns) == 0 {
+		fmt.Println("Nothing to commit")
+		return nil
+	}
+	if message == "" {
+		message = chatCommitMessage(s.turns)
+	}
+	if err := s.git.Commit(ctx, message); err != nil {
+		return err
+	}
+	start, err := s.git.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	s.start, s.turns = start, nil
+	fmt.Printf("%s Committed\n", ui.Success())
+	return nil
+}
+
+// chatCommitMessage lists the instructions of turns under a summary line
+func chatCommitMessage(turns []chatTurn) string {
+	if len(turns) == 1 {
+		return turns[0].instruction
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Apply %d chat instructions\n\n", len(turns))
+	for _, t := range turns {
+		fmt.Fprintf(&sb, "- %s\n", t.instruction)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
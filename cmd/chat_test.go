@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+)
+
+func TestParseChatInput(t *testing.T) {
+	tests := []struct {
+		line, command, arg string
+	}{
+		{"add a README\n", "", "add a README"},
+		{"  /diff  ", "diff", ""},
+		{"/COMMIT Add docs and tests\n", "commit", "Add docs and tests"},
+		{"/undo", "undo", ""},
+		{"\n", "", ""},
+		{"fix the /health endpoint", "", "fix the /health endpoint"},
+	}
+	for _, tt := range tests {
+		command, arg := parseChatInput(tt.line)
+		if command != tt.command || arg != tt.arg {
+			t.Errorf("parseChatInput(%q) = %q, %q; want %q, %q", tt.line, command, arg, tt.command, tt.arg)
+		}
+	}
+}
+
+// chatClaude stubs a model that creates turnN.go for its Nth request and
+// records the prompts
+func chatClaude(t *testing.T) (*claude.Client, *[]string) {
+	t.Helper()
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[0].Content)
+
+		n := len(prompts)
+		text := fmt.Sprintf(`[{"path":"turn%d.go","operation":"create","content":"package turn%d\n"}]`, n, n)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": text}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cl := claude.NewClient("key", server.URL, "test-model")
+	cl.SetSkipCodebase(true)
+	return cl, &prompts
+}
+
+func TestChatSessionAcceptRejectUndoCommit(t *testing.T) {
+	clone, _, gitClient := newInteractiveIssue(t)
+	cl, prompts := chatClaude(t)
+	withAnswers(t, "add one\nn\nadd two\ny\n/undo\nadd three\ny\n/diff\n/commit\n/quit\n")
+
+	session, err := newChatSession(context.Background(), cl, gitClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() {
+		err = session.run(context.Background())
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Rejected and undone turns leave nothing behind
+	for _, name := range []string{"turn1.go", "turn2.go"} {
+		if _, err := os.Stat(filepath.Join(clone, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be reverted, got %v", name, err)
+		}
+	}
+	if files := runGit(t, clone, "show", "--name-only", "--format=%s"); files != "add three\n\nturn3.go" {
+		t.Errorf("expected a commit of turn3.go named after the instruction, got %q", files)
+	}
+	if status := runGit(t, clone, "status", "--porcelain"); status != "" {
+		t.Errorf("expected a clean checkout after /commit, got %q", status)
+	}
+
+	// The kept turn was listed for the next prompt, and dropped again by /undo
+	if !strings.Contains((*prompts)[2], "add three") || strings.Contains((*prompts)[2], "add two") {
+		t.Errorf("expected the undone instruction to be left out of the third prompt:\n%s", (*prompts)[2])
+	}
+	for _, want := range []string{"+package turn1", "Discarded", "Reverted \"add two\"", "Committed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestChatSessionListsEarlierTurns(t *testing.T) {
+	_, _, gitClient := newInteractiveIssue(t)
+	cl, prompts := chatClaude(t)
+	withAnswers(t, "add one\ny\nadd two\ny\n")
+
+	session, err := newChatSession(context.Background(), cl, gitClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		err = session.run(context.Background())
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains((*prompts)[1], "already applied to the code:\n1. add one") {
+		t.Errorf("expected the second prompt to list the first instruction:\n%s", (*prompts)[1])
+	}
+}
+
+func TestNewChatSessionRequiresCleanCheckout(t *testing.T) {
+	clone, cl, gitClient := newInteractiveIssue(t)
+	if err := os.WriteFile(filepath.Join(clone, "scratch.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newChatSession(context.Background(), cl, gitClient); err == nil {
+		t.Error("expected an error for a dirty checkout")
+	}
+}
+
+func TestChatSessionRefusesEchoedSecrets(t *testing.T) {
+	clone, _, gitClient := newInteractiveIssue(t)
+	secret := "DEPLOY_TOKEN=ghp_" + strings.Repeat("a1B2", 9) + "\n"
+	if err := os.WriteFile(filepath.Join(clone, "deploy.env"), []byte(secret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, clone, "add", "deploy.env")
+	runGit(t, clone, "commit", "-m", "Add deploy settings")
+
+	// The model saw the token masked and writes the placeholder back
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := "DEPLOY_TOKEN=" + ctxloader.Redacted + "\nDEBUG=1\n"
+		text, _ := json.Marshal([]claude.FileChange{{Path: "deploy.env", Operation: "modify", Content: content}})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": string(text)}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, "test-model")
+	cl.SetSkipCodebase(true)
+
+	session, err := newChatSession(context.Background(), cl, gitClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.turn(context.Background(), "turn on debug logging")
+	if err == nil || !strings.Contains(err.Error(), "deploy.env") {
+		t.Errorf("expected the echoed placeholder to be refused, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clone, "deploy.env")); string(data) != secret {
+		t.Errorf("expected the real token to be kept, got %q", data)
+	}
+	if len(session.turns) != 0 {
+		t.Errorf("expected no kept turn, got %d", len(session.turns))
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configFile holds flag defaults for the checkout vibe-git runs in; `vibe-git
+// init` writes a starter one
+const configFile = ".vibe-git.yml"
+
+// applyConfigFile sets the flags of fs named by the "flag: value" lines of
+// path, such as "max-changed-files: 20" for --max-changed-files 20. Flags given
+// on the command line win. A missing file is not an error.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer f.Close()
+
+	given := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected \"flag: value\", got %q", path, n, line)
+		}
+		key = strings.TrimSpace(key)
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("%s:%d: unknown flag %q (run vibe-git --help for every flag)", path, n, key)
+		}
+		if given[key] {
+			continue
+		}
+		value, err := configValue(value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, n, key, err)
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("%s:%d: invalid %s: %w", path, n, key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// configValue returns the value of a config line, a double-quoted string or
+// plain text, without a trailing " # comment"
+func configValue(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, `"`) {
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+		return raw, nil
+	}
+
+	quoted, err := strconv.QuotedPrefix(raw)
+	if err != nil {
+		return "", fmt.Errorf("unterminated string %s", raw)
+	}
+	if rest := strings.TrimSpace(raw[len(quoted):]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the string", rest)
+	}
+	return strconv.Unquote(quoted)
+}
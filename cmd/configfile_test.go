@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newConfigFlags is a flag set with a few of vibe-git's flags
+func newConfigFlags() (*flag.FlagSet, *string, *int, *bool, *time.Duration, *string) {
+	fs := flag.NewFlagSet("vibe-git", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	owner := fs.String("owner", "", "")
+	maxFiles := fs.Int("max-changed-files", 0, "")
+	draft := fs.Bool("draft", false, "")
+	grace := fs.Duration("grace-period", 0, "")
+	label := fs.String("require-label", "vibe", "")
+	return fs, owner, maxFiles, draft, grace, label
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFile)
+	writeTestFile(t, path, `# vibe-git settings
+owner: myorg   # the GitHub org
+
+max-changed-files: 20
+draft: true
+grace-period: 2m
+require-label: "" # any issue
+`)
+
+	fs, owner, maxFiles, draft, grace, label := newConfigFlags()
+	if err := fs.Parse([]string{"--max-changed-files", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(fs, path); err != nil {
+		t.Fatalf("applyConfigFile: %v", err)
+	}
+	if *owner != "myorg" || !*draft || *grace != 2*time.Minute || *label != "" {
+		t.Errorf("expected the file's values, got owner=%q draft=%v grace=%v label=%q", *owner, *draft, *grace, *label)
+	}
+	if *maxFiles != 5 {
+		t.Errorf("expected the command line to win, got --max-changed-files %d", *maxFiles)
+	}
+
+	fs, _, _, _, _, _ = newConfigFlags()
+	if err := applyConfigFile(fs, filepath.Join(t.TempDir(), configFile)); err != nil {
+		t.Errorf("expected a missing file to be ignored, got %v", err)
+	}
+}
+
+func TestApplyConfigFileErrors(t *testing.T) {
+	for content, want := range map[string]string{
+		"owner: myorg\nmax-files: 3\n":        `:2: unknown flag "max-files"`,
+		"draft: maybe\n":                      ":1: invalid draft",
+		"owner myorg\n":                       `:1: expected "flag: value"`,
+		"require-label: \"needs-ai\" extra\n": `unexpected "extra" after the string`,
+	} {
+		path := filepath.Join(t.TempDir(), configFile)
+		writeTestFile(t, path, content)
+		fs, _, _, _, _, _ := newConfigFlags()
+		if err := applyConfigFile(fs, path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", content, want, err)
+		}
+	}
+}
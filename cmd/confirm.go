@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"vibe-git/internal/claude"
+)
+
+// errDeclined is returned when the user answers no at an --interactive prompt
+var errDeclined = errors.New("declined at confirmation prompt")
+
+var (
+	// confirmInput is where --interactive answers are read from
+	confirmInput = bufio.NewReader(os.Stdin)
+	// stdinIsTerminal reports whether a user can answer prompts
+	stdinIsTerminal = func() bool {
+		info, err := os.Stdin.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+// confirm asks a yes/no question and reports whether the answer was yes.
+// Anything other than y or yes, including end of input, counts as no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := confirmInput.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	if err == io.EOF {
+		fmt.Println()
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// printChangeSummary lists the proposed changes so they can be reviewed before applying
+func printChangeSummary(changes []claude.FileChange) {
+	fmt.Printf("Proposed changes (%d files):\n", len(changes))
+	for _, c := range changes {
+		if c.Operation == "delete" {
+			fmt.Printf("  %-6s %s\n", c.Operation, c.Path)
+			continue
+		}
+		fmt.Printf("  %-6s %s (%d bytes)\n", c.Operation, c.Path, len(c.Content))
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/git"
+)
+
+// withAnswers turns on --interactive and feeds input to the confirmation prompts
+func withAnswers(t *testing.T, input string) {
+	t.Helper()
+	origInteractive, origInput := interactive, confirmInput
+	t.Cleanup(func() { interactive, confirmInput = origInteractive, origInput })
+	interactive = true
+	confirmInput = bufio.NewReader(strings.NewReader(input))
+}
+
+func TestConfirm(t *testing.T) {
+	withAnswers(t, "y\nYES\nn\n\nmaybe\n")
+
+	var got []bool
+	captureStdout(t, func() {
+		for i := 0; i < 6; i++ {
+			got = append(got, confirm("Continue?"))
+		}
+	})
+
+	// The last prompt hits the end of input
+	want := []bool{true, true, false, false, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("answer %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// newInteractiveIssue returns clients for processing issue #7 against a local clone
+// and a Claude stub that proposes one new file
+func newInteractiveIssue(t *testing.T) (string, *claude.Client, *git.Client) {
+	t.Helper()
+	clone := newApplyRepo(t)
+	setTestRepos(t, "owner", "repo", "owner", "repo")
+
+	origBase, origWorker := baseBranch, useWorker
+	t.Cleanup(func() { baseBranch, useWorker = origBase, origWorker })
+	baseBranch, useWorker = "main", false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": `[{"path":"pkg/new.go","operation":"create","content":"package pkg\n"}]`}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, "test-model")
+	cl.SetSkipCodebase(true)
+
+	gitClient := git.NewClient("owner", "repo", "")
+	gitClient.SetDir(clone)
+	gitClient.SetOutput(nil)
+	return clone, cl, gitClient
+}
+
+func issueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/repos/owner/repo/issues/7" {
+		w.Write([]byte(`{"number": 7, "title": "Add pkg", "html_url": "https://github.com/owner/repo/issues/7"}`))
+		return
+	}
+	w.Write([]byte(`{"permissions": {"push": true}}`))
+}
+
+func TestProcessIssueInteractiveDeclineApply(t *testing.T) {
+	clone, cl, gitClient := newInteractiveIssue(t)
+	withAnswers(t, "n\n")
+	gh := newStubGitHub(t, issueHandler)
+
+	var err error
+	out := captureStdout(t, func() {
+		err = processIssue(context.Background(), gh, gh, cl, gitClient, 7)
+	})
+	if !errors.Is(err, errDeclined) {
+		t.Fatalf("expected errDeclined, got %v", err)
+	}
+	if !strings.Contains(out, "create pkg/new.go (12 bytes)") {
+		t.Errorf("expected the change summary, got:\n%s", out)
+	}
+	if msg := runGit(t, clone, "log", "-1", "--format=%s"); msg != "initial" {
+		t.Errorf("nothing should be committed after declining, got %q", msg)
+	}
+}
+
+func TestProcessIssueInteractiveDeclinePush(t *testing.T) {
+	clone, cl, gitClient := newInteractiveIssue(t)
+	withAnswers(t, "y\nn\n")
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			t.Errorf("no PR should be opened after declining the push, got %s %s", r.Method, r.URL.Path)
+		}
+		issueHandler(w, r)
+	})
+
+	var err error
+	captureStdout(t, func() {
+		err = processIssue(context.Background(), gh, gh, cl, gitClient, 7)
+	})
+	if !errors.Is(err, errDeclined) {
+		t.Fatalf("expected errDeclined, got %v", err)
+	}
+	if msg := runGit(t, clone, "log", "-1", "--format=%s"); msg != "Fix issue #7: Add pkg" {
+		t.Errorf("expected the changes to be committed locally, got %q", msg)
+	}
+	if remote := runGit(t, clone, "ls-remote", "--heads", "origin", "vibe-git/issue-7"); remote != "" {
+		t.Errorf("branch should not be pushed, got %q", remote)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vibe-git/internal/github"
+)
+
+var (
+	// dependsOn matches "depends on #5", "blocked by #3, #4" and "depends on #3 and #4"
+	dependsOn   = regexp.MustCompile(`(?i)\b(?:depends\s+on|blocked\s+by):?\s+(#\d+(?:(?:\s*,\s*|\s+and\s+)#\d+)*)`)
+	issueNumber = regexp.MustCompile(`#(\d+)`)
+)
+
+// errDependencyFailed marks an issue skipped because one it depends on failed
+var errDependencyFailed = errors.New("skipped: a dependency failed")
+
+// parseDependencies returns the issues body says it depends on or is blocked
+// by, in order of first mention, leaving out self, the issue body belongs to
+func parseDependencies(body string, self int) []int {
+	var deps []int
+	seen := map[int]bool{self: true}
+	for _, m := range dependsOn.FindAllStringSubmatch(body, -1) {
+		for _, ref := range issueNumber.FindAllStringSubmatch(m[1], -1) {
+			n, err := strconv.Atoi(ref[1])
+			if err != nil || seen[n] {
+				continue
+			}
+			seen[n] = true
+			deps = append(deps, n)
+		}
+	}
+	return deps
+}
+
+// loadDependencies fetches each issue of a batch and parses its dependencies.
+// Issues that can't be fetched have none; processing them reports the error.
+func loadDependencies(ctx context.Context, gh *github.Client, issueNums []int) map[int][]int {
+	deps := make(map[int][]int)
+	for _, n := range issueNums {
+		issue, err := gh.GetIssue(ctx, n)
+		if err != nil {
+			continue
+		}
+		if d := parseDependencies(issue.Body, n); len(d) > 0 {
+			deps[n] = d
+		}
+	}
+	return deps
+}
+
+// orderByDependencies sorts issueNums so that every issue comes after the
+// issues of the batch it depends on, otherwise keeping the given order.
+// Dependencies outside the batch are ignored. A cycle is an error naming it.
+func orderByDependencies(issueNums []int, deps map[int][]int) ([]int, error) {
+	inBatch := make(map[int]bool, len(issueNums))
+	for _, n := range issueNums {
+		inBatch[n] = true
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]int, len(issueNums))
+	var ordered, path []int
+
+	var visit func(n int) error
+	visit = func(n int) error {
+		switch state[n] {
+		case done:
+			return nil
+		case visiting:
+			// path holds the chain that led back to n
+			start := 0
+			for i, p := range path {
+				if p == n {
+					start = i
+				}
+			}
+			return fmt.Errorf("issues depend on each other: %s", formatCycle(append(path[start:], n)))
+		}
+		state[n] = visiting
+		path = append(path, n)
+		for _, d := range deps[n] {
+			if !inBatch[d] {
+				continue
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = done
+		ordered = append(ordered, n)
+		return nil
+	}
+
+	for _, n := range issueNums {
+		if err := visit(n); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// formatCycle formats a cycle of issues as "#3 -> #5 -> #3"
+func formatCycle(cycle []int) string {
+	return strings.Join(hashRefs(cycle), " -> ")
+}
+
+// formatIssueRefs formats issues as "#3, #5"
+func formatIssueRefs(issueNums []int) string {
+	return strings.Join(hashRefs(issueNums), ", ")
+}
+
+// hashRefs formats each issue as "#N"
+func hashRefs(issueNums []int) []string {
+	refs := make([]string, len(issueNums))
+	for i, n := range issueNums {
+		refs[i] = "#" + strconv.Itoa(n)
+	}
+	return refs
+}
+
+// failedDependencies returns the dependencies of issueNum in failed, sorted
+func failedDependencies(issueNum int, deps map[int][]int, failed map[int]bool) []int {
+	var out []int
+	for _, d := range deps[issueNum] {
+		if failed[d] {
+			out = append(out, d)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
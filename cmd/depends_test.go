@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDependencies(t *testing.T) {
+	tests := []struct {
+		body string
+		want []int
+	}{
+		{"Depends on #5", []int{5}},
+		{"blocked by #3, #4\n\nAlso depends on #3 and #7", []int{3, 4, 7}},
+		{"Blocked by: #2", []int{2}},
+		{"Depends on #9 (this issue)", nil},
+		{"Fixes #5; see #6", nil},
+	}
+	for _, tt := range tests {
+		if got := parseDependencies(tt.body, 9); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseDependencies(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	// 1 depends on 3, 3 on 2, and 4 on 10, which isn't in the batch
+	deps := map[int][]int{1: {3}, 3: {2}, 4: {10}}
+	got, err := orderByDependencies([]int{1, 2, 3, 4}, deps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{2, 3, 1, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestOrderByDependenciesCycle(t *testing.T) {
+	deps := map[int][]int{1: {2}, 2: {3}, 3: {1}}
+	_, err := orderByDependencies([]int{4, 1, 2, 3}, deps)
+	if err == nil || !strings.Contains(err.Error(), "#1 -> #2 -> #3 -> #1") {
+		t.Errorf("expected the cycle to be reported, got %v", err)
+	}
+}
+
+func TestLoadDependencies(t *testing.T) {
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/1":
+			w.Write([]byte(`{"number": 1, "body": "Blocked by #2"}`))
+		case "/repos/owner/repo/issues/2":
+			w.Write([]byte(`{"number": 2, "body": "No dependencies"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	deps := loadDependencies(context.Background(), gh, []int{1, 2, 3})
+	if want := map[int][]int{1: {2}}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("expected %v, got %v", want, deps)
+	}
+}
+
+func TestProcessIssuesSkipsDependentsOfFailures(t *testing.T) {
+	orig := failFast
+	t.Cleanup(func() { failFast = orig })
+	failFast = false
+
+	// 3 depends on 2, which fails, and 4 on 3, which is skipped
+	deps := map[int][]int{3: {2}, 4: {3}}
+	var calls []int
+	err := processIssues([]int{1, 2, 3, 4}, deps, failingIssues(&calls, 2))
+
+	if fmt.Sprint(calls) != "[1 2]" {
+		t.Errorf("expected dependents of #2 to be skipped, got %v", calls)
+	}
+	if !errors.Is(err, errDependencyFailed) {
+		t.Errorf("expected errDependencyFailed, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "3 of 4 issue(s) failed") {
+		t.Errorf("expected skipped issues to count as failed, got %v", err)
+	}
+	if code := ExitCode(err); code != ExitPartial {
+		t.Errorf("expected ExitPartial, got %d", code)
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/ui"
+	"vibe-git/internal/worker"
+)
+
+// doctorCheck is a single environment check run by `vibe-git doctor`
+type doctorCheck struct {
+	Name string
+	Hint string // Remediation shown when the check fails
+	Run  func(ctx context.Context) (string, error)
+}
+
+// doctorEnv holds the clients and settings the doctor checks run against
+type doctorEnv struct {
+	GitHub       *github.Client
+	Claude       *claude.Client
+	GatewayURL   string // Custom ANTHROPIC_BASE_URL, checked for reachability if set
+	Worker       *worker.Client
+	GitHubToken  string
+	App          *github.AppAuth // Set when authenticating as a GitHub App instead of with GitHubToken
+	ClaudeAPIKey string
+	Owner        string
+	Repo         string
+	Issues       *github.Client // Set when issues are read from a different repository (--target-repo)
+	IssueRepo    string
+}
+
+// runDoctor validates credentials and tooling before a long run
+func runDoctor() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	env := doctorEnv{
+		GitHub:       newGitHubClient(targetOwner, targetName),
+		Claude:       claude.NewClient(claudeAPIKey, baseURL, model),
+		GatewayURL:   baseURL,
+		GitHubToken:  githubToken,
+		App:          appAuth,
+		ClaudeAPIKey: claudeAPIKey,
+		Owner:        targetOwner,
+		Repo:         targetName,
+	}
+	if isCrossRepo() {
+		env.Issues = newGitHubClient(repoOwner, repoName)
+		env.IssueRepo = repoOwner + "/" + repoName
+	}
+	if useWorker {
+		env.Worker = worker.NewClient(workerURL, workerToken)
+		env.Worker.SetProject(workerProject)
+	}
+
+	fmt.Println("vibe-git doctor")
+	fmt.Println()
+
+	if failed := runDoctorChecks(ctx, os.Stdout, doctorChecks(env)); failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	fmt.Println("\nAll checks passed")
+	return nil
+}
+
+// doctorChecks builds the list of checks for the given environment
+func doctorChecks(env doctorEnv) []doctorCheck {
+	checks := []doctorCheck{
+		{
+			Name: "git",
+			Hint: "Install git and make sure it is on PATH",
+			Run:  gitVersion,
+		},
+		{
+			Name: "GitHub token",
+			Hint: "Create a token with repo scope at https://github.com/settings/tokens and set GITHUB_TOKEN or --github-token",
+			Run: func(ctx context.Context) (string, error) {
+				if env.App != nil {
+					// Installation tokens can't read /user, so getting one is the check
+					if _, err := env.App.Token(ctx); err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("authenticated as GitHub App %d (installation %d)", env.App.AppID(), env.App.InstallationID()), nil
+				}
+				if env.GitHubToken == "" {
+					return "", fmt.Errorf("not set")
+				}
+				login, err := env.GitHub.GetAuthenticatedUser(ctx)
+				if err != nil {
+					return "", err
+				}
+				return "authenticated as " + login, nil
+			},
+		},
+		{
+			Name: "GitHub repository",
+			Hint: "Check --owner/--repo and that the token has access to the repository",
+			Run: func(ctx context.Context) (string, error) {
+				if env.Owner == "" || env.Repo == "" {
+					return "", fmt.Errorf("--owner and --repo not set")
+				}
+				branch, err := env.GitHub.GetDefaultBranch(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s/%s (default branch %s)", env.Owner, env.Repo, branch), nil
+			},
+		},
+		{
+			Name: "Push access",
+			Hint: "Grant the token write access to the repository (classic: repo scope; fine-grained: Contents read/write)",
+			Run: func(ctx context.Context) (string, error) {
+				if env.Owner == "" || env.Repo == "" {
+					return "", fmt.Errorf("--owner and --repo not set")
+				}
+				perms, err := env.GitHub.GetRepoPermissions(ctx)
+				if err != nil {
+					return "", err
+				}
+				if !perms.Push {
+					return "", fmt.Errorf("token cannot push to %s/%s", env.Owner, env.Repo)
+				}
+				return "push allowed", nil
+			},
+		},
+		{
+			Name: "Anthropic API key",
+			Hint: "Set ANTHROPIC_API_KEY or --claude-api-key with a valid key",
+			Run: func(ctx context.Context) (string, error) {
+				if env.ClaudeAPIKey == "" {
+					return "", fmt.Errorf("not set")
+				}
+				if err := env.Claude.Ping(ctx); err != nil {
+					return "", err
+				}
+				return "valid", nil
+			},
+		},
+	}
+
+	if env.Issues != nil {
+		checks = append(checks, doctorCheck{
+			Name: "Issue repository",
+			Hint: "Check --owner/--repo and that the token can read issues in that repository",
+			Run: func(ctx context.Context) (string, error) {
+				if _, err := env.Issues.GetDefaultBranch(ctx); err != nil {
+					return "", err
+				}
+				return env.IssueRepo + " accessible", nil
+			},
+		})
+	}
+
+	if env.GatewayURL != "" {
+		checks = append(checks, doctorCheck{
+			Name: "Gateway",
+			Hint: "Check ANTHROPIC_BASE_URL and that the gateway is running (make docker-up)",
+			Run: func(ctx context.Context) (string, error) {
+				return checkReachable(ctx, env.GatewayURL)
+			},
+		})
+	}
+
+	if env.Worker != nil {
+		checks = append(checks, doctorCheck{
+			Name: "Worker",
+			Hint: "Check --worker-url/--worker-token and that the worker is running (make docker-up)",
+			Run: func(ctx context.Context) (string, error) {
+				if err := env.Worker.Ping(ctx); err != nil {
+					return "", err
+				}
+				return "healthy", nil
+			},
+		})
+	}
+
+	return checks
+}
+
+// runDoctorChecks runs each check, prints a checklist to w and returns the number of failures
+func runDoctorChecks(ctx context.Context, w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		detail, err := check.Run(ctx)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "%s %s: %v\n", ui.Error(), check.Name, err)
+			if check.Hint != "" {
+				fmt.Fprintf(w, "    %s %s\n", ui.Arrow(), check.Hint)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", ui.Success(), check.Name, detail)
+	}
+	return failed
+}
+
+// gitVersion reports the installed git version
+func gitVersion(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("running git --version: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkReachable reports whether baseURL answers HTTP requests at /health
+func checkReachable(ctx context.Context, baseURL string) (string, error) {
+	url := strings.TrimSuffix(baseURL, "/") + "/health"
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := (&http.Client{Transport: httpclient.Transport()}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	// Any HTTP answer means the endpoint is up; not every proxy implements /health
+	return fmt.Sprintf("%s reachable (%s)", baseURL, resp.Status), nil
+}
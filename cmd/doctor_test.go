@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+	"vibe-git/internal/worker"
+)
+
+// stubServices serves the GitHub, Anthropic, gateway and worker endpoints used by doctor.
+// Requests carrying a token other than "good" are rejected.
+func stubServices(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := r.Header.Get("Authorization") == "Bearer good" ||
+			r.Header.Get("X-Api-Key") == "good" ||
+			r.Header.Get("X-Worker-Auth") == "good"
+
+		switch {
+		case r.URL.Path == "/health":
+			w.Write([]byte(`{"status":"healthy"}`))
+		case !authorized:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+		case r.URL.Path == "/user":
+			w.Write([]byte(`{"login":"octocat"}`))
+		case r.URL.Path == "/repos/myorg/myproject":
+			w.Write([]byte(`{"default_branch":"main","permissions":{"push":true,"pull":true}}`))
+		case r.URL.Path == "/repos/myorg/readonly":
+			w.Write([]byte(`{"default_branch":"main","permissions":{"push":false,"pull":true}}`))
+		case r.URL.Path == "/v1/models":
+			w.Write([]byte(`{"data":[{"id":"claude-3-5-sonnet-latest"}]}`))
+		case r.URL.Path == "/project/info":
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newDoctorEnv(serverURL, token, repo string) doctorEnv {
+	gh := github.NewClient(token, "myorg", repo)
+	gh.SetBaseURL(serverURL)
+	return doctorEnv{
+		GitHub:       gh,
+		Claude:       claude.NewClient(token, serverURL, "test-model"),
+		GatewayURL:   serverURL,
+		Worker:       worker.NewClient(serverURL, token),
+		GitHubToken:  token,
+		ClaudeAPIKey: token,
+		Owner:        "myorg",
+		Repo:         repo,
+	}
+}
+
+func TestDoctorAllChecksPass(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	server := stubServices(t)
+
+	var out bytes.Buffer
+	failed := runDoctorChecks(context.Background(), &out, doctorChecks(newDoctorEnv(server.URL, "good", "myproject")))
+	if failed != 0 {
+		t.Fatalf("expected all checks to pass, got %d failures:\n%s", failed, out.String())
+	}
+
+	for _, want := range []string{
+		"✓ git: git version",
+		"✓ GitHub token: authenticated as octocat",
+		"✓ GitHub repository: myorg/myproject (default branch main)",
+		"✓ Push access: push allowed",
+		"✓ Anthropic API key: valid",
+		"✓ Gateway:",
+		"✓ Worker: healthy",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDoctorReportsBadCredentials(t *testing.T) {
+	server := stubServices(t)
+
+	var out bytes.Buffer
+	env := newDoctorEnv(server.URL, "bad", "myproject")
+	failed := runDoctorChecks(context.Background(), &out, doctorChecks(env)[1:])
+
+	// Token, repository, push, Anthropic key and worker fail; the gateway is still reachable
+	if failed != 5 {
+		t.Errorf("expected 5 failures, got %d:\n%s", failed, out.String())
+	}
+	for _, want := range []string{
+		"✗ GitHub token: API error (401)",
+		"→ Create a token with repo scope",
+		"✗ Anthropic API key: API error (401)",
+		"→ Set ANTHROPIC_API_KEY",
+		"✓ Gateway:",
+		"✗ Worker:",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDoctorReportsMissingRepository(t *testing.T) {
+	server := stubServices(t)
+
+	var out bytes.Buffer
+	env := newDoctorEnv(server.URL, "good", "missing")
+	runDoctorChecks(context.Background(), &out, doctorChecks(env))
+
+	if !strings.Contains(out.String(), "✗ GitHub repository: API error (404)") {
+		t.Errorf("expected repository check to fail, got:\n%s", out.String())
+	}
+}
+
+func TestDoctorReportsMissingPushAccess(t *testing.T) {
+	server := stubServices(t)
+
+	var out bytes.Buffer
+	env := newDoctorEnv(server.URL, "good", "readonly")
+	if failed := runDoctorChecks(context.Background(), &out, doctorChecks(env)[1:]); failed != 1 {
+		t.Errorf("expected only the push check to fail, got %d:\n%s", failed, out.String())
+	}
+	for _, want := range []string{
+		"✗ Push access: token cannot push to myorg/readonly",
+		"→ Grant the token write access",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDoctorReportsMissingSettings(t *testing.T) {
+	var out bytes.Buffer
+	env := doctorEnv{
+		GitHub: github.NewClient("", "", ""),
+		Claude: claude.NewClient("", "", "test-model"),
+	}
+	runDoctorChecks(context.Background(), &out, doctorChecks(env)[1:])
+
+	for _, want := range []string{
+		"✗ GitHub token: not set",
+		"✗ GitHub repository: --owner and --repo not set",
+		"✗ Anthropic API key: not set",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Gateway") || strings.Contains(out.String(), "Worker") {
+		t.Errorf("gateway and worker checks should only run when configured:\n%s", out.String())
+	}
+}
+
+func TestDoctorGitMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	var out bytes.Buffer
+	if failed := runDoctorChecks(context.Background(), &out, doctorChecks(doctorEnv{})[:1]); failed != 1 {
+		t.Fatalf("expected git check to fail, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "→ Install git") {
+		t.Errorf("expected remediation hint, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+	"vibe-git/internal/worker"
+)
+
+// Exit codes returned by vibe-git so scripts can tell outcomes apart
+const (
+	ExitOK         = 0
+	ExitError      = 1 // Any failure not covered below
+	ExitUsage      = 2 // Invalid command, flags or arguments
+	ExitAuth       = 3 // GitHub, Anthropic or worker rejected the credentials
+	ExitPartial    = 4 // Some issues succeeded and some failed
+	ExitGeneration = 5 // Claude failed to generate usable code
+	ExitConflict   = 6 // A merge conflict could not be resolved
+)
+
+// exitError attaches an exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode tags err with an exit code; nil stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode maps an error returned by Execute to a process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var ee *exitError
+	found := errors.As(err, &ee)
+
+	// A partial run stays partial even if one of the failures was an auth error;
+	// otherwise rejected credentials win over how the failure was tagged
+	if found && ee.code == ExitPartial {
+		return ExitPartial
+	}
+	if isAuthError(err) {
+		return ExitAuth
+	}
+	if found {
+		return ee.code
+	}
+	return ExitError
+}
+
+// isAuthError reports whether err comes from rejected credentials
+func isAuthError(err error) bool {
+	var ghErr *github.APIError
+	if errors.As(err, &ghErr) && ghErr.IsAuthError() {
+		return true
+	}
+	var clErr *claude.APIError
+	if errors.As(err, &clErr) && clErr.IsAuthError() {
+		return true
+	}
+	return errors.Is(err, worker.ErrUnauthorized)
+}
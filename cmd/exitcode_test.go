@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+	"vibe-git/internal/worker"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, ExitOK},
+		{"generic", errors.New("boom"), ExitError},
+		{"usage", withExitCode(ExitUsage, errors.New("no command specified")), ExitUsage},
+		{"github unauthorized", fmt.Errorf("fetching issue: %w", &github.APIError{StatusCode: 401}), ExitAuth},
+		{"github forbidden", fmt.Errorf("creating PR: %w", &github.APIError{StatusCode: 403}), ExitAuth},
+		{"github not found", fmt.Errorf("fetching issue: %w", &github.APIError{StatusCode: 404}), ExitError},
+		{"worker unauthorized", fmt.Errorf("worker rejected token: %w", worker.ErrUnauthorized), ExitAuth},
+		{
+			"claude auth beats generation tag",
+			withExitCode(ExitGeneration, fmt.Errorf("generating code: %w", &claude.APIError{StatusCode: 401})),
+			ExitAuth,
+		},
+		{"generation", withExitCode(ExitGeneration, fmt.Errorf("generating code: %w", &claude.APIError{StatusCode: 500})), ExitGeneration},
+		{"conflict", withExitCode(ExitConflict, errors.New("resolving merge conflicts")), ExitConflict},
+		{
+			"partial beats auth",
+			withExitCode(ExitPartial, fmt.Errorf("1 of 2 issue(s) failed: %w", &github.APIError{StatusCode: 403})),
+			ExitPartial,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessIssuesExitCodes(t *testing.T) {
+	orig := failFast
+	t.Cleanup(func() { failFast = orig })
+	failFast = false
+
+	generationFailure := func(issueNum int) error {
+		if issueNum == 2 {
+			return withExitCode(ExitGeneration, errors.New("generating code: bad JSON"))
+		}
+		return nil
+	}
+
+	if code := ExitCode(processIssues([]int{1, 2}, nil, generationFailure)); code != ExitPartial {
+		t.Errorf("expected partial exit code when one issue succeeds, got %d", code)
+	}
+	if code := ExitCode(processIssues([]int{2}, nil, generationFailure)); code != ExitGeneration {
+		t.Errorf("expected generation exit code when the only issue fails, got %d", code)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"vibe-git/internal/github"
+	"vibe-git/internal/ui"
+)
+
+var (
+	gracePeriod time.Duration      // --grace-period; 0 processes watched issues right away
+	graceCheck  = 30 * time.Second // How often comments are checked for /cancel during the grace period
+	cancelLine  = regexp.MustCompile(`(?m)^[ \t]*/cancel[ \t\r]*$`)
+)
+
+// maintainerRoles are the comment author associations whose /cancel is honored
+var maintainerRoles = map[string]bool{"OWNER": true, "MEMBER": true, "COLLABORATOR": true}
+
+// errCancelled is what awaitGracePeriods reports for an issue a maintainer cancelled
+var errCancelled = errors.New("cancelled by a maintainer")
+
+// awaitGracePeriod announces on the issue that vibe-git will start on it once
+// --grace-period has passed, then waits, watching for a maintainer's /cancel.
+// It reports false if the issue was cancelled.
+func awaitGracePeriod(ctx context.Context, issues *github.Client, issue *github.Issue) (bool, error) {
+	err := awaitGracePeriods(ctx, issues, []*github.Issue{issue})[issue.Number]
+	if errors.Is(err, errCancelled) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// awaitGracePeriods announces on each issue that vibe-git will start on it once
+// --grace-period has passed, then waits out one shared period, watching for a
+// maintainer's /cancel. It maps the issues that must not be processed to
+// errCancelled or the error that ended their wait. A /cancel anywhere on an
+// issue counts, including one left before the announcement.
+func awaitGracePeriods(ctx context.Context, issues *github.Client, list []*github.Issue) map[int]error {
+	results := make(map[int]error)
+	if gracePeriod <= 0 || len(list) == 0 {
+		return results
+	}
+
+	var waiting []*github.Issue
+	for _, issue := range list {
+		if by, err := cancelledBy(ctx, issues, issue.Number); err == nil && by != "" {
+			fmt.Printf("  Skipping issue #%d: cancelled by @%s\n", issue.Number, by)
+			results[issue.Number] = errCancelled
+			continue
+		}
+		announcement := fmt.Sprintf("vibe-git will start working on this issue in %s. A maintainer can reply `/cancel` to stop it.", gracePeriod)
+		if err := issues.AddIssueComment(ctx, issue.Number, announcement); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Failed to announce issue #%d: %v\n", ui.Warn(), issue.Number, err)
+		}
+		waiting = append(waiting, issue)
+	}
+	if len(waiting) == 0 {
+		return results
+	}
+	numbers := make([]string, len(waiting))
+	for i, issue := range waiting {
+		numbers[i] = fmt.Sprintf("#%d", issue.Number)
+	}
+	fmt.Printf("  Waiting %s for a /cancel on issue %s...\n", gracePeriod, strings.Join(numbers, ", "))
+
+	// check drops the waiting issues that were cancelled. On the final check
+	// an issue that can't be checked doesn't start either, or a late /cancel
+	// could be missed.
+	check := func(final bool) {
+		still := waiting[:0]
+		for _, issue := range waiting {
+			by, err := cancelledBy(ctx, issues, issue.Number)
+			switch {
+			case err != nil && final:
+				results[issue.Number] = fmt.Errorf("checking for /cancel: %w", err)
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "  %s Could not check issue #%d for /cancel: %v\n", ui.Warn(), issue.Number, err)
+				still = append(still, issue)
+			case by != "":
+				acknowledgeCancel(ctx, issues, issue, by)
+				results[issue.Number] = errCancelled
+			default:
+				still = append(still, issue)
+			}
+		}
+		waiting = still
+	}
+
+	deadline := time.NewTimer(gracePeriod)
+	defer deadline.Stop()
+	ticker := time.NewTicker(graceCheck)
+	defer ticker.Stop()
+
+	for len(waiting) > 0 {
+		select {
+		case <-ctx.Done():
+			for _, issue := range waiting {
+				results[issue.Number] = ctx.Err()
+			}
+			return results
+		case <-ticker.C:
+			check(false)
+		case <-deadline.C:
+			check(true)
+			return results
+		}
+	}
+	return results
+}
+
+// cancelledBy returns the login of the first maintainer who commented /cancel
+// on the issue, or "" if none has
+func cancelledBy(ctx context.Context, issues *github.Client, number int) (string, error) {
+	comments, err := issues.ListIssueComments(ctx, number)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range comments {
+		if maintainerRoles[c.Association] && cancelLine.MatchString(c.Body) {
+			return c.Author, nil
+		}
+	}
+	return "", nil
+}
+
+// acknowledgeCancel confirms on the issue that it won't be worked on
+func acknowledgeCancel(ctx context.Context, issues *github.Client, issue *github.Issue, by string) {
+	fmt.Printf("  %s Issue #%d cancelled by @%s\n", ui.Warn(), issue.Number, by)
+	if err := issues.AddIssueComment(ctx, issue.Number, fmt.Sprintf("Cancelled by @%s; vibe-git won't work on this issue.", by)); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s Failed to comment on issue #%d: %v\n", ui.Warn(), issue.Number, err)
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vibe-git/internal/github"
+)
+
+// withGracePeriod sets --grace-period and a short comment check interval for the test
+func withGracePeriod(t *testing.T, d time.Duration) {
+	t.Helper()
+	origPeriod, origCheck := gracePeriod, graceCheck
+	t.Cleanup(func() { gracePeriod, graceCheck = origPeriod, origCheck })
+	gracePeriod, graceCheck = d, 10*time.Millisecond
+}
+
+// graceStub serves the comments on issue 7, adding cancel to them once the
+// announcement has been posted, and records the comments vibe-git posts
+func graceStub(t *testing.T, cancel string) (*github.Client, func() []string) {
+	var mu sync.Mutex
+	var posted []string
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/repos/owner/repo/issues/7/comments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var body struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&body)
+			posted = append(posted, body.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+			return
+		}
+		if len(posted) == 0 || cancel == "" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "body": "Looks good"}, ` + cancel + `]`))
+	})
+	return gh, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), posted...)
+	}
+}
+
+func TestAwaitGracePeriodCancelled(t *testing.T) {
+	withGracePeriod(t, time.Minute)
+	gh, posted := graceStub(t, `{"id": 2, "body": "/cancel", "user": {"login": "maintainer"}, "author_association": "MEMBER"}`)
+
+	var proceed bool
+	var err error
+	start := time.Now()
+	captureStdout(t, func() {
+		proceed, err = awaitGracePeriod(context.Background(), gh, &github.Issue{Number: 7})
+	})
+
+	if err != nil || proceed {
+		t.Fatalf("expected the issue to be cancelled, got proceed=%v err=%v", proceed, err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("expected the cancellation to end the wait early")
+	}
+	comments := posted()
+	if len(comments) != 2 || !strings.Contains(comments[0], "in 1m0s") || !strings.Contains(comments[1], "Cancelled by @maintainer") {
+		t.Errorf("expected an announcement and a confirmation, got %q", comments)
+	}
+}
+
+func TestAwaitGracePeriodIgnoresNonMaintainers(t *testing.T) {
+	withGracePeriod(t, 50*time.Millisecond)
+	gh, posted := graceStub(t, `{"id": 2, "body": "/cancel", "user": {"login": "drive-by"}, "author_association": "NONE"}`)
+
+	var proceed bool
+	var err error
+	captureStdout(t, func() {
+		proceed, err = awaitGracePeriod(context.Background(), gh, &github.Issue{Number: 7})
+	})
+
+	if err != nil || !proceed {
+		t.Fatalf("expected processing to go ahead, got proceed=%v err=%v", proceed, err)
+	}
+	if comments := posted(); len(comments) != 1 {
+		t.Errorf("expected only the announcement, got %q", comments)
+	}
+}
+
+func TestCheckIssuesHonorsCancel(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	withGracePeriod(t, 50*time.Millisecond)
+
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues":
+			w.Write([]byte(`[{"number": 1, "title": "Cancelled", "state": "open"}, {"number": 2, "title": "Go ahead", "state": "open"}]`))
+		case r.URL.Path == "/repos/owner/repo/pulls":
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/repos/owner/repo/issues/1/comments":
+			w.Write([]byte(`[{"id": 3, "body": "/cancel", "user": {"login": "owner"}, "author_association": "OWNER"}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	})
+
+	var processed []int
+	captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if len(processed) != 1 || processed[0] != 2 {
+		t.Errorf("expected only issue #2 to be processed, got %v", processed)
+	}
+}
+
+func TestCheckIssuesSharesGracePeriod(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	withGracePeriod(t, 200*time.Millisecond)
+
+	var mu sync.Mutex
+	var announced []int
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/issues":
+			w.Write([]byte(`[{"number": 1, "title": "One", "state": "open"}, {"number": 2, "title": "Two", "state": "open"}, {"number": 3, "title": "Three", "state": "open"}]`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+			var number int
+			fmt.Sscanf(r.URL.Path, "/repos/owner/repo/issues/%d/comments", &number)
+			mu.Lock()
+			announced = append(announced, number)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	})
+
+	var processed []int
+	start := time.Now()
+	captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(announced) != 3 {
+				t.Errorf("issue #%d processed before every issue was announced: %v", issue.Number, announced)
+			}
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if elapsed := time.Since(start); elapsed >= 400*time.Millisecond {
+		t.Errorf("expected one shared grace period, took %s", elapsed)
+	}
+	if fmt.Sprint(processed) != "[1 2 3]" {
+		t.Errorf("expected the issues processed in order, got %v", processed)
+	}
+}
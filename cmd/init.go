@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/ui"
+)
+
+// scaffoldFile is a file written by `vibe-git init`, relative to the repo root
+type scaffoldFile struct {
+	Path    string
+	Content string
+}
+
+// runInit writes the starter files of scaffoldFiles into a checkout, leaving
+// any that already exist alone
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	var dir string
+	fs.StringVar(&dir, "dir", ".", "Root of the checkout to write the files into")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("parsing flags: %w", err))
+	}
+
+	// Execute detected them in the current directory, which --dir may not be
+	owner, name := repoOwner, repoName
+	if owner == "" || name == "" || repoFromRemote {
+		detectedOwner, detectedName, err := detectRepo(dir)
+		if err != nil {
+			fmt.Printf("%s Couldn't detect the repository (%v); fill in owner and repo in %s\n", ui.Warn(), err, configFile)
+		} else {
+			owner, name = detectedOwner, detectedName
+		}
+	}
+
+	written, err := writeScaffold(dir, scaffoldFiles(owner, name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d file(s)\n", written)
+	return nil
+}
+
+// writeScaffold writes files under dir, skipping those that already exist,
+// and returns how many it wrote
+func writeScaffold(dir string, files []scaffoldFile) (int, error) {
+	written := 0
+	for _, f := range files {
+		path := filepath.Join(dir, f.Path)
+		if _, err := os.Lstat(path); err == nil {
+			fmt.Printf("%s Skipping %s: it already exists\n", ui.Warn(), f.Path)
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return written, fmt.Errorf("checking %s: %w", f.Path, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return written, fmt.Errorf("creating directory for %s: %w", f.Path, err)
+		}
+		// O_EXCL so a file created since the check isn't clobbered either
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return written, fmt.Errorf("writing %s: %w", f.Path, err)
+		}
+		_, err = out.WriteString(f.Content)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return written, fmt.Errorf("writing %s: %w", f.Path, err)
+		}
+		fmt.Printf("%s Created %s\n", ui.Success(), f.Path)
+		written++
+	}
+	return written, nil
+}
+
+// scaffoldFiles are the files `vibe-git init` writes for owner/name; either
+// may be empty when the repository couldn't be detected
+func scaffoldFiles(owner, name string) []scaffoldFile {
+	return []scaffoldFile{
+		{configFile, scaffoldConfig(owner, name)},
+		{claude.PromptPrefixFile, scaffoldInstructions},
+		{ctxloader.IgnoreFile, scaffoldIgnore},
+		{".github/ISSUE_TEMPLATE/vibe-git.md", scaffoldIssueTemplate},
+	}
+}
+
+// scaffoldConfig is a commented configFile for owner/name whose keys are the
+// flags new users most often reach for, set to their defaults
+func scaffoldConfig(owner, name string) string {
+	repoLines := fmt.Sprintf("owner: %s\nrepo: %s\n", owner, name)
+	if owner == "" || name == "" {
+		repoLines = "# owner: myorg\n# repo: myproject\n"
+	}
+	return `# vibe-git settings for this repository.
+#
+# Each key is a command-line flag: "max-changed-files: 20" stands for
+# --max-changed-files 20. vibe-git reads this file from the directory it runs
+# in, and flags given on the command line win. Run vibe-git --help for every
+# flag.
+
+# The repository issues are read from and PRs opened against
+` + repoLines + `base: main
+model: ` + claude.DefaultModel + `
+
+# Pull requests
+draft: false
+conventional-commits: false
+auto-merge: false
+close-issue: false
+
+# Guards on generated changes: 0 means no limit
+max-changed-files: 0
+max-changed-lines: 0
+apply-mode: ` + applyStrict + `
+secret-scan: ` + secretScanWarn + `
+
+# Watch mode
+watch-mode: webhook
+poll-interval: 5m
+require-label: ""
+grace-period: 0
+`
+}
+
+// scaffoldInstructions stubs the prompt prefix, which goes into every prompt
+const scaffoldInstructions = `Follow the conventions of the surrounding code: naming, error handling,
+comments and test layout.
+Keep each change focused on the issue; don't refactor unrelated code.
+Add or update tests for the behavior you change.
+`
+
+// scaffoldIgnore keeps common credential files away from the model
+const scaffoldIgnore = `# Files vibe-git never sends to the model, in .gitignore syntax
+.env
+.env.*
+*.pem
+*.key
+`
+
+// scaffoldIssueTemplate is a GitHub issue template for work meant for
+// vibe-git. Its HTML comments are stripped before the body reaches the model.
+const scaffoldIssueTemplate = `---
+name: Task for vibe-git
+about: Describe a change for vibe-git to implement
+---
+
+## What should change
+
+<!-- Describe the behavior you want. Reference files with @path/to/file. -->
+
+## Acceptance criteria
+
+<!-- How will we know it's done? Which tests should cover it? -->
+`
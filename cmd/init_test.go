@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunInitCreatesFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"remote", "add", "origin", "git@github.com:myorg/myproject.git"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	var err error
+	captureStdout(t, func() {
+		err = runInit([]string{"--dir", dir})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, f := range scaffoldFiles("", "") {
+		if _, err := os.Stat(filepath.Join(dir, f.Path)); err != nil {
+			t.Errorf("expected %s to be created: %v", f.Path, err)
+		}
+	}
+	config, err := os.ReadFile(filepath.Join(dir, ".vibe-git.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "owner: myorg\nrepo: myproject\n") {
+		t.Errorf("expected owner and repo from the origin remote, got:\n%s", config)
+	}
+}
+
+func TestWriteScaffoldDoesNotClobber(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, ".vibe-git.yml")
+	if err := os.WriteFile(existing, []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var written int
+	var err error
+	out := captureStdout(t, func() {
+		written, err = writeScaffold(dir, scaffoldFiles("myorg", "myproject"))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := len(scaffoldFiles("", "")) - 1; written != want {
+		t.Errorf("expected %d files written, got %d", want, written)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "mine\n" {
+		t.Errorf("existing .vibe-git.yml was overwritten: %q", content)
+	}
+	if !strings.Contains(out, "Skipping .vibe-git.yml: it already exists") {
+		t.Errorf("expected a skip message, got %q", out)
+	}
+}
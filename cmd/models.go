@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ui"
+)
+
+// modelsCacheTTL is how long `vibe-git models` reuses a fetched list
+const modelsCacheTTL = 24 * time.Hour
+
+// modelsCachePath returns where the model list is cached (replaced in tests)
+var modelsCachePath = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "vibe-git", "models.json"), nil
+}
+
+// modelsCache is a fetched model list and the API it came from
+type modelsCache struct {
+	BaseURL   string         `json:"base_url"`
+	FetchedAt time.Time      `json:"fetched_at"`
+	Models    []claude.Model `json:"models"`
+}
+
+// runModels prints the models the API offers, so --model values can be checked
+func runModels(args []string) error {
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	var refresh bool
+	fs.BoolVar(&refresh, "refresh", false, "Fetch the list even if the cached one is less than a day old")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("parsing flags: %w", err))
+	}
+	if claudeAPIKey == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	cl := claude.NewClient(claudeAPIKey, baseURL, model)
+	cache, err := loadModels(ctx, cl, baseURL, refresh)
+	if err != nil {
+		return fmt.Errorf("listing models: %w", err)
+	}
+
+	printModels(ctx, os.Stdout, cl, cache.Models, model)
+	if age := time.Since(cache.FetchedAt); age > time.Minute {
+		fmt.Printf("\nFetched %s ago; pass --refresh to update\n", age.Round(time.Minute))
+	}
+	return nil
+}
+
+// loadModels returns the cached model list for baseURL if it is fresh, and
+// otherwise fetches and caches it. A cache that can't be read or written only
+// costs a fetch.
+func loadModels(ctx context.Context, cl *claude.Client, baseURL string, refresh bool) (*modelsCache, error) {
+	path, pathErr := modelsCachePath()
+	if !refresh && pathErr == nil {
+		var cache modelsCache
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cache) == nil &&
+			cache.BaseURL == baseURL && time.Since(cache.FetchedAt) < modelsCacheTTL {
+			return &cache, nil
+		}
+	}
+
+	models, err := cl.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cache := &modelsCache{BaseURL: baseURL, FetchedAt: time.Now(), Models: models}
+
+	if pathErr == nil {
+		data, _ := json.MarshalIndent(cache, "", "  ")
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, data, 0644)
+	}
+	return cache, nil
+}
+
+// printModels lists models, marking current (the --model value). A current
+// missing from the list, such as an alias, is looked up so the model it
+// resolves to is marked instead; vibe-git warns only if the API doesn't know it.
+func printModels(ctx context.Context, w io.Writer, cl *claude.Client, models []claude.Model, current string) {
+	listed := false
+	for _, m := range models {
+		listed = listed || m.ID == current
+	}
+	marked, note := current, ""
+	if current != "" && !listed {
+		resolved, err := cl.GetModel(ctx, current)
+		var apiErr *claude.APIError
+		switch {
+		case err == nil:
+			marked, note = resolved.ID, fmt.Sprintf("\n--model %s resolves to %s\n", current, resolved.ID)
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			note = fmt.Sprintf("\n%s --model %s is not offered by the API; requests with it will fail\n", ui.Warn(), current)
+		default:
+			note = fmt.Sprintf("\n%s Could not check --model %s: %v\n", ui.Warn(), current, err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  MODEL\tNAME")
+	for _, m := range models {
+		mark := " "
+		if m.ID == marked {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", mark, m.ID, m.DisplayName)
+	}
+	tw.Flush()
+	fmt.Fprint(w, note)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+)
+
+func TestLoadModelsUsesCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "vibe-git", "models.json")
+	orig := modelsCachePath
+	modelsCachePath = func() (string, error) { return cachePath, nil }
+	t.Cleanup(func() { modelsCachePath = orig })
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"data": [{"id": "claude-sonnet", "display_name": "Claude Sonnet"}], "has_more": false}`)
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, "")
+	ctx := context.Background()
+
+	for i, tc := range []struct {
+		baseURL   string
+		refresh   bool
+		wantCalls int
+	}{
+		{server.URL, false, 1},              // Nothing cached yet
+		{server.URL, false, 1},              // Served from the cache
+		{server.URL, true, 2},               // --refresh
+		{"https://other.example", false, 3}, // Cached for another API
+	} {
+		cache, err := loadModels(ctx, cl, tc.baseURL, tc.refresh)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if len(cache.Models) != 1 || cache.Models[0].ID != "claude-sonnet" {
+			t.Errorf("call %d: unexpected models %+v", i, cache.Models)
+		}
+		if calls != tc.wantCalls {
+			t.Errorf("call %d: expected %d fetches, got %d", i, tc.wantCalls, calls)
+		}
+	}
+}
+
+func TestPrintModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models/claude-3-5-sonnet-latest":
+			fmt.Fprint(w, `{"id": "claude-3-5-sonnet-20241022"}`)
+		case "/v1/models/claude-flaky":
+			http.Error(w, `{"type":"error"}`, http.StatusBadGateway)
+		default:
+			http.Error(w, `{"type":"error","error":{"type":"not_found_error"}}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, "")
+	ctx := context.Background()
+	models := []claude.Model{
+		{ID: "claude-3-5-sonnet-20241022", DisplayName: "Claude 3.5 Sonnet", CreatedAt: time.Now()},
+		{ID: "claude-3-5-haiku-20241022", DisplayName: "Claude 3.5 Haiku"},
+	}
+
+	var out strings.Builder
+	printModels(ctx, &out, cl, models, "claude-3-5-haiku-20241022")
+	want := "" +
+		"  MODEL                       NAME\n" +
+		"  claude-3-5-sonnet-20241022  Claude 3.5 Sonnet\n" +
+		"* claude-3-5-haiku-20241022   Claude 3.5 Haiku\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	// The default --model is an alias the list never contains
+	out.Reset()
+	printModels(ctx, &out, cl, models, "claude-3-5-sonnet-latest")
+	if got := out.String(); !strings.Contains(got, "* claude-3-5-sonnet-20241022") ||
+		!strings.Contains(got, "resolves to claude-3-5-sonnet-20241022") || strings.Contains(got, "will fail") {
+		t.Errorf("expected the alias to mark the model it resolves to, got:\n%s", got)
+	}
+
+	out.Reset()
+	printModels(ctx, &out, cl, models, "claude-3-5-sonet-latest")
+	if !strings.Contains(out.String(), "--model claude-3-5-sonet-latest is not offered") {
+		t.Errorf("expected a warning about the unknown model, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	printModels(ctx, &out, cl, models, "claude-flaky")
+	if got := out.String(); !strings.Contains(got, "Could not check --model claude-flaky") || strings.Contains(got, "will fail") {
+		t.Errorf("expected a failed lookup to only warn, got:\n%s", got)
+	}
+}
+
+// withModelMap sets --model and --model-map for the test
+func withModelMap(t *testing.T, base, mapping string) {
+	t.Helper()
+	origModel, origMappings := model, modelMappings
+	t.Cleanup(func() { model, modelMappings = origModel, origMappings })
+	var err error
+	model = base
+	if modelMappings, err = parseModelMap(mapping); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIssueClaudeUsesMappedModel(t *testing.T) {
+	withModelMap(t, "claude-haiku", "docs=claude-haiku, Complex=claude-opus")
+
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model string }
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = append(sent, req.Model)
+		fmt.Fprint(w, `{"content": [{"type": "text", "text": "- Done"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, model)
+
+	hard := &github.Issue{Number: 1, Labels: []string{"bug", "complex"}}
+	if got := issueModel(hard); got != "claude-opus" {
+		t.Errorf("expected the complex label to select claude-opus, got %s", got)
+	}
+	if got := issueModel(&github.Issue{Number: 2, Labels: []string{"bug"}}); got != "claude-haiku" {
+		t.Errorf("expected unmapped labels to fall back to --model, got %s", got)
+	}
+
+	changes := []claude.FileChange{{Path: "a.go", Operation: "modify"}}
+	captureStdout(t, func() {
+		if _, err := issueClaude(cl, hard, "").Summarize(context.Background(), changes); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.Summarize(context.Background(), changes); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if strings.Join(sent, ",") != "claude-opus,claude-haiku" {
+		t.Errorf("expected the mapped model without changing the shared client, got %v", sent)
+	}
+}
+
+func TestValidateModelMap(t *testing.T) {
+	orig := modelsCachePath
+	cachePath := filepath.Join(t.TempDir(), "models.json")
+	modelsCachePath = func() (string, error) { return cachePath, nil }
+	t.Cleanup(func() { modelsCachePath = orig })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"data": [{"id": "claude-haiku"}, {"id": "claude-opus-4-1-20250805"}], "has_more": false}`)
+		case "/v1/models/claude-opus-4-1", "/v1/models/claude-opus-latest":
+			fmt.Fprint(w, `{"id": "claude-opus-4-1-20250805"}`)
+		case "/v1/models/claude-flaky":
+			http.Error(w, `{"type":"error"}`, http.StatusBadGateway)
+		default:
+			http.Error(w, `{"type":"error","error":{"type":"not_found_error"}}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+	cl := claude.NewClient("key", server.URL, "claude-haiku")
+
+	// Listed IDs and aliases the API resolves both pass
+	withModelMap(t, "claude-haiku", "complex=claude-opus-4-1-20250805,hard=claude-opus-4-1,epic=claude-opus-latest")
+	if err := validateModelMap(context.Background(), cl); err != nil {
+		t.Errorf("expected offered models and aliases to pass, got %v", err)
+	}
+
+	// A model that can't be looked up is used with a warning
+	withModelMap(t, "claude-haiku", "complex=claude-flaky")
+	if err := validateModelMap(context.Background(), cl); err != nil {
+		t.Errorf("expected a failed lookup to only warn, got %v", err)
+	}
+
+	withModelMap(t, "claude-haiku", "complex=claude-opsu")
+	err := validateModelMap(context.Background(), cl)
+	if ExitCode(err) != ExitUsage || !strings.Contains(err.Error(), "claude-opsu") {
+		t.Errorf("expected a usage error naming the unknown model, got %v", err)
+	}
+
+	if _, err := parseModelMap("complex"); err == nil {
+		t.Error("expected a pair without a model to be rejected")
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	"vibe-git/internal/ui"
+)
+
+// ProgressEventType names a step of processing an issue
+type ProgressEventType string
+
+const (
+	EventBranchCreated  ProgressEventType = "branch_created"
+	EventGeneratingCode ProgressEventType = "generating_code"
+	EventChangesApplied ProgressEventType = "changes_applied"
+	EventBranchPushed   ProgressEventType = "branch_pushed"
+	EventPRCreated      ProgressEventType = "pr_created"
+	EventMerged         ProgressEventType = "merged"
+	EventError          ProgressEventType = "error"
+)
+
+// ProgressEvent is one step of processing an issue. Only the fields that
+// apply to the event's type are set.
+type ProgressEvent struct {
+	Type     ProgressEventType
+	Issue    int
+	Branch   string
+	Base     string // BranchCreated
+	Files    int    // ChangesApplied: how many file changes were applied
+	PRNumber int    // PRCreated, Merged
+	PRURL    string // PRCreated, Merged
+	Existing bool   // PRCreated: the PR was already open and was updated instead
+	Err      error  // Error
+
+	indent string // Prefix for the console's lines
+}
+
+// ProgressReporter receives the progress of processIssue and
+// processIssueWithClients. Worker runs report from PRCreated on, as the
+// worker creates, generates and pushes the branch itself.
+type ProgressReporter interface {
+	Report(ev ProgressEvent)
+}
+
+// progress receives every ProgressEvent; the default prints them to the console
+var progress ProgressReporter = consoleReporter{}
+
+// reportProgress sends ev, printed with indent on the console, to progress
+func reportProgress(ev ProgressEvent, indent string) {
+	ev.indent = indent
+	recordReport(ev)
+	progress.Report(ev)
+}
+
+// consoleReporter prints the progress lines vibe-git has always printed. Steps
+// that already print their own lines, like branch creation and opening the
+// PR, and errors, which the caller prints, stay silent.
+type consoleReporter struct{}
+
+func (consoleReporter) Report(ev ProgressEvent) {
+	switch ev.Type {
+	case EventGeneratingCode:
+		fmt.Printf("%sGenerating code with Claude...\n", ev.indent)
+	case EventMerged:
+		fmt.Printf("%s%s PR merged successfully\n", ev.indent, ui.Success())
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"vibe-git/internal/github"
+)
+
+// recordingReporter keeps every ProgressEvent it receives
+type recordingReporter struct {
+	events []ProgressEvent
+}
+
+func (r *recordingReporter) Report(ev ProgressEvent) {
+	r.events = append(r.events, ev)
+}
+
+func (r *recordingReporter) types() []ProgressEventType {
+	var types []ProgressEventType
+	for _, ev := range r.events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+// withProgress records the progress events for the rest of the test
+func withProgress(t *testing.T) *recordingReporter {
+	t.Helper()
+	orig := progress
+	t.Cleanup(func() { progress = orig })
+	rec := &recordingReporter{}
+	progress = rec
+	return rec
+}
+
+// withMergingGitHub sends the clone's pushes to its local origin and returns
+// a GitHub stub that opens PR #12 for issue #7 and merges it with --auto-merge
+func withMergingGitHub(t *testing.T, clone string) *github.Client {
+	t.Helper()
+	// Pushes go to github.com; send them to the clone's local origin instead
+	origin := runGit(t, clone, "remote", "get-url", "origin")
+	runGit(t, clone, "config", "url."+origin+".insteadOf", "https://@github.com/owner/repo.git")
+
+	origMerge, origWait := autoMerge, waitForChecks
+	t.Cleanup(func() { autoMerge, waitForChecks = origMerge, origWait })
+	autoMerge, waitForChecks = true, false
+
+	return newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/pulls":
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/pulls":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 12, "html_url": "https://github.com/owner/repo/pull/12"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/issues/7/comments":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/repos/owner/repo/pulls/12/merge":
+			w.Write([]byte(`{"merged": true}`))
+		default:
+			issueHandler(w, r)
+		}
+	})
+}
+
+func TestProcessIssueReportsProgress(t *testing.T) {
+	clone, cl, gitClient := newInteractiveIssue(t)
+	rec := withProgress(t)
+	gh := withMergingGitHub(t, clone)
+
+	var err error
+	captureStdout(t, func() {
+		err = processIssue(context.Background(), gh, gh, cl, gitClient, 7)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ProgressEventType{EventBranchCreated, EventGeneratingCode, EventChangesApplied, EventBranchPushed, EventPRCreated, EventMerged}
+	if got := rec.types(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for _, ev := range rec.events {
+		if ev.Issue != 7 || ev.Branch != "vibe-git/issue-7" {
+			t.Errorf("expected %s to be for issue #7 on vibe-git/issue-7, got #%d on %q", ev.Type, ev.Issue, ev.Branch)
+		}
+	}
+	if applied := rec.events[2]; applied.Files != 1 {
+		t.Errorf("expected 1 applied file, got %d", applied.Files)
+	}
+	if created := rec.events[4]; created.PRNumber != 12 || created.PRURL != "https://github.com/owner/repo/pull/12" || created.Existing {
+		t.Errorf("expected PR #12 to be created, got %+v", created)
+	}
+}
+
+func TestProcessIssueReportsErrors(t *testing.T) {
+	rec := withProgress(t)
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	})
+
+	var err error
+	captureStdout(t, func() {
+		err = processIssue(context.Background(), gh, gh, nil, nil, 7)
+	})
+	if err == nil {
+		t.Fatal("expected an error for a missing issue")
+	}
+	if len(rec.events) != 1 || rec.events[0].Type != EventError || !errors.Is(rec.events[0].Err, err) {
+		t.Errorf("expected a single error event, got %+v", rec.events)
+	}
+}
+
+func TestConsoleReporterKeepsOutput(t *testing.T) {
+	out := captureStdout(t, func() {
+		reportProgress(ProgressEvent{Type: EventBranchCreated, Issue: 7}, "  ")
+		reportProgress(ProgressEvent{Type: EventGeneratingCode, Issue: 7}, "  ")
+		reportProgress(ProgressEvent{Type: EventError, Issue: 7, Err: errors.New("boom")}, "  ")
+	})
+	if out != "  Generating code with Claude...\n" {
+		t.Errorf("unexpected console output %q", out)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"vibe-git/internal/github"
+	"vibe-git/internal/ui"
+)
+
+// branchPrefix is the namespace of the branches vibe-git creates
+const branchPrefix = "vibe-git/"
+
+// deleteMergedBranch deletes the branch of a merged PR with --prune-branch.
+// Failing to do so only warns; the merge itself went through.
+func deleteMergedBranch(ctx context.Context, gh *github.Client, branch, indent string) {
+	if !pruneBranch {
+		return
+	}
+	err := gh.DeleteBranch(ctx, branch)
+	var apiErr *github.APIError
+	switch {
+	case err == nil:
+		fmt.Printf("%s%s Deleted branch %s\n", indent, ui.Success(), branch)
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusUnprocessableEntity):
+		// The repository deletes head branches on merge itself
+	default:
+		fmt.Fprintf(os.Stderr, "%s%s Failed to delete branch %s: %v\n", indent, ui.Warn(), branch, err)
+	}
+}
+
+// runPrune deletes the vibe-git branches of the target repository whose PRs
+// have all been merged or closed
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dry-run", false, "List the branches that would be deleted without deleting them")
+	if err := fs.Parse(args); err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("parsing flags: %w", err))
+	}
+	if err := requireGitHubAuth(); err != nil {
+		return err
+	}
+	if repoOwner == "" || repoName == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("repository owner and name required (use --owner and --repo)"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle interrupt signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt, shutting down...")
+		cancel()
+	}()
+
+	deleted, err := pruneBranches(ctx, newGitHubClient(targetOwner, targetName), dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("Would delete %d stale branch(es)\n", deleted)
+	} else {
+		fmt.Printf("Deleted %d stale branch(es)\n", deleted)
+	}
+	return nil
+}
+
+// pruneBranches deletes, or with dryRun only lists, the stale vibe-git
+// branches, returning how many there were
+func pruneBranches(ctx context.Context, gh *github.Client, dryRun bool) (int, error) {
+	branches, err := gh.ListBranches(ctx, branchPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("listing branches: %w", err)
+	}
+
+	deleted := 0
+	for _, branch := range branches {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		prs, err := gh.ListPullRequestsForBranch(ctx, branch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Skipping %s: %v\n", ui.Warn(), branch, err)
+			continue
+		}
+
+		reason, stale := pruneReason(prs)
+		if !stale {
+			fmt.Printf("  Keeping %s: %s\n", branch, reason)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("  Would delete %s: %s\n", branch, reason)
+			deleted++
+			continue
+		}
+		if err := gh.DeleteBranch(ctx, branch); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Failed to delete %s: %v\n", ui.Warn(), branch, err)
+			continue
+		}
+		fmt.Printf("  %s Deleted %s: %s\n", ui.Success(), branch, reason)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// pruneReason decides whether a branch with the given PRs, newest first, can
+// be deleted. Branches with an open PR or none at all may still be in use.
+func pruneReason(prs []*github.PullRequest) (string, bool) {
+	if len(prs) == 0 {
+		return "no PR", false
+	}
+	for _, pr := range prs {
+		if pr.State == "open" {
+			return fmt.Sprintf("PR #%d is open", pr.Number), false
+		}
+	}
+	if prs[0].Merged {
+		return fmt.Sprintf("PR #%d was merged", prs[0].Number), true
+	}
+	return fmt.Sprintf("PR #%d was closed", prs[0].Number), true
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"vibe-git/internal/github"
+)
+
+func TestPruneReason(t *testing.T) {
+	tests := []struct {
+		prs       []*github.PullRequest
+		wantStale bool
+		want      string
+	}{
+		{nil, false, "no PR"},
+		{[]*github.PullRequest{{Number: 4, State: "open"}}, false, "PR #4 is open"},
+		{[]*github.PullRequest{{Number: 5, State: "closed"}, {Number: 4, State: "open"}}, false, "PR #4 is open"},
+		{[]*github.PullRequest{{Number: 5, State: "closed", Merged: true}}, true, "PR #5 was merged"},
+		{[]*github.PullRequest{{Number: 6, State: "closed"}, {Number: 5, State: "closed", Merged: true}}, true, "PR #6 was closed"},
+	}
+	for _, tt := range tests {
+		reason, stale := pruneReason(tt.prs)
+		if reason != tt.want || stale != tt.wantStale {
+			t.Errorf("pruneReason(%d PRs) = %q, %v; want %q, %v", len(tt.prs), reason, stale, tt.want, tt.wantStale)
+		}
+	}
+}
+
+// pruneStub serves vibe-git branches for issues 1-4 with a merged, open,
+// closed and no PR respectively, and records the branches deleted
+func pruneStub(t *testing.T) (*github.Client, func() []string) {
+	var mu sync.Mutex
+	var deleted []string
+	prs := map[string]string{
+		"owner:vibe-git/issue-1": `[{"number": 11, "state": "closed", "merged_at": "2024-05-01T10:00:00Z"}]`,
+		"owner:vibe-git/issue-2": `[{"number": 12, "state": "open"}]`,
+		"owner:vibe-git/issue-3": `[{"number": 13, "state": "closed"}]`,
+		"owner:vibe-git/issue-4": `[]`,
+	}
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/git/matching-refs/heads/vibe-git/":
+			w.Write([]byte(`[{"ref": "refs/heads/vibe-git/issue-1"}, {"ref": "refs/heads/vibe-git/issue-2"},
+				{"ref": "refs/heads/vibe-git/issue-3"}, {"ref": "refs/heads/vibe-git/issue-4"}]`))
+		case r.URL.Path == "/repos/owner/repo/pulls":
+			w.Write([]byte(prs[r.URL.Query().Get("head")]))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/repos/owner/repo/git/refs/heads/"):
+			mu.Lock()
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/git/refs/heads/"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	return gh, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(deleted)
+		return deleted
+	}
+}
+
+func TestPruneBranches(t *testing.T) {
+	gh, deleted := pruneStub(t)
+
+	var n int
+	var err error
+	out := captureStdout(t, func() { n, err = pruneBranches(context.Background(), gh, true) })
+	if err != nil || n != 2 {
+		t.Fatalf("dry run: got %d, %v; want 2 stale branches", n, err)
+	}
+	if len(deleted()) != 0 {
+		t.Errorf("expected a dry run to delete nothing, got %v", deleted())
+	}
+	if !strings.Contains(out, "Would delete vibe-git/issue-1: PR #11 was merged") || !strings.Contains(out, "Keeping vibe-git/issue-4: no PR") {
+		t.Errorf("unexpected dry-run output:\n%s", out)
+	}
+
+	captureStdout(t, func() { n, err = pruneBranches(context.Background(), gh, false) })
+	if err != nil || n != 2 {
+		t.Fatalf("got %d, %v; want 2 deleted branches", n, err)
+	}
+	if want := []string{"vibe-git/issue-1", "vibe-git/issue-3"}; !reflect.DeepEqual(deleted(), want) {
+		t.Errorf("deleted %v, want %v", deleted(), want)
+	}
+}
+
+func TestDeleteMergedBranchNeedsFlag(t *testing.T) {
+	orig := pruneBranch
+	t.Cleanup(func() { pruneBranch = orig })
+	gh, deleted := pruneStub(t)
+
+	captureStdout(t, func() {
+		pruneBranch = false
+		deleteMergedBranch(context.Background(), gh, "vibe-git/issue-1", "")
+		pruneBranch = true
+		deleteMergedBranch(context.Background(), gh, "vibe-git/issue-3", "")
+	})
+	if want := []string{"vibe-git/issue-3"}; !reflect.DeepEqual(deleted(), want) {
+		t.Errorf("deleted %v, want %v", deleted(), want)
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ui"
+)
+
+// reportFile receives a JSON line per processed issue when set (--report)
+var reportFile string
+
+// reportEntry is the line --report appends for an issue
+type reportEntry struct {
+	Time         time.Time `json:"time"` // When processing started
+	Issue        int       `json:"issue"`
+	Model        string    `json:"model,omitempty"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	FilesChanged int       `json:"files_changed"`
+	PRURL        string    `json:"pr_url,omitempty"`
+	Outcome      string    `json:"outcome"` // "success", "declined" or "failed"
+	Error        string    `json:"error,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+}
+
+// issueReport collects the report entry of an issue while it is processed
+type issueReport struct {
+	mu    sync.Mutex
+	entry reportEntry
+}
+
+var (
+	// activeReports are the issues being processed, fed by reportProgress
+	activeReports   = make(map[int]*issueReport)
+	activeReportsMu sync.Mutex
+
+	// reportMu serializes appends to reportFile between concurrent issues
+	reportMu sync.Mutex
+)
+
+// startReport begins the report entry of issueNum; it is nil without --report
+func startReport(issueNum int) *issueReport {
+	if reportFile == "" {
+		return nil
+	}
+	r := &issueReport{entry: reportEntry{Time: time.Now(), Issue: issueNum}}
+	activeReportsMu.Lock()
+	activeReports[issueNum] = r
+	activeReportsMu.Unlock()
+	return r
+}
+
+// recordReport adds what ev tells about its issue to the issue's entry
+func recordReport(ev ProgressEvent) {
+	activeReportsMu.Lock()
+	r := activeReports[ev.Issue]
+	activeReportsMu.Unlock()
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch ev.Type {
+	case EventChangesApplied:
+		r.entry.FilesChanged = ev.Files
+	case EventPRCreated, EventMerged:
+		r.entry.PRURL = ev.PRURL
+	}
+}
+
+// trackUsage returns cl reporting its token usage to r, and sets the model
+func (r *issueReport) trackUsage(cl *claude.Client, model string) *claude.Client {
+	if r == nil || cl == nil {
+		return cl
+	}
+	r.mu.Lock()
+	r.entry.Model = model
+	r.mu.Unlock()
+	return cl.WithUsageReport(func(u claude.Usage) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.entry.InputTokens += u.InputTokens
+		r.entry.OutputTokens += u.OutputTokens
+	})
+}
+
+// finish appends the entry to reportFile with err's outcome. Failing to write
+// only warns; the issue itself was processed.
+func (r *issueReport) finish(err error) {
+	if r == nil {
+		return
+	}
+	activeReportsMu.Lock()
+	if activeReports[r.entry.Issue] == r {
+		delete(activeReports, r.entry.Issue)
+	}
+	activeReportsMu.Unlock()
+
+	r.mu.Lock()
+	entry := r.entry
+	r.mu.Unlock()
+
+	entry.DurationMS = time.Since(entry.Time).Milliseconds()
+	switch {
+	case err == nil:
+		entry.Outcome = "success"
+	case errors.Is(err, errDeclined):
+		entry.Outcome = "declined"
+	default:
+		entry.Outcome = "failed"
+		entry.Error = err.Error()
+	}
+
+	if err := appendReport(reportFile, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "%s Failed to write report: %v\n", ui.Warn(), err)
+	}
+}
+
+// appendReport appends entry to path as one JSON line
+func appendReport(path string, entry reportEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"vibe-git/internal/claude"
+)
+
+// withReportFile turns on --report for the rest of the test
+func withReportFile(t *testing.T) string {
+	t.Helper()
+	orig := reportFile
+	t.Cleanup(func() { reportFile = orig })
+	reportFile = filepath.Join(t.TempDir(), "report.jsonl")
+	return reportFile
+}
+
+// readReport parses every line of the report at path
+func readReport(t *testing.T, path string) []reportEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []reportEntry
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var e reportEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid report line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestReportRecordsProcessedIssue(t *testing.T) {
+	clone, _, gitClient := newInteractiveIssue(t)
+	path := withReportFile(t)
+	gh := withMergingGitHub(t, clone)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": `[{"path":"pkg/new.go","operation":"create","content":"package pkg\n"}]`}},
+			"usage":   map[string]int{"input_tokens": 1200, "output_tokens": 34},
+		})
+	}))
+	t.Cleanup(server.Close)
+	cl := claude.NewClient("key", server.URL, "test-model")
+	cl.SetSkipCodebase(true)
+
+	var err error
+	captureStdout(t, func() {
+		err = processIssue(context.Background(), gh, gh, cl, gitClient, 7)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := readReport(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 report entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Issue != 7 || e.Outcome != "success" || e.Error != "" {
+		t.Errorf("expected a successful entry for issue #7, got %+v", e)
+	}
+	if e.InputTokens != 1200 || e.OutputTokens != 34 {
+		t.Errorf("expected 1200 input and 34 output tokens, got %d and %d", e.InputTokens, e.OutputTokens)
+	}
+	if e.FilesChanged != 1 || e.PRURL != "https://github.com/owner/repo/pull/12" {
+		t.Errorf("expected 1 file and PR #12, got %d and %q", e.FilesChanged, e.PRURL)
+	}
+	if e.Time.IsZero() || e.DurationMS < 0 {
+		t.Errorf("expected a start time and duration, got %v and %d", e.Time, e.DurationMS)
+	}
+}
+
+func TestReportRecordsFailure(t *testing.T) {
+	path := withReportFile(t)
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	captureStdout(t, func() {
+		processIssue(context.Background(), gh, gh, nil, nil, 7)
+	})
+
+	entries := readReport(t, path)
+	if len(entries) != 1 || entries[0].Outcome != "failed" || !strings.Contains(entries[0].Error, "fetching issue") {
+		t.Errorf("expected a failed entry, got %+v", entries)
+	}
+}
+
+func TestAppendReportSerializesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(issue int) {
+			defer wg.Done()
+			entry := reportEntry{Issue: issue, Outcome: "success", Error: strings.Repeat("x", 4096)}
+			if err := appendReport(path, entry); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for _, e := range readReport(t, path) {
+		seen[e.Issue] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 distinct entries, got %d", len(seen))
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"vibe-git/internal/httpclient"
 )
 
 // runRequest handles the HTTP request command
@@ -113,7 +115,7 @@ func runRequest(args []string) error {
 	}
 
 	// Execute request
-	client := &http.Client{Timeout: timeout}
+	client := &http.Client{Timeout: timeout, Transport: httpclient.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("executing request: %w", err)
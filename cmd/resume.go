@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"vibe-git/internal/github"
+	"vibe-git/internal/notify"
+	"vibe-git/internal/ui"
+)
+
+// pendingMerge is an auto-merge that was waiting for CI when vibe-git stopped
+type pendingMerge struct {
+	Repo       string    `json:"repo"`       // owner/name the PR was opened against
+	IssueRepo  string    `json:"issue_repo"` // owner/name the issue lives in
+	PR         int       `json:"pr"`
+	PRURL      string    `json:"pr_url"`
+	Branch     string    `json:"branch"`
+	Issue      int       `json:"issue"`
+	IssueTitle string    `json:"issue_title"`
+	Deadline   time.Time `json:"deadline"` // When the wait gives up, from --merge-timeout
+}
+
+// trackPendingMerge records that the PR for issue is waiting to be merged, so a
+// restarted vibe-git can pick it up. The returned func forgets it again unless ctx
+// was cancelled, i.e. the wait was interrupted rather than finished.
+func trackPendingMerge(ctx context.Context, issue *github.Issue, prNumber int, prURL, branchName string) func() {
+	entry := pendingMerge{
+		Repo:       targetOwner + "/" + targetName,
+		IssueRepo:  repoOwner + "/" + repoName,
+		PR:         prNumber,
+		PRURL:      prURL,
+		Branch:     branchName,
+		Issue:      issue.Number,
+		IssueTitle: issue.Title,
+		Deadline:   time.Now().Add(mergeTimeout),
+	}
+	if err := addPendingMerge(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s Failed to save pending merge: %v\n", ui.Warn(), err)
+	}
+
+	return func() {
+		if ctx.Err() != nil {
+			return
+		}
+		removePendingMerge(entry.Repo, entry.PR)
+	}
+}
+
+// addPendingMerge stores entry, replacing an earlier entry for the same PR
+func addPendingMerge(entry pendingMerge) error {
+	return updateState(func(state *watchState) {
+		state.PendingMerges = append(withoutPendingMerge(state.PendingMerges, entry.Repo, entry.PR), entry)
+	})
+}
+
+// removePendingMerge forgets the pending merge of PR prNumber in repo
+func removePendingMerge(repo string, prNumber int) {
+	if err := updateState(func(state *watchState) {
+		state.PendingMerges = withoutPendingMerge(state.PendingMerges, repo, prNumber)
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s Failed to update pending merges: %v\n", ui.Warn(), err)
+	}
+}
+
+func withoutPendingMerge(entries []pendingMerge, repo string, prNumber int) []pendingMerge {
+	var kept []pendingMerge
+	for _, e := range entries {
+		if e.Repo != repo || e.PR != prNumber {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// runResume finishes auto-merges that were interrupted while waiting for CI
+func runResume() error {
+	if err := requireGitHubAuth(); err != nil {
+		return err
+	}
+	if repoOwner == "" || repoName == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("repository owner and name required (use --owner and --repo)"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle interrupt signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nReceived interrupt, shutting down...")
+		cancel()
+	}()
+
+	issueClient := newGitHubClient(repoOwner, repoName)
+	githubClient := newGitHubClient(targetOwner, targetName)
+
+	if resumePendingMerges(ctx, issueClient, githubClient) == 0 {
+		fmt.Println("No pending merges to resume")
+	}
+	return nil
+}
+
+// resumePendingMerges waits for and merges the pending PRs of the current
+// repositories, returning how many there were
+func resumePendingMerges(ctx context.Context, issues, gh *github.Client) int {
+	repo, issueRepo := targetOwner+"/"+targetName, repoOwner+"/"+repoName
+
+	stateMu.Lock()
+	var pending []pendingMerge
+	for _, e := range readState().PendingMerges {
+		if e.Repo == repo && e.IssueRepo == issueRepo {
+			pending = append(pending, e)
+		}
+	}
+	stateMu.Unlock()
+
+	for _, entry := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		resumePendingMerge(ctx, issues, gh, entry)
+	}
+	return len(pending)
+}
+
+// resumePendingMerge re-enters the wait/merge step for one PR. Conflicts are not
+// resolved here; the PR is left for a manual merge.
+func resumePendingMerge(ctx context.Context, issues, gh *github.Client, entry pendingMerge) {
+	fmt.Printf("\n=== Resuming merge of PR #%d for issue %s ===\n", entry.PR, issueRef(entry.Issue))
+
+	remaining := time.Until(entry.Deadline)
+	if remaining <= 0 {
+		fmt.Fprintf(os.Stderr, "  %s Merge timeout passed while vibe-git was stopped\n", ui.Warn())
+		fmt.Println("  You can merge manually later")
+		removePendingMerge(entry.Repo, entry.PR)
+		return
+	}
+
+	fmt.Printf("  Waiting for CI checks to pass (timeout: %v)...\n", remaining.Round(time.Second))
+	if err := gh.WaitForMergeable(ctx, entry.PR, remaining); err != nil {
+		if ctx.Err() != nil {
+			// Interrupted again; keep the entry for the next run
+			return
+		}
+		fmt.Fprintf(os.Stderr, "  %s Failed to wait for checks: %v\n", ui.Warn(), err)
+		fmt.Println("  You can merge manually later")
+		removePendingMerge(entry.Repo, entry.PR)
+		return
+	}
+
+	fmt.Println("  Merging PR...")
+	prTitle := fmt.Sprintf("Fix %s: %s", issueRef(entry.Issue), entry.IssueTitle)
+	mergeTitle := fmt.Sprintf("Merge: %s", prTitle)
+	mergeMsg := fmt.Sprintf("Auto-merged by vibe-git\n\nFixes %s", issueRef(entry.Issue))
+	if err := gh.MergePullRequest(ctx, entry.PR, mergeTitle, mergeMsg); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "  %s Failed to merge PR: %v\n", ui.Warn(), err)
+		fmt.Println("  You can merge manually later")
+		removePendingMerge(entry.Repo, entry.PR)
+		return
+	}
+	removePendingMerge(entry.Repo, entry.PR)
+
+	fmt.Printf("  %s PR merged successfully\n", ui.Success())
+	sendNotification(ctx, notify.Event{Type: notify.EventPRMerged, IssueNumber: entry.Issue, IssueTitle: entry.IssueTitle, PRURL: entry.PRURL}, "  ")
+	deleteMergedBranch(ctx, gh, entry.Branch, "  ")
+
+	if closeIssue {
+		fmt.Println("  Closing issue...")
+		if err := issues.CloseIssue(ctx, entry.Issue); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Failed to close issue: %v\n", ui.Warn(), err)
+		} else {
+			fmt.Printf("  %s Issue closed\n", ui.Success())
+		}
+	}
+}
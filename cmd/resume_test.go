@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vibe-git/internal/github"
+)
+
+// withStateFile points the state file at a fresh temp file for the test and
+// forgets the issues earlier polls handled
+func withStateFile(t *testing.T) string {
+	t.Helper()
+	orig, origHandled := stateFile, lastHandled
+	t.Cleanup(func() { stateFile, lastHandled = orig, origHandled })
+	stateFile, lastHandled = filepath.Join(t.TempDir(), "state"), nil
+	return stateFile
+}
+
+func TestResumePendingMergeMergesPR(t *testing.T) {
+	withStateFile(t)
+	setTestRepos(t, "owner", "repo", "owner", "repo")
+	origClose := closeIssue
+	t.Cleanup(func() { closeIssue = origClose })
+	closeIssue = true
+
+	if err := addPendingMerge(pendingMerge{Repo: "owner/repo", IssueRepo: "owner/repo", PR: 5, Issue: 7, IssueTitle: "Fix login", Deadline: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	// An entry for another repository is left alone
+	if err := addPendingMerge(pendingMerge{Repo: "owner/other", IssueRepo: "owner/other", PR: 9, Deadline: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged, closed bool
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/pulls/5":
+			w.Write([]byte(`{"state": "open", "mergeable": true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/repos/owner/repo/pulls/5/merge":
+			merged = true
+			w.Write([]byte(`{"merged": true}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/owner/repo/issues/7":
+			closed = true
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	gh.SetMergePollInterval(10 * time.Millisecond)
+
+	var resumed int
+	out := captureStdout(t, func() {
+		resumed = resumePendingMerges(context.Background(), gh, gh)
+	})
+	if resumed != 1 || !merged || !closed {
+		t.Fatalf("expected PR #5 to be merged and issue closed, got resumed=%d merged=%v closed=%v\n%s", resumed, merged, closed, out)
+	}
+
+	pending := readState().PendingMerges
+	if len(pending) != 1 || pending[0].Repo != "owner/other" {
+		t.Errorf("expected only the other repository's entry to remain, got %+v", pending)
+	}
+}
+
+func TestResumePendingMergeDropsExpiredEntry(t *testing.T) {
+	withStateFile(t)
+	setTestRepos(t, "owner", "repo", "owner", "repo")
+
+	if err := addPendingMerge(pendingMerge{Repo: "owner/repo", IssueRepo: "owner/repo", PR: 5, Issue: 7, Deadline: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expired entry should not reach GitHub, got %s %s", r.Method, r.URL.Path)
+	})
+
+	captureStdout(t, func() {
+		resumePendingMerges(context.Background(), gh, gh)
+	})
+	if pending := readState().PendingMerges; len(pending) != 0 {
+		t.Errorf("expected the expired entry to be removed, got %+v", pending)
+	}
+}
+
+func TestTrackPendingMergeKeepsInterruptedWait(t *testing.T) {
+	withStateFile(t)
+	setTestRepos(t, "owner", "repo", "owner", "repo")
+	issue := &github.Issue{Number: 7, Title: "Fix login"}
+
+	untrack := trackPendingMerge(context.Background(), issue, 5, "https://github.com/owner/repo/pull/5", "vibe-git/issue-7")
+	if pending := readState().PendingMerges; len(pending) != 1 || pending[0].Branch != "vibe-git/issue-7" {
+		t.Fatalf("expected the pending merge to be saved, got %+v", pending)
+	}
+	untrack()
+	if pending := readState().PendingMerges; len(pending) != 0 {
+		t.Errorf("expected a finished wait to be forgotten, got %+v", pending)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	untrack = trackPendingMerge(ctx, issue, 5, "", "vibe-git/issue-7")
+	cancel()
+	untrack()
+	if pending := readState().PendingMerges; len(pending) != 1 {
+		t.Errorf("expected an interrupted wait to be kept for resume, got %+v", pending)
+	}
+}
+
+func TestStateFileKeepsLegacyTimestamp(t *testing.T) {
+	path := withStateFile(t)
+	if err := os.WriteFile(path, []byte("1700000000"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := addPendingMerge(pendingMerge{Repo: "owner/repo", PR: 5}); err != nil {
+		t.Fatal(err)
+	}
+	state := readState()
+	if state.LastChecked != 1700000000 || len(state.PendingMerges) != 1 {
+		t.Errorf("expected the timestamp to survive the upgrade, got %+v", state)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"last_checked": 1700000000`) {
+		t.Errorf("expected JSON state, got %s", data)
+	}
+}
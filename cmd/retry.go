@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"vibe-git/internal/breaker"
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+	"vibe-git/internal/notify"
+	"vibe-git/internal/ui"
+	"vibe-git/internal/worker"
+)
+
+const (
+	retryBackoffBase = time.Minute
+	retryBackoffMax  = time.Hour
+)
+
+var (
+	maxRetries   = 3           // --max-retries; 0 drops issues on their first failure
+	retryComment bool          // --retry-comment; tell the issue when its retries are used up
+	retryCheck   = time.Minute // How often webhook mode looks for due retries
+)
+
+// retryEntry is a watched issue that failed transiently and will be tried again
+type retryEntry struct {
+	IssueRepo  string    `json:"issue_repo"` // owner/name the issue lives in
+	Issue      int       `json:"issue"`
+	IssueTitle string    `json:"issue_title"`
+	Attempts   int       `json:"attempts"` // Failed attempts so far
+	NextRetry  time.Time `json:"next_retry"`
+	LastError  string    `json:"last_error"`
+}
+
+// retryBackoff is the wait after the given number of failed attempts: a minute,
+// doubling with each further failure, up to an hour
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 1 || attempts > 16 {
+		return retryBackoffMax
+	}
+	backoff := retryBackoffBase << (attempts - 1)
+	if backoff > retryBackoffMax {
+		backoff = retryBackoffMax
+	}
+	return backoff
+}
+
+// isTransient reports whether err is worth retrying later: network trouble, an
+// open circuit breaker, rate limits and server errors. Timeouts are not, since
+// the timeout tracker already backs those issues off.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, errIssueTimeout) {
+		return false
+	}
+	if errors.Is(err, breaker.ErrOpen) || errors.Is(err, worker.ErrUnreachable) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var ghErr *github.APIError
+	if errors.As(err, &ghErr) {
+		return retryableStatus(ghErr.StatusCode) ||
+			(ghErr.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(ghErr.Body), "rate limit"))
+	}
+	var clErr *claude.APIError
+	if errors.As(err, &clErr) {
+		return retryableStatus(clErr.StatusCode)
+	}
+	return false
+}
+
+// retryableStatus is true for rate limiting and server-side failures
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// recordIssueResult updates the retry queue after the watcher ran issue. Success
+// or a permanent failure takes it off the queue; a transient failure queues it
+// with backoff until --max-retries is used up. An issue interrupted by shutdown
+// is left as it was.
+func recordIssueResult(ctx context.Context, issues *github.Client, issue *github.Issue, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	issueRepo := repoOwner + "/" + repoName
+	if !isTransient(err) {
+		forgetRetry(issueRepo, issue.Number)
+		return
+	}
+
+	entry, gaveUp, saveErr := queueRetry(issueRepo, issue, err, time.Now())
+	if saveErr != nil {
+		fmt.Fprintf(os.Stderr, "  %s Failed to save retry queue: %v\n", ui.Warn(), saveErr)
+		return
+	}
+	if !gaveUp {
+		fmt.Printf("  Will retry issue #%d after %s (attempt %d of %d failed)\n", issue.Number, entry.NextRetry.Format("15:04:05"), entry.Attempts, maxRetries+1)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "  %s Giving up on issue #%d after %s\n", ui.Warn(), issue.Number, plural(entry.Attempts, "attempt"))
+	if retryComment {
+		body := fmt.Sprintf("vibe-git gave up on this issue after %s. The last error was:\n\n```\n%s\n```", plural(entry.Attempts, "attempt"), entry.LastError)
+		if err := issues.AddIssueComment(ctx, issue.Number, body); err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Failed to comment on issue #%d: %v\n", ui.Warn(), issue.Number, err)
+		}
+	}
+}
+
+// queueRetry counts a failed attempt for issue and schedules its next try, or
+// drops it once it has failed more than --max-retries times
+func queueRetry(issueRepo string, issue *github.Issue, err error, now time.Time) (entry retryEntry, gaveUp bool, saveErr error) {
+	saveErr = updateState(func(state *watchState) {
+		var kept []retryEntry
+		for _, e := range state.RetryQueue {
+			if e.IssueRepo == issueRepo && e.Issue == issue.Number {
+				entry = e
+				continue
+			}
+			kept = append(kept, e)
+		}
+
+		entry.IssueRepo, entry.Issue, entry.IssueTitle = issueRepo, issue.Number, issue.Title
+		entry.Attempts++
+		entry.LastError = err.Error()
+		entry.NextRetry = now.Add(retryBackoff(entry.Attempts))
+
+		gaveUp = entry.Attempts > maxRetries
+		if !gaveUp {
+			kept = append(kept, entry)
+		}
+		state.RetryQueue = kept
+	})
+	return entry, gaveUp, saveErr
+}
+
+// forgetRetry takes issue number off the retry queue, if it is queued
+func forgetRetry(issueRepo string, number int) {
+	stateMu.Lock()
+	queued := false
+	for _, e := range readState().RetryQueue {
+		queued = queued || (e.IssueRepo == issueRepo && e.Issue == number)
+	}
+	stateMu.Unlock()
+	if !queued {
+		return
+	}
+
+	if err := updateState(func(state *watchState) {
+		var kept []retryEntry
+		for _, e := range state.RetryQueue {
+			if e.IssueRepo != issueRepo || e.Issue != number {
+				kept = append(kept, e)
+			}
+		}
+		state.RetryQueue = kept
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s Failed to update retry queue: %v\n", ui.Warn(), err)
+	}
+}
+
+// queuedRetries returns the queued entries of issueRepo
+func queuedRetries(issueRepo string) []retryEntry {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	var entries []retryEntry
+	for _, e := range readState().RetryQueue {
+		if e.IssueRepo == issueRepo {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// retryQueueDepth is how many issues are waiting to be retried
+func retryQueueDepth() int {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return len(readState().RetryQueue)
+}
+
+// retryDueIssues processes the queued issues whose backoff has passed. Issues
+// closed, or stripped of the --require-label label, in the meantime are dropped
+// from the queue.
+func retryDueIssues(ctx context.Context, issues *github.Client, process func(context.Context, *github.Issue) error) {
+	issueRepo := repoOwner + "/" + repoName
+	now := time.Now()
+	for _, entry := range queuedRetries(issueRepo) {
+		if ctx.Err() != nil {
+			return
+		}
+		if entry.NextRetry.After(now) {
+			continue
+		}
+
+		issue, err := issues.GetIssue(ctx, entry.Issue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  %s Could not fetch issue #%d for retry: %v\n", ui.Warn(), entry.Issue, err)
+			continue
+		}
+		if !acceptsState(issue.State) {
+			fmt.Printf("  Dropping retry of issue #%d: it is %s\n", issue.Number, issue.State)
+			forgetRetry(issueRepo, issue.Number)
+			continue
+		}
+		if !triggered(issue) {
+			fmt.Printf("  Dropping retry of issue #%d: it no longer has the %q label\n", issue.Number, requireLabel)
+			forgetRetry(issueRepo, issue.Number)
+			continue
+		}
+
+		fmt.Printf("\n%sRetrying issue #%d: %s (attempt %d)\n", ui.Emoji("🔁 "), issue.Number, issue.Title, entry.Attempts+1)
+		err = processWatchedIssue(ctx, issue, func(ctx context.Context) error {
+			return process(ctx, issue)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing issue #%d: %v\n", issue.Number, err)
+			sendNotification(context.Background(), notify.Event{Type: notify.EventError, IssueNumber: issue.Number, IssueTitle: issue.Title, Err: err}, "")
+		}
+		recordIssueResult(ctx, issues, issue, err)
+	}
+}
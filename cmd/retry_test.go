@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vibe-git/internal/breaker"
+	"vibe-git/internal/claude"
+	"vibe-git/internal/github"
+)
+
+// withMaxRetries sets --max-retries and --retry-comment for the test
+func withMaxRetries(t *testing.T, n int, comment bool) {
+	t.Helper()
+	origMax, origComment := maxRetries, retryComment
+	t.Cleanup(func() { maxRetries, retryComment = origMax, origComment })
+	maxRetries, retryComment = n, comment
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		7:  time.Hour,
+		40: time.Hour,
+	} {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://api.github.com", Err: errors.New("connection reset")}, true},
+		{fmt.Errorf("fetching: %w", &url.Error{Op: "Get", URL: "https://api.github.com", Err: context.DeadlineExceeded}), true},
+		{fmt.Errorf("GitHub: %w", breaker.ErrOpen), true},
+		{fmt.Errorf("creating PR: %w", &github.APIError{StatusCode: http.StatusBadGateway}), true},
+		{&github.APIError{StatusCode: http.StatusForbidden, Body: `{"message":"API rate limit exceeded"}`}, true},
+		{&github.APIError{StatusCode: http.StatusForbidden, Body: `{"message":"Resource not accessible"}`}, false},
+		{withExitCode(ExitGeneration, fmt.Errorf("generating code: %w", &claude.APIError{StatusCode: http.StatusTooManyRequests})), true},
+		{&claude.APIError{StatusCode: http.StatusBadRequest}, false},
+		{fmt.Errorf("%w after 5m", errIssueTimeout), false},
+		{errors.New("applying changes: no such file"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRecordIssueResultQueuesAndGivesUp(t *testing.T) {
+	withStateFile(t)
+	withMaxRetries(t, 2, true)
+
+	var comments []string
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/issues/7/comments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body struct{ Body string }
+		json.NewDecoder(r.Body).Decode(&body)
+		comments = append(comments, body.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+
+	issue := &github.Issue{Number: 7, Title: "Flaky"}
+	failure := &github.APIError{StatusCode: http.StatusServiceUnavailable, Body: "unavailable"}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		start := time.Now()
+		captureStdout(t, func() { recordIssueResult(context.Background(), gh, issue, failure) })
+
+		queue := queuedRetries("/")
+		if len(queue) != 1 || queue[0].Attempts != attempt || queue[0].Issue != 7 {
+			t.Fatalf("attempt %d: unexpected queue %+v", attempt, queue)
+		}
+		if wait := queue[0].NextRetry.Sub(start); wait < retryBackoff(attempt) || wait > retryBackoff(attempt)+time.Minute {
+			t.Errorf("attempt %d: retry scheduled %v out, want %v", attempt, wait, retryBackoff(attempt))
+		}
+	}
+	if depth := healthStatus(nil)["retry_queue"]; depth != 1 {
+		t.Errorf("expected the health check to report 1 queued issue, got %v", depth)
+	}
+	if len(comments) != 0 {
+		t.Errorf("expected no comment while retries remain, got %v", comments)
+	}
+
+	// The third failure uses up --max-retries=2
+	captureStdout(t, func() { recordIssueResult(context.Background(), gh, issue, failure) })
+	if queue := queuedRetries("/"); len(queue) != 0 {
+		t.Errorf("expected the issue to be dropped, got %+v", queue)
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0], "after 3 attempts") || !strings.Contains(comments[0], "unavailable") {
+		t.Errorf("expected a give-up comment, got %v", comments)
+	}
+
+	// Permanent failures are never queued
+	captureStdout(t, func() { recordIssueResult(context.Background(), gh, issue, errors.New("bad change set")) })
+	if queue := queuedRetries("/"); len(queue) != 0 {
+		t.Errorf("expected a permanent failure not to be queued, got %+v", queue)
+	}
+}
+
+func TestRetryDueIssues(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	withMaxRetries(t, 3, false)
+
+	now := time.Now()
+	if err := updateState(func(state *watchState) {
+		state.RetryQueue = []retryEntry{
+			{IssueRepo: "/", Issue: 1, Attempts: 1, NextRetry: now.Add(-time.Second)},
+			{IssueRepo: "/", Issue: 2, Attempts: 1, NextRetry: now.Add(time.Hour)},
+			{IssueRepo: "/", Issue: 3, Attempts: 2, NextRetry: now.Add(-time.Second)},
+			{IssueRepo: "other/repo", Issue: 4, Attempts: 1, NextRetry: now.Add(-time.Second)},
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/1":
+			w.Write([]byte(`{"number": 1, "title": "Retry me", "state": "open"}`))
+		case "/repos/owner/repo/issues/3":
+			w.Write([]byte(`{"number": 3, "title": "Fixed by hand", "state": "closed"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	var processed []int
+	captureStdout(t, func() {
+		retryDueIssues(context.Background(), gh, func(ctx context.Context, issue *github.Issue) error {
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if !reflect.DeepEqual(processed, []int{1}) {
+		t.Errorf("expected only the due open issue to be retried, got %v", processed)
+	}
+	var left []int
+	for _, e := range readState().RetryQueue {
+		left = append(left, e.Issue)
+	}
+	if !reflect.DeepEqual(left, []int{2, 4}) {
+		t.Errorf("expected issues 2 and 4 to stay queued, got %v", left)
+	}
+}
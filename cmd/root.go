@@ -2,38 +2,121 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"vibe-git/internal/claude"
+	"vibe-git/internal/commitmsg"
 	"vibe-git/internal/config"
 	"vibe-git/internal/ctxloader"
 	"vibe-git/internal/git"
 	"vibe-git/internal/github"
+	"vibe-git/internal/hooks"
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/issueprep"
+	"vibe-git/internal/notify"
+	"vibe-git/internal/requestid"
+	"vibe-git/internal/ui"
+	"vibe-git/internal/worker"
 )
 
+// defaultTemperature keeps generated code close to the most likely answer
+const defaultTemperature = 0.2
+
+// repoFromRemote is set when --owner and --repo were left out and were read
+// from the origin remote of the current directory
+var repoFromRemote bool
+
 var (
-	githubToken    string
-	claudeAPIKey   string
-	repoOwner      string
-	repoName       string
-	baseBranch     string
-	model          string
-	autoMerge      bool
-	closeIssue     bool
-	waitForChecks  bool
-	mergeTimeout   time.Duration
+	githubToken      string
+	claudeAPIKey     string
+	repoOwner        string
+	repoName         string
+	baseBranch       string
+	model            string
+	autoMerge        bool
+	closeIssue       bool
+	pruneBranch      bool // --prune-branch; delete the PR branch after merging
+	waitForChecks    bool
+	mergeTimeout     time.Duration
+	conflictStrategy string
+	useWorker        bool
+	workerURL        string
+	workerToken      string
+	workerProject    string
+	maxFileSize      int64
+	refSearchRoots   string
+	redactSecrets    bool
+	checkTokens      bool
+	autoTrim         bool
+	includeImages    bool
+	maxResponseSize  int64
+	temperature      float64
+	topP             float64
+	targetRepo       string
+	targetOwner      string // Repository PRs are opened against; defaults to --owner/--repo
+	targetName       string
+	baseFromDefault  bool
+	baseMap          string
+	baseMappings     []baseMapping // Parsed --base-map, in flag order
+	modelMap         string
+	modelMappings    []modelMapping // Parsed --model-map, in flag order
+	baseDetected     bool           // baseBranch was looked up from the repository during this run
+	prLabels         string
+	prReviewers      string
+	prAssignees      string
+	commentOnIssue   bool
+	failFast         bool
+	includeClosed    bool
+	preApplyHook     string
+	postCommitHook   string
+	hookTimeout      time.Duration
+	notifyWebhook    string
+	notifyType       string
+	notifier         notify.Notifier // Set from --notify-webhook; nil disables notifications
+	dumpPrompt       string
+	draftPR          bool
+	readyPR          bool
+	dumpResponse     string
+	noCodebase       bool
+	contextMode      string // What the prompt shows besides the issue: changed, full or none
+	noEmoji          bool
+	useWorktree      bool   // Process each issue in a temporary git worktree
+	proxyURL         string // --proxy; empty leaves proxying to the environment
+	summarize        bool
+	interactive      bool // Confirm before applying and pushing; cleared when stdin isn't a terminal
+	codebaseDirs     string
+	maxFiles         int
+	commitAuthor     string
+	commitIdentity   git.Author // Parsed --commit-author; zero keeps the repository's identity
+	coAuthor         bool
+	conventional     bool // Write commit messages in the Conventional Commits format
+	commitPerFile    bool // Commit each file change on its own
+	appID            int64
+	appInstallation  int64
+	appKeyPath       string
+	appAuth          *github.AppAuth // Set from the --github-app-* flags; nil authenticates with githubToken
+	githubTimeout    time.Duration   // How long each GitHub API request may take
 )
 
 func init() {
 	// Default values from environment
 	githubToken = os.Getenv("GITHUB_TOKEN")
+	appID, _ = strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64)
+	appInstallation, _ = strconv.ParseInt(os.Getenv("GITHUB_APP_INSTALLATION_ID"), 10, 64)
+	appKeyPath = os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
 	claudeAPIKey = os.Getenv("ANTHROPIC_API_KEY")
 
 	// Load defaults from ~/.claude/settings.json if env not set
@@ -45,6 +128,13 @@ func init() {
 		}
 	}
 
+	workerURL = os.Getenv("WORKER_URL")
+	if workerURL == "" {
+		workerURL = "http://localhost:3000"
+	}
+	workerToken = os.Getenv("WORKER_TOKEN")
+	workerProject = os.Getenv("WORKER_PROJECT")
+
 	// Default poll interval from environment
 	if envPollInterval := os.Getenv("VIBE_GIT_POLL_INTERVAL"); envPollInterval != "" {
 		if d, err := time.ParseDuration(envPollInterval); err == nil {
@@ -57,41 +147,264 @@ func init() {
 func Execute() error {
 	// Define flags
 	flag.StringVar(&githubToken, "github-token", githubToken, "GitHub personal access token")
+	flag.DurationVar(&githubTimeout, "github-timeout", github.DefaultTimeout, "How long each GitHub API request may take before it fails (0 = no limit)")
+	flag.Int64Var(&appID, "github-app-id", appID, "Authenticate as this GitHub App instead of with a token (with --github-app-installation-id and --github-app-key)")
+	flag.Int64Var(&appInstallation, "github-app-installation-id", appInstallation, "GitHub App installation to request tokens for")
+	flag.StringVar(&appKeyPath, "github-app-key", appKeyPath, "Path to the GitHub App's PEM private key")
 	flag.StringVar(&claudeAPIKey, "claude-api-key", claudeAPIKey, "Anthropic API key")
 	flag.StringVar(&repoOwner, "owner", "", "GitHub repository owner")
 	flag.StringVar(&repoName, "repo", "", "GitHub repository name")
-	flag.StringVar(&baseBranch, "base", "main", "Base branch")
-	flag.StringVar(&model, "model", "claude-3-5-sonnet-latest", "Claude model")
+	flag.StringVar(&targetRepo, "target-repo", "", "Repository (owner/name) to open PRs against, if different from --owner/--repo")
+	flag.StringVar(&baseBranch, "base", "main", "Base branch (empty to use the repository's default branch)")
+	flag.StringVar(&baseMap, "base-map", "", "Comma-separated label=branch pairs choosing the base branch per issue label, e.g. hotfix=release (falls back to --base)")
+	flag.BoolVar(&baseFromDefault, "base-from-default", false, "Use the repository's default branch as the base")
+	flag.StringVar(&model, "model", claude.DefaultModel, "Claude model")
+	flag.StringVar(&modelMap, "model-map", "", "Comma-separated label=model pairs choosing the Claude model per issue label, e.g. complex=claude-opus-4-1 (falls back to --model)")
 
 	// Watch mode flags
 	flag.StringVar(&watchMode, "watch-mode", "webhook", "Watch mode: webhook or poll")
 	flag.IntVar(&webhookPort, "webhook-port", 8080, "Webhook server port")
+	flag.BoolVar(&enableTestEndpoint, "enable-test-endpoint", false, "Serve POST /webhook/test in webhook mode to process an issue by number (for local testing)")
 	pollIntervalStr := flag.String("poll-interval", pollInterval.String(), "Poll interval (e.g., 1m, 5m, 1h)")
+	flag.DurationVar(&issueTimeout, "timeout-per-issue", issueTimeout, "Maximum time spent on one issue in watch mode before it is abandoned (0 = no limit)")
+	flag.DurationVar(&maxRuntime, "max-runtime", 0, "Stop watching after this long (0 = run until interrupted)")
+	flag.IntVar(&breakerThreshold, "breaker-threshold", 5, "Consecutive GitHub or Claude failures that pause calls in watch mode (0 = never)")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", time.Minute, "How long calls stay paused before the service is tried again")
+	flag.IntVar(&maxRetries, "max-retries", maxRetries, "Times a watched issue that failed transiently (network, rate limit, server error) is retried with backoff before giving up (0 = never)")
+	flag.BoolVar(&retryComment, "retry-comment", false, "Comment on an issue when watch mode gives up retrying it")
+	flag.DurationVar(&gracePeriod, "grace-period", 0, "In watch mode, announce on a new issue and wait this long for a maintainer's /cancel comment before processing it (0 = start right away)")
+	flag.StringVar(&requireLabel, "require-label", "", "Only act on issues carrying this label in watch mode (e.g. vibe); adding the label to an open issue triggers it")
+	flag.BoolVar(&includePRs, "include-prs", false, "In poll mode, also process open pull requests, which GitHub lists as issues (default: skip them)")
+	flag.StringVar(&onEdit, "on-edit", onEdit, "What webhook mode does when an issue's title or body is edited: regenerate (process it again, updating its PR), comment (note the edit on its open PR) or ignore")
 
 	// Auto-merge flags
+	flag.StringVar(&prLabels, "pr-labels", "", "Comma-separated labels to add to created PRs (e.g. ai-generated)")
+	flag.StringVar(&prReviewers, "reviewers", "", "Comma-separated users or org/team slugs to request review from")
+	flag.StringVar(&prAssignees, "pr-assignees", "", "Comma-separated users to assign to created PRs")
+	flag.BoolVar(&draftPR, "draft", false, "Open PRs as drafts; a reprocessed issue's existing PR is converted to a draft")
+	flag.BoolVar(&readyPR, "ready", false, "Mark a reprocessed issue's existing draft PR ready for review")
+	flag.BoolVar(&interactive, "interactive", false, "Review the proposed changes and confirm before applying and pushing (issue command, terminal only)")
+	flag.BoolVar(&summarize, "summarize", false, "Ask Claude for a short summary of the changes for the PR body and issue comment (one extra API call)")
+	flag.StringVar(&commitAuthor, "commit-author", "", "Author and commit as \"Name <email>\" instead of the repository's configured identity")
+	flag.BoolVar(&coAuthor, "co-author", false, "Credit the issue's author with a Co-authored-by trailer in the commit message")
+	flag.BoolVar(&conventional, "conventional-commits", false, "Write commit messages like \"fix(auth): ...\" with Claude, typed by the issue's labels (one extra API call)")
+	flag.BoolVar(&commitPerFile, "commit-per-file", false, "Make one commit per changed file instead of a single commit per issue")
+	flag.BoolVar(&commentOnIssue, "comment-on-issue", true, "Comment on the issue with a link to the created PR")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first issue that fails instead of continuing")
+	flag.BoolVar(&includeClosed, "include-closed", false, "Process closed issues too, e.g. to regenerate a fix (default: refuse them)")
+	flag.StringVar(&reportFile, "report", "", "Append a JSON line per processed issue (outcome, PR, tokens, duration) to this file")
+	flag.StringVar(&preApplyHook, "pre-apply-hook", "", "Shell command run before generated changes are applied; a non-zero exit aborts the issue")
+	flag.StringVar(&postCommitHook, "post-commit-hook", "", "Shell command run after the changes are committed and the PR is opened")
+	flag.DurationVar(&hookTimeout, "hook-timeout", hooks.DefaultTimeout, "Maximum time a hook may run")
+	flag.StringVar(&notifyWebhook, "notify-webhook", "", "Slack or Discord webhook URL notified when PRs are created or merged and on errors")
+	flag.StringVar(&notifyType, "notify-type", "slack", "Notification webhook type: slack or discord")
 	flag.BoolVar(&autoMerge, "auto-merge", false, "Automatically merge PR after creation")
 	flag.BoolVar(&closeIssue, "close-issue", false, "Close issue after merging PR")
+	flag.BoolVar(&pruneBranch, "prune-branch", false, "Delete the PR's branch after merging it")
 	flag.BoolVar(&waitForChecks, "wait-for-checks", true, "Wait for CI checks before merging")
 	mergeTimeoutStr := flag.String("merge-timeout", "10m", "Timeout for waiting to merge")
+	flag.Int64Var(&maxFileSize, "max-file-size", ctxloader.DefaultMaxFileSize, "Maximum bytes loaded per @referenced file (0 = unlimited)")
+	flag.Int64Var(&maxResponseSize, "max-response-size", claude.DefaultMaxResponseBytes, "Maximum bytes of a Claude response before it is rejected (0 = unlimited)")
+	flag.Float64Var(&temperature, "temperature", defaultTemperature, "Sampling temperature from 0 to 1; lower is more deterministic (-1 = model default)")
+	flag.Float64Var(&topP, "top-p", claude.ModelDefault, "Nucleus sampling cutoff from 0 to 1, used instead of --temperature (-1 = model default)")
+	flag.BoolVar(&includeImages, "include-images", false, "Download images embedded in the issue body (e.g. screenshots) and send them to Claude")
+	flag.BoolVar(&redactSecrets, "redact-secrets", true, "Mask API keys, private keys and other secrets in files before sending them to Claude")
+	flag.StringVar(&secretScan, "secret-scan", secretScanWarn, "Check generated changes for secrets before committing them: block (fail the issue), warn or off")
+	flag.StringVar(&refSearchRoots, "ref-search-roots", ".", "Comma-separated directories searched for @references that aren't exact paths")
+	flag.BoolVar(&noCodebase, "no-codebase", false, "Leave the codebase out of the prompt; Claude works from the issue and @referenced files only")
+	flag.StringVar(&contextMode, "context", contextFull, "Prompt context: full (the codebase), changed (the issue branch's diff against base and its changed files) or none (same as --no-codebase)")
+	flag.StringVar(&codebaseDirs, "codebase-only-dirs", "", "Comma-separated directories to include in the prompt's codebase section (default: whole repo)")
+	flag.IntVar(&maxFiles, "max-files", defaultMaxFiles, "Maximum files in the prompt's codebase section; the most relevant are kept (0 = unlimited)")
+	flag.StringVar(&applyMode, "apply-mode", applyStrict, "What to do when some generated changes can't be applied: strict (fail the issue) or lenient (skip them and keep the rest)")
+	flag.IntVar(&maxChangedFiles, "max-changed-files", 0, "Reject generated change sets touching more files than this (0 = unlimited)")
+	flag.IntVar(&maxChangedLines, "max-changed-lines", 0, "Reject generated change sets adding and removing more lines than this in total (0 = unlimited)")
+	flag.BoolVar(&force, "force", false, "Apply change sets over --max-changed-files or --max-changed-lines anyway")
+	flag.BoolVar(&checkTokens, "check-tokens", false, "Count each prompt's tokens before generating and stop if it won't fit the model's context window (one extra API call)")
+	flag.BoolVar(&autoTrim, "auto-trim", false, "Count each prompt's tokens and drop the least relevant codebase files until it fits the model's context window")
+	flag.StringVar(&dumpPrompt, "dump-prompt", "", "Write every prompt sent to Claude to this file (- for stdout)")
+	flag.StringVar(&dumpResponse, "dump-response", "", "Write every raw Claude response to this file (- for stdout)")
+	flag.StringVar(&conflictStrategy, "conflict-strategy", "merge", "How to update a conflicting PR branch: merge or rebase")
+
+	flag.BoolVar(&noEmoji, "no-emoji", false, "Use ASCII status markers instead of emoji (also set by TERM=dumb)")
+
+	flag.BoolVar(&useWorktree, "worktree", false, "Process each issue in its own temporary git worktree so concurrent issues don't share a checkout")
+
+	// Worker flags
+	flag.BoolVar(&useWorker, "use-worker", false, "Delegate branch/generate/commit/push to the Docker worker")
+	flag.StringVar(&workerURL, "worker-url", workerURL, "Worker URL")
+	flag.StringVar(&workerToken, "worker-token", workerToken, "Worker authentication token")
+	flag.StringVar(&workerProject, "worker-project", workerProject, "Project from the worker's WORKER_PROJECTS to work on (default: its PROJECT_PATH)")
+
+	// Connection pool shared by the GitHub, Claude and webhook clients
+	transportOpts := httpclient.DefaultTransportOptions()
+	flag.IntVar(&transportOpts.MaxIdleConns, "http-max-idle-conns", transportOpts.MaxIdleConns, "Idle HTTP connections kept open across all hosts (0 = no limit)")
+	flag.IntVar(&transportOpts.MaxIdleConnsPerHost, "http-max-idle-conns-per-host", transportOpts.MaxIdleConnsPerHost, "Idle HTTP connections kept open per host")
+	flag.IntVar(&transportOpts.MaxConnsPerHost, "http-max-conns-per-host", transportOpts.MaxConnsPerHost, "Most HTTP connections per host, in use or idle (0 = no limit)")
+	flag.DurationVar(&transportOpts.IdleConnTimeout, "http-idle-timeout", transportOpts.IdleConnTimeout, "How long an idle HTTP connection is kept open (0 = forever)")
+	flag.StringVar(&proxyURL, "proxy", "", "Proxy URL for every HTTP request, e.g. http://proxy:3128 (default: HTTP_PROXY, HTTPS_PROXY and NO_PROXY)")
 
 	flag.Parse()
+	if err := applyConfigFile(flag.CommandLine, configFile); err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+	ui.Configure(noEmoji)
+
+	if githubTimeout < 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("--github-timeout must not be negative"))
+	}
+	if transportOpts.MaxIdleConns < 0 || transportOpts.MaxIdleConnsPerHost < 0 || transportOpts.MaxConnsPerHost < 0 || transportOpts.IdleConnTimeout < 0 {
+		return withExitCode(ExitUsage, fmt.Errorf("--http-* connection settings must not be negative"))
+	}
+	if proxyURL != "" {
+		u, err := httpclient.ParseProxy(proxyURL)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid --proxy: %w", err))
+		}
+		transportOpts.Proxy = u
+	}
+	httpclient.ConfigureTransport(transportOpts)
 
 	// Parse poll interval
 	var err error
 	pollInterval, err = time.ParseDuration(*pollIntervalStr)
 	if err != nil {
-		return fmt.Errorf("invalid poll interval: %w", err)
+		return withExitCode(ExitUsage, fmt.Errorf("invalid poll interval: %w", err))
 	}
 
 	// Parse merge timeout
 	mergeTimeout, err = time.ParseDuration(*mergeTimeoutStr)
 	if err != nil {
-		return fmt.Errorf("invalid merge timeout: %w", err)
+		return withExitCode(ExitUsage, fmt.Errorf("invalid merge timeout: %w", err))
+	}
+
+	if conflictStrategy != "merge" && conflictStrategy != "rebase" {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid conflict strategy: %s (use 'merge' or 'rebase')", conflictStrategy))
+	}
+
+	if draftPR && readyPR {
+		return withExitCode(ExitUsage, fmt.Errorf("--draft and --ready cannot be used together"))
+	}
+
+	if err := resolveSampling(flag.CommandLine); err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	switch contextMode {
+	case contextFull, contextChanged:
+	case contextNone:
+		noCodebase = true
+	default:
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --context: %s (use 'changed', 'full' or 'none')", contextMode))
+	}
+
+	if noCodebase && contextMode == contextChanged {
+		return withExitCode(ExitUsage, fmt.Errorf("--no-codebase and --context=changed cannot be used together"))
+	}
+
+	if useWorker && contextMode == contextChanged {
+		return withExitCode(ExitUsage, fmt.Errorf("--context=changed is not supported with --use-worker (the worker builds its own prompt)"))
+	}
+
+	if noCodebase && codebaseDirs != "" {
+		return withExitCode(ExitUsage, fmt.Errorf("--no-codebase and --codebase-only-dirs cannot be used together"))
+	}
+
+	if useWorker && (noCodebase || codebaseDirs != "") {
+		return withExitCode(ExitUsage, fmt.Errorf("--no-codebase and --codebase-only-dirs are not supported with --use-worker (the worker builds its own prompt)"))
+	}
+
+	if useWorker && (checkTokens || autoTrim) {
+		return withExitCode(ExitUsage, fmt.Errorf("--check-tokens and --auto-trim are not supported with --use-worker (the worker builds its own prompt)"))
+	}
+
+	if err := setupAppAuth(); err != nil {
+		return err
+	}
+
+	if commitAuthor != "" {
+		if commitIdentity, err = git.ParseAuthor(commitAuthor); err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid --commit-author: %w", err))
+		}
+	}
+
+	if useWorker && (commitAuthor != "" || coAuthor || conventional || commitPerFile) {
+		return withExitCode(ExitUsage, fmt.Errorf("--commit-author, --co-author, --conventional-commits and --commit-per-file are not supported with --use-worker (the worker makes its own commits)"))
+	}
+
+	if includeImages && useWorker {
+		return withExitCode(ExitUsage, fmt.Errorf("--include-images is not supported with --use-worker (the worker builds its own prompt)"))
+	}
+
+	if useWorktree && useWorker {
+		return withExitCode(ExitUsage, fmt.Errorf("--worktree is not supported with --use-worker (the worker has its own checkout)"))
+	}
+
+	if interactive && useWorker {
+		return withExitCode(ExitUsage, fmt.Errorf("--interactive is not supported with --use-worker (changes are applied and pushed inside the worker)"))
+	}
+	if interactive && !stdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "%s --interactive ignored: stdin is not a terminal\n", ui.Warn())
+		interactive = false
+	}
+
+	if secretScan != secretScanBlock && secretScan != secretScanWarn && secretScan != secretScanOff {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --secret-scan: %s (use 'block', 'warn' or 'off')", secretScan))
+	}
+	if useWorker && secretScan == secretScanBlock {
+		return withExitCode(ExitUsage, fmt.Errorf("--secret-scan=block is not supported with --use-worker (changes are committed inside the worker)"))
+	}
+	if applyMode != applyStrict && applyMode != applyLenient {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --apply-mode: %s (use 'strict' or 'lenient')", applyMode))
+	}
+	if useWorker && applyMode == applyLenient {
+		return withExitCode(ExitUsage, fmt.Errorf("--apply-mode=lenient is not supported with --use-worker (changes are applied inside the worker)"))
+	}
+	if useWorker && (maxChangedFiles > 0 || maxChangedLines > 0) {
+		return withExitCode(ExitUsage, fmt.Errorf("--max-changed-files and --max-changed-lines are not supported with --use-worker (changes are applied inside the worker)"))
+	}
+
+	if useWorker && preApplyHook != "" {
+		return withExitCode(ExitUsage, fmt.Errorf("--pre-apply-hook is not supported with --use-worker (changes are applied inside the worker)"))
+	}
+
+	if useWorker && autoMerge {
+		return withExitCode(ExitUsage, fmt.Errorf("--auto-merge is not supported with --use-worker (merge conflicts would be resolved in the local checkout, where the branch doesn't exist)"))
+	}
+
+	if notifyWebhook != "" {
+		notifier, err = notify.New(notifyType, notifyWebhook)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid notification settings: %w", err))
+		}
+	}
+
+	baseMappings, err = parseBaseMap(baseMap)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --base-map: %w", err))
+	}
+	modelMappings, err = parseModelMap(modelMap)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --model-map: %w", err))
+	}
+
+	if repoOwner == "" && repoName == "" {
+		if owner, name, err := detectRepo("."); err == nil {
+			repoOwner, repoName = owner, name
+			repoFromRemote = true
+		}
+	}
+
+	targetOwner, targetName = repoOwner, repoName
+	if targetRepo != "" {
+		targetOwner, targetName, err = parseRepoSlug(targetRepo)
+		if err != nil {
+			return withExitCode(ExitUsage, fmt.Errorf("invalid target repo: %w", err))
+		}
 	}
 
 	if flag.NArg() < 1 {
 		printUsage()
-		return fmt.Errorf("no command specified")
+		return withExitCode(ExitUsage, fmt.Errorf("no command specified"))
 	}
 
 	command := flag.Arg(0)
@@ -100,18 +413,32 @@ func Execute() error {
 	case "issue":
 		if flag.NArg() < 2 {
 			printUsage()
-			return fmt.Errorf("issue number required")
+			return withExitCode(ExitUsage, fmt.Errorf("issue number required"))
 		}
 		return runIssue(flag.Arg(1))
 	case "watch":
 		return runWatch()
 	case "request":
 		return runRequest(flag.Args()[1:])
+	case "apply":
+		return runApply(flag.Args()[1:])
+	case "resume":
+		return runResume()
+	case "doctor":
+		return runDoctor()
+	case "models":
+		return runModels(flag.Args()[1:])
+	case "prune":
+		return runPrune(flag.Args()[1:])
+	case "init":
+		return runInit(flag.Args()[1:])
+	case "chat":
+		return runChat(flag.Args()[1:])
 	case "help", "-h", "--help":
 		printUsage()
 		return nil
 	default:
-		return fmt.Errorf("unknown command: %s", command)
+		return withExitCode(ExitUsage, fmt.Errorf("unknown command: %s", command))
 	}
 }
 
@@ -122,11 +449,25 @@ Usage:
   vibe-git issue <issue-numbers> [flags]
   vibe-git watch [flags]
   vibe-git request <url> [flags]
+  vibe-git apply --from <file> <issue-number>
+  vibe-git resume [flags]
+  vibe-git doctor [flags]
+  vibe-git models [--refresh]
+  vibe-git prune [--dry-run]
+  vibe-git init [--dir <path>]
+  vibe-git chat [flags]
 
 Commands:
   issue    Process GitHub issues and create PRs with Claude-generated code
   watch    Automatically watch for new issues and process them
   request  Make HTTP requests to external services
+  apply    Apply a saved change set and open a PR without calling Claude
+  resume   Finish auto-merges interrupted while waiting for CI checks
+  doctor   Check credentials, repository access and tooling
+  models   List the models --model accepts
+  prune    Delete vibe-git branches whose PRs were merged or closed
+  init     Write a starter .vibe-git.yml, prompt instructions and templates
+  chat     Change the checkout turn by turn, reviewing each diff
 
 Flags:`)
 	flag.PrintDefaults()
@@ -139,15 +480,51 @@ Examples:
   # Auto-merge PR and close issue after processing
   vibe-git issue 42 --owner myorg --repo myproject --auto-merge --close-issue
 
+  # Resolve conflicts by rebasing instead of merging the base branch
+  vibe-git issue 42 --owner myorg --repo myproject --auto-merge --conflict-strategy rebase
+
   # Watch mode - Webhook (real-time)
   vibe-git watch --owner myorg --repo myproject --watch-mode webhook --webhook-port 8080
 
   # Watch mode - Poll (check every 5 minutes)
   vibe-git watch --owner myorg --repo myproject --watch-mode poll --poll-interval 5m
 
+  # Watch for a working day, giving each issue at most 10 minutes
+  vibe-git watch --owner myorg --repo myproject --watch-mode poll --timeout-per-issue 10m --max-runtime 8h
+
   # Watch with auto-merge (CI must pass first)
   vibe-git watch --owner myorg --repo myproject --auto-merge --close-issue
 
+  # Finish auto-merges that were waiting on CI when vibe-git stopped
+  vibe-git --owner myorg --repo myproject --close-issue resume
+
+  # Base the branch on the repository's default branch (e.g. master or develop)
+  vibe-git issue 42 --owner myorg --repo myproject --base-from-default
+
+  # Stop at the first failing issue (useful in CI)
+  vibe-git issue "1-5" --owner myorg --repo myproject --fail-fast
+
+  # Fix an issue from a tracker repo in another repository
+  vibe-git issue 42 --owner myorg --repo tracker --target-repo myorg/backend
+
+  # Delegate generation to the Docker worker
+  vibe-git issue 42 --owner myorg --repo myproject --use-worker --worker-token worker-secret-token
+
+  # Post PR and failure notifications to Slack
+  vibe-git issue 42 --owner myorg --repo myproject --notify-webhook https://hooks.slack.com/services/...
+
+  # Save the prompt and raw response to debug a bad generation
+  vibe-git issue 42 --owner myorg --repo myproject --dump-prompt prompt.txt --dump-response response.txt
+
+  # Verify tokens and repository access before a long run
+  vibe-git doctor --owner myorg --repo myproject
+
+  # Check which --model values are available
+  vibe-git models
+
+  # Delete leftover branches of merged and closed PRs
+  vibe-git prune --owner myorg --repo myproject --dry-run
+
   # Make HTTP requests
   vibe-git request https://api.example.com/users
   vibe-git request https://api.example.com/users -method POST -body '{"name":"John"}'
@@ -155,25 +532,37 @@ Examples:
 Environment Variables:
   GITHUB_TOKEN           GitHub personal access token
   ANTHROPIC_API_KEY      Anthropic API key
-  VIBE_GIT_POLL_INTERVAL Default poll interval (e.g., 1m, 5m, 1h)`)
+  VIBE_GIT_POLL_INTERVAL Default poll interval (e.g., 1m, 5m, 1h)
+  WORKER_URL             Default worker URL (default: http://localhost:3000)
+  WORKER_TOKEN           Default worker authentication token
+  WORKER_PROJECT         Default worker project
+
+Exit Codes:
+  0  Success
+  1  Other error
+  2  Invalid command, flags or arguments
+  3  Authentication failed (GitHub, Anthropic or worker)
+  4  Some issues succeeded and some failed
+  5  Code generation failed
+  6  Merge conflict could not be resolved`)
 }
 
 func runIssue(issueArg string) error {
 	// Validate flags
-	if githubToken == "" {
-		return fmt.Errorf("GitHub token required (use --github-token or GITHUB_TOKEN env)")
+	if err := requireGitHubAuth(); err != nil {
+		return err
 	}
 	if claudeAPIKey == "" {
-		return fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)")
+		return withExitCode(ExitUsage, fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)"))
 	}
 	if repoOwner == "" || repoName == "" {
-		return fmt.Errorf("repository owner and name required (use --owner and --repo)")
+		return withExitCode(ExitUsage, fmt.Errorf("repository owner and name required (use --owner and --repo)"))
 	}
 
 	// Parse issue numbers
 	issueNums, err := parseIssueNumbers(issueArg)
 	if err != nil {
-		return err
+		return withExitCode(ExitUsage, err)
 	}
 
 	// Setup context with cancellation
@@ -189,20 +578,101 @@ func runIssue(issueArg string) error {
 		cancel()
 	}()
 
-	// Initialize clients
-	githubClient := github.NewClient(githubToken, repoOwner, repoName)
+	// Initialize clients; issues are read from --repo, branches and PRs go to the target
+	issueClient := newGitHubClient(repoOwner, repoName)
+	githubClient := newGitHubClient(targetOwner, targetName)
 	claudeClient := claude.NewClient(claudeAPIKey, os.Getenv("ANTHROPIC_BASE_URL"), model)
-	gitClient := git.NewClient(repoOwner, repoName, githubToken)
+	gitClient := newGitClient()
+
+	configureCodebase(claudeClient)
+	configureImages(claudeClient)
+	claudeClient.SetMaxResponseBytes(maxResponseSize)
+	if err := claudeClient.SetSampling(temperature, topP); err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	closeDumps, err := configureDumps(claudeClient)
+	if err != nil {
+		return err
+	}
+	defer closeDumps()
+
+	if isCrossRepo() {
+		if err := validateRepoAccess(ctx, issueClient, githubClient); err != nil {
+			return err
+		}
+	}
+
+	if err := resolveBaseBranch(ctx, githubClient); err != nil {
+		return err
+	}
+	if err := validateBaseMap(ctx, githubClient); err != nil {
+		return err
+	}
+	if err := validateModelMap(ctx, claudeClient); err != nil {
+		return err
+	}
+
+	// Issues of a batch that depend on each other are processed in order
+	var deps map[int][]int
+	if len(issueNums) > 1 {
+		deps = loadDependencies(ctx, issueClient, issueNums)
+		if issueNums, err = orderByDependencies(issueNums, deps); err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+	}
 
 	// Process each issue
-	for _, issueNum := range issueNums {
-		if err := processIssue(ctx, githubClient, claudeClient, gitClient, issueNum); err != nil {
+	return processIssues(issueNums, deps, func(issueNum int) error {
+		err := processIssue(ctx, issueClient, githubClient, claudeClient, gitClient, issueNum)
+		if err != nil {
+			sendNotification(ctx, notify.Event{Type: notify.EventError, IssueNumber: issueNum, Err: err}, "")
+		}
+		return err
+	})
+}
+
+// processIssues runs process for each issue. Failures are reported and, unless
+// --fail-fast is set, the remaining issues are still processed, except those
+// that depend on a failed issue according to deps. The returned error lists
+// every issue that failed or was skipped and exits with ExitPartial if any
+// succeeded.
+func processIssues(issueNums []int, deps map[int][]int, process func(issueNum int) error) error {
+	var errs []error
+	succeeded := 0
+	failed := make(map[int]bool)
+	for i, issueNum := range issueNums {
+		if blockers := failedDependencies(issueNum, deps, failed); len(blockers) > 0 {
+			fmt.Fprintf(os.Stderr, "Skipping issue #%d: it depends on %s, which failed\n", issueNum, formatIssueRefs(blockers))
+			errs = append(errs, fmt.Errorf("issue #%d: %w", issueNum, errDependencyFailed))
+			failed[issueNum] = true
+			continue
+		}
+
+		if err := process(issueNum); err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing issue #%d: %v\n", issueNum, err)
+			errs = append(errs, fmt.Errorf("issue #%d: %w", issueNum, err))
+			failed[issueNum] = true
+
+			if failFast {
+				if skipped := len(issueNums) - i - 1; skipped > 0 {
+					fmt.Fprintf(os.Stderr, "Stopping (--fail-fast), %d issue(s) not processed\n", skipped)
+				}
+				break
+			}
 			continue
 		}
+		succeeded++
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%d of %d issue(s) failed: %w", len(errs), len(issueNums), errors.Join(errs...))
+	if succeeded > 0 {
+		return withExitCode(ExitPartial, err)
+	}
+	return err
 }
 
 func parseIssueNumbers(arg string) ([]int, error) {
@@ -248,84 +718,175 @@ func parseIssueNumbers(arg string) ([]int, error) {
 	return numbers, nil
 }
 
-func processIssue(ctx context.Context, gh *github.Client, cl *claude.Client, git *git.Client, issueNum int) error {
+// checkIssueOpen refuses a closed issue unless --include-closed is set, in
+// which case it only warns
+func checkIssueOpen(issue *github.Issue, indent string) error {
+	if issue.State == "" || issue.State == "open" {
+		return nil
+	}
+	if !includeClosed {
+		return fmt.Errorf("issue #%d is %s (use --include-closed to process it anyway)", issue.Number, issue.State)
+	}
+	fmt.Printf("%s%s Issue #%d is %s; processing it anyway (--include-closed)\n", indent, ui.Warn(), issue.Number, issue.State)
+	return nil
+}
+
+// acceptsState reports whether watch mode acts on an issue in state: open
+// issues, and closed ones too with --include-closed
+func acceptsState(state string) bool {
+	return state == "open" || includeClosed
+}
+
+// processIssue reads the issue through issues and opens the PR through gh, which
+// differ only when --target-repo is set
+func processIssue(ctx context.Context, issues, gh *github.Client, cl *claude.Client, git *git.Client, issueNum int) (err error) {
 	fmt.Printf("\n=== Processing Issue #%d ===\n", issueNum)
+	report := startReport(issueNum)
+	defer func() {
+		if err != nil {
+			reportProgress(ProgressEvent{Type: EventError, Issue: issueNum, Err: err}, "")
+		}
+		report.finish(err)
+	}()
 
 	// Fetch issue details
-	issue, err := gh.GetIssue(ctx, issueNum)
+	issue, err := issues.GetIssue(ctx, issueNum)
 	if err != nil {
 		return fmt.Errorf("fetching issue: %w", err)
 	}
 
 	fmt.Printf("Title: %s\n", issue.Title)
 	fmt.Printf("URL: %s\n", issue.URL)
+	if err := checkIssueOpen(issue, ""); err != nil {
+		return err
+	}
 
-	// Extract @file references from issue
-	refs := ctxloader.ExtractFileReferences(issue.Title + "\n" + issue.Body)
-	if len(refs) > 0 {
-		fmt.Printf("Found @references: %v\n", refs)
+	warnIfNoPushAccess(ctx, gh, "")
+	cl = issueClaude(cl, issue, "")
+	cl = report.trackUsage(cl, issueModel(issue))
+
+	branchName := issueBranchName(issueNum)
+	base := issueBase(issue)
+	scope, err := scopeIssue(issue, "")
+	if err != nil {
+		return err
 	}
 
-	// Load referenced files
-	referencedFiles := ctxloader.LoadReferencedFiles(refs, ".")
-	for _, f := range referencedFiles {
-		if f.Found {
-			fmt.Printf("✓ Loaded referenced file: %s\n", f.Path)
-		} else {
-			fmt.Printf("⚠ File not found: %s\n", f.Path)
+	var description, changeSummary string
+	if useWorker {
+		// Let the worker run branch → generate → commit → push in isolation
+		refs, _ := issueReferences(issue, ".", "")
+		if err := processIssueInWorker(ctx, issue, branchName, refs, ""); err != nil {
+			return err
 		}
-	}
+	} else {
+		// Create branch, in its own worktree with --worktree
+		var removeWorktree func()
+		git, removeWorktree, err = checkoutIssueBranch(ctx, git, base, branchName, "")
+		if err != nil {
+			return err
+		}
+		defer removeWorktree()
+		reportProgress(ProgressEvent{Type: EventBranchCreated, Issue: issueNum, Branch: branchName, Base: base}, "")
 
-	// Create branch
-	branchName := fmt.Sprintf("vibe-git/issue-%d", issueNum)
-	fmt.Printf("Creating branch: %s\n", branchName)
+		// Build the prompt from the checkout the changes are applied to
+		_, referencedFiles := issueReferences(issue, git.Dir(), "")
+		cl = cl.WithRoot(git.Dir())
 
-	if err := git.CreateBranch(ctx, baseBranch, branchName); err != nil {
-		return fmt.Errorf("creating branch: %w", err)
-	}
+		// With --context=changed, build on the branch's earlier work and send its diff
+		branch, err := branchChanges(ctx, git, base, branchName, "")
+		if err != nil {
+			return err
+		}
 
-	// Generate code with Claude, passing referenced files
-	fmt.Println("Generating code with Claude...")
-	changes, err := cl.GenerateCode(ctx, issue.Title, issue.Body, referencedFiles)
-	if err != nil {
-		return fmt.Errorf("generating code: %w", err)
-	}
+		// Generate code with Claude, passing referenced files
+		reportProgress(ProgressEvent{Type: EventGeneratingCode, Issue: issueNum, Branch: branchName}, "")
+		changes, err := cl.GenerateCodeInScope(ctx, scope, issue.Title, promptBody(issue, ""), referencedFiles, branch)
+		if err != nil {
+			return withExitCode(ExitGeneration, fmt.Errorf("generating code: %w", err))
+		}
 
-	// Apply changes
-	fmt.Printf("Applying %d file changes...\n", len(changes))
-	if err := git.ApplyChanges(changes); err != nil {
-		return fmt.Errorf("applying changes: %w", err)
-	}
+		if err := checkChangeSize(git, changes, ""); err != nil {
+			return err
+		}
+		if err := scanGeneratedSecrets(git, changes, ""); err != nil {
+			return err
+		}
 
-	// Commit changes
-	commitMsg := fmt.Sprintf("Fix issue #%d: %s\n\n%s", issueNum, issue.Title, issue.URL)
-	if err := git.Commit(commitMsg); err != nil {
-		return fmt.Errorf("committing changes: %w", err)
-	}
+		// Let the pre-apply hook veto the changes
+		if err := runHook(ctx, "pre-apply", preApplyHook, git.Dir(), hookEnv(issue, branchName, "", changes), ""); err != nil {
+			return err
+		}
+
+		if interactive {
+			printChangeSummary(changes)
+			if !confirm("Apply these changes?") {
+				return errDeclined
+			}
+		}
+
+		// Apply changes
+		fmt.Printf("Applying %d file changes...\n", len(changes))
+		changes, err = applyIssueChanges(ctx, git, scope, changes, "")
+		if err != nil {
+			return fmt.Errorf("applying changes: %w", err)
+		}
+
+		// Commit changes
+		if err := commitIssueChanges(ctx, git, cl, issue, changes, ""); err != nil {
+			return fmt.Errorf("committing changes: %w", err)
+		}
+		reportProgress(ProgressEvent{Type: EventChangesApplied, Issue: issueNum, Branch: branchName, Files: len(changes)}, "")
+		changeSummary = diffSummary(ctx, git, base, "")
+		description = summarizeChanges(ctx, cl, changes, "")
+
+		if interactive && !confirm(fmt.Sprintf("Push %s and open a PR?", branchName)) {
+			fmt.Printf("Changes are committed on the local branch %s\n", branchName)
+			return errDeclined
+		}
 
-	// Push branch
-	fmt.Printf("Pushing branch %s...\n", branchName)
-	if err := git.PushBranch(ctx, branchName); err != nil {
-		return fmt.Errorf("pushing branch: %w", err)
+		// Push branch
+		fmt.Printf("Pushing branch %s...\n", branchName)
+		if err := git.PushBranch(ctx, branchName); err != nil {
+			return fmt.Errorf("pushing branch: %w", err)
+		}
+		reportProgress(ProgressEvent{Type: EventBranchPushed, Issue: issueNum, Branch: branchName}, "")
 	}
 
 	// Create PR
-	prTitle := fmt.Sprintf("Fix #%d: %s", issueNum, issue.Title)
-	prBody := fmt.Sprintf("Closes #%d\n\n%s", issueNum, issue.URL)
+	prTitle := fmt.Sprintf("Fix %s: %s", issueRef(issueNum), issue.Title)
+	prBody := buildPRBody(issueNum, issue.URL, description, changeSummary)
 
-	prNumber, prURL, err := gh.CreatePullRequestWithNumber(ctx, baseBranch, branchName, prTitle, prBody)
+	prNumber, prURL, existing, err := openPullRequest(ctx, gh, base, branchName, prTitle, prBody, "")
 	if err != nil {
-		return fmt.Errorf("creating PR: %w", err)
+		return err
+	}
+	reportProgress(ProgressEvent{Type: EventPRCreated, Issue: issueNum, Branch: branchName, PRNumber: prNumber, PRURL: prURL, Existing: existing}, "")
+	if !existing {
+		sendNotification(ctx, notify.Event{Type: notify.EventPRCreated, IssueNumber: issueNum, IssueTitle: issue.Title, PRURL: prURL}, "  ")
+	}
+
+	labelPullRequest(ctx, gh, prNumber, "  ")
+	requestPullRequestReview(ctx, gh, prNumber, "  ")
+	assignPullRequest(ctx, gh, prNumber, "  ")
+	if !existing {
+		linkPullRequestOnIssue(ctx, issues, issueNum, prURL, description, "  ")
 	}
 
-	fmt.Printf("✓ Created PR: %s\n", prURL)
+	if err := runHook(ctx, "post-commit", postCommitHook, checkoutDir(git), hookEnv(issue, branchName, prURL, nil), "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s %v\n", ui.Warn(), err)
+	}
 
 	// Auto-merge if enabled
 	if autoMerge {
 		if waitForChecks {
+			// Lets `vibe-git resume` finish the merge if this run is stopped while waiting
+			untrack := trackPendingMerge(ctx, issue, prNumber, prURL, branchName)
+			defer untrack()
+
 			fmt.Printf("  Waiting for CI checks to pass (timeout: %v)...\n", mergeTimeout)
 			if err := gh.WaitForMergeable(ctx, prNumber, mergeTimeout); err != nil {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to wait for checks: %v\n", err)
+				fmt.Fprintf(os.Stderr, "  %s Failed to wait for checks: %v\n", ui.Warn(), err)
 				fmt.Println("  You can merge manually later")
 				return nil
 			}
@@ -333,54 +894,56 @@ func processIssue(ctx context.Context, gh *github.Client, cl *claude.Client, git
 
 		fmt.Println("  Merging PR...")
 		mergeTitle := fmt.Sprintf("Merge: %s", prTitle)
-		mergeMsg := fmt.Sprintf("Auto-merged by vibe-git\n\nFixes #%d", issueNum)
+		mergeMsg := fmt.Sprintf("Auto-merged by vibe-git\n\nFixes %s", issueRef(issueNum))
 
 		if err := gh.MergePullRequest(ctx, prNumber, mergeTitle, mergeMsg); err != nil {
 			// Check if it's a conflict
-			if isConflictError(err) {
-				fmt.Println("  ⚠ Merge conflict detected, attempting to resolve...")
+			if isMergeConflict(ctx, gh, prNumber, err) {
+				fmt.Printf("  %s Merge conflict detected, attempting to resolve...\n", ui.Warn())
 
 				// Resolve conflicts
-				if err := git.ResolveConflicts(ctx, baseBranch, issue.Title, func(filePath, conflictContent, issueTitle string) (string, error) {
-					return cl.ResolveConflict(ctx, filePath, conflictContent, issueTitle)
-				}); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to resolve conflicts: %v\n", err)
+				if err := resolveConflicts(ctx, git, cl, base, issue.Title); err != nil {
 					fmt.Println("  You need to resolve conflicts manually")
-					return nil
+					return withExitCode(ExitConflict, fmt.Errorf("resolving merge conflicts: %w", err))
 				}
 
 				// Push resolved changes
 				fmt.Println("  Pushing resolved changes...")
 				if err := git.ForcePushWithLease(ctx, branchName); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to push resolved changes: %v\n", err)
+					fmt.Fprintf(os.Stderr, "  %s Failed to push resolved changes: %v\n", ui.Warn(), err)
 					return nil
 				}
 
-				// Wait a moment for GitHub to update PR status
-				time.Sleep(2 * time.Second)
+				// GitHub recomputes mergeability after the push; merging before that fails again
+				if err := awaitMergeable(ctx, gh, prNumber); err != nil {
+					fmt.Println("  You can merge manually later")
+					return err
+				}
 
 				// Retry merge
 				fmt.Println("  Retrying merge after conflict resolution...")
 				if err := gh.MergePullRequest(ctx, prNumber, mergeTitle, mergeMsg); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to merge PR after conflict resolution: %v\n", err)
+					fmt.Fprintf(os.Stderr, "  %s Failed to merge PR after conflict resolution: %v\n", ui.Warn(), err)
 					fmt.Println("  You can merge manually later")
 					return nil
 				}
 			} else {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to merge PR: %v\n", err)
+				fmt.Fprintf(os.Stderr, "  %s Failed to merge PR: %v\n", ui.Warn(), err)
 				fmt.Println("  You can merge manually later")
 				return nil
 			}
 		}
-		fmt.Println("  ✓ PR merged successfully")
+		reportProgress(ProgressEvent{Type: EventMerged, Issue: issueNum, Branch: branchName, PRNumber: prNumber, PRURL: prURL}, "  ")
+		sendNotification(ctx, notify.Event{Type: notify.EventPRMerged, IssueNumber: issueNum, IssueTitle: issue.Title, PRURL: prURL}, "  ")
+		deleteMergedBranch(ctx, gh, branchName, "  ")
 
 		// Close issue if enabled (only after successful merge)
 		if closeIssue {
 			fmt.Println("  Closing issue...")
-			if err := gh.CloseIssue(ctx, issueNum); err != nil {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to close issue: %v\n", err)
+			if err := issues.CloseIssue(ctx, issueNum); err != nil {
+				fmt.Fprintf(os.Stderr, "  %s Failed to close issue: %v\n", ui.Warn(), err)
 			} else {
-				fmt.Println("  ✓ Issue closed")
+				fmt.Printf("  %s Issue closed\n", ui.Success())
 			}
 		}
 	}
@@ -388,6 +951,1115 @@ func processIssue(ctx context.Context, gh *github.Client, cl *claude.Client, git
 	return nil
 }
 
+// resolveConflicts brings the PR branch up to date with base using the configured
+// conflict strategy, letting Claude resolve each conflicted file
+func resolveConflicts(ctx context.Context, git *git.Client, cl *claude.Client, base, issueTitle string) error {
+	resolver := func(filePath, conflictContent, issueTitle string, versions *claude.ConflictVersions) (string, error) {
+		return cl.ResolveConflictWithVersions(ctx, filePath, conflictContent, issueTitle, versions)
+	}
+
+	if conflictStrategy == "rebase" {
+		return git.RebaseResolveConflicts(ctx, base, issueTitle, resolver)
+	}
+	return git.ResolveConflicts(ctx, base, issueTitle, resolver)
+}
+
+// resolveSampling leaves the temperature to the model when only --top-p is
+// given, since the API rejects requests that set both
+func resolveSampling(fs *flag.FlagSet) error {
+	given := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+	if !given["top-p"] || topP == claude.ModelDefault {
+		return nil
+	}
+	if given["temperature"] && temperature != claude.ModelDefault {
+		return fmt.Errorf("--temperature and --top-p cannot be used together")
+	}
+	temperature = claude.ModelDefault
+	return nil
+}
+
+// parseRepoSlug splits an "owner/name" repository reference
+func parseRepoSlug(slug string) (owner, name string, err error) {
+	parts := strings.Split(slug, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q is not in owner/name form", slug)
+	}
+	return parts[0], parts[1], nil
+}
+
+// detectRepo reads the owner and name of the GitHub repository from the
+// origin remote of the checkout at dir
+func detectRepo(dir string) (owner, name string, err error) {
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("reading the origin remote: %w", err)
+	}
+	return git.ParseRemoteURL(strings.TrimSpace(string(out)))
+}
+
+// setupAppAuth loads the GitHub App credentials when any --github-app-* flag is set
+func setupAppAuth() error {
+	if appID == 0 && appInstallation == 0 && appKeyPath == "" {
+		return nil
+	}
+	if appID <= 0 || appInstallation <= 0 || appKeyPath == "" {
+		return withExitCode(ExitUsage, fmt.Errorf("GitHub App authentication needs --github-app-id, --github-app-installation-id and --github-app-key"))
+	}
+	key, err := os.ReadFile(appKeyPath)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("reading GitHub App key: %w", err))
+	}
+	appAuth, err = github.NewAppAuth(appID, appInstallation, key)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid --github-app-key: %w", err))
+	}
+	return nil
+}
+
+// requireGitHubAuth fails unless a token or a GitHub App is configured
+func requireGitHubAuth() error {
+	if githubToken == "" && appAuth == nil {
+		return withExitCode(ExitUsage, fmt.Errorf("GitHub token required (use --github-token or GITHUB_TOKEN env, or the --github-app-* flags)"))
+	}
+	return nil
+}
+
+// newGitHubClient creates a client for owner/repo, authenticated as the GitHub
+// App when one is configured
+func newGitHubClient(owner, repo string) *github.Client {
+	if appAuth != nil {
+		gh := github.NewClientWithApp(appAuth, owner, repo)
+		gh.SetTimeout(githubTimeout)
+		return gh
+	}
+	return github.NewClientWithTimeout(githubToken, owner, repo, githubTimeout)
+}
+
+// newGitClient creates the git client pushing to the target repository
+func newGitClient() *git.Client {
+	gc := git.NewClient(targetOwner, targetName, githubToken)
+	gc.SetCommitAuthor(commitIdentity)
+	gc.SetProxy(proxyURL)
+	if appAuth != nil {
+		gc.SetTokenSource(appAuth.Token)
+	}
+	return gc
+}
+
+// isCrossRepo reports whether PRs go to a different repository than the issues come from
+func isCrossRepo() bool {
+	return !strings.EqualFold(targetOwner, repoOwner) || !strings.EqualFold(targetName, repoName)
+}
+
+// issueRef formats an issue reference usable from PRs in the target repository
+func issueRef(number int) string {
+	if isCrossRepo() {
+		return fmt.Sprintf("%s/%s#%d", repoOwner, repoName, number)
+	}
+	return fmt.Sprintf("#%d", number)
+}
+
+// validateRepoAccess checks that both the issue repository and the target repository are accessible
+func validateRepoAccess(ctx context.Context, issues, target *github.Client) error {
+	if _, err := issues.GetDefaultBranch(ctx); err != nil {
+		return fmt.Errorf("accessing issue repository %s/%s: %w", repoOwner, repoName, err)
+	}
+	if _, err := target.GetDefaultBranch(ctx); err != nil {
+		return fmt.Errorf("accessing target repository %s/%s: %w", targetOwner, targetName, err)
+	}
+	return nil
+}
+
+// resolveBaseBranch replaces baseBranch with the target repository's default branch
+// when --base is empty or --base-from-default is set. The lookup happens once per run.
+func resolveBaseBranch(ctx context.Context, gh *github.Client) error {
+	if baseDetected || (baseBranch != "" && !baseFromDefault) {
+		return nil
+	}
+
+	branch, err := gh.GetDefaultBranch(ctx)
+	if err != nil {
+		return fmt.Errorf("detecting default branch: %w", err)
+	}
+	if branch == "" {
+		return fmt.Errorf("detecting default branch: repository reported no default branch")
+	}
+
+	baseBranch = branch
+	baseDetected = true
+	fmt.Printf("Using default branch as base: %s\n", branch)
+	return nil
+}
+
+// baseMapping sends issues carrying Label to the Base branch
+type baseMapping struct {
+	Label string
+	Base  string
+}
+
+// parseBaseMap parses --base-map, e.g. "hotfix=release,docs=gh-pages"
+func parseBaseMap(value string) ([]baseMapping, error) {
+	var mappings []baseMapping
+	for _, pair := range splitList(value) {
+		label, base, ok := strings.Cut(pair, "=")
+		label, base = strings.TrimSpace(label), strings.TrimSpace(base)
+		if !ok || label == "" || base == "" {
+			return nil, fmt.Errorf("%q is not in label=branch form", pair)
+		}
+		mappings = append(mappings, baseMapping{Label: label, Base: base})
+	}
+	return mappings, nil
+}
+
+// modelMapping sends issues carrying Label to the Model
+type modelMapping struct {
+	Label string
+	Model string
+}
+
+// parseModelMap parses --model-map, e.g. "complex=claude-opus-4-1,docs=claude-3-5-haiku-latest"
+func parseModelMap(value string) ([]modelMapping, error) {
+	var mappings []modelMapping
+	for _, pair := range splitList(value) {
+		label, m, ok := strings.Cut(pair, "=")
+		label, m = strings.TrimSpace(label), strings.TrimSpace(m)
+		if !ok || label == "" || m == "" {
+			return nil, fmt.Errorf("%q is not in label=model form", pair)
+		}
+		mappings = append(mappings, modelMapping{Label: label, Model: m})
+	}
+	return mappings, nil
+}
+
+// issueBranchName returns the branch vibe-git works on for an issue
+func issueBranchName(issueNum int) string {
+	return fmt.Sprintf("vibe-git/issue-%d", issueNum)
+}
+
+// pathDirective matches a "/path dir" line in an issue body
+var pathDirective = regexp.MustCompile(`(?m)^[ \t]*/path[ \t]+(\S+)[ \t\r]*$`)
+
+// issueScope returns the directory an issue's "/path dir" directive confines
+// generation to, relative to the repository root, or "" without a directive
+func issueScope(issue *github.Issue) (string, error) {
+	matches := pathDirective.FindAllStringSubmatch(issue.Body, -1)
+	switch len(matches) {
+	case 0:
+		return "", nil
+	case 1:
+	default:
+		return "", fmt.Errorf("issue has %d /path directives; use one", len(matches))
+	}
+
+	dir := path.Clean(strings.TrimSuffix(matches[0][1], "/"))
+	if path.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", fmt.Errorf("invalid /path %q: must be a directory inside the repository", matches[0][1])
+	}
+	return dir, nil
+}
+
+// scopeIssue reads the issue's /path directive and reports the scope. The
+// worker doesn't enforce scopes, so scoped issues are refused with --use-worker
+// rather than run against the whole repository.
+func scopeIssue(issue *github.Issue, indent string) (string, error) {
+	scope, err := issueScope(issue)
+	if err != nil || scope == "" {
+		return "", err
+	}
+	if useWorker {
+		return "", fmt.Errorf("the /path directive is not supported with --use-worker")
+	}
+	fmt.Printf("%sLimited to %s/ by the issue's /path directive\n", indent, scope)
+	return scope, nil
+}
+
+// issueBase returns the base branch for issue: the branch of the first --base-map
+// entry whose label the issue carries, or --base
+func issueBase(issue *github.Issue) string {
+	for _, m := range baseMappings {
+		for _, label := range issue.Labels {
+			if strings.EqualFold(label, m.Label) {
+				return m.Base
+			}
+		}
+	}
+	return baseBranch
+}
+
+// issueModel returns the model for issue: the model of the first --model-map
+// entry whose label the issue carries, or --model
+func issueModel(issue *github.Issue) string {
+	for _, m := range modelMappings {
+		for _, label := range issue.Labels {
+			if strings.EqualFold(label, m.Label) {
+				return m.Model
+			}
+		}
+	}
+	return model
+}
+
+// issueClaude returns cl switched to the issue's --model-map model, or cl
+// itself when the issue uses --model
+func issueClaude(cl *claude.Client, issue *github.Issue, indent string) *claude.Client {
+	m := issueModel(issue)
+	if cl == nil || m == model {
+		return cl
+	}
+	fmt.Printf("%sUsing model %s for this issue's labels\n", indent, m)
+	return cl.WithModel(m)
+}
+
+// validateModelMap checks that every model named by --model-map is offered by
+// the API. Models missing from the list, such as aliases, are looked up one by
+// one. If the list or a lookup can't be fetched, e.g. from a gateway without
+// /v1/models, the models are used unchecked.
+func validateModelMap(ctx context.Context, cl *claude.Client) error {
+	if len(modelMappings) == 0 {
+		return nil
+	}
+	cache, err := loadModels(ctx, cl, os.Getenv("ANTHROPIC_BASE_URL"), false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s Could not list models to check --model-map: %v\n", ui.Warn(), err)
+		return nil
+	}
+
+	offered := make(map[string]bool, len(cache.Models))
+	for _, m := range cache.Models {
+		offered[m.ID] = true
+	}
+	for _, m := range modelMappings {
+		if offered[m.Model] {
+			continue
+		}
+		if _, err := cl.GetModel(ctx, m.Model); err != nil {
+			var apiErr *claude.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				return withExitCode(ExitUsage, fmt.Errorf("model %s for label %s is not offered by the API (see `vibe-git models`)", m.Model, m.Label))
+			}
+			fmt.Fprintf(os.Stderr, "%s Could not check model %s for label %s: %v\n", ui.Warn(), m.Model, m.Label, err)
+			continue
+		}
+		offered[m.Model] = true
+	}
+	return nil
+}
+
+// validateBaseMap checks that every branch named by --base-map exists in the target repository
+func validateBaseMap(ctx context.Context, gh *github.Client) error {
+	checked := make(map[string]bool)
+	for _, m := range baseMappings {
+		if checked[m.Base] {
+			continue
+		}
+		checked[m.Base] = true
+
+		exists, err := gh.BranchExists(ctx, m.Base)
+		if err != nil {
+			return fmt.Errorf("checking base branch %s for label %s: %w", m.Base, m.Label, err)
+		}
+		if !exists {
+			return withExitCode(ExitUsage, fmt.Errorf("base branch %s for label %s does not exist in %s/%s", m.Base, m.Label, targetOwner, targetName))
+		}
+	}
+	return nil
+}
+
+// Attempts and spacing for creating a PR when GitHub's answer is lost
+var (
+	createPRAttempts   = 3
+	createPRRetryDelay = 2 * time.Second
+)
+
+// openPullRequest creates the PR for branchName, or updates the one already open
+// for it, and reports which. Reruns converge on a single PR: an open PR is looked
+// up before creating one, and when a create fails without a clear answer (the
+// request or reading its response failed) GitHub is checked for the PR before
+// the create is retried.
+func openPullRequest(ctx context.Context, gh *github.Client, base, branchName, title, body, indent string) (prNumber int, prURL string, existing bool, err error) {
+	if pr, lookupErr := gh.GetPullRequestForBranch(ctx, branchName); lookupErr == nil && pr != nil {
+		return updateExistingPullRequest(ctx, gh, pr, title, body, indent)
+	}
+
+	for attempt := 1; ; attempt++ {
+		prNumber, prURL, err = gh.CreatePullRequestWithNumber(ctx, base, branchName, title, body, draftPR)
+		if err == nil {
+			fmt.Printf("%s%s Created PR: %s\n", indent, ui.Success(), prURL)
+			return prNumber, prURL, false, nil
+		}
+
+		var apiErr *github.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError && apiErr.StatusCode != http.StatusUnprocessableEntity {
+			return 0, "", false, fmt.Errorf("creating PR: %w", err)
+		}
+
+		pr, lookupErr := gh.GetPullRequestForBranch(ctx, branchName)
+		switch {
+		case lookupErr == nil && pr != nil && attempt == 1 && apiErr != nil && apiErr.StatusCode == http.StatusUnprocessableEntity:
+			// Opened by an earlier run that this lookup raced with
+			return updateExistingPullRequest(ctx, gh, pr, title, body, indent)
+		case lookupErr == nil && pr != nil:
+			// An earlier attempt of this run got through
+			fmt.Printf("%s%s Created PR: %s\n", indent, ui.Success(), pr.URL)
+			return pr.Number, pr.URL, false, nil
+		case apiErr != nil && apiErr.StatusCode == http.StatusUnprocessableEntity:
+			// The 422 had another cause, such as no commits between the branches
+			return 0, "", false, fmt.Errorf("creating PR: %w", err)
+		case attempt >= createPRAttempts:
+			return 0, "", false, fmt.Errorf("creating PR (%s): %w", plural(attempt, "attempt"), err)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s%s Creating PR failed, retrying: %v\n", indent, ui.Warn(), err)
+		select {
+		case <-ctx.Done():
+			return 0, "", false, fmt.Errorf("creating PR: %w", ctx.Err())
+		case <-time.After(time.Duration(attempt) * createPRRetryDelay):
+		}
+	}
+}
+
+// updateExistingPullRequest refreshes the open PR of a reprocessed issue: its title
+// and body are replaced, its draft state follows --draft/--ready and a comment
+// notes the new commits
+func updateExistingPullRequest(ctx context.Context, gh *github.Client, pr *github.PullRequest, title, body, indent string) (int, string, bool, error) {
+	fmt.Printf("%s%s Updated existing PR: %s\n", indent, ui.Success(), pr.URL)
+	if err := gh.UpdatePullRequest(ctx, pr.Number, title, body); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to update PR title and body: %v\n", indent, ui.Warn(), err)
+	}
+	if (draftPR && !pr.Draft) || (readyPR && pr.Draft) {
+		if err := gh.SetPullRequestDraft(ctx, pr.Number, draftPR); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%s Failed to change draft state: %v\n", indent, ui.Warn(), err)
+		} else if draftPR {
+			fmt.Printf("%s%s Converted PR to draft\n", indent, ui.Success())
+		} else {
+			fmt.Printf("%s%s Marked PR ready for review\n", indent, ui.Success())
+		}
+	}
+	if err := gh.AddIssueComment(ctx, pr.Number, "vibe-git pushed new changes to this branch after reprocessing the issue."); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to comment on PR #%d: %v\n", indent, ui.Warn(), pr.Number, err)
+	}
+	return pr.Number, pr.URL, true, nil
+}
+
+// mergeRecheckTimeout bounds the wait for GitHub to recompute a PR's
+// mergeability after resolved conflicts are pushed
+var mergeRecheckTimeout = 2 * time.Minute
+
+// awaitMergeable waits until GitHub reports the PR mergeable again after its
+// branch was updated
+func awaitMergeable(ctx context.Context, gh *github.Client, prNumber int) error {
+	fmt.Println("  Waiting for GitHub to recheck mergeability...")
+	if err := gh.WaitForMergeable(ctx, prNumber, mergeRecheckTimeout); err != nil {
+		return withExitCode(ExitConflict, fmt.Errorf("PR #%d is still not mergeable after resolving conflicts (waited up to %s): %w", prNumber, mergeRecheckTimeout, err))
+	}
+	return nil
+}
+
+// labelPullRequest applies --pr-labels to a new PR, creating missing labels.
+// Labels that can't be created are skipped with a warning rather than failing the run.
+func labelPullRequest(ctx context.Context, gh *github.Client, prNumber int, indent string) {
+	var labels []string
+	for _, label := range splitList(prLabels) {
+		if err := gh.EnsureLabel(ctx, label); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%s Skipping label %q: %v\n", indent, ui.Warn(), label, err)
+			continue
+		}
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return
+	}
+
+	if err := gh.AddLabelsToIssue(ctx, prNumber, labels); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to add labels: %v\n", indent, ui.Warn(), err)
+		return
+	}
+	fmt.Printf("%s%s Added labels: %s\n", indent, ui.Success(), strings.Join(labels, ", "))
+}
+
+// requestPullRequestReview requests review from --reviewers. Entries of the form
+// org/team are team reviewers. If the combined request is rejected, each reviewer
+// is requested on its own so one invalid name doesn't block the rest.
+func requestPullRequestReview(ctx context.Context, gh *github.Client, prNumber int, indent string) {
+	reviewers := splitList(prReviewers)
+	if len(reviewers) == 0 {
+		return
+	}
+
+	users, teams := splitReviewers(reviewers)
+	err := gh.RequestReviewers(ctx, prNumber, users, teams)
+	if err == nil {
+		fmt.Printf("%s%s Requested review from: %s\n", indent, ui.Success(), strings.Join(reviewers, ", "))
+		return
+	}
+	if len(reviewers) == 1 {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to request review from %s: %v\n", indent, ui.Warn(), reviewers[0], err)
+		return
+	}
+
+	var requested []string
+	for _, reviewer := range reviewers {
+		users, teams := splitReviewers([]string{reviewer})
+		if err := gh.RequestReviewers(ctx, prNumber, users, teams); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%s Failed to request review from %s: %v\n", indent, ui.Warn(), reviewer, err)
+			continue
+		}
+		requested = append(requested, reviewer)
+	}
+	if len(requested) > 0 {
+		fmt.Printf("%s%s Requested review from: %s\n", indent, ui.Success(), strings.Join(requested, ", "))
+	}
+}
+
+// splitReviewers separates user logins from org/team entries, returning team slugs
+func splitReviewers(reviewers []string) (users, teams []string) {
+	for _, r := range reviewers {
+		if i := strings.Index(r, "/"); i >= 0 {
+			teams = append(teams, r[i+1:])
+		} else {
+			users = append(users, r)
+		}
+	}
+	return users, teams
+}
+
+// assignPullRequest assigns --pr-assignees and reports any that GitHub ignored
+func assignPullRequest(ctx context.Context, gh *github.Client, prNumber int, indent string) {
+	assignees := splitList(prAssignees)
+	if len(assignees) == 0 {
+		return
+	}
+
+	assigned, err := gh.AddAssignees(ctx, prNumber, assignees)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to add assignees: %v\n", indent, ui.Warn(), err)
+		return
+	}
+
+	var missing []string
+	for _, a := range assignees {
+		found := false
+		for _, got := range assigned {
+			if strings.EqualFold(a, got) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, a)
+		}
+	}
+
+	if len(missing) < len(assignees) {
+		fmt.Printf("%s%s Assigned: %s\n", indent, ui.Success(), strings.Join(assigned, ", "))
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "%s%s Could not assign: %s\n", indent, ui.Warn(), strings.Join(missing, ", "))
+	}
+}
+
+// linkPullRequestOnIssue comments on the source issue with the PR URL so watchers are notified
+func linkPullRequestOnIssue(ctx context.Context, issues *github.Client, issueNum int, prURL, description, indent string) {
+	if !commentOnIssue {
+		return
+	}
+
+	body := fmt.Sprintf("vibe-git opened a pull request for this issue: %s", prURL)
+	if description != "" {
+		body += "\n\n" + description
+	}
+	if err := issues.AddIssueComment(ctx, issueNum, body); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to comment on issue: %v\n", indent, ui.Warn(), err)
+		return
+	}
+	fmt.Printf("%s%s Linked PR on issue %s\n", indent, ui.Success(), issueRef(issueNum))
+}
+
+// defaultMaxFiles keeps the codebase section of large repositories usable
+const defaultMaxFiles = 1000
+
+// configureCodebase applies --no-codebase, --codebase-only-dirs, --max-files,
+// --redact-secrets, --check-tokens and --auto-trim to the client
+func configureCodebase(cl *claude.Client) {
+	cl.SetSkipCodebase(noCodebase)
+	cl.SetCodebaseDirs(splitList(codebaseDirs))
+	cl.SetMaxFiles(maxFiles)
+	cl.SetRedactSecrets(redactSecrets)
+	cl.SetCodebaseReport(reportCodebase)
+	cl.SetCheckTokens(checkTokens)
+	cl.SetTokenReport(func(tokens int, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Couldn't count prompt tokens, sending it anyway: %v\n", ui.Warn(), err)
+			return
+		}
+		fmt.Printf("Prompt size: %s of %d\n", plural(tokens, "token"), claude.ContextWindow)
+	})
+	cl.SetAutoTrim(autoTrim)
+	cl.SetTrimReport(func(s claude.TrimStats) {
+		fmt.Printf("%s Trimmed the codebase section to %s (%d dropped) to fit: %s\n",
+			ui.Warn(), plural(s.FilesKept, "file"), s.FilesDropped, plural(s.Tokens, "token"))
+	})
+}
+
+// configureImages applies --include-images to the client
+func configureImages(cl *claude.Client) {
+	cl.SetIncludeImages(includeImages, githubToken)
+	if appAuth != nil {
+		cl.SetImageTokenSource(appAuth.Token)
+	}
+	cl.SetImageReport(func(url string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Skipped issue image %s: %v\n", ui.Warn(), url, err)
+			return
+		}
+		fmt.Printf("%s Attached issue image %s\n", ui.Success(), url)
+	})
+}
+
+// reportCodebase logs what went into a prompt's codebase section
+func reportCodebase(stats ctxloader.CodebaseStats) {
+	summary := fmt.Sprintf("Codebase context: %s, %d KB", plural(stats.FilesIncluded, "file"), (stats.Bytes+1023)/1024)
+	if stats.FilesOmitted > 0 {
+		summary += fmt.Sprintf(" (%d omitted by --max-files)", stats.FilesOmitted)
+	}
+	fmt.Println(summary)
+	if stats.Redactions > 0 {
+		fmt.Printf("%s Redacted %s from the codebase before sending it to Claude\n", ui.Warn(), plural(stats.Redactions, "possible secret"))
+	}
+}
+
+// configureDumps points the client's prompt and response dumps at the files named by
+// --dump-prompt and --dump-response. The returned func closes the files.
+func configureDumps(cl *claude.Client) (func(), error) {
+	files := map[string]*os.File{}
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	// Both dumps may name the same file, which is then opened once
+	open := func(path string) (io.Writer, error) {
+		if path == "-" {
+			return os.Stdout, nil
+		}
+		if f, ok := files[path]; ok {
+			return f, nil
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = f
+		return f, nil
+	}
+
+	if dumpPrompt != "" {
+		w, err := open(dumpPrompt)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("opening prompt dump: %w", err)
+		}
+		cl.SetPromptDump(w)
+	}
+	if dumpResponse != "" {
+		w, err := open(dumpResponse)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("opening response dump: %w", err)
+		}
+		cl.SetResponseDump(w)
+	}
+
+	return closeAll, nil
+}
+
+// sendNotification posts event to the configured webhook, if any. Failures only
+// warn so a broken webhook never fails the run.
+func sendNotification(ctx context.Context, event notify.Event, indent string) {
+	if notifier == nil {
+		return
+	}
+	if event.Repo == "" {
+		event.Repo = repoOwner + "/" + repoName
+	}
+	if err := notifier.Notify(ctx, event); err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Failed to send notification: %v\n", indent, ui.Warn(), err)
+	}
+}
+
+// hookEnv describes the current issue for hook scripts
+func hookEnv(issue *github.Issue, branchName, prURL string, changes []claude.FileChange) hooks.Env {
+	env := hooks.Env{
+		IssueNumber: issue.Number,
+		IssueTitle:  issue.Title,
+		Repo:        targetOwner + "/" + targetName,
+		Branch:      branchName,
+		BaseBranch:  issueBase(issue),
+		PRURL:       prURL,
+	}
+	for _, c := range changes {
+		env.ChangedFiles = append(env.ChangedFiles, c.Path)
+	}
+	return env
+}
+
+// checkoutDir returns the directory of gc's checkout, or "" for the current
+// directory when there is none, e.g. because a worker processed the issue
+func checkoutDir(gc *git.Client) string {
+	if gc == nil {
+		return ""
+	}
+	return gc.Dir()
+}
+
+// runHook runs a user hook if one is configured in dir, the checkout the
+// issue's changes are applied to, echoing its output with indent
+func runHook(ctx context.Context, name, command, dir string, env hooks.Env, indent string) error {
+	if command == "" {
+		return nil
+	}
+
+	fmt.Printf("%sRunning %s hook...\n", indent, name)
+	if _, err := hooks.Run(ctx, command, dir, env, hookTimeout, &prefixWriter{w: os.Stdout, prefix: indent + "  | "}); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	fmt.Printf("%s%s %s hook passed\n", indent, ui.Success(), name)
+	return nil
+}
+
+// prefixWriter prefixes each line written to w
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		if !p.midLine {
+			if _, err := io.WriteString(p.w, p.prefix); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := io.WriteString(p.w, line); err != nil {
+			return 0, err
+		}
+		p.midLine = !strings.HasSuffix(line, "\n")
+	}
+	return len(b), nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// promptBody is the issue body as sent to the model: template comments removed and
+// open tasks listed as requirements. issue.Body itself is left untouched.
+func promptBody(issue *github.Issue, indent string) string {
+	prepared := issueprep.Prepare(issue.Body)
+	if n := len(prepared.Requirements); n > 0 {
+		fmt.Printf("%sFound %s in the issue's task list\n", indent, plural(n, "open task"))
+	}
+	return prepared.PromptBody()
+}
+
+// summarizeChanges asks Claude to describe the changes when --summarize is set.
+// Failures only warn, since the PR is still useful without a description.
+func summarizeChanges(ctx context.Context, cl *claude.Client, changes []claude.FileChange, indent string) string {
+	if !summarize {
+		return ""
+	}
+
+	fmt.Printf("%sSummarizing changes with Claude...\n", indent)
+	summary, err := cl.Summarize(ctx, changes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Could not summarize changes: %v\n", indent, ui.Warn(), err)
+		return ""
+	}
+	return summary
+}
+
+// maxPRBodyLength is the most characters GitHub accepts in a PR body
+const maxPRBodyLength = 65536
+
+// maxSummaryFiles caps how many files are listed in a PR body's change summary
+const maxSummaryFiles = 100
+
+// buildPRBody links the PR to its issue and appends the model's description and
+// the change summary, when there are any
+func buildPRBody(issueNum int, issueURL, description, changeSummary string) string {
+	body := fmt.Sprintf("Closes %s\n\n%s", issueRef(issueNum), issueURL)
+	if description != "" {
+		body += "\n\n### Summary\n\n" + description
+	}
+	if changeSummary != "" {
+		body += "\n\n" + changeSummary
+	}
+
+	if runes := []rune(body); len(runes) > maxPRBodyLength {
+		const note = "\n\n*Truncated to fit GitHub's size limit.*"
+		body = string(runes[:maxPRBodyLength-len(note)]) + note
+	}
+	return body
+}
+
+// diffSummary describes the changes committed on the issue branch for the PR
+// body. It returns "" if they can't be diffed; that only costs the summary.
+func diffSummary(ctx context.Context, gc *git.Client, base, indent string) string {
+	stat, err := gc.DiffStat(ctx, "origin/"+base)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Could not summarize changes for the PR: %v\n", indent, ui.Warn(), err)
+		return ""
+	}
+	return formatDiffStat(stat)
+}
+
+// formatDiffStat renders stat as Markdown, like `git diff --stat`
+func formatDiffStat(stat *git.DiffStat) string {
+	var sb strings.Builder
+	sb.WriteString("### Changes\n\n")
+
+	sb.WriteString(fmt.Sprintf("%s changed, %s(+), %s(-)\n\n",
+		plural(len(stat.Files), "file"), plural(stat.Insertions, "insertion"), plural(stat.Deletions, "deletion")))
+
+	for i, f := range stat.Files {
+		if i == maxSummaryFiles {
+			sb.WriteString(fmt.Sprintf("- ...and %d more\n", len(stat.Files)-i))
+			break
+		}
+		if f.Binary {
+			sb.WriteString(fmt.Sprintf("- `%s` (binary)\n", f.Path))
+		} else {
+			sb.WriteString(fmt.Sprintf("- `%s` (+%d -%d)\n", f.Path, f.Insertions, f.Deletions))
+		}
+	}
+	return sb.String()
+}
+
+// plural formats a count with its noun, e.g. "1 file" or "2 files"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// checkoutIssueBranch creates branchName from base. With --worktree the
+// branch is checked out in a temporary worktree instead of the current checkout,
+// and the returned client works there; the returned func removes the worktree.
+func checkoutIssueBranch(ctx context.Context, gc *git.Client, base, branchName, indent string) (*git.Client, func(), error) {
+	fmt.Printf("%sCreating branch: %s (from %s)\n", indent, branchName, base)
+
+	if !useWorktree {
+		if err := gc.CreateBranch(ctx, base, branchName); err != nil {
+			return nil, nil, fmt.Errorf("creating branch: %w", err)
+		}
+		return gc, func() {}, nil
+	}
+
+	wt, err := gc.CreateWorktree(ctx, base, branchName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating worktree: %w", err)
+	}
+	fmt.Printf("%s  Working in %s\n", indent, wt.Dir())
+
+	return wt, func() {
+		if err := wt.RemoveWorktree(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%s Failed to remove worktree %s: %v\n", indent, ui.Warn(), wt.Dir(), err)
+		}
+	}, nil
+}
+
+// commitMessage is the message of an issue's commit. With --conventional-commits
+// its subject follows Conventional Commits, and with --co-author it credits the
+// issue's author, unless that is a bot.
+func commitMessage(ctx context.Context, cl *claude.Client, issue *github.Issue, changes []claude.FileChange, indent string) string {
+	msg := fmt.Sprintf("Fix issue #%d: %s\n\n%s", issue.Number, issue.Title, issue.URL)
+	if conventional {
+		msg = conventionalCommitMessage(ctx, cl, issue, changes, indent) + "\n\n" + issue.URL
+	}
+	if coAuthor && issue.Author != "" && !strings.HasSuffix(issue.Author, "[bot]") {
+		msg = git.WithCoAuthor(msg, git.GitHubUser(issue.Author, issue.AuthorID))
+	}
+	return msg
+}
+
+// commitIssueChanges commits the applied changes of issue, in one commit or,
+// with --commit-per-file, one per change
+func commitIssueChanges(ctx context.Context, gc *git.Client, cl *claude.Client, issue *github.Issue, changes []claude.FileChange, indent string) error {
+	if !commitPerFile {
+		return gc.Commit(ctx, commitMessage(ctx, cl, issue, changes, indent))
+	}
+	commits, err := gc.CommitEach(ctx, changes, func(change claude.FileChange) string {
+		return fileCommitMessage(issue, change)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%sMade %d commits, one per file\n", indent, commits)
+	return nil
+}
+
+// fileCommitMessage is the message of the --commit-per-file commit of one
+// change, like "Update cmd/root.go", typed by the issue's labels with
+// --conventional-commits
+func fileCommitMessage(issue *github.Issue, change claude.FileChange) string {
+	verb := "Update"
+	switch change.Operation {
+	case "create":
+		verb = "Add"
+	case "delete":
+		verb = "Delete"
+	}
+	subject := verb + " " + change.Path
+	if conventional {
+		commitType := commitmsg.TypeForLabels(issue.Labels)
+		if commitType == "" {
+			commitType = "fix"
+		}
+		subject = commitmsg.Subject(commitType, subject)
+	}
+
+	msg := fmt.Sprintf("%s\n\nPart of issue #%d: %s\n\n%s", subject, issue.Number, issue.Title, issue.URL)
+	if coAuthor && issue.Author != "" && !strings.HasSuffix(issue.Author, "[bot]") {
+		msg = git.WithCoAuthor(msg, git.GitHubUser(issue.Author, issue.AuthorID))
+	}
+	return msg
+}
+
+// conventionalCommitMessage has Claude write the commit message, typed by the
+// issue's labels. Without a client, or when Claude's subject doesn't validate,
+// the subject is derived from the labels and the issue title instead.
+func conventionalCommitMessage(ctx context.Context, cl *claude.Client, issue *github.Issue, changes []claude.FileChange, indent string) string {
+	commitType := commitmsg.TypeForLabels(issue.Labels)
+	fallbackType := commitType
+	if fallbackType == "" {
+		fallbackType = "fix"
+	}
+	fallback := commitmsg.Subject(fallbackType, issue.Title)
+	if cl == nil {
+		return fallback
+	}
+
+	fmt.Printf("%sWriting commit message with Claude...\n", indent)
+	msg, err := cl.CommitMessage(ctx, issue.Title, commitType, changes)
+	if err == nil {
+		subject, _, _ := strings.Cut(msg, "\n")
+		err = commitmsg.Validate(subject)
+		if err == nil && commitType != "" && !strings.HasPrefix(subject, commitType) {
+			err = fmt.Errorf("subject %q is not of type %s", subject, commitType)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%s Using %q as the commit subject: %v\n", indent, ui.Warn(), fallback, err)
+		return fallback
+	}
+	return msg
+}
+
+// Values of --context
+const (
+	contextFull    = "full"
+	contextChanged = "changed"
+	contextNone    = "none"
+)
+
+// branchChanges implements --context=changed. When branchName was already
+// pushed, the fresh branch is moved onto it so the new commit builds on that
+// work, and its diff against base plus the changed files become the prompt's
+// context. It returns nil, meaning the full codebase is sent, otherwise.
+func branchChanges(ctx context.Context, gc *git.Client, base, branchName, indent string) (*claude.BranchChanges, error) {
+	if contextMode != contextChanged {
+		return nil, nil
+	}
+
+	found, err := gc.ContinueRemoteBranch(ctx, branchName)
+	if err != nil {
+		return nil, fmt.Errorf("continuing branch: %w", err)
+	}
+	if !found {
+		fmt.Printf("%s%s No earlier work on %s, sending the full codebase\n", indent, ui.Warn(), branchName)
+		return nil, nil
+	}
+
+	baseRef := "origin/" + base
+	files, err := gc.ChangedFiles(ctx, baseRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep files excluded by .vibe-git/ignore out of the diff as well
+	ignore, err := ctxloader.LoadIgnoreRules(gc.Dir())
+	if err != nil {
+		return nil, err
+	}
+	var exclude []string
+	for _, f := range files {
+		if ignore.Excluded(f, false) {
+			exclude = append(exclude, ":(exclude)"+f)
+		}
+	}
+	diff, err := gc.DiffAgainst(ctx, baseRef, exclude...)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := &claude.BranchChanges{Base: baseRef, Diff: diff}
+	if redactSecrets {
+		branch.Diff, branch.Redactions = ctxloader.RedactSecrets(diff)
+	}
+	branch.Files = ctxloader.LoadReferencedFiles(files, gc.Dir(), ctxloader.LoadOptions{
+		MaxFileSize:   maxFileSize,
+		RedactSecrets: redactSecrets,
+	})
+
+	fmt.Printf("%s%s Continuing %s: sending its diff against %s and %s\n", indent, ui.Success(), branchName, baseRef, plural(len(files), "changed file"))
+	if branch.Redactions > 0 {
+		fmt.Printf("%s%s Redacted %s in the diff\n", indent, ui.Warn(), plural(branch.Redactions, "possible secret"))
+	}
+	return branch, nil
+}
+
+// warnIfNoPushAccess warns up front when the token can't push, instead of failing at PushBranch
+func warnIfNoPushAccess(ctx context.Context, gh *github.Client, indent string) {
+	perms, err := gh.GetRepoPermissions(ctx)
+	if err != nil {
+		// Not fatal: the run will surface real access problems itself
+		return
+	}
+	if !perms.Push {
+		fmt.Printf("%s%s Token has no push access to %s/%s, pushing the branch will likely fail\n", indent, ui.Warn(), targetOwner, targetName)
+	}
+}
+
+// issueReferences extracts the @file references from issue and loads them from
+// root, the checkout the issue's changes are applied to
+func issueReferences(issue *github.Issue, root, indent string) ([]string, []*ctxloader.FileReference) {
+	refs := ctxloader.ExtractFileReferencesIn(issue.Title+"\n"+issue.Body, root)
+	if len(refs) > 0 {
+		fmt.Printf("%sFound @references: %v\n", indent, refs)
+	}
+	return refs, loadReferencedFiles(refs, root, indent)
+}
+
+// loadReferencedFiles loads @referenced files from root and reports what was
+// loaded, truncated or skipped
+func loadReferencedFiles(refs []string, root, indent string) []*ctxloader.FileReference {
+	opts := ctxloader.LoadOptions{
+		MaxFileSize:   maxFileSize,
+		SearchRoots:   splitList(refSearchRoots),
+		RedactSecrets: redactSecrets,
+	}
+
+	referencedFiles := ctxloader.LoadReferencedFiles(refs, root, opts)
+	for _, f := range referencedFiles {
+		if len(f.Matches) > 1 {
+			fmt.Printf("%s%s Ambiguous reference %s matched %d files, using %s\n", indent, ui.Warn(), f.Path, len(f.Matches), f.ResolvedPath)
+		}
+		if f.Redactions > 0 {
+			fmt.Printf("%s%s Redacted %s in %s\n", indent, ui.Warn(), plural(f.Redactions, "possible secret"), f.Path)
+		}
+		switch {
+		case f.Truncated:
+			fmt.Printf("%s%s Loaded referenced file: %s (truncated at %d of %d bytes)\n", indent, ui.Warn(), f.Path, len(f.Content), f.Size)
+		case f.Found && f.ResolvedPath != "":
+			fmt.Printf("%s%s Loaded referenced file: %s (found at %s)\n", indent, ui.Success(), f.Path, f.ResolvedPath)
+		case f.Found:
+			fmt.Printf("%s%s Loaded referenced file: %s\n", indent, ui.Success(), f.Path)
+		case f.Reason == "not found":
+			fmt.Printf("%s%s File not found: %s\n", indent, ui.Warn(), f.Path)
+		default:
+			fmt.Printf("%s%s Skipped referenced file: %s (%s)\n", indent, ui.Warn(), f.Path, f.Reason)
+		}
+	}
+	return referencedFiles
+}
+
+// processIssueInWorker delegates branch creation, code generation, commit and push to the worker
+func processIssueInWorker(ctx context.Context, issue *github.Issue, branchName string, refs []string, indent string) error {
+	// One ID ties this issue's lines in the worker and gateway logs together
+	ctx, requestID := requestid.Ensure(ctx)
+	fmt.Printf("%sDelegating to worker at %s (request %s)...\n", indent, workerURL, requestID)
+
+	// The worker pushes with this token, so an app's is fetched fresh for each issue
+	token := githubToken
+	if appAuth != nil {
+		var err error
+		if token, err = appAuth.Token(ctx); err != nil {
+			return fmt.Errorf("getting installation token for worker: %w", err)
+		}
+	}
+
+	wc, err := worker.NewClientWithHealthCheck(ctx, workerURL, workerToken, workerProject, 30*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, worker.ErrUnauthorized):
+			return fmt.Errorf("worker rejected token (check --worker-token or WORKER_TOKEN): %w", err)
+		case errors.Is(err, worker.ErrUnknownProject):
+			return fmt.Errorf("check --worker-project against the worker's WORKER_PROJECTS: %w", err)
+		case errors.Is(err, worker.ErrUnreachable):
+			return fmt.Errorf("worker not reachable at %s (is it running? try: make docker-up): %w", workerURL, err)
+		default:
+			return fmt.Errorf("worker not ready: %w", err)
+		}
+	}
+
+	result, err := wc.ProcessIssue(ctx, worker.IssueProcessRequest{
+		Number:      issue.Number,
+		Title:       issue.Title,
+		Body:        promptBody(issue, indent),
+		URL:         issue.URL,
+		Refs:        refs,
+		BaseBranch:  issueBase(issue),
+		Branch:      branchName,
+		Owner:       targetOwner,
+		Repo:        targetName,
+		GitHubToken: token,
+		Model:       issueModel(issue),
+	}, func(ev worker.IssueProgressEvent) {
+		if ev.Message != "" {
+			fmt.Printf("%s[worker] %s\n", indent, ev.Message)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("processing in worker (request %s): %w", requestID, err)
+	}
+
+	fmt.Printf("%s%s Worker pushed branch %s (%d file(s) changed)\n", indent, ui.Success(), result.Branch, len(result.Files))
+	return nil
+}
+
+// isMergeConflict checks whether a failed merge was caused by conflicts with the base branch.
+// It asks GitHub for the PR's mergeable_state and only falls back to matching the
+// merge error text when the state is unavailable or not yet computed.
+func isMergeConflict(ctx context.Context, gh *github.Client, prNumber int, mergeErr error) bool {
+	pr, err := gh.GetPullRequest(ctx, prNumber)
+	if err == nil {
+		switch pr.MergeableState {
+		case "dirty":
+			return true
+		case "", "unknown":
+			// GitHub hasn't computed mergeability yet
+		default:
+			return false
+		}
+	}
+
+	return isConflictError(mergeErr)
+}
+
 // isConflictError checks if the error is due to merge conflicts
 func isConflictError(err error) bool {
 	if err == nil {
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/git"
+	"vibe-git/internal/ui"
+)
+
+// --secret-scan values
+const (
+	secretScanBlock = "block" // Fail the issue when generated changes hold a likely secret
+	secretScanWarn  = "warn"  // List the likely secrets and commit anyway
+	secretScanOff   = "off"
+)
+
+var secretScan = secretScanWarn // --secret-scan
+
+// scanGeneratedSecrets looks for credentials Claude wrote into changes, such as
+// an example key copied from documentation, with the detection that redacts
+// them from prompts. Findings are listed, and fail the issue with
+// --secret-scan=block. Changes that write a masked secret's placeholder back are
+// always refused.
+func scanGeneratedSecrets(git *git.Client, changes []claude.FileChange, indent string) error {
+	// A masked secret echoed back would overwrite the real one, whatever --secret-scan says
+	if err := claude.CheckRedacted(checkoutDir(git), changes); err != nil {
+		return withExitCode(ExitGeneration, err)
+	}
+	if secretScan == secretScanOff {
+		return nil
+	}
+	total := 0
+	for _, change := range changes {
+		if change.Operation == "delete" {
+			continue
+		}
+		for _, s := range ctxloader.FindFileSecrets(change.Path, change.Content) {
+			fmt.Fprintf(os.Stderr, "%s%s Possible %s in %s:%d\n", indent, ui.Warn(), s.Kind, change.Path, s.Line)
+			total++
+		}
+	}
+	if total == 0 || secretScan != secretScanBlock {
+		return nil
+	}
+	return withExitCode(ExitGeneration, fmt.Errorf("generated changes contain %s (use --secret-scan=warn to commit them anyway)", plural(total, "possible secret")))
+}
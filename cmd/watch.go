@@ -3,25 +3,52 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"vibe-git/internal/breaker"
 	"vibe-git/internal/claude"
-	"vibe-git/internal/ctxloader"
 	"vibe-git/internal/git"
 	"vibe-git/internal/github"
+	"vibe-git/internal/notify"
+	"vibe-git/internal/ui"
 )
 
 var (
-	watchMode    string        // "webhook" or "poll"
+	watchMode    string // "webhook" or "poll"
 	webhookPort  int
 	pollInterval = 5 * time.Minute // default poll interval
 	lastChecked  time.Time
+	lastHandled  map[int]time.Time // Issues the last check handled, and when each was finished
+	issueTimeout = 5 * time.Minute // --timeout-per-issue
+	maxRuntime   time.Duration     // --max-runtime; 0 runs until interrupted
+	timeouts     = newTimeoutTracker()
+
+	enableTestEndpoint bool // --enable-test-endpoint; serves /webhook/test for local testing
+
+	breakerThreshold int           // --breaker-threshold; 0 disables the circuit breakers
+	breakerCooldown  time.Duration // --breaker-cooldown
+
+	onEdit   = onEditRegenerate // --on-edit; what webhook mode does when an issue is edited
+	botLogin string             // Login behind the GitHub token; edits it makes are ignored
+
+	requireLabel string // --require-label; watch mode only acts on issues carrying it
+	includePRs   bool   // --include-prs; poll mode treats open pull requests as issues too
+)
+
+// --on-edit values
+const (
+	onEditRegenerate = "regenerate"
+	onEditComment    = "comment"
+	onEditIgnore     = "ignore"
 )
 
 func init() {
@@ -31,14 +58,19 @@ func init() {
 // runWatch starts watching for new issues
 func runWatch() error {
 	// Validate flags
-	if githubToken == "" {
-		return fmt.Errorf("GitHub token required (use --github-token or GITHUB_TOKEN env)")
+	if err := requireGitHubAuth(); err != nil {
+		return err
 	}
 	if claudeAPIKey == "" {
-		return fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)")
+		return withExitCode(ExitUsage, fmt.Errorf("Claude API key required (use --claude-api-key or ANTHROPIC_API_KEY env)"))
 	}
 	if repoOwner == "" || repoName == "" {
-		return fmt.Errorf("repository owner and name required (use --owner and --repo)")
+		return withExitCode(ExitUsage, fmt.Errorf("repository owner and name required (use --owner and --repo)"))
+	}
+	switch onEdit {
+	case onEditRegenerate, onEditComment, onEditIgnore:
+	default:
+		return withExitCode(ExitUsage, fmt.Errorf("unknown --on-edit value: %s (use regenerate, comment or ignore)", onEdit))
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -53,18 +85,64 @@ func runWatch() error {
 		cancel()
 	}()
 
-	// Initialize clients
-	githubClient := github.NewClient(githubToken, repoOwner, repoName)
+	if maxRuntime > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, maxRuntime)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Printf("\nMax runtime of %v reached, stopping...\n", maxRuntime)
+			}
+		}()
+	}
+
+	// Initialize clients; issues are read from --repo, branches and PRs go to the target
+	issueClient := newGitHubClient(repoOwner, repoName)
+	githubClient := newGitHubClient(targetOwner, targetName)
 	claudeClient := claude.NewClient(claudeAPIKey, os.Getenv("ANTHROPIC_BASE_URL"), model)
-	gitClient := git.NewClient(repoOwner, repoName, githubToken)
+	gitClient := newGitClient()
+	breakers := setupBreakers(claudeClient, issueClient, githubClient)
+
+	configureCodebase(claudeClient)
+	configureImages(claudeClient)
+	claudeClient.SetMaxResponseBytes(maxResponseSize)
+	if err := claudeClient.SetSampling(temperature, topP); err != nil {
+		return withExitCode(ExitUsage, err)
+	}
+
+	closeDumps, err := configureDumps(claudeClient)
+	if err != nil {
+		return err
+	}
+	defer closeDumps()
+
+	if isCrossRepo() {
+		if err := validateRepoAccess(ctx, issueClient, githubClient); err != nil {
+			return err
+		}
+	}
+
+	if err := resolveBaseBranch(ctx, githubClient); err != nil {
+		return err
+	}
+	if err := validateBaseMap(ctx, githubClient); err != nil {
+		return err
+	}
+	if err := validateModelMap(ctx, claudeClient); err != nil {
+		return err
+	}
+
+	// Pick up auto-merges a previous run was waiting on when it stopped
+	go resumePendingMerges(ctx, issueClient, githubClient)
 
 	switch watchMode {
 	case "webhook":
-		return runWebhookServer(ctx, githubClient, claudeClient, gitClient)
+		return runWebhookServer(ctx, issueClient, githubClient, claudeClient, gitClient, breakers)
 	case "poll":
-		return runPollMode(ctx, githubClient, claudeClient, gitClient)
+		return runPollMode(ctx, issueClient, githubClient, claudeClient, gitClient)
 	default:
-		return fmt.Errorf("unknown watch mode: %s (use 'webhook' or 'poll')", watchMode)
+		return withExitCode(ExitUsage, fmt.Errorf("unknown watch mode: %s (use 'webhook' or 'poll')", watchMode))
 	}
 }
 
@@ -81,11 +159,98 @@ type webhookPayload struct {
 			Name string `json:"name"`
 		} `json:"labels"`
 		HTMLURL string `json:"html_url"`
+		User    struct {
+			Login string `json:"login"`
+			ID    int64  `json:"id"`
+		} `json:"user"`
 	} `json:"issue"`
+	// Changes holds the previous title or body of an edited issue
+	Changes struct {
+		Title *struct {
+			From string `json:"from"`
+		} `json:"title"`
+		Body *struct {
+			From string `json:"from"`
+		} `json:"body"`
+	} `json:"changes"`
+	Sender struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"sender"`
+	// Label is the label added by a labeled delivery
+	Label struct {
+		Name string `json:"name"`
+	} `json:"label"`
+}
+
+func runWebhookServer(ctx context.Context, issues, gh *github.Client, cl *claude.Client, git *git.Client, breakers []*breaker.Breaker) error {
+	process := func(ctx context.Context, issue *github.Issue) error {
+		if pr, ok := openPRBranches(ctx, gh)[issueBranchName(issue.Number)]; ok {
+			fmt.Printf("  Skipping issue #%d: PR #%d is already open (%s)\n", issue.Number, pr.Number, pr.URL)
+			return nil
+		}
+		return processIssueWithClients(ctx, issues, gh, cl, git, issue)
+	}
+	edited := func(ctx context.Context, issue *github.Issue) error {
+		return handleIssueEdit(ctx, gh, issue, func(ctx context.Context) error {
+			return processIssueWithClients(ctx, issues, gh, cl, git, issue)
+		})
+	}
+
+	// Without the login only edits by GitHub Apps are recognized as our own
+	if login, err := issues.GetAuthenticatedUser(ctx); err == nil {
+		botLogin = login
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", webhookPort),
+		Handler: newWebhookMux(ctx, issues, breakers, process, edited),
+	}
+
+	fmt.Printf("%sWebhook server starting on port %d\n", ui.Emoji("🚀 "), webhookPort)
+	fmt.Printf("%sConfigure GitHub webhook to: http://your-server:%d/webhook\n", ui.Emoji("📋 "), webhookPort)
+	if enableTestEndpoint {
+		fmt.Printf("%s Test endpoint enabled: POST {\"issue_number\": N} to http://localhost:%d/webhook/test\n", ui.Warn(), webhookPort)
+	}
+	fmt.Printf("%s Waiting for new issues...\n", ui.Success())
+
+	// Start server in goroutine
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		}
+	}()
+
+	// Deliveries aren't repeated, so retry failed issues on a timer
+	go func() {
+		ticker := time.NewTicker(retryCheck)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				retryDueIssues(ctx, issues, process)
+			}
+		}
+	}()
+
+	// Wait for context cancellation
+	<-ctx.Done()
+
+	// Graceful shutdown
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	return server.Shutdown(shutdownCtx)
 }
 
-func runWebhookServer(ctx context.Context, gh *github.Client, cl *claude.Client, git *git.Client) error {
-	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+// newWebhookMux routes GitHub deliveries, the optional test endpoint and the
+// health check. Opened issues are handed to process and edited ones to edited,
+// both in the background.
+func newWebhookMux(ctx context.Context, issues *github.Client, breakers []*breaker.Breaker, process, edited func(context.Context, *github.Issue) error) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -97,78 +262,171 @@ func runWebhookServer(ctx context.Context, gh *github.Client, cl *claude.Client,
 			return
 		}
 
-		// Only process opened issues
-		if payload.Action != "opened" {
+		// Only process opened issues, and edited ones unless --on-edit=ignore.
+		// Adding the --require-label label counts as opening the issue.
+		handle := process
+		switch {
+		case payload.Action == "opened":
+		case payload.Action == "labeled" && requireLabel != "" && strings.EqualFold(payload.Label.Name, requireLabel):
+		case payload.Action == "edited" && onEdit != onEditIgnore:
+			handle = edited
+		default:
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
 		// Skip if issue is closed
-		if payload.Issue.State != "open" {
+		if !acceptsState(payload.Issue.State) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
-		fmt.Printf("\n📥 New issue received: #%d - %s\n", payload.Issue.Number, payload.Issue.Title)
-
-		// Process in background
-		go func() {
-			issue := &github.Issue{
-				Number: payload.Issue.Number,
-				Title:  payload.Issue.Title,
-				Body:   payload.Issue.Body,
-				URL:    payload.Issue.HTMLURL,
-				State:  payload.Issue.State,
+		if payload.Action == "edited" {
+			// Label and milestone changes arrive as other actions; these are
+			// edits of the title or body. Our own edits would loop.
+			if payload.Changes.Title == nil && payload.Changes.Body == nil {
+				w.WriteHeader(http.StatusOK)
+				return
 			}
-			for _, l := range payload.Issue.Labels {
-				issue.Labels = append(issue.Labels, l.Name)
+			if payload.Sender.Type == "Bot" || (botLogin != "" && payload.Sender.Login == botLogin) {
+				fmt.Printf("\nIgnoring edit of issue #%d by %s\n", payload.Issue.Number, payload.Sender.Login)
+				w.WriteHeader(http.StatusOK)
+				return
 			}
+			fmt.Printf("\n%sIssue edited: #%d - %s\n", ui.Emoji("📝 "), payload.Issue.Number, payload.Issue.Title)
+		} else {
+			fmt.Printf("\n%sNew issue received: #%d - %s\n", ui.Emoji("📥 "), payload.Issue.Number, payload.Issue.Title)
+		}
 
-			if err := processIssueWithClients(gh, cl, git, issue); err != nil {
-				fmt.Fprintf(os.Stderr, "Error processing issue #%d: %v\n", payload.Issue.Number, err)
-			}
-		}()
+		issue := &github.Issue{
+			Number: payload.Issue.Number,
+			Title:  payload.Issue.Title,
+			Body:   payload.Issue.Body,
+			URL:    payload.Issue.HTMLURL,
+			State:  payload.Issue.State,
+
+			Author:   payload.Issue.User.Login,
+			AuthorID: payload.Issue.User.ID,
+		}
+		for _, l := range payload.Issue.Labels {
+			issue.Labels = append(issue.Labels, l.Name)
+		}
+		if !triggered(issue) {
+			fmt.Printf("  Skipping issue #%d: it doesn't have the %q label\n", issue.Number, requireLabel)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		dispatchIssue(ctx, issues, issue, payload.Action != "edited", handle)
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	// Health check endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
-	})
+	// Local stand-in for a GitHub delivery: fetches the issue and processes it
+	// as if it had just been opened
+	if enableTestEndpoint {
+		mux.HandleFunc("/webhook/test", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", webhookPort),
-		Handler: nil,
+			var req struct {
+				IssueNumber int `json:"issue_number"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if req.IssueNumber <= 0 {
+				http.Error(w, "issue_number required", http.StatusBadRequest)
+				return
+			}
+
+			issue, err := issues.GetIssue(r.Context(), req.IssueNumber)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("fetching issue: %v", err), http.StatusBadGateway)
+				return
+			}
+			if !acceptsState(issue.State) {
+				http.Error(w, fmt.Sprintf("issue #%d is %s", issue.Number, issue.State), http.StatusUnprocessableEntity)
+				return
+			}
+			if !triggered(issue) {
+				http.Error(w, fmt.Sprintf("issue #%d doesn't have the %q label", issue.Number, requireLabel), http.StatusUnprocessableEntity)
+				return
+			}
+
+			fmt.Printf("\n%sTest delivery for issue: #%d - %s\n", ui.Emoji("📥 "), issue.Number, issue.Title)
+			dispatchIssue(ctx, issues, issue, true, process)
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"ok"}`))
+		})
 	}
 
-	fmt.Printf("🚀 Webhook server starting on port %d\n", webhookPort)
-	fmt.Printf("📋 Configure GitHub webhook to: http://your-server:%d/webhook\n", webhookPort)
-	fmt.Println("✓ Waiting for new issues...")
+	// Health check endpoint, including circuit breaker state
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(healthStatus(breakers))
+	})
 
-	// Start server in goroutine
+	return mux
+}
+
+// dispatchIssue processes a delivered issue in the background, reporting failures
+// and queueing transient ones for retry. A new issue first waits out --grace-period.
+func dispatchIssue(ctx context.Context, issues *github.Client, issue *github.Issue, isNew bool, process func(context.Context, *github.Issue) error) {
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		proceed := true
+		var err error
+		if isNew {
+			proceed, err = awaitGracePeriod(ctx, issues, issue)
+		}
+		if proceed {
+			err = processWatchedIssue(ctx, issue, func(ctx context.Context) error {
+				return process(ctx, issue)
+			})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing issue #%d: %v\n", issue.Number, err)
+			sendNotification(context.Background(), notify.Event{Type: notify.EventError, IssueNumber: issue.Number, IssueTitle: issue.Title, Err: err}, "")
 		}
+		recordIssueResult(ctx, issues, issue, err)
 	}()
+}
 
-	// Wait for context cancellation
-	<-ctx.Done()
+// handleIssueEdit applies --on-edit to an edited issue. With regenerate the
+// issue is processed again against its new text, updating its open PR if it
+// has one; with comment the open PR is told its changes predate the edit.
+func handleIssueEdit(ctx context.Context, gh *github.Client, issue *github.Issue, regenerate func(ctx context.Context) error) error {
+	pr := openPRBranches(ctx, gh)[issueBranchName(issue.Number)]
 
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
-	return server.Shutdown(shutdownCtx)
+	switch onEdit {
+	case onEditRegenerate:
+		if pr != nil {
+			fmt.Printf("  Regenerating PR #%d against the edited issue\n", pr.Number)
+		}
+		return regenerate(ctx)
+	case onEditComment:
+		if pr == nil {
+			fmt.Printf("  No open PR for issue #%d, nothing to update\n", issue.Number)
+			return nil
+		}
+		body := fmt.Sprintf("%s was edited after this PR was opened, so these changes may not reflect it. Process the issue again to regenerate them.", issueRef(issue.Number))
+		if err := gh.AddIssueComment(ctx, pr.Number, body); err != nil {
+			return fmt.Errorf("commenting on PR #%d: %w", pr.Number, err)
+		}
+		fmt.Printf("  %s Noted the edit on PR #%d\n", ui.Success(), pr.Number)
+	}
+	return nil
 }
 
 // ========== Poll Mode ==========
 
-func runPollMode(ctx context.Context, gh *github.Client, cl *claude.Client, git *git.Client) error {
-	fmt.Printf("🔄 Poll mode started (interval: %v)\n", pollInterval)
-	fmt.Println("✓ Checking for new issues...")
+func runPollMode(ctx context.Context, issues, gh *github.Client, cl *claude.Client, git *git.Client) error {
+	fmt.Printf("%sPoll mode started (interval: %v)\n", ui.Emoji("🔄 "), pollInterval)
+	fmt.Printf("%s Checking for new issues...\n", ui.Success())
 
 	// Load last checked time from file if exists
 	loadLastCheckedTime()
@@ -177,128 +435,278 @@ func runPollMode(ctx context.Context, gh *github.Client, cl *claude.Client, git
 	defer ticker.Stop()
 
 	// Check immediately on start
-	checkAndProcessIssues(gh, cl, git)
+	checkAndProcessIssues(ctx, issues, gh, cl, git)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			checkAndProcessIssues(gh, cl, git)
+			checkAndProcessIssues(ctx, issues, gh, cl, git)
 		}
 	}
 }
 
-func checkAndProcessIssues(gh *github.Client, cl *claude.Client, git *git.Client) {
+func checkAndProcessIssues(ctx context.Context, issues, gh *github.Client, cl *claude.Client, git *git.Client) {
+	checkIssues(ctx, issues, gh, func(ctx context.Context, issue *github.Issue) error {
+		return processIssueWithClients(ctx, issues, gh, cl, git, issue)
+	})
+}
+
+// checkIssues retries queued issues that are due, then hands each open issue
+// created or updated since the last check to process. Issues that already have an open
+// vibe-git PR or are waiting in the retry queue are skipped.
+func checkIssues(ctx context.Context, issues, gh *github.Client, process func(context.Context, *github.Issue) error) {
 	fmt.Printf("\n[%s] Checking for new issues...\n", time.Now().Format("2006-01-02 15:04:05"))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	retryDueIssues(ctx, issues, process)
+
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// Get recent issues
-	issues, err := gh.ListRecentIssues(ctx, lastChecked)
+	// Get recent issues. The next check starts from before this listing, so
+	// issues updated while these are processed are listed then.
+	state := "open"
+	if includeClosed {
+		state = "all"
+	}
+	checkedAt := time.Now()
+	recent, err := issues.ListRecentIssues(listCtx, lastChecked, state, includePRs)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error fetching issues: %v\n", err)
 		return
 	}
 
-	if len(issues) == 0 {
+	if len(recent) == 0 {
 		fmt.Println("  No new issues found")
 		return
 	}
 
-	fmt.Printf("  Found %d new issue(s)\n", len(issues))
+	fmt.Printf("  Found %d new issue(s)\n", len(recent))
+
+	openPRs := openPRBranches(listCtx, gh)
+	queued := make(map[int]bool)
+	for _, e := range queuedRetries(repoOwner + "/" + repoName) {
+		queued[e.Issue] = true
+	}
 
-	for _, issue := range issues {
-		if issue.State != "open" {
+	handled := make(map[int]time.Time)
+	var pending []*github.Issue
+	for _, issue := range recent {
+		if ctx.Err() != nil {
+			// Shutting down; leave lastChecked so unprocessed issues are picked up next run
+			return
+		}
+		if !acceptsState(issue.State) {
+			continue
+		}
+		if at, ok := lastHandled[issue.Number]; ok && !issue.UpdatedAt.After(at) {
+			// Listed again only for the comments and labels we added while handling it
+			continue
+		}
+		if pr, ok := openPRs[issueBranchName(issue.Number)]; ok {
+			fmt.Printf("  Skipping issue #%d: PR #%d is already open (%s)\n", issue.Number, pr.Number, pr.URL)
+			continue
+		}
+		if queued[issue.Number] {
+			fmt.Printf("  Skipping issue #%d: it is waiting to be retried\n", issue.Number)
+			continue
+		}
+		if !triggered(issue) {
+			fmt.Printf("  Skipping issue #%d: it doesn't have the %q label\n", issue.Number, requireLabel)
 			continue
 		}
 
-		fmt.Printf("\n📥 Processing issue #%d: %s\n", issue.Number, issue.Title)
+		pending = append(pending, issue)
+	}
 
-		if err := processIssueWithClients(gh, cl, git, issue); err != nil {
+	// One grace period covers every issue found, rather than each waiting in turn
+	waits := awaitGracePeriods(ctx, issues, pending)
+	for _, issue := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		err := waits[issue.Number]
+		switch {
+		case errors.Is(err, errCancelled):
+			err = nil
+		case err == nil:
+			fmt.Printf("\n%sProcessing issue #%d: %s\n", ui.Emoji("📥 "), issue.Number, issue.Title)
+			err = processWatchedIssue(ctx, issue, func(ctx context.Context) error {
+				return process(ctx, issue)
+			})
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing issue #%d: %v\n", issue.Number, err)
-			continue
+			sendNotification(context.Background(), notify.Event{Type: notify.EventError, IssueNumber: issue.Number, IssueTitle: issue.Title, Err: err}, "")
 		}
+		recordIssueResult(ctx, issues, issue, err)
+		handled[issue.Number] = time.Now()
 	}
 
-	// Update last checked time
-	lastChecked = time.Now()
+	lastChecked, lastHandled = checkedAt, handled
 	saveLastCheckedTime()
 }
 
-// ========== Shared Processing ==========
-
-func processIssueWithClients(gh *github.Client, cl *claude.Client, git *git.Client, issue *github.Issue) error {
-	// Extract @file references from issue
-	refs := ctxloader.ExtractFileReferences(issue.Title + "\n" + issue.Body)
-	if len(refs) > 0 {
-		fmt.Printf("  Found @references: %v\n", refs)
+// triggered reports whether watch mode may act on issue: always, unless
+// --require-label is set and the issue doesn't carry that label
+func triggered(issue *github.Issue) bool {
+	if requireLabel == "" {
+		return true
 	}
-
-	// Load referenced files
-	referencedFiles := ctxloader.LoadReferencedFiles(refs, ".")
-	for _, f := range referencedFiles {
-		if f.Found {
-			fmt.Printf("  ✓ Loaded referenced file: %s\n", f.Path)
-		} else {
-			fmt.Printf("  ⚠ File not found: %s\n", f.Path)
+	for _, label := range issue.Labels {
+		if strings.EqualFold(label, requireLabel) {
+			return true
 		}
 	}
+	return false
+}
 
-	// Create branch
-	branchName := fmt.Sprintf("vibe-git/issue-%d", issue.Number)
-	fmt.Printf("  Creating branch: %s\n", branchName)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// openPRBranches maps the head branch of each open PR to the PR. The poll
+// position in the state file can be lost or rewound; open PRs are what tell
+// us an issue was already handled. If they can't be listed the check is
+// skipped rather than stalling the watcher.
+func openPRBranches(ctx context.Context, gh *github.Client) map[string]*github.PullRequest {
+	prs, err := gh.ListOpenPullRequests(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  %s Could not list open PRs, not checking for duplicates: %v\n", ui.Warn(), err)
+		return nil
+	}
 
-	if err := git.CreateBranch(ctx, baseBranch, branchName); err != nil {
-		return fmt.Errorf("creating branch: %w", err)
+	branches := make(map[string]*github.PullRequest, len(prs))
+	for _, pr := range prs {
+		branches[pr.Head] = pr
 	}
+	return branches
+}
+
+// ========== Shared Processing ==========
+
+// processIssueWithClients opens the PR through gh and closes the issue through issues
+func processIssueWithClients(ctx context.Context, issues, gh *github.Client, cl *claude.Client, git *git.Client, issue *github.Issue) (err error) {
+	report := startReport(issue.Number)
+	defer func() {
+		if err != nil {
+			reportProgress(ProgressEvent{Type: EventError, Issue: issue.Number, Err: err}, "  ")
+		}
+		report.finish(err)
+	}()
 
-	// Generate code with Claude, passing referenced files
-	fmt.Println("  Generating code with Claude...")
-	changes, err := cl.GenerateCode(ctx, issue.Title, issue.Body, referencedFiles)
+	branchName := issueBranchName(issue.Number)
+	base := issueBase(issue)
+	scope, err := scopeIssue(issue, "  ")
 	if err != nil {
-		return fmt.Errorf("generating code: %w", err)
+		return err
 	}
 
-	// Apply changes
-	fmt.Printf("  Applying %d file changes...\n", len(changes))
-	if err := git.ApplyChanges(changes); err != nil {
-		return fmt.Errorf("applying changes: %w", err)
-	}
+	warnIfNoPushAccess(ctx, gh, "  ")
+	cl = issueClaude(cl, issue, "  ")
+	cl = report.trackUsage(cl, issueModel(issue))
 
-	// Commit changes
-	commitMsg := fmt.Sprintf("Fix issue #%d: %s\n\n%s", issue.Number, issue.Title, issue.URL)
-	if err := git.Commit(commitMsg); err != nil {
-		return fmt.Errorf("committing changes: %w", err)
-	}
+	var description, changeSummary string
+	if useWorker {
+		// Let the worker run branch → generate → commit → push in isolation
+		refs, _ := issueReferences(issue, ".", "  ")
+		if err := processIssueInWorker(ctx, issue, branchName, refs, "  "); err != nil {
+			return err
+		}
+	} else {
+		// Create branch, in its own worktree with --worktree
+		var removeWorktree func()
+		var err error
+		git, removeWorktree, err = checkoutIssueBranch(ctx, git, base, branchName, "  ")
+		if err != nil {
+			return err
+		}
+		defer removeWorktree()
+		reportProgress(ProgressEvent{Type: EventBranchCreated, Issue: issue.Number, Branch: branchName, Base: base}, "  ")
+
+		// Build the prompt from the checkout the changes are applied to
+		_, referencedFiles := issueReferences(issue, git.Dir(), "  ")
+		cl = cl.WithRoot(git.Dir())
+
+		// With --context=changed, build on the branch's earlier work and send its diff
+		branch, err := branchChanges(ctx, git, base, branchName, "  ")
+		if err != nil {
+			return err
+		}
+
+		// Generate code with Claude, passing referenced files
+		reportProgress(ProgressEvent{Type: EventGeneratingCode, Issue: issue.Number, Branch: branchName}, "  ")
+		changes, err := cl.GenerateCodeInScope(ctx, scope, issue.Title, promptBody(issue, "  "), referencedFiles, branch)
+		if err != nil {
+			return withExitCode(ExitGeneration, fmt.Errorf("generating code: %w", err))
+		}
+
+		if err := checkChangeSize(git, changes, "  "); err != nil {
+			return err
+		}
+		if err := scanGeneratedSecrets(git, changes, "  "); err != nil {
+			return err
+		}
+
+		// Let the pre-apply hook veto the changes
+		if err := runHook(ctx, "pre-apply", preApplyHook, git.Dir(), hookEnv(issue, branchName, "", changes), "  "); err != nil {
+			return err
+		}
 
-	// Push branch
-	fmt.Printf("  Pushing branch...\n")
-	if err := git.PushBranch(ctx, branchName); err != nil {
-		return fmt.Errorf("pushing branch: %w", err)
+		// Apply changes
+		fmt.Printf("  Applying %d file changes...\n", len(changes))
+		changes, err = applyIssueChanges(ctx, git, scope, changes, "  ")
+		if err != nil {
+			return fmt.Errorf("applying changes: %w", err)
+		}
+
+		// Commit changes
+		if err := commitIssueChanges(ctx, git, cl, issue, changes, "  "); err != nil {
+			return fmt.Errorf("committing changes: %w", err)
+		}
+		reportProgress(ProgressEvent{Type: EventChangesApplied, Issue: issue.Number, Branch: branchName, Files: len(changes)}, "  ")
+		changeSummary = diffSummary(ctx, git, base, "  ")
+		description = summarizeChanges(ctx, cl, changes, "  ")
+
+		// Push branch
+		fmt.Printf("  Pushing branch...\n")
+		if err := git.PushBranch(ctx, branchName); err != nil {
+			return fmt.Errorf("pushing branch: %w", err)
+		}
+		reportProgress(ProgressEvent{Type: EventBranchPushed, Issue: issue.Number, Branch: branchName}, "  ")
 	}
 
 	// Create PR
-	prTitle := fmt.Sprintf("Fix #%d: %s", issue.Number, issue.Title)
-	prBody := fmt.Sprintf("Closes #%d\n\n%s", issue.Number, issue.URL)
+	prTitle := fmt.Sprintf("Fix %s: %s", issueRef(issue.Number), issue.Title)
+	prBody := buildPRBody(issue.Number, issue.URL, description, changeSummary)
 
-	prNumber, prURL, err := gh.CreatePullRequestWithNumber(ctx, baseBranch, branchName, prTitle, prBody)
+	prNumber, prURL, existing, err := openPullRequest(ctx, gh, base, branchName, prTitle, prBody, "  ")
 	if err != nil {
-		return fmt.Errorf("creating PR: %w", err)
+		return err
+	}
+	reportProgress(ProgressEvent{Type: EventPRCreated, Issue: issue.Number, Branch: branchName, PRNumber: prNumber, PRURL: prURL, Existing: existing}, "  ")
+	if !existing {
+		sendNotification(ctx, notify.Event{Type: notify.EventPRCreated, IssueNumber: issue.Number, IssueTitle: issue.Title, PRURL: prURL}, "  ")
+	}
+
+	labelPullRequest(ctx, gh, prNumber, "  ")
+	requestPullRequestReview(ctx, gh, prNumber, "  ")
+	assignPullRequest(ctx, gh, prNumber, "  ")
+	if !existing {
+		linkPullRequestOnIssue(ctx, issues, issue.Number, prURL, description, "  ")
 	}
 
-	fmt.Printf("  ✓ Created PR: %s\n", prURL)
+	if err := runHook(ctx, "post-commit", postCommitHook, checkoutDir(git), hookEnv(issue, branchName, prURL, nil), "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "  %s %v\n", ui.Warn(), err)
+	}
 
 	// Auto-merge if enabled
 	if autoMerge {
 		if waitForChecks {
+			// Lets `vibe-git resume` finish the merge if this run is stopped while waiting
+			untrack := trackPendingMerge(ctx, issue, prNumber, prURL, branchName)
+			defer untrack()
+
 			fmt.Printf("  Waiting for CI checks to pass (timeout: %v)...\n", mergeTimeout)
 			if err := gh.WaitForMergeable(ctx, prNumber, mergeTimeout); err != nil {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to wait for checks: %v\n", err)
+				fmt.Fprintf(os.Stderr, "  %s Failed to wait for checks: %v\n", ui.Warn(), err)
 				fmt.Println("  You can merge manually later")
 				return nil
 			}
@@ -306,54 +714,56 @@ func processIssueWithClients(gh *github.Client, cl *claude.Client, git *git.Clie
 
 		fmt.Println("  Merging PR...")
 		mergeTitle := fmt.Sprintf("Merge: %s", prTitle)
-		mergeMsg := fmt.Sprintf("Auto-merged by vibe-git\n\nFixes #%d", issue.Number)
+		mergeMsg := fmt.Sprintf("Auto-merged by vibe-git\n\nFixes %s", issueRef(issue.Number))
 
 		if err := gh.MergePullRequest(ctx, prNumber, mergeTitle, mergeMsg); err != nil {
 			// Check if it's a conflict
-			if isConflictError(err) {
-				fmt.Println("  ⚠ Merge conflict detected, attempting to resolve...")
+			if isMergeConflict(ctx, gh, prNumber, err) {
+				fmt.Printf("  %s Merge conflict detected, attempting to resolve...\n", ui.Warn())
 
 				// Resolve conflicts
-				if err := git.ResolveConflicts(ctx, baseBranch, issue.Title, func(filePath, conflictContent, issueTitle string) (string, error) {
-					return cl.ResolveConflict(ctx, filePath, conflictContent, issueTitle)
-				}); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to resolve conflicts: %v\n", err)
+				if err := resolveConflicts(ctx, git, cl, base, issue.Title); err != nil {
 					fmt.Println("  You need to resolve conflicts manually")
-					return nil
+					return withExitCode(ExitConflict, fmt.Errorf("resolving merge conflicts: %w", err))
 				}
 
 				// Push resolved changes
 				fmt.Println("  Pushing resolved changes...")
 				if err := git.ForcePushWithLease(ctx, branchName); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to push resolved changes: %v\n", err)
+					fmt.Fprintf(os.Stderr, "  %s Failed to push resolved changes: %v\n", ui.Warn(), err)
 					return nil
 				}
 
-				// Wait a moment for GitHub to update PR status
-				time.Sleep(2 * time.Second)
+				// GitHub recomputes mergeability after the push; merging before that fails again
+				if err := awaitMergeable(ctx, gh, prNumber); err != nil {
+					fmt.Println("  You can merge manually later")
+					return err
+				}
 
 				// Retry merge
 				fmt.Println("  Retrying merge after conflict resolution...")
 				if err := gh.MergePullRequest(ctx, prNumber, mergeTitle, mergeMsg); err != nil {
-					fmt.Fprintf(os.Stderr, "  ⚠ Failed to merge PR after conflict resolution: %v\n", err)
+					fmt.Fprintf(os.Stderr, "  %s Failed to merge PR after conflict resolution: %v\n", ui.Warn(), err)
 					fmt.Println("  You can merge manually later")
 					return nil
 				}
 			} else {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to merge PR: %v\n", err)
+				fmt.Fprintf(os.Stderr, "  %s Failed to merge PR: %v\n", ui.Warn(), err)
 				fmt.Println("  You can merge manually later")
 				return nil
 			}
 		}
-		fmt.Println("  ✓ PR merged successfully")
+		reportProgress(ProgressEvent{Type: EventMerged, Issue: issue.Number, Branch: branchName, PRNumber: prNumber, PRURL: prURL}, "  ")
+		sendNotification(ctx, notify.Event{Type: notify.EventPRMerged, IssueNumber: issue.Number, IssueTitle: issue.Title, PRURL: prURL}, "  ")
+		deleteMergedBranch(ctx, gh, branchName, "  ")
 
 		// Close issue if enabled
 		if closeIssue {
 			fmt.Println("  Closing issue...")
-			if err := gh.CloseIssue(ctx, issue.Number); err != nil {
-				fmt.Fprintf(os.Stderr, "  ⚠ Failed to close issue: %v\n", err)
+			if err := issues.CloseIssue(ctx, issue.Number); err != nil {
+				fmt.Fprintf(os.Stderr, "  %s Failed to close issue: %v\n", ui.Warn(), err)
 			} else {
-				fmt.Println("  ✓ Issue closed")
+				fmt.Printf("  %s Issue closed\n", ui.Success())
 			}
 		}
 	}
@@ -361,27 +771,288 @@ func processIssueWithClients(gh *github.Client, cl *claude.Client, git *git.Clie
 	return nil
 }
 
+// ========== Circuit Breakers ==========
+
+// setupBreakers puts the GitHub clients behind one circuit breaker and Claude
+// behind another, so an outage pauses calls instead of hammering the service
+func setupBreakers(cl *claude.Client, ghClients ...*github.Client) []*breaker.Breaker {
+	if breakerThreshold <= 0 {
+		return nil
+	}
+
+	logChange := func(name string, from, to breaker.State) {
+		switch to {
+		case breaker.Open:
+			fmt.Fprintf(os.Stderr, "%s %s circuit open after %d consecutive failures, pausing calls for %v\n", ui.Warn(), name, breakerThreshold, breakerCooldown)
+		case breaker.HalfOpen:
+			fmt.Printf("%s circuit half-open, testing recovery\n", name)
+		case breaker.Closed:
+			fmt.Printf("%s %s circuit closed, service recovered\n", ui.Success(), name)
+		}
+	}
+
+	ghBreaker := breaker.New("GitHub", breakerThreshold, breakerCooldown)
+	ghBreaker.OnStateChange = logChange
+	for _, gh := range ghClients {
+		gh.SetBreaker(ghBreaker)
+	}
+
+	clBreaker := breaker.New("Claude", breakerThreshold, breakerCooldown)
+	clBreaker.OnStateChange = logChange
+	cl.SetBreaker(clBreaker)
+
+	return []*breaker.Breaker{ghBreaker, clBreaker}
+}
+
+// healthStatus reports the watcher as degraded while any circuit is not closed,
+// and how many failed issues are waiting to be retried
+func healthStatus(breakers []*breaker.Breaker) map[string]interface{} {
+	status := map[string]interface{}{"status": "healthy", "retry_queue": retryQueueDepth()}
+	if len(breakers) == 0 {
+		return status
+	}
+
+	circuits := make(map[string]string, len(breakers))
+	for _, b := range breakers {
+		state := b.State()
+		circuits[b.Name] = state.String()
+		if state != breaker.Closed {
+			status["status"] = "degraded"
+		}
+	}
+	status["circuits"] = circuits
+	return status
+}
+
+// ========== Timeouts ==========
+
+const (
+	timeoutBackoffBase = 10 * time.Minute
+	timeoutBackoffMax  = 6 * time.Hour
+)
+
+// errIssueTimeout is returned when an issue exceeds --timeout-per-issue
+var errIssueTimeout = errors.New("issue timed out")
+
+// processWatchedIssue runs process under the per-issue timeout. Issues that keep
+// timing out are skipped until their backoff expires so they can't stall the watcher.
+func processWatchedIssue(ctx context.Context, issue *github.Issue, process func(ctx context.Context) error) error {
+	if until, skip := timeouts.backingOff(issue.Number, time.Now()); skip {
+		fmt.Printf("  Skipping issue #%d after repeated timeouts (retry after %s)\n", issue.Number, until.Format("15:04:05"))
+		return nil
+	}
+
+	err := runWithTimeout(ctx, issueTimeout, process)
+	switch {
+	case errors.Is(err, errIssueTimeout):
+		retryAt := timeouts.recordTimeout(issue.Number, time.Now())
+		fmt.Fprintf(os.Stderr, "  %s Abandoned issue #%d; it will be retried after %s\n", ui.Warn(), issue.Number, retryAt.Format("15:04:05"))
+	case err == nil:
+		timeouts.reset(issue.Number)
+	}
+	return err
+}
+
+// abandonGrace is how long runWithTimeout waits for fn to stop once its
+// context is done, so the git commands it was running are killed and cleaned
+// up before the next issue starts or the process exits
+var abandonGrace = 30 * time.Second
+
+// abandoned tracks an fn that outlived abandonGrace. Without --worktree it may
+// still be writing to the shared checkout, so the next fn waits for it.
+var abandoned struct {
+	sync.Mutex
+	done chan struct{} // Closed when the abandoned fn returns; nil if none is running
+}
+
+// awaitAbandoned blocks until an abandoned fn sharing the checkout has
+// returned, or ctx is done
+func awaitAbandoned(ctx context.Context) error {
+	if useWorktree {
+		return nil
+	}
+	abandoned.Lock()
+	done := abandoned.done
+	abandoned.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+	fmt.Printf("  Waiting for an abandoned issue to stop using the checkout...\n")
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runWithTimeout runs fn with a context that expires after timeout. Once the
+// context is done fn gets abandonGrace to return; if it doesn't it is
+// abandoned, so a step that ignores cancellation can't hold up the caller, and
+// the next fn on the shared checkout waits for it instead. A timeout of 0
+// disables the limit.
+func runWithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if err := awaitAbandoned(ctx); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	fnCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		done <- fn(fnCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-fnCtx.Done():
+		err = fnCtx.Err()
+		select {
+		case <-done:
+		case <-time.After(abandonGrace):
+			abandoned.Lock()
+			abandoned.done = stopped
+			abandoned.Unlock()
+		}
+	}
+
+	// Only our own deadline counts as an issue timeout, not the caller's
+	if ctx.Err() == nil && errors.Is(fnCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", errIssueTimeout, timeout)
+	}
+	return err
+}
+
+// timeoutTracker counts consecutive timeouts per issue
+type timeoutTracker struct {
+	mu      sync.Mutex
+	entries map[int]*timeoutEntry
+}
+
+type timeoutEntry struct {
+	count   int
+	retryAt time.Time
+}
+
+func newTimeoutTracker() *timeoutTracker {
+	return &timeoutTracker{entries: make(map[int]*timeoutEntry)}
+}
+
+// recordTimeout counts a timeout for the issue and returns when it may be retried
+func (t *timeoutTracker) recordTimeout(issueNum int, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[issueNum]
+	if !ok {
+		e = &timeoutEntry{}
+		t.entries[issueNum] = e
+	}
+	e.count++
+
+	backoff := timeoutBackoffMax
+	if e.count < 16 {
+		backoff = timeoutBackoffBase << (e.count - 1)
+	}
+	if backoff > timeoutBackoffMax {
+		backoff = timeoutBackoffMax
+	}
+	e.retryAt = now.Add(backoff)
+	return e.retryAt
+}
+
+// backingOff reports whether the issue is still waiting out its backoff
+func (t *timeoutTracker) backingOff(issueNum int, now time.Time) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[issueNum]
+	if !ok || !now.Before(e.retryAt) {
+		return time.Time{}, false
+	}
+	return e.retryAt, true
+}
+
+// reset forgets the issue's timeouts after it succeeds
+func (t *timeoutTracker) reset(issueNum int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, issueNum)
+}
+
 // ========== State Persistence ==========
 
-const stateFile = ".vibe-git-state"
+// stateFile persists the poll position, pending merges and retry queue between runs
+var stateFile = ".vibe-git-state"
 
-func loadLastCheckedTime() {
+// stateMu serializes read-modify-write cycles of the state file
+var stateMu sync.Mutex
+
+// watchState is the content of the state file. Older versions stored only the
+// last checked time as a Unix timestamp, which is still accepted.
+type watchState struct {
+	LastChecked   int64          `json:"last_checked,omitempty"`
+	PendingMerges []pendingMerge `json:"pending_merges,omitempty"`
+	RetryQueue    []retryEntry   `json:"retry_queue,omitempty"`
+}
+
+// readState loads the state file; a missing or unreadable file is an empty state
+func readState() watchState {
+	var state watchState
 	data, err := os.ReadFile(stateFile)
 	if err != nil {
-		lastChecked = time.Now().Add(-24 * time.Hour) // Default to 24 hours ago
-		return
+		return state
 	}
+	if ts, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+		state.LastChecked = ts
+		return state
+	}
+	json.Unmarshal(data, &state)
+	return state
+}
+
+// updateState applies fn to the stored state and writes it back
+func updateState(fn func(*watchState)) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	state := readState()
+	fn(&state)
 
-	ts, err := strconv.ParseInt(string(data), 10, 64)
+	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
-		lastChecked = time.Now().Add(-24 * time.Hour)
-		return
+		return err
 	}
+	return os.WriteFile(stateFile, data, 0644)
+}
+
+func loadLastCheckedTime() {
+	stateMu.Lock()
+	state := readState()
+	stateMu.Unlock()
 
-	lastChecked = time.Unix(ts, 0)
+	if state.LastChecked == 0 {
+		lastChecked = time.Now().Add(-24 * time.Hour) // Default to 24 hours ago
+		return
+	}
+	lastChecked = time.Unix(state.LastChecked, 0)
 }
 
 func saveLastCheckedTime() {
-	ts := strconv.FormatInt(lastChecked.Unix(), 10)
-	os.WriteFile(stateFile, []byte(ts), 0644)
+	updateState(func(state *watchState) {
+		state.LastChecked = lastChecked.Unix()
+	})
 }
@@ -0,0 +1,724 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vibe-git/internal/breaker"
+	"vibe-git/internal/github"
+)
+
+// withIssueTimeout sets --timeout-per-issue and a fresh timeout tracker for the test
+func withIssueTimeout(t *testing.T, d time.Duration) {
+	t.Helper()
+	origTimeout, origTracker := issueTimeout, timeouts
+	t.Cleanup(func() { issueTimeout, timeouts = origTimeout, origTracker })
+	issueTimeout = d
+	timeouts = newTimeoutTracker()
+}
+
+// withAbandonGrace shortens the wait for a timed-out step and forgets any
+// step abandoned by an earlier test
+func withAbandonGrace(t *testing.T, d time.Duration) {
+	t.Helper()
+	orig := abandonGrace
+	t.Cleanup(func() {
+		abandonGrace = orig
+		abandoned.done = nil
+	})
+	abandonGrace = d
+	abandoned.done = nil
+}
+
+func TestProcessWatchedIssueAbandonsSlowIssue(t *testing.T) {
+	withIssueTimeout(t, 20*time.Millisecond)
+	withAbandonGrace(t, 20*time.Millisecond)
+	origWorktree := useWorktree
+	t.Cleanup(func() { useWorktree = origWorktree })
+	useWorktree = true
+
+	release := make(chan struct{})
+	defer close(release)
+
+	// The stuck step ignores its context entirely
+	stuck := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+
+	var err error
+	start := time.Now()
+	captureStdout(t, func() {
+		err = processWatchedIssue(context.Background(), &github.Issue{Number: 1}, stuck)
+	})
+	if !errors.Is(err, errIssueTimeout) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stuck issue held the watcher for %v", elapsed)
+	}
+
+	// With its own worktree the next issue doesn't wait for the stuck one
+	ran := false
+	captureStdout(t, func() {
+		err = processWatchedIssue(context.Background(), &github.Issue{Number: 2}, func(ctx context.Context) error {
+			ran = true
+			return nil
+		})
+	})
+	if err != nil || !ran {
+		t.Errorf("expected the next issue to be processed, got ran=%v err=%v", ran, err)
+	}
+
+	// The timed-out issue is skipped while it backs off
+	out := captureStdout(t, func() {
+		err = processWatchedIssue(context.Background(), &github.Issue{Number: 1}, func(ctx context.Context) error {
+			t.Error("issue backing off should not be processed")
+			return nil
+		})
+	})
+	if err != nil || !strings.Contains(out, "Skipping issue #1 after repeated timeouts") {
+		t.Errorf("expected issue #1 to be skipped, got err=%v output=%q", err, out)
+	}
+}
+
+func TestRunWithTimeoutWaitsForCancelledStep(t *testing.T) {
+	withAbandonGrace(t, time.Second)
+
+	// The step honors cancellation but takes a while to clean up
+	stopped := false
+	err := runWithTimeout(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		stopped = true
+		return ctx.Err()
+	})
+	if !errors.Is(err, errIssueTimeout) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if !stopped {
+		t.Error("expected runWithTimeout to wait for the step to stop")
+	}
+}
+
+func TestNextIssueWaitsForAbandonedStepOnSharedCheckout(t *testing.T) {
+	withIssueTimeout(t, 10*time.Millisecond)
+	withAbandonGrace(t, 10*time.Millisecond)
+	origWorktree := useWorktree
+	t.Cleanup(func() { useWorktree = origWorktree })
+	useWorktree = false
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var events []string
+	record := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+
+	var err error
+	captureStdout(t, func() {
+		err = processWatchedIssue(context.Background(), &github.Issue{Number: 1}, func(ctx context.Context) error {
+			<-release
+			record("first stopped")
+			return nil
+		})
+	})
+	if !errors.Is(err, errIssueTimeout) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+
+	// The abandoned step is still running when the next issue starts
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		record("released")
+		close(release)
+	}()
+	out := captureStdout(t, func() {
+		err = processWatchedIssue(context.Background(), &github.Issue{Number: 2}, func(ctx context.Context) error {
+			record("second started")
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"released", "first stopped", "second started"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("expected the next issue to wait for the abandoned one, got %v", events)
+	}
+	if !strings.Contains(out, "Waiting for an abandoned issue") {
+		t.Errorf("expected a note about waiting, got %q", out)
+	}
+}
+
+func TestRunWithTimeoutIgnoresCallerCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runWithTimeout(ctx, time.Minute, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if errors.Is(err, errIssueTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("shutdown should not count as an issue timeout, got %v", err)
+	}
+}
+
+func TestTimeoutTrackerBackoff(t *testing.T) {
+	tracker := newTimeoutTracker()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := tracker.recordTimeout(5, now); !got.Equal(now.Add(timeoutBackoffBase)) {
+		t.Errorf("first backoff: got %v", got.Sub(now))
+	}
+	if got := tracker.recordTimeout(5, now); !got.Equal(now.Add(2 * timeoutBackoffBase)) {
+		t.Errorf("second backoff should double, got %v", got.Sub(now))
+	}
+	for i := 0; i < 20; i++ {
+		tracker.recordTimeout(5, now)
+	}
+	if got := tracker.recordTimeout(5, now); !got.Equal(now.Add(timeoutBackoffMax)) {
+		t.Errorf("backoff should be capped, got %v", got.Sub(now))
+	}
+
+	if _, skip := tracker.backingOff(5, now.Add(timeoutBackoffMax)); skip {
+		t.Error("issue should be retried once the backoff has passed")
+	}
+	tracker.reset(5)
+	if _, skip := tracker.backingOff(5, now); skip {
+		t.Error("reset should clear the backoff")
+	}
+}
+
+func TestHealthStatusReportsOpenCircuit(t *testing.T) {
+	gh := breaker.New("GitHub", 1, time.Hour)
+	cl := breaker.New("Claude", 1, time.Hour)
+	gh.Do(func() error { return errors.New("502") })
+
+	status := healthStatus([]*breaker.Breaker{gh, cl})
+	if status["status"] != "degraded" {
+		t.Errorf("expected degraded status, got %v", status["status"])
+	}
+	circuits := status["circuits"].(map[string]string)
+	if circuits["GitHub"] != "open" || circuits["Claude"] != "closed" {
+		t.Errorf("unexpected circuits %v", circuits)
+	}
+
+	if status := healthStatus(nil); status["status"] != "healthy" || status["circuits"] != nil {
+		t.Errorf("expected plain healthy status without breakers, got %v", status)
+	}
+}
+
+// startWebhookMux serves the webhook routes and returns the issues handed to processing
+func startWebhookMux(t *testing.T, testEndpoint bool) (*httptest.Server, <-chan *github.Issue) {
+	t.Helper()
+	withIssueTimeout(t, time.Minute)
+	orig := enableTestEndpoint
+	t.Cleanup(func() { enableTestEndpoint = orig })
+	enableTestEndpoint = testEndpoint
+
+	issues := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/7":
+			w.Write([]byte(`{"number": 7, "title": "Fix login", "body": "See @auth.go", "state": "open", "html_url": "https://github.com/owner/repo/issues/7", "labels": [{"name": "bug"}]}`))
+		case "/repos/owner/repo/issues/8":
+			w.Write([]byte(`{"number": 8, "title": "Old", "state": "closed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	processed := make(chan *github.Issue, 2)
+	process := func(ctx context.Context, issue *github.Issue) error {
+		processed <- issue
+		return nil
+	}
+	server := httptest.NewServer(newWebhookMux(context.Background(), issues, nil, process, process))
+	t.Cleanup(server.Close)
+	return server, processed
+}
+
+func postJSON(t *testing.T, url, body string) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func receive(t *testing.T, processed <-chan *github.Issue) *github.Issue {
+	t.Helper()
+	select {
+	case issue := <-processed:
+		return issue
+	case <-time.After(5 * time.Second):
+		t.Fatal("issue was not processed")
+		return nil
+	}
+}
+
+func TestWebhookTestEndpointMatchesDelivery(t *testing.T) {
+	server, processed := startWebhookMux(t, true)
+
+	var delivered, tested *github.Issue
+	captureStdout(t, func() {
+		status := postJSON(t, server.URL+"/webhook", `{"action": "opened", "issue": {"number": 7, "title": "Fix login", "body": "See @auth.go", "state": "open", "html_url": "https://github.com/owner/repo/issues/7", "labels": [{"name": "bug"}]}}`)
+		if status != http.StatusOK {
+			t.Fatalf("webhook: got status %d", status)
+		}
+		delivered = receive(t, processed)
+
+		if status := postJSON(t, server.URL+"/webhook/test", `{"issue_number": 7}`); status != http.StatusOK {
+			t.Fatalf("test endpoint: got status %d", status)
+		}
+		tested = receive(t, processed)
+	})
+
+	if !reflect.DeepEqual(delivered, tested) {
+		t.Errorf("test endpoint issue %+v differs from webhook issue %+v", tested, delivered)
+	}
+}
+
+func TestWebhookTestEndpointRejects(t *testing.T) {
+	server, _ := startWebhookMux(t, true)
+
+	captureStdout(t, func() {
+		for body, want := range map[string]int{
+			`{}`:                  http.StatusBadRequest,
+			`{"issue_number": 8}`: http.StatusUnprocessableEntity,
+			`{"issue_number": 9}`: http.StatusBadGateway,
+		} {
+			if status := postJSON(t, server.URL+"/webhook/test", body); status != want {
+				t.Errorf("%s: got status %d, want %d", body, status, want)
+			}
+		}
+	})
+}
+
+func TestWebhookTestEndpointDisabledByDefault(t *testing.T) {
+	server, _ := startWebhookMux(t, false)
+
+	if status := postJSON(t, server.URL+"/webhook/test", `{"issue_number": 7}`); status != http.StatusNotFound {
+		t.Errorf("expected 404 without --enable-test-endpoint, got %d", status)
+	}
+}
+
+// issueListHandler serves open issues 1 and 2, and open PRs from pulls
+func issueListHandler(pulls func(w http.ResponseWriter)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues":
+			w.Write([]byte(`[{"number": 1, "title": "Covered", "state": "open"}, {"number": 2, "title": "New", "state": "open"}]`))
+		case "/repos/owner/repo/pulls":
+			pulls(w)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestCheckIssuesSkipsIssuesWithOpenPR(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+
+	gh := newStubGitHub(t, issueListHandler(func(w http.ResponseWriter) {
+		w.Write([]byte(`[{"number": 5, "html_url": "https://github.com/owner/repo/pull/5", "head": {"ref": "vibe-git/issue-1"}},
+			{"number": 6, "head": {"ref": "feature"}}]`))
+	}))
+
+	var processed []int
+	out := captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if !reflect.DeepEqual(processed, []int{2}) {
+		t.Errorf("expected only issue #2 to be processed, got %v", processed)
+	}
+	if !strings.Contains(out, "Skipping issue #1: PR #5 is already open") {
+		t.Errorf("expected skip message for issue #1, got %q", out)
+	}
+}
+
+func TestCheckIssuesProcessesWhenPRListFails(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+
+	gh := newStubGitHub(t, issueListHandler(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	var processed []int
+	captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if !reflect.DeepEqual(processed, []int{1, 2}) {
+		t.Errorf("expected both issues to be processed, got %v", processed)
+	}
+}
+
+func TestCheckIssuesIncludeClosed(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	orig := includeClosed
+	t.Cleanup(func() { includeClosed = orig })
+
+	for _, tt := range []struct {
+		includeClosed bool
+		state         string
+		processed     []int
+	}{
+		{false, "open", []int{1}},
+		{true, "all", []int{1, 2}},
+	} {
+		includeClosed, lastHandled = tt.includeClosed, nil
+		gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/owner/repo/issues":
+				if got := r.URL.Query().Get("state"); got != tt.state {
+					t.Errorf("expected state=%s, got %q", tt.state, got)
+				}
+				if tt.state == "open" {
+					w.Write([]byte(`[{"number": 1, "state": "open"}]`))
+					return
+				}
+				w.Write([]byte(`[{"number": 1, "state": "open"}, {"number": 2, "state": "closed"}]`))
+			default:
+				w.Write([]byte(`[]`))
+			}
+		})
+
+		var processed []int
+		captureStdout(t, func() {
+			checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+				processed = append(processed, issue.Number)
+				return nil
+			})
+		})
+		if !reflect.DeepEqual(processed, tt.processed) {
+			t.Errorf("includeClosed=%v: expected %v to be processed, got %v", tt.includeClosed, tt.processed, processed)
+		}
+	}
+}
+
+func TestCheckIssuesListsIssuesUpdatedWhileProcessing(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	origChecked := lastChecked
+	t.Cleanup(func() { lastChecked = origChecked })
+	lastChecked = time.Now().Add(-time.Hour)
+
+	// Serves the issues updated since the since parameter, as GitHub does
+	var mu sync.Mutex
+	updated := map[int]time.Time{1: time.Now().Add(-time.Minute)}
+	touch := func(number int) {
+		mu.Lock()
+		updated[number] = time.Now()
+		mu.Unlock()
+	}
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+		if err != nil {
+			t.Errorf("invalid since: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var list []map[string]interface{}
+		for number, at := range updated {
+			if !at.Before(since) {
+				list = append(list, map[string]interface{}{"number": number, "state": "open", "updated_at": at.UTC().Format(time.RFC3339)})
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+
+	var processed []int
+	process := func(ctx context.Context, issue *github.Issue) error {
+		processed = append(processed, issue.Number)
+		if issue.Number == 1 {
+			// Issue #2 is opened while #1 is processed, which comments on #1.
+			// since has whole seconds, so finish #1 in a later second.
+			touch(2)
+			time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second + 10*time.Millisecond)))
+			touch(1)
+		}
+		return nil
+	}
+	captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, process)
+		checkIssues(context.Background(), gh, gh, process)
+	})
+
+	if !reflect.DeepEqual(processed, []int{1, 2}) {
+		t.Errorf("expected #1 and then #2 to be processed once each, got %v", processed)
+	}
+}
+
+func TestWebhookEditedIssues(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, time.Minute)
+	origOnEdit, origLogin := onEdit, botLogin
+	t.Cleanup(func() { onEdit, botLogin = origOnEdit, origLogin })
+	botLogin = "vibe-bot"
+
+	type call struct {
+		action string
+		issue  int
+	}
+	calls := make(chan call, 4)
+	handler := func(action string) func(context.Context, *github.Issue) error {
+		return func(ctx context.Context, issue *github.Issue) error {
+			calls <- call{action, issue.Number}
+			return nil
+		}
+	}
+	server := httptest.NewServer(newWebhookMux(context.Background(), nil, nil, handler("opened"), handler("edited")))
+	t.Cleanup(server.Close)
+
+	delivery := func(action, changes, sender string) string {
+		return `{"action": "` + action + `", "issue": {"number": 7, "title": "Fix login", "body": "Use OAuth", "state": "open"}, "changes": ` + changes + `, "sender": ` + sender + `}`
+	}
+	bodyChange := `{"body": {"from": "Fix it"}}`
+	user := `{"login": "octocat", "type": "User"}`
+
+	skipped := map[string]string{
+		"own edit":       delivery("edited", bodyChange, `{"login": "vibe-bot", "type": "User"}`),
+		"app edit":       delivery("edited", bodyChange, `{"login": "vibe-git[bot]", "type": "Bot"}`),
+		"nothing edited": delivery("edited", `{}`, user),
+	}
+
+	captureStdout(t, func() {
+		onEdit = onEditRegenerate
+		for name, body := range skipped {
+			if status := postJSON(t, server.URL+"/webhook", body); status != http.StatusOK {
+				t.Errorf("%s: got status %d", name, status)
+			}
+		}
+		postJSON(t, server.URL+"/webhook", delivery("edited", bodyChange, user))
+		if got := <-calls; got != (call{"edited", 7}) {
+			t.Errorf("expected the edit to reach the edit handler, got %+v", got)
+		}
+
+		onEdit = onEditIgnore
+		postJSON(t, server.URL+"/webhook", delivery("edited", `{"title": {"from": "Login"}}`, user))
+		postJSON(t, server.URL+"/webhook", delivery("opened", `{}`, user))
+		if got := <-calls; got != (call{"opened", 7}) {
+			t.Errorf("expected --on-edit=ignore to drop the edit, got %+v", got)
+		}
+	})
+	if len(calls) != 0 {
+		t.Errorf("unexpected extra calls: %d", len(calls))
+	}
+}
+
+func TestHandleIssueEdit(t *testing.T) {
+	origOnEdit := onEdit
+	t.Cleanup(func() { onEdit = origOnEdit })
+
+	var comments []string
+	prOpen := true
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo/pulls" && prOpen:
+			w.Write([]byte(`[{"number": 5, "head": {"ref": "vibe-git/issue-7"}}]`))
+		case r.URL.Path == "/repos/owner/repo/pulls":
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/issues/5/comments":
+			var body struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&body)
+			comments = append(comments, body.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	issue := &github.Issue{Number: 7, Title: "Fix login"}
+	regenerated := 0
+	regenerate := func(ctx context.Context) error {
+		regenerated++
+		return nil
+	}
+
+	captureStdout(t, func() {
+		onEdit = onEditRegenerate
+		if err := handleIssueEdit(context.Background(), gh, issue, regenerate); err != nil {
+			t.Errorf("regenerate: %v", err)
+		}
+
+		onEdit = onEditComment
+		if err := handleIssueEdit(context.Background(), gh, issue, regenerate); err != nil {
+			t.Errorf("comment: %v", err)
+		}
+		prOpen = false
+		if err := handleIssueEdit(context.Background(), gh, issue, regenerate); err != nil {
+			t.Errorf("comment without PR: %v", err)
+		}
+	})
+
+	if regenerated != 1 {
+		t.Errorf("expected one regeneration, got %d", regenerated)
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0], "was edited after this PR was opened") {
+		t.Errorf("expected one comment on PR #5, got %v", comments)
+	}
+}
+
+func TestRegenerateEditedIssueReplacesPushedBranch(t *testing.T) {
+	clone, cl, gitClient := newInteractiveIssue(t)
+	origOnEdit, origWorktree := onEdit, useWorktree
+	t.Cleanup(func() { onEdit, useWorktree = origOnEdit, origWorktree })
+	onEdit, useWorktree = onEditRegenerate, false
+
+	// An earlier run pushed the issue branch and opened PR #5
+	origin := runGit(t, clone, "remote", "get-url", "origin")
+	runGit(t, clone, "config", "url."+origin+".insteadOf", "https://@github.com/owner/repo.git")
+	runGit(t, clone, "checkout", "-q", "-b", "vibe-git/issue-7")
+	writeTestFile(t, filepath.Join(clone, "README.md"), "# demo\n\nEarlier attempt\n")
+	runGit(t, clone, "commit", "-q", "-am", "Fix issue #7: earlier attempt")
+	runGit(t, clone, "push", "-q", "origin", "vibe-git/issue-7")
+	runGit(t, clone, "checkout", "-q", "main")
+
+	var comments []string
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/pulls":
+			w.Write([]byte(`[{"number": 5, "html_url": "https://github.com/owner/repo/pull/5", "head": {"ref": "vibe-git/issue-7"}}]`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/owner/repo/pulls/5":
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/issues/5/comments":
+			var body struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&body)
+			comments = append(comments, body.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		default:
+			issueHandler(w, r)
+		}
+	})
+
+	issue := &github.Issue{Number: 7, Title: "Add pkg", URL: "https://github.com/owner/repo/issues/7"}
+	var err error
+	captureStdout(t, func() {
+		err = handleIssueEdit(context.Background(), gh, issue, func(ctx context.Context) error {
+			return processIssueWithClients(ctx, gh, gh, cl, gitClient, issue)
+		})
+	})
+	if err != nil {
+		t.Fatalf("regenerating: %v", err)
+	}
+
+	if msg := runGit(t, origin, "log", "-1", "--format=%s", "vibe-git/issue-7"); msg != "Fix issue #7: Add pkg" {
+		t.Errorf("expected the regenerated commit on origin, got %q", msg)
+	}
+	if count := runGit(t, origin, "rev-list", "--count", "main..vibe-git/issue-7"); count != "1" {
+		t.Errorf("expected the earlier attempt to be replaced, branch has %s commits", count)
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0], "pushed new changes") {
+		t.Errorf("expected PR #5 to note the update, got %v", comments)
+	}
+}
+
+// withRequireLabel sets --require-label for the test
+func withRequireLabel(t *testing.T, label string) {
+	t.Helper()
+	orig := requireLabel
+	t.Cleanup(func() { requireLabel = orig })
+	requireLabel = label
+}
+
+func TestCheckIssuesRequiresLabel(t *testing.T) {
+	withStateFile(t)
+	withIssueTimeout(t, 0)
+	withRequireLabel(t, "vibe")
+
+	gh := newStubGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues":
+			w.Write([]byte(`[{"number": 1, "title": "Unlabeled", "state": "open"},
+				{"number": 2, "title": "Other label", "state": "open", "labels": [{"name": "bug"}]},
+				{"number": 3, "title": "Triggered", "state": "open", "labels": [{"name": "bug"}, {"name": "Vibe"}]}]`))
+		case "/repos/owner/repo/pulls":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	var processed []int
+	out := captureStdout(t, func() {
+		checkIssues(context.Background(), gh, gh, func(ctx context.Context, issue *github.Issue) error {
+			processed = append(processed, issue.Number)
+			return nil
+		})
+	})
+
+	if !reflect.DeepEqual(processed, []int{3}) {
+		t.Errorf("expected only the labeled issue to be processed, got %v", processed)
+	}
+	if !strings.Contains(out, `Skipping issue #1: it doesn't have the "vibe" label`) {
+		t.Errorf("expected a skip message for issue #1, got %q", out)
+	}
+}
+
+func TestWebhookRequiresLabel(t *testing.T) {
+	withStateFile(t)
+	withRequireLabel(t, "vibe")
+	server, processed := startWebhookMux(t, false)
+
+	delivery := func(action, labels, added string) string {
+		return `{"action": "` + action + `", "issue": {"number": 7, "title": "Fix login", "state": "open", "labels": ` + labels + `}, "label": {"name": "` + added + `"}}`
+	}
+
+	var got []string
+	captureStdout(t, func() {
+		for _, body := range []string{
+			delivery("opened", `[]`, ""),
+			delivery("opened", `[{"name": "bug"}]`, ""),
+			delivery("labeled", `[{"name": "bug"}]`, "bug"),
+			delivery("opened", `[{"name": "vibe"}]`, ""),
+			delivery("labeled", `[{"name": "bug"}, {"name": "vibe"}]`, "vibe"),
+		} {
+			if status := postJSON(t, server.URL+"/webhook", body); status != http.StatusOK {
+				t.Fatalf("got status %d for %s", status, body)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			got = append(got, strings.Join(receive(t, processed).Labels, ","))
+		}
+	})
+
+	if len(processed) != 0 || !reflect.DeepEqual(got, []string{"vibe", "bug,vibe"}) && !reflect.DeepEqual(got, []string{"bug,vibe", "vibe"}) {
+		t.Errorf("expected only the two labeled deliveries to be processed, got %v and %d more", got, len(processed))
+	}
+}
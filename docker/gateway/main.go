@@ -1,26 +1,51 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
+
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/requestid"
 )
 
 const (
 	anthropicAPI = "https://api.anthropic.com"
 	apiVersion   = "2023-06-01"
+
+	// defaultMaxBodyBytes matches the Messages API request limit; override with GATEWAY_MAX_BODY_BYTES
+	defaultMaxBodyBytes = 32 << 20
+
+	// jsonContentType is sent with every JSON response the gateway writes itself
+	jsonContentType = "application/json; charset=utf-8"
+
+	// defaultMessagesTimeout bounds proxied API calls, which can take minutes for
+	// long completions; override with GATEWAY_MESSAGES_TIMEOUT (0 = no limit)
+	defaultMessagesTimeout = 10 * time.Minute
 )
 
 var (
-	anthropicKey  string
-	gatewayToken  string
-	proxy         *httputil.ReverseProxy
+	anthropicKey    string
+	gatewayToken    string
+	proxy           *httputil.ReverseProxy
+	maxBodyBytes    int64 = defaultMaxBodyBytes
+	messagesTimeout       = defaultMessagesTimeout
+	// shortTimeout bounds /health, /metrics and /claude/*, which should answer quickly
+	shortTimeout = 15 * time.Second
+	// allowedPaths are the API paths proxied, with everything below them (so
+	// /v1/messages/count_tokens too); override with GATEWAY_ALLOWED_PATHS
+	allowedPaths = []string{"/v1/messages", "/v1/models"}
 )
 
 func main() {
@@ -35,51 +60,167 @@ func main() {
 		log.Println("Warning: Using default gateway token. Set GATEWAY_TOKEN for production.")
 	}
 
+	if v := os.Getenv("GATEWAY_MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid GATEWAY_MAX_BODY_BYTES: %q", v)
+		}
+		maxBodyBytes = n
+	}
+
+	if v := os.Getenv("GATEWAY_ALLOWED_PATHS"); v != "" {
+		allowedPaths = parseAllowedPaths(v)
+		if len(allowedPaths) == 0 {
+			log.Fatalf("Invalid GATEWAY_ALLOWED_PATHS: %q", v)
+		}
+	}
+
+	if v := os.Getenv("GATEWAY_MESSAGES_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid GATEWAY_MESSAGES_TIMEOUT: %q", v)
+		}
+		messagesTimeout = d
+	}
+
 	// Create reverse proxy to Anthropic
 	targetURL, _ := url.Parse(anthropicAPI)
-	proxy = httputil.NewSingleHostReverseProxy(targetURL)
+	proxy = newProxy(targetURL)
 
-	// Modify the director to add our headers
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
-		req.Host = targetURL.Host
-		req.Header.Set("X-Api-Key", anthropicKey)
-		req.Header.Set("Anthropic-Version", apiVersion)
-		// Remove internal auth header before forwarding
-		req.Header.Del("X-Gateway-Auth")
+	mux := newMux()
+
+	port := os.Getenv("GATEWAY_PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Claude Gateway starting on port %s", port)
+	log.Printf("Protecting Anthropic API key - workers use local authentication")
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           requestid.Middleware(authMiddleware(limitBody(mux))),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		// No WriteTimeout: each route sets its own, see newMux
+		IdleTimeout: 120 * time.Second,
 	}
 
+	log.Fatal(server.ListenAndServe())
+}
+
+// newMux routes requests, giving API calls messagesTimeout and everything
+// else shortTimeout
+func newMux() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Health check
-	mux.HandleFunc("/health", handleHealth)
+	mux.Handle("/health", shortRoute(handleHealth))
 
 	// Metrics endpoint
-	mux.HandleFunc("/metrics", handleMetrics)
+	mux.Handle("/metrics", shortRoute(handleMetrics))
 
 	// Proxy all Anthropic API requests
-	mux.HandleFunc("/v1/", handleProxy)
+	mux.Handle("/v1/", withDeadline(http.HandlerFunc(handleProxy), messagesTimeout))
 
 	// Claude Code specific endpoints
-	mux.HandleFunc("/claude/", handleClaude)
+	mux.Handle("/claude/", shortRoute(handleClaude))
 
-	port := os.Getenv("GATEWAY_PORT")
-	if port == "" {
-		port = "8080"
+	// Everything else gets a JSON 404 rather than ServeMux's plain text one
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, "Not found: "+r.URL.Path, http.StatusNotFound)
+	})
+
+	return mux
+}
+
+// shortRoute answers 503 when h takes longer than shortTimeout
+func shortRoute(h http.HandlerFunc) http.Handler {
+	timeout := http.TimeoutHandler(h, shortTimeout, fmt.Sprintf(`{"error": "Request timed out", "code": %d}`, http.StatusServiceUnavailable))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// TimeoutHandler writes its message without a content type; h's own
+		// headers replace this one when it finishes in time
+		w.Header().Set("Content-Type", jsonContentType)
+		timeout.ServeHTTP(w, r)
+	})
+}
+
+// withDeadline cancels the request's context after d (0 = never). Unlike
+// http.TimeoutHandler it doesn't buffer the response, so streamed completions
+// reach the client as they arrive.
+func withDeadline(next http.Handler, d time.Duration) http.Handler {
+	if d <= 0 {
+		return next
 	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-	log.Printf("Claude Gateway starting on port %s", port)
-	log.Printf("Protecting Anthropic API key - workers use local authentication")
+// newProxy creates the reverse proxy that forwards requests to target with the real API key
+func newProxy(target *url.URL) *httputil.ReverseProxy {
+	p := httputil.NewSingleHostReverseProxy(target)
+	p.Transport = httpclient.Transport()
 
-	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      authMiddleware(mux),
-		ReadTimeout:  60 * time.Second,
-		WriteTimeout: 120 * time.Second,
+	// Modify the director to add our headers
+	originalDirector := p.Director
+	p.Director = func(req *http.Request) {
+		originalDirector(req)
+		req.Host = target.Host
+		req.Header.Set("X-Api-Key", anthropicKey)
+		req.Header.Set("Anthropic-Version", apiVersion)
+		// Remove internal auth header before forwarding
+		req.Header.Del("X-Gateway-Auth")
 	}
 
-	log.Fatal(server.ListenAndServe())
+	// A body cut off by limitBody surfaces here while forwarding
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeTooLarge(w)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			requestid.Logf(r, "Proxy timeout for %s %s after %v", r.Method, r.URL.Path, messagesTimeout)
+			writeError(w, "Upstream request timed out", http.StatusGatewayTimeout)
+			return
+		}
+		requestid.Logf(r, "Proxy error for %s %s: %v", r.Method, r.URL.Path, err)
+		writeError(w, "Bad gateway", http.StatusBadGateway)
+	}
+
+	return p
+}
+
+// limitBody rejects requests whose body is larger than maxBodyBytes with 413
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBodyBytes {
+			writeTooLarge(w)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeTooLarge(w http.ResponseWriter) {
+	writeError(w, fmt.Sprintf("Request body exceeds %d bytes", maxBodyBytes), http.StatusRequestEntityTooLarge)
+}
+
+// writeError writes a JSON error as {"error", "code"}, plus the request ID so
+// a failure seen by a client can be found in the gateway log
+func writeError(w http.ResponseWriter, message string, code int) {
+	body := map[string]interface{}{"error": message, "code": code}
+	if id := w.Header().Get(requestid.Header); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
 }
 
 // authMiddleware validates gateway token
@@ -98,8 +239,8 @@ func authMiddleware(next http.Handler) http.Handler {
 		}
 
 		if token != gatewayToken {
-			log.Printf("Unauthorized request from %s", r.RemoteAddr)
-			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+			requestid.Logf(r, "Unauthorized request from %s", r.RemoteAddr)
+			writeError(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
@@ -108,7 +249,7 @@ func authMiddleware(next http.Handler) http.Handler {
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":    "healthy",
 		"service":   "claude-gateway",
@@ -120,7 +261,7 @@ var requestCount int64
 var lastRequestTime time.Time
 
 func handleMetrics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"requests":          requestCount,
 		"last_request_time": lastRequestTime,
@@ -132,7 +273,7 @@ func handleProxy(w http.ResponseWriter, r *http.Request) {
 	requestCount++
 	lastRequestTime = time.Now()
 
-	log.Printf("Proxying %s %s", r.Method, r.URL.Path)
+	requestid.Logf(r, "Proxying %s %s", r.Method, r.URL.Path)
 
 	// Add CORS headers for local development
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -144,9 +285,80 @@ func handleProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !isAllowedPath(r.URL.Path) {
+		requestid.Logf(r, "Blocked %s %s: path not in GATEWAY_ALLOWED_PATHS", r.Method, r.URL.Path)
+		writeError(w, "Path not allowed by gateway", http.StatusForbidden)
+		return
+	}
+
+	if isDryRun(r) {
+		handleDryRun(w, r)
+		return
+	}
+
 	proxy.ServeHTTP(w, r)
 }
 
+// parseAllowedPaths splits a comma-separated list of paths
+func parseAllowedPaths(s string) []string {
+	var paths []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, path.Clean("/"+p))
+		}
+	}
+	return paths
+}
+
+// isAllowedPath reports whether p is an allowed path or lies below one
+func isAllowedPath(p string) bool {
+	p = path.Clean(p)
+	for _, allowed := range allowedPaths {
+		if p == allowed || strings.HasPrefix(p, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isDryRun reports whether the request asks to see the upstream request
+// instead of sending it, with ?dry-run=true or an X-Gateway-DryRun header
+func isDryRun(r *http.Request) bool {
+	if v := r.Header.Get("X-Gateway-DryRun"); v != "" {
+		on, _ := strconv.ParseBool(v)
+		return on
+	}
+	on, _ := strconv.ParseBool(r.URL.Query().Get("dry-run"))
+	return on
+}
+
+// handleDryRun runs the proxy's Director on a copy of the request and echoes
+// the result as JSON, with the API key redacted, without calling Anthropic
+func handleDryRun(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	out.Header.Del("X-Gateway-DryRun")
+	query := out.URL.Query()
+	query.Del("dry-run")
+	out.URL.RawQuery = query.Encode()
+
+	proxy.Director(out)
+
+	headers := out.Header.Clone()
+	if headers.Get("X-Api-Key") != "" {
+		headers.Set("X-Api-Key", "[REDACTED]")
+	}
+
+	requestid.Logf(r, "Dry run %s %s", r.Method, r.URL.Path)
+	w.Header().Set("Content-Type", jsonContentType)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"method":  out.Method,
+		"url":     out.URL.String(),
+		"host":    out.Host,
+		"path":    out.URL.Path,
+		"headers": headers,
+	})
+}
+
 // handleClaude provides additional Claude-specific endpoints
 func handleClaude(w http.ResponseWriter, r *http.Request) {
 	switch r.URL.Path {
@@ -155,7 +367,7 @@ func handleClaude(w http.ResponseWriter, r *http.Request) {
 	case "/claude/models":
 		handleModels(w, r)
 	default:
-		http.NotFound(w, r)
+		writeError(w, "Not found: "+r.URL.Path, http.StatusNotFound)
 	}
 }
 
@@ -164,8 +376,9 @@ func handleClaudeStatus(w http.ResponseWriter, r *http.Request) {
 	req, _ := http.NewRequest("GET", anthropicAPI+"/v1/models", nil)
 	req.Header.Set("X-Api-Key", anthropicKey)
 	req.Header.Set("Anthropic-Version", apiVersion)
+	req.Header.Set(requestid.Header, requestid.FromContext(r.Context()))
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: httpclient.Transport()}
 	resp, err := client.Do(req)
 
 	status := map[string]interface{}{
@@ -182,7 +395,7 @@ func handleClaudeStatus(w http.ResponseWriter, r *http.Request) {
 		status["anthropic"] = resp.Status
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 	json.NewEncoder(w).Encode(status)
 }
 
@@ -190,16 +403,17 @@ func handleModels(w http.ResponseWriter, r *http.Request) {
 	req, _ := http.NewRequest("GET", anthropicAPI+"/v1/models", nil)
 	req.Header.Set("X-Api-Key", anthropicKey)
 	req.Header.Set("Anthropic-Version", apiVersion)
+	req.Header.Set(requestid.Header, requestid.FromContext(r.Context()))
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: httpclient.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer resp.Body.Close()
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 	w.WriteHeader(resp.StatusCode)
 	io.Copy(w, resp.Body)
 }
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/requestid"
+)
+
+func TestLimitBodyRejectsOversizedProxyRequest(t *testing.T) {
+	orig := maxBodyBytes
+	t.Cleanup(func() { maxBodyBytes = orig })
+	maxBodyBytes = 64
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+	handler := limitBody(http.HandlerFunc(handleProxy))
+
+	small := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"m"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, small)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected small request to be proxied, got %d", rec.Code)
+	}
+
+	big := strings.Repeat("x", 1000)
+
+	// Declared Content-Length is rejected without contacting the API
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(big)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for declared length, got %d", rec.Code)
+	}
+
+	// A streamed body is cut off while forwarding
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", io.NopCloser(strings.NewReader(big)))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for streamed body, got %d", rec.Code)
+	}
+}
+
+func TestDryRunEchoesRewrittenRequest(t *testing.T) {
+	origKey, origToken := anthropicKey, gatewayToken
+	t.Cleanup(func() { anthropicKey, gatewayToken = origKey, origToken })
+	anthropicKey = "sk-ant-secret"
+	gatewayToken = "gw-token"
+
+	called := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	t.Cleanup(upstream.Close)
+
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+	handler := authMiddleware(limitBody(http.HandlerFunc(handleProxy)))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages?beta=true", strings.NewReader(`{"model":"m"}`))
+	req.Header.Set("X-Gateway-Auth", "gw-token")
+	req.Header.Set("X-Gateway-DryRun", "true")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if called {
+		t.Error("dry run must not contact the upstream API")
+	}
+
+	var echoed struct {
+		Method  string      `json:"method"`
+		URL     string      `json:"url"`
+		Host    string      `json:"host"`
+		Path    string      `json:"path"`
+		Headers http.Header `json:"headers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &echoed); err != nil {
+		t.Fatalf("decoding dry run response: %v", err)
+	}
+	if echoed.Method != http.MethodPost || echoed.Path != "/v1/messages" {
+		t.Errorf("unexpected request line %s %s", echoed.Method, echoed.Path)
+	}
+	if echoed.Host != target.Host || echoed.URL != upstream.URL+"/v1/messages?beta=true" {
+		t.Errorf("expected request rewritten to %s, got host %s url %s", target.Host, echoed.Host, echoed.URL)
+	}
+	if got := echoed.Headers.Get("X-Api-Key"); got != "[REDACTED]" {
+		t.Errorf("X-Api-Key = %q, want it redacted", got)
+	}
+	if got := echoed.Headers.Get("Anthropic-Version"); got != apiVersion {
+		t.Errorf("Anthropic-Version = %q, want %q", got, apiVersion)
+	}
+	if echoed.Headers.Get("X-Gateway-Auth") != "" || echoed.Headers.Get("X-Gateway-DryRun") != "" {
+		t.Errorf("gateway headers should not be forwarded: %v", echoed.Headers)
+	}
+	if echoed.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("client headers should be kept: %v", echoed.Headers)
+	}
+	if strings.Contains(rec.Body.String(), "sk-ant-secret") {
+		t.Error("dry run response leaked the API key")
+	}
+
+	// The query parameter works too, and dry runs still need the gateway token
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages?dry-run=true", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected unauthenticated dry run to get 401, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages?dry-run=true&token=gw-token", nil))
+	if rec.Code != http.StatusOK || called {
+		t.Errorf("expected ?dry-run=true to echo without proxying, got %d", rec.Code)
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	origShort, origMessages := shortTimeout, messagesTimeout
+	t.Cleanup(func() { shortTimeout, messagesTimeout = origShort, origMessages })
+	shortTimeout = 50 * time.Millisecond
+	messagesTimeout = time.Second
+
+	// A slow short route is cut off with 503
+	slow := shortRoute(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"status": "healthy"}`))
+	})
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected slow /health to be cut off with 503, got %d", rec.Code)
+	}
+
+	// An API call may run well past the short timeout
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"id": "msg_1"}`))
+	}))
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+	mux := newMux()
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "msg_1") {
+		t.Errorf("expected slow /v1/messages to complete, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// ...but not past messagesTimeout
+	messagesTimeout = 50 * time.Millisecond
+	mux = newMux()
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504 past the messages timeout, got %d", rec.Code)
+	}
+}
+
+func TestProxyAllowlist(t *testing.T) {
+	orig := allowedPaths
+	t.Cleanup(func() { allowedPaths = orig })
+	allowedPaths = parseAllowedPaths(" /v1/messages, v1/models ,")
+
+	var proxied []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/v1/messages", http.StatusOK},
+		{"/v1/messages/count_tokens", http.StatusOK},
+		{"/v1/models/claude-3-5-sonnet-latest", http.StatusOK},
+		{"/v1/messagesx", http.StatusForbidden},
+		{"/v1/organizations/usage", http.StatusForbidden},
+		{"/v1/messages/../organizations", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.URL.Path = tt.path
+		rec := httptest.NewRecorder()
+		handleProxy(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+	if len(proxied) != 3 {
+		t.Errorf("expected only allowed paths to be proxied, got %v", proxied)
+	}
+}
+
+func TestRequestIDForwardedUpstream(t *testing.T) {
+	origToken := gatewayToken
+	t.Cleanup(func() { gatewayToken = origToken })
+	gatewayToken = "gw-token"
+
+	var upstreamSaw string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamSaw = r.Header.Get(requestid.Header)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+	handler := requestid.Middleware(authMiddleware(limitBody(newMux())))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	req.Header.Set("X-Gateway-Auth", "gw-token")
+	req.Header.Set(requestid.Header, "run-1234")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if upstreamSaw != "run-1234" {
+		t.Errorf("upstream saw request ID %q, want run-1234", upstreamSaw)
+	}
+	if got := rec.Header().Get(requestid.Header); got != "run-1234" {
+		t.Errorf("response echoed request ID %q, want run-1234", got)
+	}
+
+	// Error responses name the ID too
+	req = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set(requestid.Header, "run-5678")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusUnauthorized || body["request_id"] != "run-5678" {
+		t.Errorf("expected 401 carrying the request ID, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestErrorResponseShape(t *testing.T) {
+	origToken, origPaths := gatewayToken, allowedPaths
+	t.Cleanup(func() { gatewayToken, allowedPaths = origToken, origPaths })
+	gatewayToken = "secret"
+	allowedPaths = parseAllowedPaths("/v1/messages")
+	handler := requestid.Middleware(authMiddleware(limitBody(newMux())))
+
+	tests := []struct {
+		name, path string
+		auth       bool
+		want       int
+	}{
+		{"unauthorized", "/v1/messages", false, http.StatusUnauthorized},
+		{"forbidden", "/v1/organizations/usage", true, http.StatusForbidden},
+		{"not found", "/admin", true, http.StatusNotFound},
+		{"unknown claude endpoint", "/claude/unknown", true, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{}`))
+		if tt.auth {
+			req.Header.Set("X-Gateway-Auth", "secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s: unexpected content type %q", tt.name, ct)
+		}
+		var body struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" || body.Code != tt.want {
+			t.Errorf("%s: expected {error, code: %d}, got %s", tt.name, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestCountTokensThroughGateway(t *testing.T) {
+	origKey, origToken := anthropicKey, gatewayToken
+	t.Cleanup(func() { anthropicKey, gatewayToken = origKey, origToken })
+	anthropicKey, gatewayToken = "sk-ant-secret", "gw-token"
+
+	var upstreamSaw *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamSaw = r
+		w.Write([]byte(`{"input_tokens": 4321}`))
+	}))
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+	proxy = newProxy(target)
+	gateway := httptest.NewServer(requestid.Middleware(authMiddleware(limitBody(newMux()))))
+	t.Cleanup(gateway.Close)
+
+	cl := claude.NewClient("placeholder", gateway.URL, "test-model")
+	cl.SetHeader("X-Gateway-Auth", "gw-token")
+	cl.SetHeader("Anthropic-Beta", "token-counting-2024-11-01")
+	tokens, err := cl.CountTokens(context.Background(), "Hello")
+	if err != nil || tokens != 4321 {
+		t.Fatalf("expected 4321 tokens, got %d (err %v)", tokens, err)
+	}
+
+	if upstreamSaw.URL.Path != "/v1/messages/count_tokens" {
+		t.Errorf("proxied to %s", upstreamSaw.URL.Path)
+	}
+	for header, want := range map[string]string{
+		"X-Api-Key":         "sk-ant-secret",
+		"Anthropic-Version": apiVersion,
+		"Anthropic-Beta":    "token-counting-2024-11-01",
+		"X-Gateway-Auth":    "",
+	} {
+		if got := upstreamSaw.Header.Get(header); got != want {
+			t.Errorf("upstream saw %s %q, want %q", header, got, want)
+		}
+	}
+}
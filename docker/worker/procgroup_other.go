@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op where process groups aren't available
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup kills cmd's process; its children are left running
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,71 @@
+//go:build unix
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClaudeRunTimeoutKillsChildren(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	// Stands in for claude starting a node process that would outlive it
+	script := "#!/bin/sh\nsleep 30 &\necho $! > \"$CHILD_PID_FILE\"\necho started >&2\nwait\n"
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CHILD_PID_FILE", pidFile)
+
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handleClaudeRun(rec, httptest.NewRequest(http.MethodPost, "/claude/run", strings.NewReader(`{"command": "-p", "timeout": 1}`)))
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("run took %v after a 1s timeout", elapsed)
+	}
+	var resp ClaudeRunResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.ExitCode == 0 || !strings.Contains(resp.Stderr, "started") {
+		t.Errorf("expected a failed run with captured stderr, got %+v", resp)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("child never started: %v", err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	deadline := time.Now().Add(2 * time.Second)
+	for alive(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child %d still running after claude timed out", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// alive reports whether pid is running; an unreaped zombie counts as dead
+func alive(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	return err != nil || !strings.Contains(string(stat), ") Z ")
+}
@@ -1,22 +1,62 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/git"
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/requestid"
+)
+
+const (
+	// defaultMaxBodyBytes bounds request bodies unless WORKER_MAX_BODY_BYTES says otherwise
+	defaultMaxBodyBytes = 10 << 20
+
+	// defaultMaxOutputBytes bounds the stdout and stderr returned for a claude
+	// or git command unless WORKER_MAX_OUTPUT_BYTES says otherwise
+	defaultMaxOutputBytes = 1 << 20
+
+	// defaultShutdownTimeout is how long in-flight requests may finish after
+	// SIGTERM unless WORKER_SHUTDOWN_TIMEOUT says otherwise
+	defaultShutdownTimeout = 30 * time.Second
+
+	// claudeStopGrace is how long a cancelled claude run has to exit after
+	// SIGTERM before it is killed
+	claudeStopGrace = 10 * time.Second
 )
 
 var (
-	workerToken string
-	projectPath string
+	workerToken     string
+	projectPath     string
+	maxBodyBytes    int64 = defaultMaxBodyBytes
+	maxOutputBytes  int64 = defaultMaxOutputBytes
+	shutdownTimeout       = defaultShutdownTimeout
+
+	// projects maps WORKER_PROJECTS names to their roots, besides the default
+	// projectPath
+	projects map[string]string
 )
 
 func main() {
@@ -30,53 +70,277 @@ func main() {
 	if projectPath == "" {
 		projectPath = "/workspace/project"
 	}
+	projectPath = filepath.Clean(projectPath)
+
+	if v := os.Getenv("WORKER_PROJECTS"); v != "" {
+		var err error
+		if projects, err = parseProjects(v); err != nil {
+			log.Fatalf("Invalid WORKER_PROJECTS: %v", err)
+		}
+	}
+
+	if v := os.Getenv("WORKER_MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid WORKER_MAX_BODY_BYTES: %q", v)
+		}
+		maxBodyBytes = n
+	}
+
+	if v := os.Getenv("WORKER_MAX_OUTPUT_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid WORKER_MAX_OUTPUT_BYTES: %q", v)
+		}
+		maxOutputBytes = n
+	}
+
+	if v := os.Getenv("WORKER_SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid WORKER_SHUTDOWN_TIMEOUT: %q", v)
+		}
+		shutdownTimeout = d
+	}
+
+	port := os.Getenv("WORKER_HTTP_PORT")
+	if port == "" {
+		port = "3000"
+	}
+
+	log.Printf("Worker server starting on port %s", port)
+	log.Printf("Project path: %s", projectPath)
+	for name, root := range projects {
+		log.Printf("Project %s: %s", name, root)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           requestid.Middleware(authMiddleware(limitBody(newWorkerRouter()))),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      300 * time.Second, // Lifted by /issue/process, which streams
+		IdleTimeout:       120 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := serve(ctx, server, ln, shutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Worker server stopped")
+}
+
+// serve runs server on ln until ctx is cancelled, then stops accepting
+// requests and waits up to drain for in-flight ones to finish. Requests still
+// running after that have their contexts cancelled, which stops their claude
+// and git commands, and get claudeStopGrace more to return.
+func serve(ctx context.Context, server *http.Server, ln net.Listener, drain time.Duration) error {
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return requestCtx }
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, waiting up to %v for in-flight requests", drain)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err == nil {
+		return nil
+	}
 
-	mux := http.NewServeMux()
+	log.Printf("In-flight requests still running after %v, cancelling them", drain)
+	cancelRequests()
+	graceCtx, cancelGrace := context.WithTimeout(context.Background(), claudeStopGrace+time.Second)
+	defer cancelGrace()
+	if err := server.Shutdown(graceCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("requests did not stop after cancellation: %w", err)
+	}
+	return nil
+}
+
+// newWorkerRouter declares every worker endpoint with the methods it accepts
+func newWorkerRouter() *router {
+	rt := newRouter()
 
 	// Health check
-	mux.HandleFunc("/health", handleHealth)
+	rt.handle("/health", handleHealth, http.MethodGet)
 
 	// Claude operations
-	mux.HandleFunc("/claude/run", handleClaudeRun)
-	mux.HandleFunc("/claude/status", handleClaudeStatus)
+	rt.handle("/claude/run", handleClaudeRun, http.MethodPost)
+	rt.handle("/claude/status", handleClaudeStatus, http.MethodGet)
 
 	// Git operations (替代 Git 命令)
-	mux.HandleFunc("/git/status", handleGitStatus)
-	mux.HandleFunc("/git/diff", handleGitDiff)
-	mux.HandleFunc("/git/log", handleGitLog)
-	mux.HandleFunc("/git/show", handleGitShow)
-	mux.HandleFunc("/git/ls-files", handleGitLsFiles)
-	mux.HandleFunc("/git/cat-file", handleGitCatFile)
+	rt.handle("/git/status", handleGitStatus, http.MethodGet)
+	rt.handle("/git/diff", handleGitDiff, http.MethodGet)
+	rt.handle("/git/log", handleGitLog, http.MethodGet)
+	rt.handle("/git/show", handleGitShow, http.MethodGet)
+	rt.handle("/git/ls-files", handleGitLsFiles, http.MethodGet)
+	rt.handle("/git/cat-file", handleGitCatFile, http.MethodGet)
 
 	// File operations
-	mux.HandleFunc("/file/read", handleFileRead)
-	mux.HandleFunc("/file/write", handleFileWrite)
-	mux.HandleFunc("/file/list", handleFileList)
-	mux.HandleFunc("/file/stat", handleFileStat)
+	rt.handle("/file/read", handleFileRead, http.MethodPost)
+	rt.handle("/file/write", handleFileWrite, http.MethodPost)
+	rt.handle("/file/delete", handleFileDelete, http.MethodPost)
+	rt.handle("/file/rename", handleFileRename, http.MethodPost)
+	rt.handle("/file/list", handleFileList, http.MethodGet)
+	rt.handle("/file/stat", handleFileStat, http.MethodGet)
 
 	// Project info
-	mux.HandleFunc("/project/info", handleProjectInfo)
-	mux.HandleFunc("/project/tree", handleProjectTree)
+	rt.handle("/project/info", handleProjectInfo, http.MethodGet)
+	rt.handle("/project/tree", handleProjectTree, http.MethodGet)
+	rt.handle("/project/search", handleProjectSearch, http.MethodPost)
 
 	// HTTP request service
-	mux.HandleFunc("/http/request", handleHTTPRequest)
+	rt.handle("/http/request", handleHTTPRequest, http.MethodPost)
 
-	port := os.Getenv("WORKER_HTTP_PORT")
-	if port == "" {
-		port = "3000"
+	// End-to-end issue processing
+	rt.handle("/issue/process", handleIssueProcess, http.MethodPost)
+
+	return rt
+}
+
+// router dispatches requests by exact path. Unknown paths get a JSON 404 and
+// methods a route doesn't accept get a JSON 405 with an Allow header; OPTIONS
+// is answered for every route.
+type router struct {
+	routes map[string]route
+}
+
+// route is a handler and the methods it accepts
+type route struct {
+	handler http.HandlerFunc
+	methods []string
+}
+
+func newRouter() *router {
+	return &router{routes: make(map[string]route)}
+}
+
+// handle registers handler for path. GET routes also accept HEAD.
+func (rt *router) handle(path string, handler http.HandlerFunc, methods ...string) {
+	for _, m := range methods {
+		if m == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+			break
+		}
 	}
+	rt.routes[path] = route{handler: handler, methods: append(methods, http.MethodOptions)}
+}
 
-	log.Printf("Worker server starting on port %s", port)
-	log.Printf("Project path: %s", projectPath)
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := rt.routes[r.URL.Path]
+	if !ok {
+		writeError(w, "Not found: "+r.URL.Path, http.StatusNotFound)
+		return
+	}
 
-	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      authMiddleware(mux),
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 300 * time.Second,
+	allow := strings.Join(route.methods, ", ")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for _, m := range route.methods {
+		if m == r.Method {
+			if r.URL.Path == "/health" {
+				route.handler(w, r)
+				return
+			}
+			name := r.Header.Get(projectHeader)
+			if name == "" {
+				name = r.URL.Query().Get("project")
+			}
+			root, ok := selectProject(name)
+			if !ok {
+				writeError(w, "Unknown project: "+name, http.StatusNotFound)
+				return
+			}
+			route.handler(w, r.WithContext(context.WithValue(r.Context(), projectKey{}, root)))
+			return
+		}
+	}
+
+	w.Header().Set("Allow", allow)
+	writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+}
+
+// limitBody rejects requests whose body is larger than maxBodyBytes with 413.
+// A declared Content-Length is checked up front; otherwise reading past the
+// limit fails and decodeRequest turns that into a 413.
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBodyBytes {
+			writeError(w, fmt.Sprintf("Request body exceeds %d bytes", maxBodyBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// projectHeader names the project a request works on; a project query
+// parameter does the same. Without either the request uses PROJECT_PATH.
+const projectHeader = "X-Worker-Project"
+
+type projectKey struct{}
+
+// parseProjects parses WORKER_PROJECTS, a comma-separated list of name=root
+// pairs. Roots must be absolute and are cleaned so path checks against them
+// are exact.
+func parseProjects(v string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, root, ok := strings.Cut(pair, "=")
+		name, root = strings.TrimSpace(name), strings.TrimSpace(root)
+		if !ok || name == "" || root == "" {
+			return nil, fmt.Errorf("%q is not name=path", pair)
+		}
+		if !filepath.IsAbs(root) {
+			return nil, fmt.Errorf("project %s: %q is not an absolute path", name, root)
+		}
+		if _, dup := m[name]; dup {
+			return nil, fmt.Errorf("project %s is listed twice", name)
+		}
+		m[name] = filepath.Clean(root)
 	}
+	return m, nil
+}
 
-	log.Fatal(server.ListenAndServe())
+// selectProject returns the root of the named project, or projectPath when
+// name is empty
+func selectProject(name string) (string, bool) {
+	if name == "" {
+		return projectPath, true
+	}
+	root, ok := projects[name]
+	return root, ok
+}
+
+// projectRoot returns the root of the project r works on
+func projectRoot(r *http.Request) string {
+	if root, ok := r.Context().Value(projectKey{}).(string); ok {
+		return root
+	}
+	return projectPath
 }
 
 func authMiddleware(next http.Handler) http.Handler {
@@ -92,7 +356,7 @@ func authMiddleware(next http.Handler) http.Handler {
 		}
 
 		if token != workerToken {
-			log.Printf("Unauthorized request from %s", r.RemoteAddr)
+			requestid.Logf(r, "Unauthorized request from %s", r.RemoteAddr)
 			writeError(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -102,39 +366,49 @@ func authMiddleware(next http.Handler) http.Handler {
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	writeJSON(w, map[string]interface{}{
 		"status":       "healthy",
 		"service":      "claude-worker",
 		"timestamp":    time.Now().Format(time.RFC3339),
 		"project_path": projectPath,
+		"projects":     names,
 	})
 }
 
 // ClaudeRunRequest represents a request to run Claude
 type ClaudeRunRequest struct {
-	Command string   `json:"command"`
-	Args    []string `json:"args"`
-	Timeout int      `json:"timeout"` // seconds
-	Stdin   string   `json:"stdin"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Timeout   int               `json:"timeout"` // seconds
+	Stdin     string            `json:"stdin"`
+	MaxOutput int64             `json:"max_output"` // bytes each of stdout and stderr; at most maxOutputBytes
+	Env       map[string]string `json:"env"`        // Added to the worker's environment; see allowedEnvVar
 }
 
-// ClaudeRunResponse represents the response from running Claude
+// ClaudeRunResponse represents the response from running Claude. It is sent
+// with 200 whenever claude ran, whatever its exit code; Success is whether it
+// exited 0. A claude that couldn't be started at all gets a 422 error instead.
 type ClaudeRunResponse struct {
-	Stdout   string `json:"stdout"`
-	Stderr   string `json:"stderr"`
-	ExitCode int    `json:"exit_code"`
-	Duration string `json:"duration"`
+	Success     bool     `json:"success"`
+	Stdout      string   `json:"stdout"`
+	Stderr      string   `json:"stderr"`
+	ExitCode    int      `json:"exit_code"`
+	Duration    string   `json:"duration"`
+	Truncated   bool     `json:"truncated"`    // stdout or stderr was cut off
+	StdoutBytes int64    `json:"stdout_bytes"` // Length before truncation
+	StderrBytes int64    `json:"stderr_bytes"`
+	DroppedEnv  []string `json:"dropped_env,omitempty"` // Env names that were not passed on
 }
 
 func handleClaudeRun(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req ClaudeRunRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
@@ -146,33 +420,143 @@ func handleClaudeRun(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cmd := exec.CommandContext(ctx, "claude", append([]string{req.Command}, req.Args...)...)
-	cmd.Dir = projectPath
+	cmd.Dir = projectRoot(r)
+	// claude runs node and tools of its own; stopping it must stop them too
+	setProcessGroup(cmd)
+	// Let claude finish its current write on timeout or shutdown before killing it
+	cmd.Cancel = func() error { return signalProcessGroup(cmd, syscall.SIGTERM) }
+	cmd.WaitDelay = claudeStopGrace
 
 	if req.Stdin != "" {
 		cmd.Stdin = strings.NewReader(req.Stdin)
 	}
 
-	stdout, err := cmd.Output()
-	exitCode := 0
-	stderr := ""
+	env, dropped := runEnv(req.Env)
+	if len(dropped) > 0 {
+		requestid.Logf(r, "Dropped env for claude run: %s", strings.Join(dropped, ", "))
+	}
+	cmd.Env = env
 
+	limit := maxOutputBytes
+	if req.MaxOutput > 0 && req.MaxOutput < limit {
+		limit = req.MaxOutput
+	}
+	stdout, stderr := &cappedBuffer{limit: limit}, &cappedBuffer{limit: limit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil && cmd.Process != nil {
+		// WaitDelay only kills claude itself; don't leave its children behind
+		signalProcessGroup(cmd, syscall.SIGKILL)
+	}
+
+	if err != nil && cmd.Process == nil {
+		// Not found or not executable; there is no exit code to report
+		requestid.Logf(r, "Could not start claude: %v", err)
+		writeError(w, "Could not start claude: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	exitCode := 0
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			exitCode = exitError.ExitCode()
-			stderr = string(exitError.Stderr)
 		} else {
 			exitCode = 1
-			stderr = err.Error()
+			stderr.Write([]byte(err.Error()))
 		}
 	}
 
 	writeJSON(w, ClaudeRunResponse{
-		Stdout:   string(stdout),
-		Stderr:   stderr,
-		ExitCode: exitCode,
+		Success:     exitCode == 0,
+		Stdout:      stdout.String(),
+		Stderr:      stderr.String(),
+		ExitCode:    exitCode,
+		Truncated:   stdout.Truncated() || stderr.Truncated(),
+		StdoutBytes: stdout.total,
+		StderrBytes: stderr.total,
+		DroppedEnv:  dropped,
 	})
 }
 
+// runEnv returns the worker's environment with extra added, leaving out the
+// names allowedEnvVar refuses. Those are returned sorted.
+func runEnv(extra map[string]string) (env, dropped []string) {
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env = os.Environ()
+	for _, name := range names {
+		if !allowedEnvVar(name) {
+			dropped = append(dropped, name)
+			continue
+		}
+		// Later entries win, so these override the worker's own values
+		env = append(env, name+"="+extra[name])
+	}
+	return env, dropped
+}
+
+// allowedEnvPrefixes are the variables a request may set: claude's own
+// settings, such as ANTHROPIC_MODEL or CLAUDE_CODE_MAX_OUTPUT_TOKENS. Anything
+// else, like NODE_OPTIONS or BASH_ENV, could change which code runs.
+var allowedEnvPrefixes = []string{"ANTHROPIC_", "CLAUDE_"}
+
+// allowedEnvVar reports whether a request may set name: one of
+// allowedEnvPrefixes that doesn't look like a credential, such as
+// ANTHROPIC_AUTH_TOKEN. Settings like CLAUDE_CODE_MAX_OUTPUT_TOKENS are fine.
+func allowedEnvVar(name string) bool {
+	if strings.ContainsAny(name, "=\x00") {
+		return false
+	}
+	for _, word := range strings.Split(strings.ToUpper(name), "_") {
+		for _, secret := range []string{"TOKEN", "KEY", "SECRET", "PASSWORD", "CREDENTIAL", "CREDENTIALS"} {
+			if strings.HasSuffix(word, secret) {
+				return false
+			}
+		}
+	}
+	for _, prefix := range allowedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the rest,
+// so a chatty command can't grow a response without bound
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int64
+	total int64
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if room := b.limit - int64(b.buf.Len()); room > 0 {
+		if int64(len(p)) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// Truncated reports whether anything written was dropped
+func (b *cappedBuffer) Truncated() bool {
+	return b.total > int64(b.buf.Len())
+}
+
 func handleClaudeStatus(w http.ResponseWriter, r *http.Request) {
 	cmd := exec.Command("which", "claude")
 	output, err := cmd.Output()
@@ -183,7 +567,7 @@ func handleClaudeStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if gateway is accessible
-	resp, err := http.Get("http://claude-gateway:8080/health")
+	resp, err := (&http.Client{Transport: httpclient.Transport()}).Get("http://claude-gateway:8080/health")
 	if err != nil {
 		status["gateway"] = "unreachable"
 		status["gateway_error"] = err.Error()
@@ -201,7 +585,7 @@ type GitRequest struct {
 }
 
 func handleGitStatus(w http.ResponseWriter, r *http.Request) {
-	runGitCommand(w, []string{"status", "--porcelain"})
+	runGitCommand(w, r, []string{"status", "--porcelain"})
 }
 
 func handleGitDiff(w http.ResponseWriter, r *http.Request) {
@@ -212,7 +596,7 @@ func handleGitDiff(w http.ResponseWriter, r *http.Request) {
 	if file := r.URL.Query().Get("file"); file != "" {
 		args = append(args, file)
 	}
-	runGitCommand(w, args)
+	runGitCommand(w, r, args)
 }
 
 func handleGitLog(w http.ResponseWriter, r *http.Request) {
@@ -220,7 +604,7 @@ func handleGitLog(w http.ResponseWriter, r *http.Request) {
 	if limit == "" {
 		limit = "10"
 	}
-	runGitCommand(w, []string{"log", "--oneline", "-" + limit})
+	runGitCommand(w, r, []string{"log", "--oneline", "-" + limit})
 }
 
 func handleGitShow(w http.ResponseWriter, r *http.Request) {
@@ -228,11 +612,11 @@ func handleGitShow(w http.ResponseWriter, r *http.Request) {
 	if ref == "" {
 		ref = "HEAD"
 	}
-	runGitCommand(w, []string{"show", "--stat", ref})
+	runGitCommand(w, r, []string{"show", "--stat", ref})
 }
 
 func handleGitLsFiles(w http.ResponseWriter, r *http.Request) {
-	runGitCommand(w, []string{"ls-files"})
+	runGitCommand(w, r, []string{"ls-files"})
 }
 
 func handleGitCatFile(w http.ResponseWriter, r *http.Request) {
@@ -241,49 +625,76 @@ func handleGitCatFile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "object parameter required", http.StatusBadRequest)
 		return
 	}
-	runGitCommand(w, []string{"cat-file", "-p", object})
+	runGitCommand(w, r, []string{"cat-file", "-p", object})
 }
 
-func runGitCommand(w http.ResponseWriter, args []string) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = projectPath
+// runGitCommand runs git in the project and writes its output, cut off after
+// maxOutputBytes. The command is killed when the client goes away (499) or
+// after gitCommandTimeout (408).
+func runGitCommand(w http.ResponseWriter, r *http.Request, args []string) {
+	ctx, cancel := context.WithTimeout(r.Context(), gitCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = projectRoot(r)
+	// Don't wait on children of a killed git that still hold its output open
+	cmd.WaitDelay = time.Second
 
-	output, err := cmd.CombinedOutput()
+	output := &cappedBuffer{limit: maxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	switch {
+	case r.Context().Err() != nil:
+		requestid.Logf(r, "git %s cancelled: client went away", args[0])
+		writeError(w, "Request cancelled", statusClientClosedRequest)
+		return
+	case ctx.Err() != nil:
+		requestid.Logf(r, "git %s timed out after %v", args[0], gitCommandTimeout)
+		writeError(w, fmt.Sprintf("git %s timed out after %v", args[0], gitCommandTimeout), http.StatusRequestTimeout)
+		return
+	}
 	if err != nil {
 		writeJSON(w, map[string]interface{}{
-			"success": false,
-			"output":  string(output),
-			"error":   err.Error(),
+			"success":      false,
+			"output":       output.String(),
+			"truncated":    output.Truncated(),
+			"output_bytes": output.total,
+			"error":        err.Error(),
 		})
 		return
 	}
 
 	writeJSON(w, map[string]interface{}{
-		"success": true,
-		"output":  string(output),
+		"success":      true,
+		"output":       output.String(),
+		"truncated":    output.Truncated(),
+		"output_bytes": output.total,
 	})
 }
 
+// gitCommandTimeout bounds each git command run for a request
+var gitCommandTimeout = 60 * time.Second
+
+// statusClientClosedRequest is nginx's status for a request the client
+// abandoned; nobody reads the response, but it marks the request in logs
+const statusClientClosedRequest = 499
+
 // FileReadRequest represents a file read request
 type FileReadRequest struct {
 	Path string `json:"path"`
 }
 
 func handleFileRead(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req FileReadRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
 	// Security: ensure path is within project
-	fullPath := filepath.Join(projectPath, req.Path)
-	if !strings.HasPrefix(fullPath, projectPath) {
+	fullPath, ok := inProject(projectRoot(r), req.Path)
+	if !ok {
 		writeError(w, "Invalid path", http.StatusForbidden)
 		return
 	}
@@ -308,20 +719,14 @@ type FileWriteRequest struct {
 }
 
 func handleFileWrite(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req FileWriteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
 	// Security: ensure path is within project
-	fullPath := filepath.Join(projectPath, req.Path)
-	if !strings.HasPrefix(fullPath, projectPath) {
+	fullPath, ok := inProject(projectRoot(r), req.Path)
+	if !ok {
 		writeError(w, "Invalid path", http.StatusForbidden)
 		return
 	}
@@ -333,7 +738,7 @@ func handleFileWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := os.WriteFile(fullPath, []byte(req.Content), 0644); err != nil {
+	if err := writeFileAtomic(fullPath, []byte(req.Content), 0644); err != nil {
 		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -345,14 +750,148 @@ func handleFileWrite(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// FileDeleteRequest represents a file delete request
+type FileDeleteRequest struct {
+	Path string `json:"path"`
+}
+
+func handleFileDelete(w http.ResponseWriter, r *http.Request) {
+	var req FileDeleteRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	fullPath, ok := projectFile(projectRoot(r), req.Path)
+	if !ok {
+		writeError(w, "Invalid path", http.StatusForbidden)
+		return
+	}
+
+	// Removes a file or an empty directory, never a whole tree
+	if err := os.Remove(fullPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, "No such file: "+req.Path, http.StatusNotFound)
+			return
+		}
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"success": true,
+		"path":    req.Path,
+	})
+}
+
+// FileRenameRequest represents a file rename request
+type FileRenameRequest struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Overwrite bool   `json:"overwrite"` // Replace an existing file at To
+}
+
+func handleFileRename(w http.ResponseWriter, r *http.Request) {
+	var req FileRenameRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	root := projectRoot(r)
+	from, ok := projectFile(root, req.From)
+	if !ok {
+		writeError(w, "Invalid path: "+req.From, http.StatusForbidden)
+		return
+	}
+	to, ok := projectFile(root, req.To)
+	if !ok {
+		writeError(w, "Invalid path: "+req.To, http.StatusForbidden)
+		return
+	}
+
+	if _, err := os.Lstat(from); errors.Is(err, os.ErrNotExist) {
+		writeError(w, "No such file: "+req.From, http.StatusNotFound)
+		return
+	}
+	if _, err := os.Lstat(to); err == nil && !req.Overwrite {
+		writeError(w, req.To+" already exists (set overwrite to replace it)", http.StatusConflict)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(from, to); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"success": true,
+		"from":    req.From,
+		"to":      req.To,
+	})
+}
+
+// inProject resolves path relative to the project at root, refusing paths
+// that lead outside it
+func inProject(root, path string) (string, bool) {
+	fullPath := filepath.Join(root, path)
+	if fullPath != root && !strings.HasPrefix(fullPath, root+string(filepath.Separator)) {
+		return "", false
+	}
+	return fullPath, true
+}
+
+// projectFile is inProject, also refusing the project directory itself
+func projectFile(root, path string) (string, bool) {
+	fullPath, ok := inProject(root, path)
+	if !ok || fullPath == root {
+		return "", false
+	}
+	return fullPath, true
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so an interrupted write never leaves a partial file. An existing
+// file keeps its permissions; a new one gets perm.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Once renamed, the temp name no longer exists and this is a no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 func handleFileList(w http.ResponseWriter, r *http.Request) {
 	dir := r.URL.Query().Get("dir")
 	if dir == "" {
 		dir = "."
 	}
 
-	fullPath := filepath.Join(projectPath, dir)
-	if !strings.HasPrefix(fullPath, projectPath) {
+	fullPath, ok := inProject(projectRoot(r), dir)
+	if !ok {
 		writeError(w, "Invalid path", http.StatusForbidden)
 		return
 	}
@@ -387,8 +926,8 @@ func handleFileStat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fullPath := filepath.Join(projectPath, path)
-	if !strings.HasPrefix(fullPath, projectPath) {
+	fullPath, ok := inProject(projectRoot(r), path)
+	if !ok {
 		writeError(w, "Invalid path", http.StatusForbidden)
 		return
 	}
@@ -409,13 +948,14 @@ func handleFileStat(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleProjectInfo(w http.ResponseWriter, r *http.Request) {
+	root := projectRoot(r)
 	info := map[string]interface{}{
-		"path": projectPath,
+		"path": root,
 	}
 
 	// Check if git repo
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = projectPath
+	cmd := exec.CommandContext(r.Context(), "git", "rev-parse", "--git-dir")
+	cmd.Dir = root
 	output, err := cmd.Output()
 	info["is_git_repo"] = err == nil
 	if err == nil {
@@ -423,16 +963,16 @@ func handleProjectInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get branch
-	cmd = exec.Command("git", "branch", "--show-current")
-	cmd.Dir = projectPath
+	cmd = exec.CommandContext(r.Context(), "git", "branch", "--show-current")
+	cmd.Dir = root
 	output, err = cmd.Output()
 	if err == nil {
 		info["branch"] = strings.TrimSpace(string(output))
 	}
 
 	// Get last commit
-	cmd = exec.Command("git", "log", "-1", "--format=%H")
-	cmd.Dir = projectPath
+	cmd = exec.CommandContext(r.Context(), "git", "log", "-1", "--format=%H")
+	cmd.Dir = root
 	output, err = cmd.Output()
 	if err == nil {
 		info["last_commit"] = strings.TrimSpace(string(output))
@@ -441,35 +981,274 @@ func handleProjectInfo(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, info)
 }
 
+// maxTreeEntries bounds how many entries /project/tree returns
+const maxTreeEntries = 10000
+
+// treeSkipDirs are listed by /project/tree but never descended into
+var treeSkipDirs = map[string]bool{".git": true, "vendor": true, "node_modules": true}
+
+// TreeNode is a file or directory in /project/tree's response
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"` // Slash-separated, relative to the project
+	Type     string      `json:"type"` // "file", "dir" or "symlink"
+	Size     int64       `json:"size,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// ProjectTreeResponse represents the project's file tree
+type ProjectTreeResponse struct {
+	Tree      *TreeNode `json:"tree"`
+	Depth     int       `json:"depth"`
+	Truncated bool      `json:"truncated"` // maxTreeEntries was reached
+}
+
+// handleProjectTree returns the project as a nested tree, depth levels deep
+// (default 3). With gitignore=true, paths matched by the root .gitignore are
+// left out.
 func handleProjectTree(w http.ResponseWriter, r *http.Request) {
-	depth := r.URL.Query().Get("depth")
-	if depth == "" {
-		depth = "3"
+	depth := 3
+	if v := r.URL.Query().Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, "depth must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		depth = n
 	}
 
-	cmd := exec.Command("find", ".", "-maxdepth", depth, "-type", "f")
-	cmd.Dir = projectPath
-	output, err := cmd.Output()
-	if err != nil {
+	root := projectRoot(r)
+	var ignore *ctxloader.IgnoreRules
+	if r.URL.Query().Get("gitignore") == "true" {
+		f, err := os.Open(filepath.Join(root, ".gitignore"))
+		if err == nil {
+			ignore, err = ctxloader.ParseIgnoreRules(f)
+			f.Close()
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			writeError(w, "reading .gitignore: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	b := treeBuilder{root: root, ignore: ignore, remaining: maxTreeEntries}
+	tree := &TreeNode{Name: filepath.Base(root), Path: ".", Type: "dir"}
+	if err := b.fill(tree, root, depth); err != nil {
 		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	files := strings.Split(strings.TrimSpace(string(output)), "\n")
-	writeJSON(w, map[string]interface{}{
-		"files": files,
-		"depth": depth,
+	writeJSON(w, ProjectTreeResponse{Tree: tree, Depth: depth, Truncated: b.remaining <= 0})
+}
+
+// treeBuilder walks the project for handleProjectTree
+type treeBuilder struct {
+	root      string
+	ignore    *ctxloader.IgnoreRules // nil keeps everything
+	remaining int                    // Entries left before the tree is cut off
+}
+
+// fill adds dir's entries to node, descending levels more directories deep
+func (b *treeBuilder) fill(node *TreeNode, dir string, levels int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if b.remaining <= 0 {
+			return nil
+		}
+		full := filepath.Join(dir, e.Name())
+		rel, _ := filepath.Rel(b.root, full)
+		if b.ignore.Excluded(rel, e.IsDir()) {
+			continue
+		}
+
+		child := &TreeNode{Name: e.Name(), Path: filepath.ToSlash(rel)}
+		switch {
+		case e.Type()&os.ModeSymlink != 0:
+			child.Type = "symlink"
+		case e.IsDir():
+			child.Type = "dir"
+		default:
+			child.Type = "file"
+			if info, err := e.Info(); err == nil {
+				child.Size = info.Size()
+			}
+		}
+		node.Children = append(node.Children, child)
+		b.remaining--
+
+		if child.Type == "dir" && levels > 1 && !treeSkipDirs[e.Name()] {
+			// An unreadable subdirectory is listed without children
+			b.fill(child, full, levels-1)
+		}
+	}
+	return nil
+}
+
+// Bounds on /project/search so one query can't tie up the worker
+const (
+	defaultSearchResults = 100
+	maxSearchResults     = 1000
+	searchTimeout        = 10 * time.Second
+	searchMaxFileSize    = 1 << 20 // Larger files are skipped
+	searchSnippetLength  = 200     // Longer matching lines are cut
+)
+
+// searchSkipDirs are never descended into
+var searchSkipDirs = map[string]bool{".git": true, "node_modules": true}
+
+// ProjectSearchRequest represents a search across the project's files
+type ProjectSearchRequest struct {
+	Pattern    string `json:"pattern"`
+	Literal    bool   `json:"literal"`     // Match Pattern as plain text instead of a regexp
+	Glob       string `json:"glob"`        // Only search files matching this glob, e.g. "*.go" or "cmd/*.go"
+	MaxResults int    `json:"max_results"` // Defaults to 100, at most 1000
+}
+
+// SearchMatch is one matching line
+type SearchMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// ProjectSearchResponse represents the result of a project search
+type ProjectSearchResponse struct {
+	Matches   []SearchMatch `json:"matches"`
+	Truncated bool          `json:"truncated"` // The result cap or timeout cut the search short
+}
+
+func handleProjectSearch(w http.ResponseWriter, r *http.Request) {
+	var req ProjectSearchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if req.Pattern == "" {
+		writeError(w, "pattern is required", http.StatusBadRequest)
+		return
+	}
+	expr := req.Pattern
+	if req.Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		writeError(w, "invalid pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Glob != "" {
+		if _, err := filepath.Match(req.Glob, ""); err != nil {
+			writeError(w, "invalid glob: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := req.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchResults
+	}
+	if limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+	defer cancel()
+
+	writeJSON(w, searchProject(ctx, projectRoot(r), re, req.Glob, limit))
+}
+
+// searchProject walks root for lines matching re in files matching glob,
+// stopping at limit matches or when ctx is done. Results are marked truncated
+// only if a match past limit turned up or ctx cut the walk short.
+func searchProject(ctx context.Context, root string, re *regexp.Regexp, glob string, limit int) ProjectSearchResponse {
+	result := ProjectSearchResponse{Matches: []SearchMatch{}}
+
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		if ctx.Err() != nil {
+			result.Truncated = true
+			return filepath.SkipAll
+		}
+		if len(result.Matches) > limit {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if searchSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if glob != "" && !matchGlob(glob, rel) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > searchMaxFileSize {
+			return nil
+		}
+
+		// One match past limit tells a full result from a cut-off one
+		result.Matches = append(result.Matches, searchFile(path, rel, re, limit+1-len(result.Matches))...)
+		return nil
 	})
+
+	if len(result.Matches) > limit {
+		result.Matches = result.Matches[:limit]
+		result.Truncated = true
+	}
+	return result
+}
+
+// matchGlob matches a glob against the path, or against the file name for
+// globs without a slash, so "*.go" finds Go files at any depth
+func matchGlob(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		rel = filepath.Base(rel)
+	}
+	ok, _ := filepath.Match(glob, rel)
+	return ok
+}
+
+// searchFile returns up to limit lines of a text file matching re
+func searchFile(path, rel string, re *regexp.Regexp, limit int) []SearchMatch {
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil // Unreadable or binary
+	}
+
+	var matches []SearchMatch
+	for i, line := range strings.Split(string(data), "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		text := strings.TrimRight(line, "\r")
+		if len(text) > searchSnippetLength {
+			text = strings.ToValidUTF8(text[:searchSnippetLength], "")
+		}
+		matches = append(matches, SearchMatch{Path: rel, Line: i + 1, Text: text})
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches
 }
 
 // HTTPRequestRequest represents an HTTP request to be made
 type HTTPRequestRequest struct {
-	URL       string            `json:"url"`
-	Method    string            `json:"method"`
-	Headers   map[string]string `json:"headers"`
-	Query     map[string]string `json:"query"`
-	Body      string            `json:"body"`
-	Timeout   int               `json:"timeout"` // seconds
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Query   map[string]string `json:"query"`
+	Body    string            `json:"body"`
+	Timeout int               `json:"timeout"` // seconds
 }
 
 // HTTPRequestResponse represents the response from an HTTP request
@@ -480,14 +1259,8 @@ type HTTPRequestResponse struct {
 }
 
 func handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req HTTPRequestRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
@@ -565,7 +1338,7 @@ func handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Execute request
-	client := &http.Client{Timeout: timeout}
+	client := &http.Client{Timeout: timeout, Transport: httpclient.Transport()}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		writeError(w, "executing request: "+err.Error(), http.StatusInternalServerError)
@@ -595,16 +1368,366 @@ func handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// IssueProcessRequest represents a request to process an issue end-to-end
+type IssueProcessRequest struct {
+	Number      int      `json:"number"`
+	Title       string   `json:"title"`
+	Body        string   `json:"body"`
+	URL         string   `json:"url"`
+	Refs        []string `json:"refs"`
+	BaseBranch  string   `json:"base_branch"`
+	Branch      string   `json:"branch"`
+	Owner       string   `json:"owner"`
+	Repo        string   `json:"repo"`
+	GitHubToken string   `json:"github_token"`
+	Model       string   `json:"model"`
+}
+
+// IssueProgressEvent is a single line of the streamed /issue/process response
+type IssueProgressEvent struct {
+	Step    string              `json:"step"`
+	Message string              `json:"message,omitempty"`
+	Branch  string              `json:"branch,omitempty"`
+	Files   []string            `json:"files,omitempty"`
+	Error   string              `json:"error,omitempty"`
+	Changes []claude.FileChange `json:"changes,omitempty"` // Proposed changes, on a dry run's "done"
+	Diff    string              `json:"diff,omitempty"`    // Unified diff of Changes against the project
+}
+
+// issuePipeline is the set of operations /issue/process runs in order
+type issuePipeline interface {
+	CreateBranch(ctx context.Context, baseBranch, newBranch string) error
+	GenerateCode(ctx context.Context, title, body string, refs []*ctxloader.FileReference) ([]claude.FileChange, error)
+	ApplyChanges(ctx context.Context, changes []claude.FileChange) error
+	Commit(ctx context.Context, message string) error
+	PushBranch(ctx context.Context, branch string) error
+}
+
+// defaultIssuePipeline runs the pipeline with the real git and Claude clients
+type defaultIssuePipeline struct {
+	git    *git.Client
+	claude *claude.Client
+}
+
+func (p *defaultIssuePipeline) CreateBranch(ctx context.Context, baseBranch, newBranch string) error {
+	return p.git.CreateBranch(ctx, baseBranch, newBranch)
+}
+
+func (p *defaultIssuePipeline) GenerateCode(ctx context.Context, title, body string, refs []*ctxloader.FileReference) ([]claude.FileChange, error) {
+	return p.claude.GenerateCode(ctx, title, body, refs)
+}
+
+func (p *defaultIssuePipeline) ApplyChanges(ctx context.Context, changes []claude.FileChange) error {
+	return p.git.ApplyChanges(ctx, changes)
+}
+
+func (p *defaultIssuePipeline) Commit(ctx context.Context, message string) error {
+	return p.git.Commit(ctx, message)
+}
+
+func (p *defaultIssuePipeline) PushBranch(ctx context.Context, branch string) error {
+	return p.git.PushBranch(ctx, branch)
+}
+
+// newIssuePipeline builds the pipeline for a request on the project at root
+// (replaced in tests)
+var newIssuePipeline = func(root string, req *IssueProcessRequest) issuePipeline {
+	gitClient := git.NewClient(req.Owner, req.Repo, req.GitHubToken)
+	gitClient.SetDir(root)
+
+	model := req.Model
+	if model == "" {
+		model = claude.DefaultModel
+	}
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://claude-gateway:8080"
+	}
+	claudeClient := claude.NewClient(os.Getenv("ANTHROPIC_API_KEY"), baseURL, model)
+	if token := os.Getenv("GATEWAY_TOKEN"); token != "" {
+		claudeClient.SetHeader("X-Gateway-Auth", token)
+	}
+
+	return &defaultIssuePipeline{git: gitClient, claude: claudeClient.WithRoot(root)}
+}
+
+// activeRuns holds the project roots with an /issue/process run in progress,
+// since two runs would switch branches under each other
+var activeRuns = struct {
+	sync.Mutex
+	roots map[string]bool
+}{roots: make(map[string]bool)}
+
+// claimRoot marks root as busy, reporting false if a run already holds it
+func claimRoot(root string) bool {
+	activeRuns.Lock()
+	defer activeRuns.Unlock()
+	if activeRuns.roots[root] {
+		return false
+	}
+	activeRuns.roots[root] = true
+	return true
+}
+
+// releaseRoot frees root for the next run
+func releaseRoot(root string) {
+	activeRuns.Lock()
+	defer activeRuns.Unlock()
+	delete(activeRuns.roots, root)
+}
+
+func handleIssueProcess(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry-run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, "dry-run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var req IssueProcessRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if req.Title == "" {
+		writeError(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	if req.BaseBranch == "" {
+		req.BaseBranch = "main"
+	}
+	if req.Branch == "" {
+		req.Branch = fmt.Sprintf("vibe-git/issue-%d", req.Number)
+	}
+
+	root := projectRoot(r)
+	if !claimRoot(root) {
+		writeError(w, "an issue is already being processed in "+root, http.StatusConflict)
+		return
+	}
+	defer releaseRoot(root)
+
+	// A run can outlast the server's WriteTimeout; the client's context bounds
+	// it instead, since a disconnect cancels the request
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		requestid.Logf(r, "Could not lift the write deadline: %v", err)
+	}
+
+	// Stream progress as newline-delimited JSON
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	emit := func(ev IssueProgressEvent) {
+		enc.Encode(ev)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if dryRun {
+		requestid.Logf(r, "Planning issue #%d (dry run)", req.Number)
+	} else {
+		requestid.Logf(r, "Processing issue #%d on branch %s", req.Number, req.Branch)
+	}
+
+	if err := processIssue(r.Context(), newIssuePipeline(root, &req), root, &req, dryRun, emit); err != nil {
+		requestid.Logf(r, "Issue #%d failed: %v", req.Number, err)
+		emit(IssueProgressEvent{Step: "error", Error: err.Error()})
+	}
+}
+
+// processIssue runs create-branch → generate → apply → commit → push on the
+// project at root, reporting each step. A dry run only generates, and reports
+// the changes and their diff instead of applying them.
+func processIssue(ctx context.Context, p issuePipeline, root string, req *IssueProcessRequest, dryRun bool, emit func(IssueProgressEvent)) error {
+	refs := req.Refs
+	if len(refs) == 0 {
+		refs = ctxloader.ExtractFileReferencesIn(req.Title+"\n"+req.Body, root)
+	}
+	referencedFiles := ctxloader.LoadReferencedFiles(refs, root, ctxloader.DefaultLoadOptions())
+
+	// A dry run generates against whatever is checked out, leaving the tree alone
+	if !dryRun {
+		emit(IssueProgressEvent{Step: "branch", Message: "Creating branch " + req.Branch})
+		if err := p.CreateBranch(ctx, req.BaseBranch, req.Branch); err != nil {
+			return fmt.Errorf("creating branch: %w", err)
+		}
+	}
+
+	emit(IssueProgressEvent{Step: "generate", Message: "Generating code with Claude..."})
+	changes, err := p.GenerateCode(ctx, req.Title, req.Body, referencedFiles)
+	if err != nil {
+		return fmt.Errorf("generating code: %w", err)
+	}
+	for _, change := range changes {
+		if _, ok := projectFile(root, change.Path); !ok {
+			return fmt.Errorf("change to %s is outside the project", change.Path)
+		}
+	}
+
+	files := make([]string, len(changes))
+	for i, change := range changes {
+		files[i] = change.Path
+	}
+
+	if dryRun {
+		emit(IssueProgressEvent{Step: "diff", Message: fmt.Sprintf("Diffing %d proposed file changes...", len(changes))})
+		diff, err := previewDiff(ctx, root, changes)
+		if err != nil {
+			return fmt.Errorf("diffing changes: %w", err)
+		}
+		emit(IssueProgressEvent{Step: "done", Message: "Dry run: nothing was written", Files: files, Changes: changes, Diff: diff})
+		return nil
+	}
+
+	emit(IssueProgressEvent{Step: "apply", Message: fmt.Sprintf("Applying %d file changes...", len(changes))})
+	if err := claude.CheckRedacted(root, changes); err != nil {
+		return err
+	}
+	if err := p.ApplyChanges(ctx, changes); err != nil {
+		return fmt.Errorf("applying changes: %w", err)
+	}
+
+	emit(IssueProgressEvent{Step: "commit", Message: "Committing changes..."})
+	commitMsg := fmt.Sprintf("Fix issue #%d: %s\n\n%s", req.Number, req.Title, req.URL)
+	if err := p.Commit(ctx, commitMsg); err != nil {
+		return fmt.Errorf("committing changes: %w", err)
+	}
+
+	emit(IssueProgressEvent{Step: "push", Message: "Pushing branch " + req.Branch + "..."})
+	if err := p.PushBranch(ctx, req.Branch); err != nil {
+		return fmt.Errorf("pushing branch: %w", err)
+	}
+
+	emit(IssueProgressEvent{Step: "done", Branch: req.Branch, Files: files})
+	return nil
+}
+
+// previewDiff returns the unified diff that applying changes to the project
+// at root would make. The affected files are copied into a scratch repository
+// and the changes applied there, so the project is never touched.
+func previewDiff(ctx context.Context, root string, changes []claude.FileChange) (string, error) {
+	tmp, err := os.MkdirTemp("", "vibe-git-plan-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	git := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = tmp
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(out.String()))
+		}
+		return out.String(), nil
+	}
+
+	rels := make([]string, len(changes))
+	for i, change := range changes {
+		fullPath, ok := projectFile(root, change.Path)
+		if !ok {
+			return "", fmt.Errorf("change to %s is outside the project", change.Path)
+		}
+		if rels[i], err = filepath.Rel(root, fullPath); err != nil {
+			return "", err
+		}
+
+		current, err := os.ReadFile(fullPath)
+		switch {
+		case err == nil:
+			if err := writePreviewFile(filepath.Join(tmp, rels[i]), current); err != nil {
+				return "", err
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return "", err
+		}
+	}
+
+	if _, err := git("init", "-q"); err != nil {
+		return "", err
+	}
+	if _, err := git("add", "-A"); err != nil {
+		return "", err
+	}
+	before, err := git("write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	for i, change := range changes {
+		path := filepath.Join(tmp, rels[i])
+		switch change.Operation {
+		case "create", "modify":
+			err = writePreviewFile(path, []byte(change.Content))
+		case "delete":
+			if err = os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		default:
+			err = fmt.Errorf("unknown operation %q for %s", change.Operation, change.Path)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := git("add", "-A"); err != nil {
+		return "", err
+	}
+	return git("diff", "--cached", "--no-color", "--no-ext-diff", strings.TrimSpace(before))
+}
+
+// writePreviewFile writes one side of a previewed change, creating its directories
+func writePreviewFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// decodeRequest decodes the JSON request body into v. On failure it writes a 400,
+// or a 413 if the body was larger than maxBodyBytes, and returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	writeError(w, err.Error(), http.StatusBadRequest)
+	return false
+}
+
+// jsonContentType is sent with every JSON response
+const jsonContentType = "application/json; charset=utf-8"
+
 func writeJSON(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", jsonContentType)
 	json.NewEncoder(w).Encode(data)
 }
 
+// writeError writes a JSON error as {"error", "code", "success": false}, with
+// the request ID when there is one so the failure can be found in the worker
+// and gateway logs
 func writeError(w http.ResponseWriter, message string, code int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"error":   message,
+		"code":    code,
 		"success": false,
-	})
+	}
+	if id := w.Header().Get(requestid.Header); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
 }
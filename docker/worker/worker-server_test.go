@@ -0,0 +1,1175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/requestid"
+	"vibe-git/internal/worker"
+)
+
+// fakeIssuePipeline records the steps run by processIssue
+type fakeIssuePipeline struct {
+	calls       []string
+	changes     []claude.FileChange
+	generateErr error
+	generateFor time.Duration // How long generating takes
+	commitMsg   string
+	claude      *claude.Client // Generates through a real client when set
+}
+
+func (f *fakeIssuePipeline) CreateBranch(ctx context.Context, baseBranch, newBranch string) error {
+	f.calls = append(f.calls, "branch:"+baseBranch+"->"+newBranch)
+	return nil
+}
+
+func (f *fakeIssuePipeline) GenerateCode(ctx context.Context, title, body string, refs []*ctxloader.FileReference) ([]claude.FileChange, error) {
+	f.calls = append(f.calls, "generate")
+	time.Sleep(f.generateFor)
+	if f.claude != nil {
+		return f.claude.GenerateCode(ctx, title, body, refs)
+	}
+	return f.changes, f.generateErr
+}
+
+func (f *fakeIssuePipeline) ApplyChanges(ctx context.Context, changes []claude.FileChange) error {
+	f.calls = append(f.calls, fmt.Sprintf("apply:%d", len(changes)))
+	return nil
+}
+
+func (f *fakeIssuePipeline) Commit(ctx context.Context, message string) error {
+	f.calls = append(f.calls, "commit")
+	f.commitMsg = message
+	return nil
+}
+
+func (f *fakeIssuePipeline) PushBranch(ctx context.Context, branch string) error {
+	f.calls = append(f.calls, "push:"+branch)
+	return nil
+}
+
+func withFakePipeline(t *testing.T, fake *fakeIssuePipeline) {
+	t.Helper()
+	origPipeline, origPath := newIssuePipeline, projectPath
+	newIssuePipeline = func(root string, req *IssueProcessRequest) issuePipeline { return fake }
+	projectPath = t.TempDir()
+	t.Cleanup(func() {
+		newIssuePipeline, projectPath = origPipeline, origPath
+	})
+}
+
+func readEvents(t *testing.T, body string) []IssueProgressEvent {
+	t.Helper()
+	var events []IssueProgressEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var ev IssueProgressEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestHandleIssueProcess(t *testing.T) {
+	fake := &fakeIssuePipeline{
+		changes: []claude.FileChange{
+			{Path: "a.go", Operation: "create", Content: "package a"},
+			{Path: "b.go", Operation: "modify", Content: "package b"},
+		},
+	}
+	withFakePipeline(t, fake)
+
+	body := `{"number": 42, "title": "Fix bug", "body": "details", "url": "https://example.com/42"}`
+	req := httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleIssueProcess(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected ndjson content type, got %s", ct)
+	}
+
+	expectedCalls := []string{
+		"branch:main->vibe-git/issue-42",
+		"generate",
+		"apply:2",
+		"commit",
+		"push:vibe-git/issue-42",
+	}
+	if strings.Join(fake.calls, ",") != strings.Join(expectedCalls, ",") {
+		t.Errorf("unexpected calls: %v", fake.calls)
+	}
+	if !strings.Contains(fake.commitMsg, "Fix issue #42: Fix bug") {
+		t.Errorf("unexpected commit message: %s", fake.commitMsg)
+	}
+
+	events := readEvents(t, rec.Body.String())
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events, got %d: %+v", len(events), events)
+	}
+	last := events[len(events)-1]
+	if last.Step != "done" {
+		t.Errorf("expected final step done, got %s", last.Step)
+	}
+	if last.Branch != "vibe-git/issue-42" {
+		t.Errorf("expected branch vibe-git/issue-42, got %s", last.Branch)
+	}
+	if strings.Join(last.Files, ",") != "a.go,b.go" {
+		t.Errorf("unexpected files: %v", last.Files)
+	}
+}
+
+func TestHandleIssueProcessOutlastsWriteTimeout(t *testing.T) {
+	fake := &fakeIssuePipeline{
+		changes:     []claude.FileChange{{Path: "a.go", Operation: "create", Content: "package a"}},
+		generateFor: 300 * time.Millisecond,
+	}
+	withFakePipeline(t, fake)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handleIssueProcess))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	done, err := worker.NewClient(server.URL, "").ProcessIssue(context.Background(), worker.IssueProcessRequest{Number: 42, Title: "Fix bug"}, nil)
+	if err != nil {
+		t.Fatalf("expected the stream to outlast the write timeout, got %v", err)
+	}
+	if done.Step != "done" {
+		t.Errorf("expected the done event, got %+v", done)
+	}
+}
+
+func TestHandleIssueProcessRefusesRedactedContent(t *testing.T) {
+	fake := &fakeIssuePipeline{
+		changes: []claude.FileChange{{Path: "go.sum", Operation: "modify", Content: "github.com/google/uuid v1.6.0 h1:[REDACTED]\n"}},
+	}
+	withFakePipeline(t, fake)
+
+	req := httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(`{"number": 9, "title": "Bump uuid"}`))
+	rec := httptest.NewRecorder()
+	handleIssueProcess(rec, req)
+
+	if strings.Join(fake.calls, ",") != "branch:main->vibe-git/issue-9,generate" {
+		t.Errorf("expected nothing to be applied, got %v", fake.calls)
+	}
+	events := readEvents(t, rec.Body.String())
+	if last := events[len(events)-1]; last.Step != "error" || !strings.Contains(last.Error, "go.sum") {
+		t.Errorf("expected the go.sum round trip to be refused, got %+v", last)
+	}
+}
+
+func TestHandleIssueProcessRejectsConcurrentRun(t *testing.T) {
+	fake := &fakeIssuePipeline{changes: []claude.FileChange{{Path: "a.go", Operation: "create", Content: "package a"}}}
+	withFakePipeline(t, fake)
+
+	// Another run holds the project
+	if !claimRoot(projectPath) {
+		t.Fatal("expected the project to be free")
+	}
+	rec := httptest.NewRecorder()
+	handleIssueProcess(rec, httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(`{"number": 1, "title": "First"}`)))
+	if rec.Code != http.StatusConflict || len(fake.calls) != 0 {
+		t.Errorf("expected 409 and no pipeline steps while a run is active, got %d %v", rec.Code, fake.calls)
+	}
+
+	releaseRoot(projectPath)
+	rec = httptest.NewRecorder()
+	handleIssueProcess(rec, httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(`{"number": 1, "title": "First"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"step":"done"`) {
+		t.Errorf("expected the run to proceed once the project is free, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if !claimRoot(projectPath) {
+		t.Error("expected the finished run to release the project")
+	}
+	releaseRoot(projectPath)
+}
+
+// stubClaude serves one set of changes from a Claude API stub for
+// newIssuePipeline and returns the prompts it receives
+func stubClaude(t *testing.T) func() []string {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		prompts []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		prompts = append(prompts, req.Messages[0].Content)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": `[{"path":"a.go","operation":"create","content":"package a\n"}]`}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), prompts...)
+	}
+}
+
+func TestNewIssuePipelineReadsProject(t *testing.T) {
+	prompts := stubClaude(t)
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "util.go"), []byte("package pkg\n\nfunc ProjectHelper() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newIssuePipeline(root, &IssueProcessRequest{Title: "Use the helper"})
+	if _, err := p.GenerateCode(context.Background(), "Use the helper", "", nil); err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	got := prompts()
+	if len(got) != 1 {
+		t.Fatalf("expected one prompt, got %d", len(got))
+	}
+	if !strings.Contains(got[0], "// File: pkg/util.go\npackage pkg") || !strings.Contains(got[0], "ProjectHelper") {
+		t.Errorf("expected the project's files in the prompt:\n%s", got[0])
+	}
+	if strings.Contains(got[0], "worker-server.go") {
+		t.Error("expected no files from the worker's working directory")
+	}
+}
+
+func TestHandleIssueProcessGenerateError(t *testing.T) {
+	fake := &fakeIssuePipeline{generateErr: fmt.Errorf("model unavailable")}
+	withFakePipeline(t, fake)
+
+	body := `{"number": 7, "title": "Broken", "branch": "custom-branch", "base_branch": "develop"}`
+	req := httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleIssueProcess(rec, req)
+
+	if strings.Join(fake.calls, ",") != "branch:develop->custom-branch,generate" {
+		t.Errorf("pipeline should stop after generation failure, got %v", fake.calls)
+	}
+
+	events := readEvents(t, rec.Body.String())
+	last := events[len(events)-1]
+	if last.Step != "error" {
+		t.Fatalf("expected final step error, got %s", last.Step)
+	}
+	if !strings.Contains(last.Error, "model unavailable") {
+		t.Errorf("expected error to mention cause, got %s", last.Error)
+	}
+}
+
+func TestHandleIssueProcessDryRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	fake := &fakeIssuePipeline{
+		changes: []claude.FileChange{
+			{Path: "pkg/new.go", Operation: "create", Content: "package pkg\n"},
+			{Path: "main.go", Operation: "modify", Content: "package main\n\nfunc main() {}\n"},
+			{Path: "old.txt", Operation: "delete"},
+		},
+	}
+	withFakePipeline(t, fake)
+	for name, content := range map[string]string{"main.go": "package main\n", "old.txt": "obsolete\n"} {
+		if err := os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+
+	var steps []string
+	done, err := worker.NewClient(server.URL, "").PlanIssue(context.Background(), worker.IssueProcessRequest{Number: 42, Title: "Add pkg"}, func(ev worker.IssueProgressEvent) {
+		steps = append(steps, ev.Step)
+	})
+	if err != nil {
+		t.Fatalf("PlanIssue: %v", err)
+	}
+
+	if strings.Join(fake.calls, ",") != "generate" {
+		t.Errorf("expected only generation on a dry run, got %v", fake.calls)
+	}
+	if strings.Join(steps, ",") != "generate,diff,done" {
+		t.Errorf("unexpected steps %v", steps)
+	}
+	if len(done.Changes) != 3 || strings.Join(done.Files, ",") != "pkg/new.go,main.go,old.txt" {
+		t.Errorf("expected the proposed changes, got %+v", done)
+	}
+	for _, want := range []string{
+		"diff --git a/pkg/new.go b/pkg/new.go\nnew file mode",
+		"+func main() {}",
+		"diff --git a/old.txt b/old.txt\ndeleted file mode",
+		"-obsolete",
+	} {
+		if !strings.Contains(done.Diff, want) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", want, done.Diff)
+		}
+	}
+
+	// Nothing in the project changed
+	entries, _ := os.ReadDir(projectPath)
+	if len(entries) != 2 {
+		t.Errorf("expected only the original files, got %d entries", len(entries))
+	}
+	if got, _ := os.ReadFile(filepath.Join(projectPath, "main.go")); string(got) != "package main\n" {
+		t.Errorf("main.go was modified: %q", got)
+	}
+
+	// Changes that escape the project are refused, as on a real run
+	fake.changes = []claude.FileChange{{Path: "../escape.go", Operation: "create", Content: "x"}}
+	if _, err := worker.NewClient(server.URL, "").PlanIssue(context.Background(), worker.IssueProcessRequest{Title: "Escape"}, nil); err == nil || !strings.Contains(err.Error(), "outside the project") {
+		t.Errorf("expected an out-of-project change to be refused, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handleIssueProcess(rec, httptest.NewRequest(http.MethodPost, "/issue/process?dry-run=maybe", strings.NewReader(`{"title": "x"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid dry-run value, got %d", rec.Code)
+	}
+}
+
+func TestHandleIssueProcessValidation(t *testing.T) {
+	withFakePipeline(t, &fakeIssuePipeline{})
+
+	req := httptest.NewRequest(http.MethodGet, "/issue/process", nil)
+	rec := httptest.NewRecorder()
+	newWorkerRouter().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/issue/process", strings.NewReader(`{"number": 1}`))
+	rec = httptest.NewRecorder()
+	handleIssueProcess(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for missing title, got %d", rec.Code)
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	rt := newWorkerRouter()
+
+	for _, tc := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodGet, "/file/write", "POST, OPTIONS"},
+		{http.MethodPost, "/git/status", "GET, HEAD, OPTIONS"},
+		{http.MethodDelete, "/project/info", "GET, HEAD, OPTIONS"},
+	} {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected 405, got %d", tc.method, tc.path, rec.Code)
+		}
+		if got := rec.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tc.method, tc.path, tc.allow, got)
+		}
+		if !strings.Contains(rec.Body.String(), `"error":"Method not allowed"`) {
+			t.Errorf("%s %s: expected JSON error body, got %s", tc.method, tc.path, rec.Body.String())
+		}
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newWorkerRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/git/push", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != jsonContentType {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `"success":false`) {
+		t.Errorf("expected JSON error body, got %s", rec.Body.String())
+	}
+}
+
+func TestErrorResponseShape(t *testing.T) {
+	origToken, origPath := workerToken, projectPath
+	workerToken, projectPath = "secret", t.TempDir()
+	t.Cleanup(func() { workerToken, projectPath = origToken, origPath })
+	handler := requestid.Middleware(authMiddleware(limitBody(newWorkerRouter())))
+
+	tests := []struct {
+		name, method, path, body string
+		auth                     bool
+		want                     int
+	}{
+		{"unauthorized", http.MethodGet, "/git/status", "", false, http.StatusUnauthorized},
+		{"forbidden", http.MethodPost, "/file/read", `{"path": "../../etc/passwd"}`, true, http.StatusForbidden},
+		{"not found", http.MethodGet, "/git/push", "", true, http.StatusNotFound},
+		{"method not allowed", http.MethodGet, "/file/write", "", true, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.auth {
+			req.Header.Set("X-Worker-Auth", "secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s: unexpected content type %q", tt.name, ct)
+		}
+		var body struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" || body.Code != tt.want {
+			t.Errorf("%s: expected {error, code: %d}, got %s", tt.name, tt.want, rec.Body.String())
+		}
+	}
+}
+
+func TestRouterOptions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newWorkerRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/issue/process", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "POST, OPTIONS" {
+		t.Errorf("unexpected Allow header %q", got)
+	}
+}
+
+func withMaxBodyBytes(t *testing.T, n int64) {
+	t.Helper()
+	orig := maxBodyBytes
+	t.Cleanup(func() { maxBodyBytes = orig })
+	maxBodyBytes = n
+}
+
+func TestLimitBodyRejectsOversizedRequest(t *testing.T) {
+	withMaxBodyBytes(t, 64)
+	body := `{"path": "big.txt", "content": "` + strings.Repeat("x", 100) + `"}`
+
+	// Declared Content-Length is rejected before the handler runs
+	rec := httptest.NewRecorder()
+	limitBody(newWorkerRouter()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/file/write", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for declared length, got %d", rec.Code)
+	}
+
+	// Without a Content-Length the limit trips while decoding
+	req := httptest.NewRequest(http.MethodPost, "/file/write", io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	limitBody(newWorkerRouter()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for streamed body, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "exceeds 64 bytes") {
+		t.Errorf("expected limit in error body, got %s", rec.Body.String())
+	}
+}
+
+func TestRequestIDPropagatesToGateway(t *testing.T) {
+	// Stands in for the gateway: records the ID the worker's Claude client sends
+	var gatewaySaw string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatewaySaw = r.Header.Get(requestid.Header)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": `[{"path":"a.go","operation":"create","content":"package a"}]`}},
+		})
+	}))
+	t.Cleanup(gateway.Close)
+
+	cl := claude.NewClient("key", gateway.URL, "test-model")
+	cl.SetSkipCodebase(true)
+	withFakePipeline(t, &fakeIssuePipeline{claude: cl})
+
+	origToken := workerToken
+	workerToken = "secret"
+	t.Cleanup(func() { workerToken = origToken })
+
+	server := httptest.NewServer(requestid.Middleware(authMiddleware(limitBody(newWorkerRouter()))))
+	t.Cleanup(server.Close)
+
+	ctx := requestid.WithID(context.Background(), "run-1234")
+	client := worker.NewClient(server.URL, "secret")
+	if _, err := client.ProcessIssue(ctx, worker.IssueProcessRequest{Number: 7, Title: "Add a"}, nil); err != nil {
+		t.Fatalf("ProcessIssue: %v", err)
+	}
+	if gatewaySaw != "run-1234" {
+		t.Errorf("gateway saw request ID %q, want run-1234", gatewaySaw)
+	}
+
+	// Errors carry the ID in the body and every response echoes it
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/git/status", nil)
+	req.Header.Set(requestid.Header, "run-1234")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	if echoed := resp.Header.Get(requestid.Header); echoed != "run-1234" {
+		t.Errorf("response header echoed %q, want run-1234", echoed)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusUnauthorized || body["request_id"] != "run-1234" {
+		t.Errorf("expected 401 carrying the request ID, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "new" {
+		t.Errorf("content = %q, want %q", data, "new")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0755 {
+		t.Errorf("expected existing mode 0755 to be kept, got %v", info.Mode().Perm())
+	}
+
+	// A failed rename leaves no partial or temporary file behind
+	target := filepath.Join(dir, "subdir")
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(target, []byte("x"), 0644); err == nil {
+		t.Error("expected error writing over a non-empty directory")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+// startServe runs serve on a local port with handler, returning the base URL,
+// the function that starts shutdown and serve's result
+func startServe(t *testing.T, handler http.Handler, drain time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, &http.Server{Handler: handler}, ln, drain) }()
+	return "http://" + ln.Addr().String(), cancel, done
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	url, shutdown, done := startServe(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("finished"))
+	}), 5*time.Second)
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		result <- string(body)
+	}()
+
+	<-started
+	shutdown()
+	select {
+	case err := <-done:
+		t.Fatalf("serve returned with a request in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if got := <-result; got != "finished" {
+		t.Errorf("in-flight request got %q, want it to finish", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve: %v", err)
+	}
+}
+
+func TestServeCancelsRequestsAfterDrain(t *testing.T) {
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	url, shutdown, done := startServe(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(cancelled)
+	}), 50*time.Millisecond)
+
+	go http.Get(url)
+	<-started
+	shutdown()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request context was not cancelled after the drain timeout")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve: %v", err)
+	}
+}
+
+func TestHandleFileWriteKeepsExecutableBit(t *testing.T) {
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+
+	script := filepath.Join(projectPath, "build.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"path": "build.sh", "content": "#!/bin/sh\necho built\n"}`
+	rec := httptest.NewRecorder()
+	handleFileWrite(rec, httptest.NewRequest(http.MethodPost, "/file/write", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected build.sh to stay executable, got %v", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(script); !strings.Contains(string(data), "echo built") {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestFileDeleteAndRename(t *testing.T) {
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+	for name, content := range map[string]string{"old.go": "package old", "keep.go": "package keep"} {
+		if err := os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+	client := worker.NewClient(server.URL, "")
+	ctx := context.Background()
+
+	// Rename into a new directory
+	if err := client.FileRename(ctx, "old.go", "pkg/new.go", false); err != nil {
+		t.Fatalf("FileRename: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(projectPath, "pkg/new.go")); err != nil || string(data) != "package old" {
+		t.Errorf("expected pkg/new.go to hold the old content, got %q (err %v)", data, err)
+	}
+
+	// Existing targets are only replaced with overwrite
+	err := client.FileRename(ctx, "pkg/new.go", "keep.go", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected refusal to overwrite keep.go, got %v", err)
+	}
+	if err := client.FileRename(ctx, "pkg/new.go", "keep.go", true); err != nil {
+		t.Errorf("FileRename with overwrite: %v", err)
+	}
+
+	// Missing sources and paths outside the project are reported
+	if err := client.FileRename(ctx, "missing.go", "x.go", false); err == nil || !strings.Contains(err.Error(), "No such file: missing.go") {
+		t.Errorf("expected missing source error, got %v", err)
+	}
+	if err := client.FileRename(ctx, "keep.go", "../escape.go", false); err == nil || !strings.Contains(err.Error(), "Invalid path") {
+		t.Errorf("expected invalid path error, got %v", err)
+	}
+
+	if err := client.FileDelete(ctx, "keep.go"); err != nil {
+		t.Fatalf("FileDelete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectPath, "keep.go")); !os.IsNotExist(err) {
+		t.Errorf("expected keep.go to be deleted, stat err %v", err)
+	}
+	if err := client.FileDelete(ctx, "keep.go"); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("expected 404 deleting a missing file, got %v", err)
+	}
+	if err := client.FileDelete(ctx, "."); err == nil || !strings.Contains(err.Error(), "Invalid path") {
+		t.Errorf("expected refusal to delete the project directory, got %v", err)
+	}
+}
+
+func TestProjectSearch(t *testing.T) {
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+	files := map[string]string{
+		"main.go":            "package main\n\nfunc main() {\n\tRunServer()\n}\n",
+		"cmd/server.go":      "package cmd\n\n// RunServer starts it\nfunc RunServer() {}\n",
+		"docs/notes.md":      "Call RunServer() once.\n",
+		".git/HEAD":          "RunServer\n",
+		"node_modules/x.js":  "RunServer()\n",
+		"assets/logo.bin":    "RunServer\x00\x01",
+		"cmd/other_test.go":  "package cmd\n",
+		"cmd/nested/deep.go": "package nested // RunServer\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(projectPath, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+	client := worker.NewClient(server.URL, "")
+	ctx := context.Background()
+
+	result, err := client.Search(ctx, `RunServer\(`, "*.go")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var got []string
+	for _, m := range result.Matches {
+		got = append(got, fmt.Sprintf("%s:%d", m.Path, m.Line))
+	}
+	if strings.Join(got, ",") != "cmd/server.go:4,main.go:4" || result.Truncated {
+		t.Errorf("unexpected matches %v (truncated %v)", got, result.Truncated)
+	}
+	if result.Matches[1].Text != "\tRunServer()" {
+		t.Errorf("unexpected snippet %q", result.Matches[1].Text)
+	}
+
+	// Without a glob every text file outside .git and node_modules is searched;
+	// a glob with a slash matches the path
+	result, _ = client.Search(ctx, "RunServer", "")
+	if len(result.Matches) != 5 {
+		t.Errorf("expected 5 matches across the tree, got %+v", result.Matches)
+	}
+	result, _ = client.Search(ctx, "RunServer", "cmd/*.go")
+	if len(result.Matches) != 2 {
+		t.Errorf("expected the cmd/*.go glob to match only cmd/server.go, got %+v", result.Matches)
+	}
+
+	// The result cap truncates, and literal patterns aren't regexps
+	body := `{"pattern": "RunServer()", "literal": true, "max_results": 1}`
+	rec := httptest.NewRecorder()
+	handleProjectSearch(rec, httptest.NewRequest(http.MethodPost, "/project/search", strings.NewReader(body)))
+	var capped ProjectSearchResponse
+	json.Unmarshal(rec.Body.Bytes(), &capped)
+	if len(capped.Matches) != 1 || !capped.Truncated || !strings.Contains(capped.Matches[0].Text, "RunServer()") {
+		t.Errorf("expected one literal match marked truncated, got %+v", capped)
+	}
+
+	// Exactly max_results matches is a full result, not a truncated one
+	body = `{"pattern": "RunServer\\(", "glob": "*.go", "max_results": 2}`
+	rec = httptest.NewRecorder()
+	handleProjectSearch(rec, httptest.NewRequest(http.MethodPost, "/project/search", strings.NewReader(body)))
+	var full ProjectSearchResponse
+	json.Unmarshal(rec.Body.Bytes(), &full)
+	if len(full.Matches) != 2 || full.Truncated {
+		t.Errorf("expected both matches without truncation, got %+v", full)
+	}
+
+	if _, err := client.Search(ctx, "(", ""); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected invalid pattern error, got %v", err)
+	}
+}
+
+func TestProjectTree(t *testing.T) {
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+	files := map[string]string{
+		"main.go":         "package main\n",
+		"cmd/root.go":     "package cmd\n",
+		"cmd/sub/deep.go": "package sub\n",
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"vendor/lib/a.go": "package lib\n",
+		"build/out.bin":   "binary",
+		"debug.log":       "log",
+		".gitignore":      "build/\n*.log\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(projectPath, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Fail if the handler still shells out to find
+	t.Setenv("PATH", "")
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+	client := worker.NewClient(server.URL, "")
+
+	result, err := client.ProjectTree(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("ProjectTree: %v", err)
+	}
+	var paths []string
+	var walk func(n *worker.TreeNode)
+	walk = func(n *worker.TreeNode) {
+		for _, c := range n.Children {
+			paths = append(paths, c.Path+":"+c.Type)
+			walk(c)
+		}
+	}
+	walk(result.Tree)
+	want := ".git:dir,.gitignore:file,build:dir,build/out.bin:file,cmd:dir,cmd/root.go:file,cmd/sub:dir,debug.log:file,main.go:file,vendor:dir"
+	if strings.Join(paths, ",") != want {
+		t.Errorf("unexpected tree at depth 2:\n got %s\nwant %s", strings.Join(paths, ","), want)
+	}
+	if result.Depth != 2 || result.Truncated {
+		t.Errorf("unexpected depth %d / truncated %v", result.Depth, result.Truncated)
+	}
+	if main := result.Tree.Children[len(result.Tree.Children)-2]; main.Name != "main.go" || main.Size != int64(len("package main\n")) {
+		t.Errorf("expected main.go with its size, got %+v", main)
+	}
+
+	// With gitignore=true, build/ and *.log are left out
+	rec := httptest.NewRecorder()
+	handleProjectTree(rec, httptest.NewRequest(http.MethodGet, "/project/tree?depth=1&gitignore=true", nil))
+	var ignored ProjectTreeResponse
+	json.Unmarshal(rec.Body.Bytes(), &ignored)
+	var names []string
+	for _, c := range ignored.Tree.Children {
+		names = append(names, c.Name)
+		if c.Children != nil {
+			t.Errorf("depth 1 should not expand %s", c.Name)
+		}
+	}
+	if strings.Join(names, ",") != ".git,.gitignore,cmd,main.go,vendor" {
+		t.Errorf("unexpected entries with gitignore: %v", names)
+	}
+
+	rec = httptest.NewRecorder()
+	handleProjectTree(rec, httptest.NewRequest(http.MethodGet, "/project/tree?depth=zero", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid depth, got %d", rec.Code)
+	}
+}
+
+// installFakeCommand puts a shell script named name first on PATH and points
+// projectPath at an empty directory
+func installFakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake " + name + " script requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	origPath := projectPath
+	projectPath = t.TempDir()
+	t.Cleanup(func() { projectPath = origPath })
+}
+
+// installSlowGit puts a `git` on PATH that sleeps until it is killed and
+// points the project at an empty directory
+func installSlowGit(t *testing.T) {
+	installFakeCommand(t, "git", "exec sleep 30")
+}
+
+func TestGitCommandStopsWhenClientCancels(t *testing.T) {
+	installSlowGit(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handleGitStatus(rec, httptest.NewRequest(http.MethodGet, "/git/status", nil).WithContext(ctx))
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("git was not stopped when the request was cancelled (took %v)", elapsed)
+	}
+	if rec.Code != statusClientClosedRequest {
+		t.Errorf("expected %d for a cancelled request, got %d", statusClientClosedRequest, rec.Code)
+	}
+}
+
+func TestGitCommandTimeout(t *testing.T) {
+	installSlowGit(t)
+	orig := gitCommandTimeout
+	gitCommandTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { gitCommandTimeout = orig })
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handleGitLog(rec, httptest.NewRequest(http.MethodGet, "/git/log", nil))
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("git was not stopped at the timeout (took %v)", elapsed)
+	}
+	if rec.Code != http.StatusRequestTimeout || !strings.Contains(rec.Body.String(), "timed out") {
+		t.Errorf("expected 408 timeout error, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseProjects(t *testing.T) {
+	got, err := parseProjects(" api=/srv/api/ , web=/srv/web,")
+	if err != nil {
+		t.Fatalf("parseProjects: %v", err)
+	}
+	if len(got) != 2 || got["api"] != "/srv/api" || got["web"] != "/srv/web" {
+		t.Errorf("unexpected projects %v", got)
+	}
+
+	for _, bad := range []string{"api", "=/srv/api", "api=srv/api", "api=/a,api=/b"} {
+		if _, err := parseProjects(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestProjectSelection(t *testing.T) {
+	origPath, origProjects := projectPath, projects
+	t.Cleanup(func() { projectPath, projects = origPath, origProjects })
+	projectPath = t.TempDir()
+	projects = map[string]string{"api": t.TempDir(), "web": t.TempDir()}
+	for root, content := range map[string]string{projectPath: "default", projects["api"]: "api", projects["web"]: "web"} {
+		if err := os.WriteFile(filepath.Join(root, "NAME"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	for _, project := range []string{"", "api", "web"} {
+		client := worker.NewClient(server.URL, "")
+		client.SetProject(project)
+		content, err := client.FileRead(ctx, "NAME")
+		want := project
+		if want == "" {
+			want = "default"
+		}
+		if err != nil || content != want {
+			t.Errorf("project %q: read %q (err %v), want %q", project, content, err, want)
+		}
+	}
+
+	// Paths are checked against the selected root, not the default one
+	api := worker.NewClient(server.URL, "")
+	api.SetProject("api")
+	if err := api.FileWrite(ctx, "../"+filepath.Base(projects["web"])+"/NAME", "overwritten"); err == nil {
+		t.Error("expected write outside the api project to be refused")
+	}
+	if data, _ := os.ReadFile(filepath.Join(projects["web"], "NAME")); string(data) != "web" {
+		t.Errorf("web project was modified: %q", data)
+	}
+	if err := api.FileWrite(ctx, "new.txt", "x"); err != nil {
+		t.Fatalf("FileWrite: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projects["api"], "new.txt")); err != nil {
+		t.Errorf("expected new.txt in the api project: %v", err)
+	}
+
+	unknown := worker.NewClient(server.URL, "")
+	unknown.SetProject("mobile")
+	if err := unknown.Ping(ctx); !errors.Is(err, worker.ErrUnknownProject) {
+		t.Errorf("expected ErrUnknownProject, got %v", err)
+	}
+}
+
+func TestIssueProcessPromptUsesSelectedProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	prompts := stubClaude(t)
+	origPath, origProjects := projectPath, projects
+	t.Cleanup(func() { projectPath, projects = origPath, origProjects })
+	projectPath = t.TempDir()
+	projects = map[string]string{"api": t.TempDir(), "web": t.TempDir()}
+	files := map[string]string{"api": "handler.go", "web": "app.js"}
+	for name, file := range files {
+		if err := os.WriteFile(filepath.Join(projects[name], file), []byte("// "+name+" project source\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+
+	for _, project := range []string{"api", "web"} {
+		client := worker.NewClient(server.URL, "")
+		client.SetProject(project)
+		if _, err := client.PlanIssue(context.Background(), worker.IssueProcessRequest{Number: 1, Title: "Add a feature"}, nil); err != nil {
+			t.Fatalf("%s: PlanIssue: %v", project, err)
+		}
+		got := prompts()
+		prompt := got[len(got)-1]
+		for name, file := range files {
+			want := name == project
+			if has := strings.Contains(prompt, "// File: "+file+"\n// "+name+" project source"); has != want {
+				t.Errorf("%s: prompt includes %s = %v, want %v:\n%s", project, file, has, want, prompt)
+			}
+		}
+	}
+}
+
+func withMaxOutputBytes(t *testing.T, n int64) {
+	t.Helper()
+	orig := maxOutputBytes
+	t.Cleanup(func() { maxOutputBytes = orig })
+	maxOutputBytes = n
+}
+
+func TestClaudeRunTruncatesOutput(t *testing.T) {
+	installFakeCommand(t, "claude", "head -c 5000 /dev/zero | tr '\\0' x\necho oops >&2")
+	withMaxOutputBytes(t, 1000)
+
+	run := func(body string) ClaudeRunResponse {
+		rec := httptest.NewRecorder()
+		handleClaudeRun(rec, httptest.NewRequest(http.MethodPost, "/claude/run", strings.NewReader(body)))
+		var resp ClaudeRunResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return resp
+	}
+
+	// The server limit applies by default
+	resp := run(`{"command": "-p"}`)
+	if len(resp.Stdout) != 1000 || !resp.Truncated || resp.StdoutBytes != 5000 {
+		t.Errorf("expected stdout cut to 1000 of 5000 bytes, got %d bytes, truncated=%v, stdout_bytes=%d", len(resp.Stdout), resp.Truncated, resp.StdoutBytes)
+	}
+	if resp.Stderr != "oops\n" || resp.StderrBytes != 5 {
+		t.Errorf("expected stderr kept whole, got %q (%d bytes)", resp.Stderr, resp.StderrBytes)
+	}
+
+	// A request can lower it but not raise it
+	if resp := run(`{"command": "-p", "max_output": 10}`); len(resp.Stdout) != 10 || !resp.Truncated {
+		t.Errorf("expected stdout cut to 10 bytes, got %d", len(resp.Stdout))
+	}
+	if resp := run(`{"command": "-p", "max_output": 100000}`); len(resp.Stdout) != 1000 {
+		t.Errorf("expected the server limit to win, got %d bytes", len(resp.Stdout))
+	}
+}
+
+func TestGitCommandTruncatesOutput(t *testing.T) {
+	installFakeCommand(t, "git", "head -c 5000 /dev/zero | tr '\\0' x")
+	withMaxOutputBytes(t, 1000)
+
+	rec := httptest.NewRecorder()
+	handleGitLog(rec, httptest.NewRequest(http.MethodGet, "/git/log", nil))
+
+	var resp struct {
+		Output      string `json:"output"`
+		Truncated   bool   `json:"truncated"`
+		OutputBytes int64  `json:"output_bytes"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(resp.Output) != 1000 || !resp.Truncated || resp.OutputBytes != 5000 {
+		t.Errorf("expected output cut to 1000 of 5000 bytes, got %d bytes, truncated=%v, output_bytes=%d", len(resp.Output), resp.Truncated, resp.OutputBytes)
+	}
+}
+
+func TestClaudeRunEnv(t *testing.T) {
+	installFakeCommand(t, "claude", `echo "$ANTHROPIC_MODEL|$CLAUDE_CODE_MAX_OUTPUT_TOKENS|$CLAUDE_CODE_USE_BEDROCK|$GATEWAY_TOKEN|$LD_PRELOAD|$NODE_OPTIONS|$BASH_ENV"`)
+	t.Setenv("GATEWAY_TOKEN", "worker-own-token")
+	t.Setenv("CLAUDE_CODE_MAX_OUTPUT_TOKENS", "1000")
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+
+	resp, err := worker.NewClient(server.URL, "").RunClaude(context.Background(), "-p", nil, 10, map[string]string{
+		"ANTHROPIC_MODEL":               "claude-3-5-haiku-latest",
+		"ANTHROPIC_AUTH_TOKEN":          "stolen",
+		"CLAUDE_CODE_USE_BEDROCK":       "0",
+		"CLAUDE_CODE_MAX_OUTPUT_TOKENS": "4096",
+		"GATEWAY_TOKEN":                 "stolen",
+		"LD_PRELOAD":                    "/tmp/evil.so",
+		"NODE_OPTIONS":                  "--require /tmp/evil.js",
+		"BASH_ENV":                      "/tmp/evil.sh",
+	})
+	if err != nil {
+		t.Fatalf("RunClaude: %v", err)
+	}
+	if want := "claude-3-5-haiku-latest|4096|0|worker-own-token|||\n"; resp.Stdout != want {
+		t.Errorf("expected %q, got %q", want, resp.Stdout)
+	}
+	if got := strings.Join(resp.DroppedEnv, ","); got != "ANTHROPIC_AUTH_TOKEN,BASH_ENV,GATEWAY_TOKEN,LD_PRELOAD,NODE_OPTIONS" {
+		t.Errorf("expected everything but claude's settings to be reported as dropped, got %q", got)
+	}
+}
+
+func TestClaudeRunOutcomes(t *testing.T) {
+	installFakeCommand(t, "claude", `echo "bad flag $1" >&2; exit 3`)
+
+	server := httptest.NewServer(newWorkerRouter())
+	t.Cleanup(server.Close)
+	client := worker.NewClient(server.URL, "")
+
+	// claude ran and failed: a 200 with the output, and an error for the caller
+	resp, err := client.RunClaude(context.Background(), "--bogus", nil, 10, nil)
+	if !errors.Is(err, worker.ErrClaudeFailed) {
+		t.Fatalf("expected ErrClaudeFailed, got %v", err)
+	}
+	if resp == nil || resp.Success || resp.ExitCode != 3 || resp.Stderr != "bad flag --bogus\n" {
+		t.Errorf("expected the failed run's details, got %+v", resp)
+	}
+
+	// claude isn't installed: nothing ran, so there is no response
+	t.Setenv("PATH", t.TempDir())
+	resp, err = client.RunClaude(context.Background(), "-p", nil, 10, nil)
+	if !errors.Is(err, worker.ErrClaudeNotStarted) || resp != nil {
+		t.Errorf("expected ErrClaudeNotStarted without a response, got %+v, %v", resp, err)
+	}
+
+	rec := httptest.NewRecorder()
+	handleClaudeRun(rec, httptest.NewRequest(http.MethodPost, "/claude/run", strings.NewReader(`{"command": "-p"}`)))
+	var body map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusUnprocessableEntity || body["success"] != false || !strings.Contains(fmt.Sprint(body["error"]), "Could not start claude") {
+		t.Errorf("expected a 422 error response, got %d %v", rec.Code, body)
+	}
+}
@@ -0,0 +1,204 @@
+// Package breaker implements a circuit breaker that stops calling a failing
+// service for a cooldown period instead of hammering it during an outage.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"vibe-git/internal/httpclient"
+)
+
+// ErrOpen is returned instead of calling the service while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the breaker's current mode
+type State int
+
+const (
+	Closed   State = iota // Calls go through; failures are counted
+	Open                  // Calls fail fast with ErrOpen until the cooldown passes
+	HalfOpen              // One trial call is let through to test recovery
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Breaker opens after Threshold consecutive failures, rejects calls for
+// Cooldown, then lets a single trial call through. A successful trial closes
+// the breaker; a failed one opens it again.
+type Breaker struct {
+	Name      string
+	Threshold int
+	Cooldown  time.Duration
+
+	// OnStateChange, if set, is called after every transition. It runs with
+	// the breaker locked and must not call back into it.
+	OnStateChange func(name string, from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+	now      func() time.Time
+}
+
+// New creates a closed breaker
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Name:      name,
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State returns the current state, moving from open to half-open once the cooldown has passed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// Allow reports whether a call may be made now. Every allowed call must be
+// followed by Record with its outcome, or Release if it has none.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+
+	switch b.state {
+	case Open:
+		return fmt.Errorf("%s: %w", b.Name, ErrOpen)
+	case HalfOpen:
+		if b.trial {
+			return fmt.Errorf("%s: %w", b.Name, ErrOpen)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a call allowed by Allow
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen {
+		b.trial = false
+		if failed {
+			b.open()
+		} else {
+			b.failures = 0
+			b.setState(Closed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == Closed && b.failures >= b.Threshold {
+		b.open()
+	}
+}
+
+// Release ends a call allowed by Allow that says nothing about the service's
+// health, such as one the caller cancelled. The state and failure count are
+// kept; a half-open breaker lets another trial call through.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen {
+		b.trial = false
+	}
+}
+
+// Do calls fn if the breaker allows it and records whether it failed
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err != nil)
+	return err
+}
+
+// Transport wraps base so every request goes through the breaker. Network
+// errors and 429/5xx responses count as failures; other responses, including
+// 4xx client errors, count as successes since the service is up.
+func (b *Breaker) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = httpclient.Transport()
+	}
+	return &transport{breaker: b, base: base}
+}
+
+type transport struct {
+	breaker *Breaker
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.Allow(); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// A cancelled request says nothing about the service's health
+		t.breaker.Release()
+	case err != nil:
+		t.breaker.Record(true)
+	default:
+		t.breaker.Record(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+	}
+	return resp, err
+}
+
+// refresh moves an open breaker to half-open once the cooldown has passed; b.mu must be held
+func (b *Breaker) refresh() {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.Cooldown {
+		b.trial = false
+		b.setState(HalfOpen)
+	}
+}
+
+// open trips the breaker; b.mu must be held
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.setState(Open)
+}
+
+// setState records a transition and notifies OnStateChange; b.mu must be held
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	if b.OnStateChange != nil {
+		b.OnStateChange(b.Name, from, to)
+	}
+}
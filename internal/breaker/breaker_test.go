@@ -0,0 +1,184 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker driven by a manually advanced clock
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New("test", threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+var errBoom = errors.New("boom")
+
+func TestBreakerTransitions(t *testing.T) {
+	b, now := newTestBreaker(3, time.Minute)
+
+	var transitions []string
+	b.OnStateChange = func(name string, from, to State) {
+		transitions = append(transitions, from.String()+"→"+to.String())
+	}
+
+	fail := func() error { return errBoom }
+	succeed := func() error { return nil }
+
+	// Failures below the threshold keep it closed; a success resets the count
+	b.Do(fail)
+	b.Do(fail)
+	b.Do(succeed)
+	b.Do(fail)
+	b.Do(fail)
+	if b.State() != Closed {
+		t.Fatalf("expected closed below threshold, got %s", b.State())
+	}
+
+	// Third consecutive failure opens it
+	b.Do(fail)
+	if b.State() != Open {
+		t.Fatalf("expected open after 3 failures, got %s", b.State())
+	}
+
+	// Calls are short-circuited during the cooldown
+	called := false
+	err := b.Do(func() error { called = true; return nil })
+	if called || !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen without calling, got called=%v err=%v", called, err)
+	}
+
+	// After the cooldown one trial is allowed; a failure reopens
+	*now = now.Add(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open after cooldown, got %s", b.State())
+	}
+	b.Do(fail)
+	if b.State() != Open {
+		t.Fatalf("expected failed trial to reopen, got %s", b.State())
+	}
+
+	// A successful trial closes it again
+	*now = now.Add(time.Minute)
+	if err := b.Do(succeed); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("expected closed after successful trial, got %s", b.State())
+	}
+
+	want := []string{"closed→open", "open→half-open", "half-open→open", "open→half-open", "half-open→closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d: expected %s, got %s", i, want[i], transitions[i])
+		}
+	}
+}
+
+func TestBreakerHalfOpenAllowsSingleTrial(t *testing.T) {
+	b, now := newTestBreaker(1, time.Second)
+	b.Do(func() error { return errBoom })
+	*now = now.Add(time.Second)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected trial to be allowed, got %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected second concurrent call to be rejected, got %v", err)
+	}
+	b.Record(false)
+	if err := b.Allow(); err != nil {
+		t.Errorf("expected calls after recovery, got %v", err)
+	}
+}
+
+func TestTransportCountsServerErrors(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	b, _ := newTestBreaker(2, time.Hour)
+	client := &http.Client{Transport: b.Transport(nil)}
+
+	// Client errors mean the service is up and don't count
+	status = http.StatusNotFound
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if b.State() != Closed {
+		t.Fatalf("404s should not open the breaker, got %s", b.State())
+	}
+
+	status = http.StatusBadGateway
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if b.State() != Open {
+		t.Fatalf("expected 5xx responses to open the breaker, got %s", b.State())
+	}
+
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected requests to fail fast with ErrOpen, got %v", err)
+	}
+}
+
+func TestTransportIgnoresCancelledRequests(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	b, now := newTestBreaker(1, time.Minute)
+	client := &http.Client{Transport: b.Transport(nil)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	*now = now.Add(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open after the cooldown, got %s", b.State())
+	}
+
+	// The cancelled trial neither closes the breaker nor keeps the trial slot
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/slow", nil)
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected the cancelled request to fail")
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("expected a cancelled trial to leave the breaker half-open, got %s", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("expected another trial to be allowed, got %v", err)
+	}
+}
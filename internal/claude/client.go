@@ -4,12 +4,19 @@ import (
 	"bytes"
 	stdctx "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"vibe-git/internal/breaker"
 	"vibe-git/internal/ctxloader"
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/requestid"
 )
 
 // Client wraps the Anthropic API
@@ -18,6 +25,41 @@ type Client struct {
 	baseURL string
 	model   string
 	http    *http.Client
+	headers map[string]string
+
+	promptDump   io.Writer // Receives each request's headers and prompt when set
+	responseDump io.Writer // Receives each raw model response when set
+
+	root         string   // Checkout the codebase and prompt files are read from
+	skipCodebase bool     // Leave the codebase out of the prompt
+	codebaseDirs []string // Directories the codebase section is limited to; empty means all
+	maxFiles     int      // Most files in the codebase section; <= 0 means no cap
+
+	redactSecrets  bool                          // Mask secrets in the codebase section
+	codebaseReport func(ctxloader.CodebaseStats) // Told what each codebase section contains
+
+	maxResponseBytes int64 // Largest response read into memory; <= 0 means no limit
+
+	includeImages    bool                                 // Send the issue's images with generation prompts
+	imageToken       string                               // GitHub token for downloading attachments
+	imageTokenSource func(stdctx.Context) (string, error) // Supplies short-lived download tokens; overrides imageToken
+	imageReport      func(url string, err error)          // Told about every image download
+
+	checkTokens bool                        // Count generation prompts before sending them
+	autoTrim    bool                        // Drop codebase files from prompts that don't fit
+	tokenReport func(tokens int, err error) // Told every checked prompt's token count
+	trimReport  func(TrimStats)             // Told how each trimmed prompt was cut down
+
+	temperature float64 // Sampling temperature; ModelDefault leaves it to the model
+	topP        float64 // Nucleus sampling cutoff; ModelDefault leaves it to the model
+
+	usageReport func(Usage) // Told the tokens each response used
+}
+
+// Usage is the tokens a response used, as the API reports them
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 // FileChange represents a file modification
@@ -27,6 +69,48 @@ type FileChange struct {
 	Content   string `json:"content"`
 }
 
+// CheckRedacted returns an error naming the changes that write
+// ctxloader.Redacted into a file more often than the file under root already
+// holds it. The model echoed a secret masked in its prompt, and applying the
+// change would replace the real value with the placeholder.
+func CheckRedacted(root string, changes []FileChange) error {
+	var paths []string
+	for _, change := range changes {
+		if change.Operation == "delete" || !strings.Contains(change.Content, ctxloader.Redacted) {
+			continue
+		}
+		existing, _ := os.ReadFile(filepath.Join(root, change.Path))
+		if strings.Count(change.Content, ctxloader.Redacted) > strings.Count(string(existing), ctxloader.Redacted) {
+			paths = append(paths, change.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return fmt.Errorf("changes to %s contain the %s placeholder of a masked secret", strings.Join(paths, ", "), ctxloader.Redacted)
+}
+
+// DefaultMaxResponseBytes is the default cap on the size of a model response
+const DefaultMaxResponseBytes = 16 * 1024 * 1024
+
+// ErrResponseTooLarge is returned when a response exceeds the configured maximum size
+var ErrResponseTooLarge = errors.New("response too large")
+
+// APIError is returned when the API answers with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// IsAuthError reports whether the API rejected the credentials
+func (e *APIError) IsAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
 // NewClient creates a new Claude client
 func NewClient(apiKey, baseURL, model string) *Client {
 	if baseURL == "" {
@@ -36,56 +120,291 @@ func NewClient(apiKey, baseURL, model string) *Client {
 		apiKey:  apiKey,
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		model:   model,
-		http:    &http.Client{},
+		http:    &http.Client{Transport: httpclient.Transport()},
+		headers: make(map[string]string),
+		root:    ".",
+
+		redactSecrets:    true,
+		maxResponseBytes: DefaultMaxResponseBytes,
+		temperature:      ModelDefault,
+		topP:             ModelDefault,
 	}
 }
 
+// WithModel returns a copy of the client that uses model. The copy shares the
+// client's settings and HTTP client.
+func (c *Client) WithModel(model string) *Client {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+// WithRoot returns a copy of the client that reads the codebase and the prompt
+// files from dir, e.g. the worktree an issue's changes are applied to, while
+// the original serves others. The copy shares the client's settings and HTTP
+// client.
+func (c *Client) WithRoot(dir string) *Client {
+	clone := *c
+	clone.root = dir
+	return &clone
+}
+
+// WithUsageReport returns a copy of the client that tells report the tokens
+// each of its responses used, e.g. to total them per issue while the original
+// serves others. The copy shares the client's settings and HTTP client.
+func (c *Client) WithUsageReport(report func(Usage)) *Client {
+	clone := *c
+	clone.usageReport = report
+	return &clone
+}
+
+// SetHTTPClient replaces the HTTP client API requests are sent with (e.g. for tests)
+func (c *Client) SetHTTPClient(h *http.Client) {
+	c.http = h
+}
+
+// SetHeader sets an extra header sent with every API request (e.g. gateway auth)
+func (c *Client) SetHeader(key, value string) {
+	c.headers[key] = value
+}
+
+// SetBreaker routes every API request through the circuit breaker b
+func (c *Client) SetBreaker(b *breaker.Breaker) {
+	c.http.Transport = b.Transport(c.http.Transport)
+}
+
+// SetPromptDump makes the client write every request's headers and prompt to w
+// before it is sent. Credential headers are redacted.
+func (c *Client) SetPromptDump(w io.Writer) {
+	c.promptDump = w
+}
+
+// SetResponseDump makes the client write the raw text of every model response to w
+func (c *Client) SetResponseDump(w io.Writer) {
+	c.responseDump = w
+}
+
+// SetSkipCodebase leaves the codebase out of generation prompts, so Claude works
+// from the issue and its @referenced files only
+func (c *Client) SetSkipCodebase(skip bool) {
+	c.skipCodebase = skip
+}
+
+// SetCodebaseDirs limits the codebase section of generation prompts to dirs
+func (c *Client) SetCodebaseDirs(dirs []string) {
+	c.codebaseDirs = dirs
+}
+
+// SetRedactSecrets controls whether secrets in the codebase are masked before
+// they are sent; it is on by default. Referenced files are redacted when they are
+// loaded (see ctxloader.LoadOptions).
+func (c *Client) SetRedactSecrets(redact bool) {
+	c.redactSecrets = redact
+}
+
+// SetMaxResponseBytes caps how large a response may be; n <= 0 removes the limit
+func (c *Client) SetMaxResponseBytes(n int64) {
+	c.maxResponseBytes = n
+}
+
+// ModelDefault leaves a sampling parameter to the model's default
+const ModelDefault = -1
+
+// ValidateSampling checks that temperature and topP are each ModelDefault or
+// from 0 to 1, and that at most one of them is set; the API rejects requests
+// that set both
+func ValidateSampling(temperature, topP float64) error {
+	if !validSampling(temperature) {
+		return fmt.Errorf("temperature %g is out of range (0 to 1, or %d for the model default)", temperature, ModelDefault)
+	}
+	if !validSampling(topP) {
+		return fmt.Errorf("top_p %g is out of range (0 to 1, or %d for the model default)", topP, ModelDefault)
+	}
+	if temperature != ModelDefault && topP != ModelDefault {
+		return fmt.Errorf("temperature and top_p cannot both be set")
+	}
+	return nil
+}
+
+// validSampling reports whether v is ModelDefault or from 0 to 1; NaN is neither
+func validSampling(v float64) bool {
+	return v == ModelDefault || (v >= 0 && v <= 1)
+}
+
+// SetSampling sets the temperature or top_p sent with every message;
+// ModelDefault leaves it to the model. Lower temperatures give more
+// deterministic output.
+func (c *Client) SetSampling(temperature, topP float64) error {
+	if err := ValidateSampling(temperature, topP); err != nil {
+		return err
+	}
+	c.temperature = temperature
+	c.topP = topP
+	return nil
+}
+
+// SetMaxFiles caps how many files the codebase section includes. Over the cap,
+// the files most relevant to the issue are kept.
+func (c *Client) SetMaxFiles(n int) {
+	c.maxFiles = n
+}
+
+// SetCodebaseReport sets a func called with the stats of every codebase section
+// built for a prompt, e.g. to log its size and redactions
+func (c *Client) SetCodebaseReport(report func(ctxloader.CodebaseStats)) {
+	c.codebaseReport = report
+}
+
+// BranchChanges is the work already committed on an issue branch. It is sent in
+// place of the codebase when iterating on that branch.
+type BranchChanges struct {
+	Base       string                     // What the diff is against, e.g. "origin/main"
+	Diff       string                     // Output of `git diff base...HEAD`
+	Files      []*ctxloader.FileReference // Full content of the changed files
+	Redactions int                        // Secrets masked in Diff
+}
+
 // GenerateCode generates code changes based on the issue
 func (c *Client) GenerateCode(ctx stdctx.Context, issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference) ([]FileChange, error) {
+	return c.GenerateCodeWithChanges(ctx, issueTitle, issueBody, referencedFiles, nil)
+}
+
+// GenerateCodeWithChanges is GenerateCode with the branch's existing changes
+// as context instead of the codebase. A nil branch behaves like GenerateCode.
+func (c *Client) GenerateCodeWithChanges(ctx stdctx.Context, issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference, branch *BranchChanges) ([]FileChange, error) {
+	return c.GenerateCodeInScope(ctx, "", issueTitle, issueBody, referencedFiles, branch)
+}
+
+// GenerateCodeInScope is GenerateCodeWithChanges for an issue confined to the
+// directory scope, relative to the repository root: the codebase section only
+// covers scope, in place of SetCodebaseDirs, and Claude is told to change files
+// under it only. An empty scope is the whole repository.
+func (c *Client) GenerateCodeInScope(ctx stdctx.Context, scope, issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference, branch *BranchChanges) ([]FileChange, error) {
 	// Build prompt with context
-	prompt, err := c.buildPrompt(issueTitle, issueBody, referencedFiles)
+	build := func(maxFiles int, skipCodebase bool) (string, *ctxloader.CodebaseStats, error) {
+		return c.composePrompt(issueTitle, issueBody, referencedFiles, branch, scope, maxFiles, skipCodebase)
+	}
+	prompt, stats, err := build(c.maxFiles, c.skipCodebase)
 	if err != nil {
 		return nil, fmt.Errorf("building prompt: %w", err)
 	}
+	if stats != nil && c.codebaseReport != nil {
+		c.codebaseReport(*stats)
+	}
+
+	var images []Image
+	if c.includeImages {
+		images = c.loadImages(ctx, issueBody)
+	}
+
+	if prompt, err = c.fitPrompt(ctx, prompt, stats, images, build); err != nil {
+		return nil, err
+	}
+
+	responseText, err := c.sendMessageWithImages(ctx, prompt, images)
+	if err != nil {
+		return nil, err
+	}
 
+	// Parse JSON changes
+	changes, err := ParseChanges(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("parsing changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+// Ping verifies the API key and base URL by listing available models
+func (c *Client) Ping(ctx stdctx.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1/models?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	c.setHeaders(ctx, req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// setHeaders adds the API key and version, the request ID carried by ctx and
+// any SetHeader headers to req
+func (c *Client) setHeaders(ctx stdctx.Context, req *http.Request) {
+	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("Anthropic-Version", "2023-06-01")
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// sendMessage sends a single user message to the Messages API and returns the text response
+func (c *Client) sendMessage(ctx stdctx.Context, prompt string) (string, error) {
+	return c.sendMessageWithImages(ctx, prompt, nil)
+}
+
+// sendMessageWithImages sends a user message made of images and prompt and returns the text response
+func (c *Client) sendMessageWithImages(ctx stdctx.Context, prompt string, images []Image) (string, error) {
 	requestBody := map[string]interface{}{
 		"model":      c.model,
-		"max_tokens": 4096,
+		"max_tokens": maxOutputTokens,
 		"messages": []map[string]interface{}{
 			{
 				"role":    "user",
-				"content": prompt,
+				"content": messageContent(prompt, images),
 			},
 		},
 	}
+	if c.temperature != ModelDefault {
+		requestBody["temperature"] = c.temperature
+	}
+	if c.topP != ModelDefault {
+		requestBody["top_p"] = c.topP
+	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return "", fmt.Errorf("marshaling request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return "", fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-Key", c.apiKey)
-	req.Header.Set("Anthropic-Version", "2023-06-01")
+	c.setHeaders(ctx, req)
+
+	if c.promptDump != nil {
+		c.dumpPrompt(req.Header, prompt, images)
+	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("calling Claude API: %w", err)
+		return "", fmt.Errorf("calling Claude API: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readResponse(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return "", err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Parse response
@@ -94,10 +413,14 @@ func (c *Client) GenerateCode(ctx stdctx.Context, issueTitle, issueBody string,
 			Type string `json:"type"`
 			Text string `json:"text"`
 		} `json:"content"`
+		Usage Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+	if c.usageReport != nil {
+		c.usageReport(result.Usage)
 	}
 
 	// Extract text content
@@ -108,21 +431,132 @@ func (c *Client) GenerateCode(ctx stdctx.Context, issueTitle, issueBody string,
 		}
 	}
 
-	// Parse JSON changes
-	changes, err := parseChangesFromResponse(responseText)
+	if c.responseDump != nil {
+		fmt.Fprintf(c.responseDump, "=== Response (model: %s) ===\n%s\n\n", c.model, responseText)
+	}
+
+	return responseText, nil
+}
+
+// readResponse reads a response body, failing with ErrResponseTooLarge rather
+// than reading more than the configured maximum into memory
+func (c *Client) readResponse(r io.Reader) ([]byte, error) {
+	if c.maxResponseBytes <= 0 {
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		return body, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, c.maxResponseBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("parsing changes: %w", err)
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if int64(len(body)) > c.maxResponseBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes (raise --max-response-size or split the issue)", ErrResponseTooLarge, c.maxResponseBytes)
 	}
+	return body, nil
+}
 
-	return changes, nil
+// dumpPrompt writes the request headers and prompt to the prompt dump
+func (c *Client) dumpPrompt(header http.Header, prompt string, images []Image) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "=== Request: POST %s/v1/messages (model: %s) ===\n", c.baseURL, c.model)
+	for _, k := range keys {
+		value := header.Get(k)
+		if isSecretHeader(k) {
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", k, value)
+	}
+	sb.WriteString("\n")
+	for _, img := range images {
+		fmt.Fprintf(&sb, "[image: %s (%s, %d bytes)]\n", img.URL, img.MediaType, len(img.Data))
+	}
+	sb.WriteString(prompt)
+	sb.WriteString("\n\n")
+
+	io.WriteString(c.promptDump, sb.String())
+}
+
+// isSecretHeader reports whether a header carries credentials and must not be dumped
+func isSecretHeader(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range []string{"auth", "key", "token", "secret", "cookie"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildPrompt builds the complete prompt with issue and context, as sent by GenerateCode
+func (c *Client) BuildPrompt(issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference) (string, error) {
+	return c.buildPrompt(issueTitle, issueBody, referencedFiles, nil)
 }
 
-// buildPrompt builds the complete prompt with issue and context
-func (c *Client) buildPrompt(issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference) (string, error) {
+func (c *Client) buildPrompt(issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference, branch *BranchChanges) (string, error) {
+	prompt, stats, err := c.composePrompt(issueTitle, issueBody, referencedFiles, branch, "", c.maxFiles, c.skipCodebase)
+	if err != nil {
+		return "", err
+	}
+	if stats != nil && c.codebaseReport != nil {
+		c.codebaseReport(*stats)
+	}
+	return prompt, nil
+}
+
+// Optional files, relative to the repository root, whose content is placed
+// before and after the issue and codebase in every generation prompt, for
+// instructions a team wants Claude to always follow
+const (
+	PromptPrefixFile = ".vibe-git/prompt-prefix.md"
+	PromptSuffixFile = ".vibe-git/prompt-suffix.md"
+)
+
+// readPromptFile returns the content of the prompt file name under root,
+// trimmed, or "" if it doesn't exist
+func readPromptFile(root, name string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(root, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// composePrompt builds the prompt with a codebase section of at most maxFiles
+// files, or none if skipCodebase. A non-empty scope limits the section and the
+// allowed changes to that directory. The stats are nil when there is no
+// codebase section.
+func (c *Client) composePrompt(issueTitle, issueBody string, referencedFiles []*ctxloader.FileReference, branch *BranchChanges, scope string, maxFiles int, skipCodebase bool) (string, *ctxloader.CodebaseStats, error) {
 	var sb strings.Builder
+	var codebaseStats *ctxloader.CodebaseStats
 
 	sb.WriteString("You are an expert software developer. Given a GitHub issue, analyze the codebase and implement the necessary changes.\n\n")
 
+	prefix, err := readPromptFile(c.root, PromptPrefixFile)
+	if err != nil {
+		return "", nil, err
+	}
+	suffix, err := readPromptFile(c.root, PromptSuffixFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if prefix != "" {
+		sb.WriteString(prefix)
+		sb.WriteString("\n\n")
+	}
+
 	// Issue information
 	sb.WriteString("## Issue Title\n")
 	sb.WriteString(issueTitle)
@@ -133,27 +567,66 @@ func (c *Client) buildPrompt(issueTitle, issueBody string, referencedFiles []*ct
 	sb.WriteString("\n\n")
 
 	// Referenced files (from @mentions)
+	redacted := false
 	if len(referencedFiles) > 0 {
 		sb.WriteString(ctxloader.BuildReferencedFilesSection(referencedFiles))
+		for _, f := range referencedFiles {
+			redacted = redacted || f.Redactions > 0
+		}
 	}
 
-	// Full codebase context
-	sb.WriteString("## Current Codebase\n\n")
+	switch {
+	case branch != nil:
+		sb.WriteString(buildChangesSection(branch, referencedFiles))
+		redacted = redacted || branch.Redactions > 0
+		for _, f := range branch.Files {
+			redacted = redacted || f.Redactions > 0
+		}
+	case skipCodebase:
+		sb.WriteString("## Current Codebase\n\n")
+		sb.WriteString("The codebase is not included; rely on the issue and the referenced files above.")
+		sb.WriteString(" Only modify or delete files whose full content you have been shown, since modified files")
+		sb.WriteString(" are replaced with the content you return. New files may be created anywhere.\n")
+	default:
+		// Full codebase context
+		sb.WriteString("## Current Codebase\n\n")
+
+		// Build exclude list from referenced files
+		excludeFiles := make([]string, 0)
+		for _, f := range referencedFiles {
+			if !f.Found {
+				continue
+			}
+			if f.ResolvedPath != "" {
+				excludeFiles = append(excludeFiles, f.ResolvedPath)
+			} else {
+				excludeFiles = append(excludeFiles, f.Path)
+			}
+		}
 
-	// Build exclude list from referenced files
-	excludeFiles := make([]string, 0)
-	for _, f := range referencedFiles {
-		if f.Found {
-			excludeFiles = append(excludeFiles, f.Path)
+		dirs := c.codebaseDirs
+		if scope != "" {
+			dirs = []string{scope}
+		}
+		codebase, stats, err := ctxloader.BuildCodebaseSectionWithOptions(c.root, ctxloader.CodebaseOptions{
+			Dirs:          dirs,
+			ExcludeFiles:  excludeFiles,
+			RedactSecrets: c.redactSecrets,
+			MaxFiles:      maxFiles,
+			Keywords:      ctxloader.IssueKeywords(issueTitle + "\n" + issueBody),
+		})
+		if err != nil {
+			return "", nil, err
 		}
+		sb.WriteString(codebase)
+		redacted = redacted || stats.Redactions > 0
+		codebaseStats = &stats
 	}
 
-	codebase, err := ctxloader.BuildCodebaseSection(".", excludeFiles)
-	if err != nil {
-		return "", err
+	if suffix != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(suffix)
 	}
-	sb.WriteString(codebase)
-
 	sb.WriteString("\n\n")
 	sb.WriteString("Please analyze this issue and provide the necessary code changes.")
 	sb.WriteString(" Pay special attention to the referenced files mentioned with @ in the issue.\n\n")
@@ -175,22 +648,169 @@ func (c *Client) buildPrompt(issueTitle, issueBody string, referencedFiles []*ct
 	if len(referencedFiles) > 0 {
 		sb.WriteString("- The @referenced files are particularly relevant to this issue\n")
 	}
+	if scope != "" {
+		sb.WriteString("- This issue is limited to " + scope + "/; only create, modify or delete files under it, since changes elsewhere are rejected\n")
+	}
+	if redacted {
+		sb.WriteString("- Secrets in the files above were replaced with " + ctxloader.Redacted + "; never write " + ctxloader.Redacted + " into a file\n")
+	}
 	sb.WriteString("\nRespond ONLY with the JSON array, no other text.")
 
-	return sb.String(), nil
+	return sb.String(), codebaseStats, nil
+}
+
+// buildChangesSection describes the branch's existing work: the diff, then the
+// full content of each changed file not already shown as a referenced file
+func buildChangesSection(changes *BranchChanges, referencedFiles []*ctxloader.FileReference) string {
+	shown := make(map[string]bool)
+	for _, f := range referencedFiles {
+		if f.Found {
+			shown[f.Path] = true
+			shown[f.ResolvedPath] = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Changes on This Branch\n\n")
+	sb.WriteString(fmt.Sprintf("The branch already contains the changes below (diff against %s).", changes.Base))
+	sb.WriteString(" The rest of the codebase is not included; build on these changes, and only modify or delete")
+	sb.WriteString(" files whose full content you have been shown. New files may be created anywhere.\n\n")
+	sb.WriteString("```diff\n")
+	sb.WriteString(strings.TrimSuffix(changes.Diff, "\n"))
+	sb.WriteString("\n```\n")
+
+	for _, f := range changes.Files {
+		if shown[f.Path] {
+			continue
+		}
+		if !f.Found {
+			sb.WriteString(fmt.Sprintf("\n### %s\n**File skipped: %s**\n", f.Path, f.Reason))
+			continue
+		}
+		header := f.Path
+		if f.Truncated {
+			header += fmt.Sprintf(" (truncated at %d bytes)", len(f.Content))
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s\n```\n%s\n```\n", header, f.Content))
+	}
+	return sb.String()
+}
+
+// maxSummaryFileBytes caps how much of each changed file is sent to Summarize
+const maxSummaryFileBytes = 8 * 1024
+
+// Summarize asks the model for a short bullet list describing what changes do,
+// for PR descriptions and issue comments
+func (c *Client) Summarize(ctx stdctx.Context, changes []FileChange) (string, error) {
+	if len(changes) == 0 {
+		return "", fmt.Errorf("no changes to summarize")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("You are reviewing a code change. Summarize what it does for the pull request description.\n\n")
+	writeChangedFiles(&sb, changes)
+	sb.WriteString("Respond with 2 to 6 concise Markdown bullet points (\"- ...\") describing the behavior ")
+	sb.WriteString("the change adds or fixes, not a file-by-file listing. No headings or other text.")
+
+	summary, err := c.sendMessage(ctx, sb.String())
+	if err != nil {
+		return "", err
+	}
+
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	return summary, nil
 }
 
-// ResolveConflict resolves a git merge conflict using Claude
+// CommitMessage asks the model for a Conventional Commits message, e.g.
+// "fix(auth): refresh expired tokens", for changes made for an issue. A
+// non-empty commitType is required as the subject's type. The subject is not
+// validated here.
+func (c *Client) CommitMessage(ctx stdctx.Context, issueTitle, commitType string, changes []FileChange) (string, error) {
+	if len(changes) == 0 {
+		return "", fmt.Errorf("no changes to describe")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("You are committing a code change. Write its commit message in the Conventional Commits format.\n\n")
+	sb.WriteString("## Issue Title\n")
+	sb.WriteString(issueTitle)
+	sb.WriteString("\n\n")
+	writeChangedFiles(&sb, changes)
+	sb.WriteString("Rules:\n")
+	sb.WriteString("- The first line is \"type(scope): description\", at most 72 characters; the scope is optional and lower case\n")
+	if commitType != "" {
+		sb.WriteString(fmt.Sprintf("- The type is %s\n", commitType))
+	} else {
+		sb.WriteString("- The type is one of feat, fix, docs, style, refactor, perf, test, build, ci or chore\n")
+	}
+	sb.WriteString("- The description is in the imperative mood, starts lower case and has no trailing period\n")
+	sb.WriteString("- Optionally add a blank line and a body of up to three lines, wrapped at 72 characters, saying why\n\n")
+	sb.WriteString("Respond with the commit message only, no code fences or other text.")
+
+	message, err := c.sendMessage(ctx, sb.String())
+	if err != nil {
+		return "", err
+	}
+
+	message = strings.TrimSpace(stripCodeFence(message))
+	if message == "" {
+		return "", fmt.Errorf("model returned an empty commit message")
+	}
+	return message, nil
+}
+
+// writeChangedFiles writes each change for the model, capping file content at
+// maxSummaryFileBytes
+func writeChangedFiles(sb *strings.Builder, changes []FileChange) {
+	sb.WriteString("## Changed Files\n\n")
+	for _, change := range changes {
+		if change.Operation == "delete" {
+			sb.WriteString(fmt.Sprintf("### %s (deleted)\n\n", change.Path))
+			continue
+		}
+		content := change.Content
+		if len(content) > maxSummaryFileBytes {
+			content = content[:maxSummaryFileBytes] + "\n... (truncated)"
+		}
+		sb.WriteString(fmt.Sprintf("### %s (%s)\n```\n%s\n```\n\n", change.Path, change.Operation, content))
+	}
+}
+
+// ConflictVersions are the complete versions of a conflicted file on each side
+// of the merge. A version is empty when the file doesn't exist on that side,
+// as in a modify/delete conflict or when both sides added the file.
+type ConflictVersions struct {
+	Base   string // The common ancestor
+	Ours   string // The checked-out side (HEAD)
+	Theirs string // The side being merged in
+}
+
+// ResolveConflict resolves a git merge conflict using Claude.
+// If the model's answer still contains conflict markers it is asked once more with a
+// stricter prompt; a second marker-laden answer is returned as an error.
 func (c *Client) ResolveConflict(ctx stdctx.Context, filePath string, conflictContent string, issueTitle string) (string, error) {
+	return c.ResolveConflictWithVersions(ctx, filePath, conflictContent, issueTitle, nil)
+}
+
+// ResolveConflictWithVersions is ResolveConflict with the file's base, ours and
+// theirs versions added to the prompt, so the model can see what each side
+// changed. Nil versions leave them out.
+func (c *Client) ResolveConflictWithVersions(ctx stdctx.Context, filePath string, conflictContent string, issueTitle string, versions *ConflictVersions) (string, error) {
 	prompt := "You are an expert software developer. Resolve the following git merge conflict.\n\n" +
 		"## Context\n" +
 		"This conflict occurred while implementing: " + issueTitle + "\n\n" +
+		conflictVersionsSection(versions) +
 		"## Conflicted File: " + filePath + "\n" +
 		"```\n" + conflictContent + "\n```\n\n" +
 		"The conflict markers show:\n" +
 		"- `<<<<<<< HEAD` - Current branch changes\n" +
 		"- `=======` - Separator\n" +
 		"- `>>>>>>> branch-name` - Incoming changes from base branch\n\n" +
+		"The content may be an excerpt of the file surrounding a single conflict. " +
+		"If so, return the same excerpt with the conflict resolved, keeping the surrounding lines.\n\n" +
 		"Please resolve this conflict by:\n" +
 		"1. Keeping the best parts of both versions\n" +
 		"2. Ensuring the code is syntactically correct\n" +
@@ -198,81 +818,99 @@ func (c *Client) ResolveConflict(ctx stdctx.Context, filePath string, conflictCo
 		"4. Removing all conflict markers\n\n" +
 		"Respond ONLY with the resolved file content, no explanations or markdown formatting."
 
-	requestBody := map[string]interface{}{
-		"model":      c.model,
-		"max_tokens": 4096,
-		"messages": []map[string]interface{}{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
+	resolved, err := c.resolveConflictOnce(ctx, prompt)
 	if err != nil {
-		return "", fmt.Errorf("marshaling request: %w", err)
+		return "", err
 	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+	if !containsConflictMarkers(resolved) {
+		return resolved, nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-Key", c.apiKey)
-	req.Header.Set("Anthropic-Version", "2023-06-01")
+	// Retry once, making the marker requirement impossible to miss
+	strictPrompt := prompt + "\n\n" +
+		"IMPORTANT: Your previous answer still contained conflict markers " +
+		"(lines starting with `<<<<<<<` or `>>>>>>>`). The output MUST NOT contain any " +
+		"`<<<<<<<`, `=======` separator or `>>>>>>>` lines. Choose or combine the changes " +
+		"and output only the final resolved code."
 
-	resp, err := c.http.Do(req)
+	resolved, err = c.resolveConflictOnce(ctx, strictPrompt)
 	if err != nil {
-		return "", fmt.Errorf("calling Claude API: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("reading response: %w", err)
+	if containsConflictMarkers(resolved) {
+		return "", fmt.Errorf("resolution for %s still contains conflict markers after retry", filePath)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+	return resolved, nil
+}
+
+// conflictVersionsSection shows the full versions of a conflicted file for
+// reference, or nothing without versions
+func conflictVersionsSection(versions *ConflictVersions) string {
+	if versions == nil {
+		return ""
 	}
 
-	// Parse response
-	var result struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
+	var b strings.Builder
+	b.WriteString("## Full Versions of the File\n")
+	b.WriteString("For reference only; compare them to see what each side changed since the common ancestor.\n\n")
+	for _, v := range []struct{ name, content string }{
+		{"Base (common ancestor)", versions.Base},
+		{"Ours (HEAD)", versions.Ours},
+		{"Theirs (incoming)", versions.Theirs},
+	} {
+		b.WriteString("### " + v.name + "\n")
+		if v.content == "" {
+			b.WriteString("(The file does not exist on this side)\n\n")
+			continue
+		}
+		b.WriteString("```\n" + strings.TrimSuffix(v.content, "\n") + "\n```\n\n")
 	}
+	return b.String()
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("parsing response: %w", err)
+// resolveConflictOnce sends a conflict resolution prompt and cleans up the answer
+func (c *Client) resolveConflictOnce(ctx stdctx.Context, prompt string) (string, error) {
+	resolvedContent, err := c.sendMessage(ctx, prompt)
+	if err != nil {
+		return "", err
 	}
 
-	// Extract text content
-	var resolvedContent string
-	for _, block := range result.Content {
-		if block.Type == "text" {
-			resolvedContent += block.Text
-		}
+	// Clean up the response - remove markdown code blocks if present.
+	// Only surrounding blank lines are trimmed so the first line keeps its indentation.
+	return stripCodeFence(strings.Trim(resolvedContent, "\r\n")), nil
+}
+
+// stripCodeFence removes a Markdown code block wrapped around s
+func stripCodeFence(s string) string {
+	if !strings.HasPrefix(strings.TrimSpace(s), "```") {
+		return s
+	}
+	s = strings.TrimSpace(s)
+	lines := strings.Split(s, "\n")
+	if len(lines) > 2 {
+		// Remove first line (```language) and last line (```)
+		s = strings.Join(lines[1:len(lines)-1], "\n")
 	}
+	return s
+}
 
-	// Clean up the response - remove markdown code blocks if present
-	resolvedContent = strings.TrimSpace(resolvedContent)
-	if strings.HasPrefix(resolvedContent, "```") {
-		lines := strings.Split(resolvedContent, "\n")
-		if len(lines) > 2 {
-			// Remove first line (```language) and last line (```)
-			resolvedContent = strings.Join(lines[1:len(lines)-1], "\n")
+// containsConflictMarkers reports whether content has `<<<<<<<` or `>>>>>>>` marker lines
+func containsConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		for _, marker := range []string{"<<<<<<<", ">>>>>>>"} {
+			if line == marker || strings.HasPrefix(line, marker+" ") {
+				return true
+			}
 		}
 	}
-
-	return resolvedContent, nil
+	return false
 }
 
-// parseChangesFromResponse extracts the JSON array from Claude's response
-func parseChangesFromResponse(response string) ([]FileChange, error) {
+// ParseChanges extracts the JSON array of file changes from Claude's response.
+// A bare JSON array is accepted as well.
+func ParseChanges(response string) ([]FileChange, error) {
 	// Extract JSON code block if present
 	start := strings.Index(response, "[")
 	end := strings.LastIndex(response, "]")
@@ -0,0 +1,207 @@
+package claude
+
+import (
+	stdctx "context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"vibe-git/internal/httpclient"
+)
+
+const (
+	// maxImages bounds how many issue images are sent with one request
+	maxImages = 5
+	// maxImageBytes is the largest image the Messages API accepts
+	maxImageBytes = 5 * 1024 * 1024
+	// imageTimeout bounds each image download
+	imageTimeout = 30 * time.Second
+)
+
+var (
+	// markdownImage matches ![alt](url) and ![alt](url "title")
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// htmlImage matches <img src="url">, as GitHub inserts for pasted screenshots
+	htmlImage = regexp.MustCompile(`(?i)<img\s[^>]*src\s*=\s*["']([^"']+)["']`)
+)
+
+// supportedImageTypes are the media types the Messages API accepts for images
+var supportedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Image is a picture sent to the model alongside the prompt
+type Image struct {
+	URL       string
+	MediaType string
+	Data      []byte
+}
+
+// ExtractImageURLs returns the distinct http(s) image URLs embedded in an issue
+// body with Markdown or <img> tags, in order of appearance
+func ExtractImageURLs(body string) []string {
+	type match struct {
+		pos int
+		url string
+	}
+	var matches []match
+	for _, re := range []*regexp.Regexp{markdownImage, htmlImage} {
+		for _, m := range re.FindAllStringSubmatchIndex(body, -1) {
+			matches = append(matches, match{pos: m[0], url: body[m[2]:m[3]]})
+		}
+	}
+
+	// Keep the order of appearance across both syntaxes
+	sort.Slice(matches, func(i, j int) bool { return matches[i].pos < matches[j].pos })
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		u, err := url.Parse(m.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || seen[m.url] {
+			continue
+		}
+		seen[m.url] = true
+		urls = append(urls, m.url)
+	}
+	return urls
+}
+
+// SetIncludeImages makes GenerateCode download the images in the issue body and
+// send them with the prompt. githubToken, if set, authenticates downloads from
+// GitHub, which private repositories' attachments require.
+func (c *Client) SetIncludeImages(include bool, githubToken string) {
+	c.includeImages = include
+	c.imageToken = githubToken
+}
+
+// SetImageTokenSource makes attachment downloads authenticate with a token
+// fetched from source at download time, such as a GitHub App installation token
+func (c *Client) SetImageTokenSource(source func(stdctx.Context) (string, error)) {
+	c.imageTokenSource = source
+}
+
+// SetImageReport sets a func told about every image download: err is nil when
+// the image will be sent and says why it was skipped otherwise
+func (c *Client) SetImageReport(report func(url string, err error)) {
+	c.imageReport = report
+}
+
+// loadImages downloads the images in body. Failures are reported and skipped so
+// a broken link never blocks generation.
+func (c *Client) loadImages(ctx stdctx.Context, body string) []Image {
+	urls := ExtractImageURLs(body)
+	if len(urls) > maxImages {
+		for _, u := range urls[maxImages:] {
+			c.reportImage(u, fmt.Errorf("more than %d images in the issue", maxImages))
+		}
+		urls = urls[:maxImages]
+	}
+
+	var images []Image
+	for _, u := range urls {
+		img, err := c.downloadImage(ctx, u)
+		c.reportImage(u, err)
+		if err == nil {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+func (c *Client) reportImage(url string, err error) {
+	if c.imageReport != nil {
+		c.imageReport(url, err)
+	}
+}
+
+// downloadImage fetches one image and checks it can be sent to the model
+func (c *Client) downloadImage(ctx stdctx.Context, rawURL string) (Image, error) {
+	headers, err := c.imageHeaders(ctx, rawURL)
+	if err != nil {
+		return Image{}, err
+	}
+	opts := &httpclient.RequestOptions{Timeout: imageTimeout, Headers: headers}
+
+	resp, err := httpclient.NewClient("").Get(ctx, rawURL, opts)
+	if err != nil {
+		return Image{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Image{}, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	if len(resp.Body) > maxImageBytes {
+		return Image{}, fmt.Errorf("image is %d bytes, over the %d byte limit", len(resp.Body), maxImageBytes)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers["Content-Type"])
+	if !supportedImageTypes[mediaType] {
+		// Attachment hosts often answer application/octet-stream; trust the bytes
+		mediaType = http.DetectContentType(resp.Body)
+	}
+	if !supportedImageTypes[mediaType] {
+		return Image{}, fmt.Errorf("unsupported image type %s", mediaType)
+	}
+
+	return Image{URL: rawURL, MediaType: mediaType, Data: resp.Body}, nil
+}
+
+// imageHeaders returns the headers a download of rawURL is sent with: the
+// GitHub token for GitHub attachments, and none for other hosts
+func (c *Client) imageHeaders(ctx stdctx.Context, rawURL string) (map[string]string, error) {
+	if !isGitHubHost(rawURL) {
+		return nil, nil
+	}
+	token := c.imageToken
+	if c.imageTokenSource != nil {
+		var err error
+		if token, err = c.imageTokenSource(ctx); err != nil {
+			return nil, fmt.Errorf("getting GitHub token: %w", err)
+		}
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// isGitHubHost reports whether rawURL is served by GitHub, so the token may be sent
+func isGitHubHost(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "github.com" || strings.HasSuffix(host, ".githubusercontent.com")
+}
+
+// messageContent is the user message content: the prompt alone, or the images
+// followed by the prompt as content blocks
+func messageContent(prompt string, images []Image) interface{} {
+	if len(images) == 0 {
+		return prompt
+	}
+
+	blocks := make([]map[string]interface{}, 0, len(images)+1)
+	for _, img := range images {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "image",
+			"source": map[string]string{
+				"type":       "base64",
+				"media_type": img.MediaType,
+				"data":       base64.StdEncoding.EncodeToString(img.Data),
+			},
+		})
+	}
+	blocks = append(blocks, map[string]interface{}{"type": "text", "text": prompt})
+	return blocks
+}
@@ -0,0 +1,163 @@
+package claude
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// contentBlock is a user message content block as the Messages API receives it
+type contentBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Source struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+	} `json:"source"`
+}
+
+// stubVision serves images under /img/ and answers /v1/messages, recording the
+// raw content of each user message
+func stubVision(t *testing.T) (*httptest.Server, *[]json.RawMessage) {
+	t.Helper()
+	var contents []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img/screenshot.png":
+			// Served without a useful type, like many attachment hosts
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngHeader)
+		case "/img/notes.txt":
+			w.Write([]byte("not an image"))
+		case "/v1/messages":
+			var req struct {
+				Messages []struct {
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decoding request: %v", err)
+			}
+			contents = append(contents, req.Messages[0].Content)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": `[{"path":"a.go","operation":"create","content":"package a"}]`}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &contents
+}
+
+func TestExtractImageURLs(t *testing.T) {
+	body := "Broken layout:\n" +
+		`<img width="600" alt="menu" src="https://github.com/user-attachments/assets/abc">` + "\n" +
+		"![before](https://example.com/before.png \"Before\") and ![after](<https://example.com/after.png>)\n" +
+		"![local](docs/local.png) ![again](https://example.com/before.png)"
+
+	want := []string{
+		"https://github.com/user-attachments/assets/abc",
+		"https://example.com/before.png",
+		"https://example.com/after.png",
+	}
+	if got := ExtractImageURLs(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractImageURLs = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateCodeSendsIssueImages(t *testing.T) {
+	server, contents := stubVision(t)
+	client := NewClient("key", server.URL, "test-model")
+	client.SetSkipCodebase(true)
+	client.SetIncludeImages(true, "")
+
+	reports := map[string]error{}
+	client.SetImageReport(func(url string, err error) { reports[url] = err })
+
+	body := "The menu overlaps the header:\n\n![screenshot](" + server.URL + "/img/screenshot.png)\n" +
+		"![missing](" + server.URL + "/img/missing.png)\n![notes](" + server.URL + "/img/notes.txt)"
+	if _, err := client.GenerateCode(context.Background(), "Fix menu", body, nil); err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal((*contents)[0], &blocks); err != nil {
+		t.Fatalf("expected content blocks, got %s", (*contents)[0])
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected one image and the prompt, got %d blocks", len(blocks))
+	}
+	img, text := blocks[0], blocks[1]
+	if img.Type != "image" || img.Source.Type != "base64" || img.Source.MediaType != "image/png" {
+		t.Errorf("unexpected image block %+v", img)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(img.Source.Data); string(data) != string(pngHeader) {
+		t.Error("image data was not sent intact")
+	}
+	if text.Type != "text" || !strings.Contains(text.Text, "## Issue Title\nFix menu") {
+		t.Errorf("expected the prompt after the image, got %+v", text)
+	}
+
+	// Failed downloads are reported and skipped rather than failing generation
+	if err := reports[server.URL+"/img/screenshot.png"]; err != nil {
+		t.Errorf("screenshot: unexpected error %v", err)
+	}
+	if err := reports[server.URL+"/img/missing.png"]; err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing.png: expected a 404 error, got %v", err)
+	}
+	if err := reports[server.URL+"/img/notes.txt"]; err == nil || !strings.Contains(err.Error(), "unsupported image type") {
+		t.Errorf("notes.txt: expected an unsupported type error, got %v", err)
+	}
+}
+
+func TestGenerateCodeWithoutIncludeImagesSendsText(t *testing.T) {
+	server, contents := stubVision(t)
+	client := NewClient("key", server.URL, "test-model")
+	client.SetSkipCodebase(true)
+
+	body := "![screenshot](" + server.URL + "/img/screenshot.png)"
+	if _, err := client.GenerateCode(context.Background(), "Fix menu", body, nil); err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	var prompt string
+	if err := json.Unmarshal((*contents)[0], &prompt); err != nil {
+		t.Errorf("expected plain text content without --include-images, got %s", (*contents)[0])
+	}
+}
+
+func TestImageHeadersUseTokenSource(t *testing.T) {
+	client := NewClient("key", "", "test-model")
+	client.SetIncludeImages(true, "")
+	attachment := "https://github.com/user-attachments/assets/abc"
+
+	if headers, err := client.imageHeaders(context.Background(), attachment); err != nil || headers != nil {
+		t.Errorf("expected no auth without a token, got %v (err %v)", headers, err)
+	}
+
+	// GitHub App auth has no PAT; the installation token is fetched per download
+	client.SetImageTokenSource(func(context.Context) (string, error) { return "ghs_installation", nil })
+	headers, err := client.imageHeaders(context.Background(), attachment)
+	if err != nil || headers["Authorization"] != "Bearer ghs_installation" {
+		t.Errorf("expected the installation token, got %v (err %v)", headers, err)
+	}
+	if headers, _ := client.imageHeaders(context.Background(), "https://example.com/a.png"); headers != nil {
+		t.Errorf("expected no token sent to other hosts, got %v", headers)
+	}
+
+	client.SetImageTokenSource(func(context.Context) (string, error) { return "", errors.New("app key rejected") })
+	if _, err := client.imageHeaders(context.Background(), attachment); err == nil || !strings.Contains(err.Error(), "app key rejected") {
+		t.Errorf("expected the token error, got %v", err)
+	}
+}
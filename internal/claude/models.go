@@ -0,0 +1,92 @@
+package claude
+
+import (
+	stdctx "context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultModel is the model used when none is configured
+const DefaultModel = "claude-3-5-sonnet-latest"
+
+// Model is a model offered by the API
+type Model struct {
+	ID          string    `json:"id"`
+	DisplayName string    `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListModels returns every model the API offers, newest first, following the
+// API's pagination
+func (c *Client) ListModels(ctx stdctx.Context) ([]Model, error) {
+	var models []Model
+	query := url.Values{"limit": {"1000"}}
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		c.setHeaders(ctx, req)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("calling Claude API: %w", err)
+		}
+		body, err := c.readResponse(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+
+		var page struct {
+			Data    []Model `json:"data"`
+			HasMore bool    `json:"has_more"`
+			LastID  string  `json:"last_id"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		models = append(models, page.Data...)
+
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		query.Set("after_id", page.LastID)
+	}
+}
+
+// GetModel looks up a model by ID or alias, such as claude-opus-4-1 or a
+// -latest alias, returning the model it resolves to. An unknown model is an
+// APIError with status 404.
+func (c *Client) GetModel(ctx stdctx.Context, id string) (*Model, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1/models/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.setHeaders(ctx, req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := c.readResponse(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var model Model
+	if err := json.Unmarshal(body, &model); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &model, nil
+}
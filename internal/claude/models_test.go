@@ -0,0 +1,67 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListModelsFollowsPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("X-Api-Key") != "key" {
+			t.Errorf("unexpected request %s with key %q", r.URL.Path, r.Header.Get("X-Api-Key"))
+		}
+		switch r.URL.Query().Get("after_id") {
+		case "":
+			fmt.Fprint(w, `{"data": [{"id": "claude-new", "display_name": "Claude New", "created_at": "2025-02-01T00:00:00Z"}], "has_more": true, "last_id": "claude-new"}`)
+		case "claude-new":
+			fmt.Fprint(w, `{"data": [{"id": "claude-old", "display_name": "Claude Old", "created_at": "2024-01-01T00:00:00Z"}], "has_more": false, "last_id": "claude-old"}`)
+		default:
+			t.Errorf("unexpected after_id %q", r.URL.Query().Get("after_id"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	models, err := NewClient("key", server.URL, "").ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "claude-new" || models[1].DisplayName != "Claude Old" || models[1].CreatedAt.Year() != 2024 {
+		t.Errorf("unexpected models %+v", models)
+	}
+}
+
+func TestListModelsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error"}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := NewClient("bad", server.URL, "").ListModels(context.Background())
+	if apiErr, ok := err.(*APIError); !ok || !apiErr.IsAuthError() {
+		t.Errorf("expected an auth APIError, got %v", err)
+	}
+}
+
+func TestGetModelResolvesAlias(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models/claude-opus-4-1" {
+			http.Error(w, `{"type":"error"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id": "claude-opus-4-1-20250805", "display_name": "Claude Opus 4.1"}`)
+	}))
+	t.Cleanup(server.Close)
+	cl := NewClient("key", server.URL, "")
+
+	model, err := cl.GetModel(context.Background(), "claude-opus-4-1")
+	if err != nil || model.ID != "claude-opus-4-1-20250805" {
+		t.Errorf("expected the alias to resolve, got %+v, %v", model, err)
+	}
+	_, err = cl.GetModel(context.Background(), "claude-opsu")
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 APIError, got %v", err)
+	}
+}
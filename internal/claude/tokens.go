@@ -0,0 +1,185 @@
+package claude
+
+import (
+	"bytes"
+	stdctx "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"vibe-git/internal/ctxloader"
+)
+
+// ContextWindow is how many tokens, input and output together, a Claude
+// model handles in one request
+const ContextWindow = 200000
+
+// maxOutputTokens is the max_tokens sent with every message
+const maxOutputTokens = 4096
+
+// ErrPromptTooLong is returned when a checked prompt leaves no room for the
+// response in the model's context window
+var ErrPromptTooLong = errors.New("prompt too long for the model's context window")
+
+// SetCheckTokens makes generation count each prompt's tokens before sending it
+// and fail with ErrPromptTooLong instead of sending one that can't fit (see
+// SetAutoTrim to shrink it instead). This costs one extra API call per
+// generation.
+func (c *Client) SetCheckTokens(check bool) {
+	c.checkTokens = check
+}
+
+// SetTokenReport sets a func told the token count of every checked prompt, or
+// why it couldn't be counted
+func (c *Client) SetTokenReport(report func(tokens int, err error)) {
+	c.tokenReport = report
+}
+
+// CountTokens returns how many input tokens prompt takes as a user message,
+// using the API's token counting endpoint
+func (c *Client) CountTokens(ctx stdctx.Context, prompt string) (int, error) {
+	return c.countTokens(ctx, prompt, nil)
+}
+
+func (c *Client) countTokens(ctx stdctx.Context, prompt string, images []Image) (int, error) {
+	requestBody := map[string]interface{}{
+		"model": c.model,
+		"messages": []map[string]interface{}{
+			{
+				"role":    "user",
+				"content": messageContent(prompt, images),
+			},
+		},
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return 0, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages/count_tokens", bytes.NewReader(jsonBody))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(ctx, req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("calling Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := c.readResponse(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("parsing response: %w", err)
+	}
+	return result.InputTokens, nil
+}
+
+// maxTrimAttempts bounds how many smaller codebase sections fitPrompt tries
+// before leaving the codebase out altogether
+const maxTrimAttempts = 4
+
+// TrimStats describes how an oversized prompt was cut down to fit
+type TrimStats struct {
+	FilesKept    int // Codebase files left in the prompt
+	FilesDropped int // Codebase files removed to make it fit
+	Tokens       int // Size of the trimmed prompt
+}
+
+// SetAutoTrim makes generation count each prompt's tokens and, when a prompt
+// doesn't fit, drop the least relevant codebase files until it does. The issue
+// and referenced files are always kept. Each count is one extra API call.
+func (c *Client) SetAutoTrim(trim bool) {
+	c.autoTrim = trim
+}
+
+// SetTrimReport sets a func told how every trimmed prompt was cut down
+func (c *Client) SetTrimReport(report func(TrimStats)) {
+	c.trimReport = report
+}
+
+// promptBuilder rebuilds a generation prompt with at most maxFiles codebase
+// files, or none if skipCodebase
+type promptBuilder func(maxFiles int, skipCodebase bool) (string, *ctxloader.CodebaseStats, error)
+
+// fitPrompt checks that prompt leaves room for the response when token
+// checking or trimming is on. Without room it fails with ErrPromptTooLong or,
+// with auto-trim, rebuilds it with fewer codebase files until it fits. A prompt
+// that can't be counted is reported and sent anyway.
+func (c *Client) fitPrompt(ctx stdctx.Context, prompt string, stats *ctxloader.CodebaseStats, images []Image, build promptBuilder) (string, error) {
+	if !c.checkTokens && !c.autoTrim {
+		return prompt, nil
+	}
+
+	tokens, err := c.countTokens(ctx, prompt, images)
+	c.reportTokens(tokens, err)
+	if err != nil {
+		return prompt, nil
+	}
+
+	budget := ContextWindow - maxOutputTokens
+	if tokens <= budget {
+		return prompt, nil
+	}
+	if !c.autoTrim || stats == nil {
+		return "", promptTooLong(tokens, c.autoTrim)
+	}
+
+	kept := stats.FilesIncluded
+	for attempt := 1; tokens > budget; attempt++ {
+		if kept == 0 {
+			return "", promptTooLong(tokens, true)
+		}
+		// Assume tokens shrink with the file count, and aim a little under
+		next := kept * budget / tokens * 9 / 10
+		if next >= kept {
+			next = kept - 1
+		}
+		if attempt == maxTrimAttempts {
+			next = 0
+		}
+		kept = next
+
+		if prompt, _, err = build(kept, kept == 0); err != nil {
+			return "", fmt.Errorf("building trimmed prompt: %w", err)
+		}
+		if tokens, err = c.countTokens(ctx, prompt, images); err != nil {
+			return "", fmt.Errorf("counting trimmed prompt: %w", err)
+		}
+	}
+
+	if c.trimReport != nil {
+		c.trimReport(TrimStats{FilesKept: kept, FilesDropped: stats.FilesIncluded - kept, Tokens: tokens})
+	}
+	return prompt, nil
+}
+
+func (c *Client) reportTokens(tokens int, err error) {
+	if c.tokenReport != nil {
+		c.tokenReport(tokens, err)
+	}
+}
+
+// promptTooLong describes a prompt of tokens that doesn't fit, suggesting
+// --auto-trim unless it was already tried
+func promptTooLong(tokens int, trimmed bool) error {
+	hint := "narrow it with --max-files, --codebase-only-dirs or --no-codebase, or pass --auto-trim"
+	if trimmed {
+		hint = "even without the codebase; shorten the issue or its referenced files"
+	}
+	return fmt.Errorf("%w: %d tokens plus %d for the response exceed %d (%s)",
+		ErrPromptTooLong, tokens, maxOutputTokens, ContextWindow, hint)
+}
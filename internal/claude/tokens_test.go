@@ -0,0 +1,164 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/ctxloader"
+)
+
+// stubTokens serves count_tokens with count's answer for the prompt (or status,
+// if not 200) and answers /v1/messages with a single file change, recording the
+// prompts sent
+func stubTokens(t *testing.T, count func(prompt string) int, status int) (*Client, *[]string) {
+	t.Helper()
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/messages/count_tokens":
+			if r.Header.Get("X-Api-Key") != "key" || r.Header.Get("Anthropic-Version") == "" {
+				t.Errorf("count_tokens sent without API headers: %v", r.Header)
+			}
+			req := decodeMessage(t, r)
+			if req.Model != "test-model" {
+				t.Errorf("count_tokens sent for model %q", req.Model)
+			}
+			if status != http.StatusOK {
+				http.Error(w, `{"type":"error"}`, status)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]int{"input_tokens": count(req.Messages[0].Content)})
+		case "/v1/messages":
+			sent = append(sent, decodeMessage(t, r).Messages[0].Content)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": `[{"path":"a.go","operation":"create","content":"package a"}]`}},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cl := NewClient("key", server.URL, "test-model")
+	cl.SetSkipCodebase(true)
+	return cl, &sent
+}
+
+// tokens returns a count func that always answers n
+func tokens(n int) func(string) int {
+	return func(string) int { return n }
+}
+
+type messageRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func decodeMessage(t *testing.T, r *http.Request) messageRequest {
+	var req messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 1 {
+		t.Errorf("unexpected request to %s: %+v (err %v)", r.URL.Path, req, err)
+		req.Messages = append(req.Messages, struct {
+			Content string `json:"content"`
+		}{})
+	}
+	return req
+}
+
+func TestCountTokens(t *testing.T) {
+	cl, _ := stubTokens(t, tokens(1234), http.StatusOK)
+	tokens, err := cl.CountTokens(context.Background(), "Hello")
+	if err != nil || tokens != 1234 {
+		t.Errorf("expected 1234 tokens, got %d (err %v)", tokens, err)
+	}
+}
+
+func TestGenerateCodeChecksTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokens    int
+		status    int
+		wantErr   error
+		wantSent  int
+		reportErr bool
+	}{
+		{"fits", 1000, http.StatusOK, nil, 1, false},
+		{"too long", ContextWindow - 100, http.StatusOK, ErrPromptTooLong, 0, false},
+		{"count fails", 0, http.StatusNotFound, nil, 1, true},
+	}
+	for _, tt := range tests {
+		cl, sent := stubTokens(t, tokens(tt.tokens), tt.status)
+		cl.SetCheckTokens(true)
+		var reported int
+		var reportedErr error
+		cl.SetTokenReport(func(tokens int, err error) { reported, reportedErr = tokens, err })
+
+		_, err := cl.GenerateCode(context.Background(), "Add a", "", nil)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+		if len(*sent) != tt.wantSent {
+			t.Errorf("%s: expected %d messages sent, got %d", tt.name, tt.wantSent, len(*sent))
+		}
+		if (reportedErr != nil) != tt.reportErr || (!tt.reportErr && reported != tt.tokens) {
+			t.Errorf("%s: reported %d tokens (err %v)", tt.name, reported, reportedErr)
+		}
+	}
+
+	// Without checking, nothing is counted
+	cl, sent := stubTokens(t, tokens(ContextWindow), http.StatusOK)
+	if _, err := cl.GenerateCode(context.Background(), "Add a", "", nil); err != nil || len(*sent) != 1 {
+		t.Errorf("expected generation without a count, got %v (%d sent)", err, len(*sent))
+	}
+}
+
+func TestGenerateCodeAutoTrim(t *testing.T) {
+	// Every codebase file costs 40,000 tokens, so the package's files don't fit
+	perFile := func(prompt string) int { return 1000 + 40000*strings.Count(prompt, "\n// File: ") }
+	cl, sent := stubTokens(t, perFile, http.StatusOK)
+	cl.SetSkipCodebase(false)
+	cl.SetAutoTrim(true)
+	var trim TrimStats
+	cl.SetTrimReport(func(s TrimStats) { trim = s })
+
+	refs := []*ctxloader.FileReference{{Path: "notes.md", Content: "keep these notes", Found: true}}
+	if _, err := cl.GenerateCode(context.Background(), "Add dark mode", "Users want a dark theme", refs); err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	prompt := (*sent)[0]
+	if perFile(prompt) > ContextWindow-maxOutputTokens {
+		t.Errorf("sent a prompt of %d tokens", perFile(prompt))
+	}
+	for _, want := range []string{"Add dark mode", "Users want a dark theme", "keep these notes", "more files were omitted"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected the trimmed prompt to contain %q", want)
+		}
+	}
+	if trim.FilesKept == 0 || trim.FilesDropped == 0 || trim.FilesKept != strings.Count(prompt, "\n// File: ") || trim.Tokens != perFile(prompt) {
+		t.Errorf("unexpected trim report %+v", trim)
+	}
+
+	// Without auto-trim the same prompt is refused
+	cl, sent = stubTokens(t, perFile, http.StatusOK)
+	cl.SetSkipCodebase(false)
+	cl.SetCheckTokens(true)
+	if _, err := cl.GenerateCode(context.Background(), "Add dark mode", "", nil); !errors.Is(err, ErrPromptTooLong) || len(*sent) != 0 {
+		t.Errorf("expected ErrPromptTooLong without sending, got %v (%d sent)", err, len(*sent))
+	}
+
+	// A prompt too long without any codebase can't be trimmed
+	cl, sent = stubTokens(t, tokens(ContextWindow), http.StatusOK)
+	cl.SetSkipCodebase(false)
+	cl.SetAutoTrim(true)
+	if _, err := cl.GenerateCode(context.Background(), "Add dark mode", "", nil); !errors.Is(err, ErrPromptTooLong) || len(*sent) != 0 {
+		t.Errorf("expected ErrPromptTooLong without sending, got %v (%d sent)", err, len(*sent))
+	}
+}
@@ -0,0 +1,103 @@
+// Package commitmsg builds and checks Conventional Commits messages
+// (https://www.conventionalcommits.org), as semantic-release and commit linters
+// expect them.
+package commitmsg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSubjectLength is the longest subject line commit linters accept by default
+const MaxSubjectLength = 72
+
+// Types are the commit types accepted in a subject
+var Types = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// subjectPattern is "type(scope)!: description", with scope and ! optional
+var subjectPattern = regexp.MustCompile(`^(` + strings.Join(Types, "|") + `)(\([a-z0-9][a-z0-9._/-]*\))?!?: \S`)
+
+// labelTypes maps common issue labels to commit types
+var labelTypes = map[string]string{
+	"bug":           "fix",
+	"fix":           "fix",
+	"regression":    "fix",
+	"enhancement":   "feat",
+	"feature":       "feat",
+	"documentation": "docs",
+	"docs":          "docs",
+	"refactor":      "refactor",
+	"refactoring":   "refactor",
+	"performance":   "perf",
+	"perf":          "perf",
+	"test":          "test",
+	"tests":         "test",
+	"ci":            "ci",
+	"build":         "build",
+	"dependencies":  "build",
+	"chore":         "chore",
+}
+
+// TypeForLabels returns the commit type implied by the first recognised issue
+// label, or "" when no label says. Labels like "type: bug" count as "bug".
+func TypeForLabels(labels []string) string {
+	for _, label := range labels {
+		name := strings.ToLower(strings.TrimSpace(label))
+		if i := strings.LastIndexAny(name, ":/"); i >= 0 {
+			name = strings.TrimSpace(name[i+1:])
+		}
+		if t, ok := labelTypes[name]; ok {
+			return t
+		}
+	}
+	return ""
+}
+
+// Validate checks that subject is a Conventional Commits subject line no longer
+// than MaxSubjectLength
+func Validate(subject string) error {
+	if strings.Contains(subject, "\n") {
+		return fmt.Errorf("subject spans several lines")
+	}
+	if n := utf8.RuneCountInString(subject); n > MaxSubjectLength {
+		return fmt.Errorf("subject is %d characters, over the %d character limit", n, MaxSubjectLength)
+	}
+	if !subjectPattern.MatchString(subject) {
+		return fmt.Errorf("subject %q is not of the form \"type(scope): description\"", subject)
+	}
+	return nil
+}
+
+// Subject builds "type: description" from an issue title, shortened at a word
+// boundary to fit MaxSubjectLength
+func Subject(commitType, title string) string {
+	description := strings.Join(strings.Fields(title), " ")
+	description = strings.TrimRight(description, ".")
+	// Descriptions start lower case, except for acronyms like "API"
+	if r, size := utf8.DecodeRuneInString(description); size > 0 && !isUpperWord(description) {
+		description = strings.ToLower(string(r)) + description[size:]
+	}
+	if description == "" {
+		description = "address issue"
+	}
+
+	prefix := commitType + ": "
+	budget := MaxSubjectLength - utf8.RuneCountInString(prefix)
+	if utf8.RuneCountInString(description) > budget {
+		runes := []rune(description)[:budget]
+		cut := string(runes)
+		if i := strings.LastIndex(cut, " "); i > budget/2 {
+			cut = cut[:i]
+		}
+		description = strings.TrimRight(cut, " ,;:-")
+	}
+	return prefix + description
+}
+
+// isUpperWord reports whether s starts with a word of two or more capitals
+func isUpperWord(s string) bool {
+	word := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ':' })
+	return len(word) > 0 && len(word[0]) > 1 && strings.ToUpper(word[0]) == word[0]
+}
@@ -0,0 +1,67 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTypeForLabels(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   string
+	}{
+		{[]string{"bug"}, "fix"},
+		{[]string{"priority: high", "Type: Enhancement"}, "feat"},
+		{[]string{"kind/documentation"}, "docs"},
+		{[]string{"good first issue"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := TypeForLabels(tt.labels); got != tt.want {
+			t.Errorf("TypeForLabels(%q) = %q, want %q", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectFromBugIssue(t *testing.T) {
+	title := "Login fails with a 500 error when the session cookie has expired and the user is redirected back from SSO"
+	subject := Subject(TypeForLabels([]string{"bug"}), title)
+
+	if !strings.HasPrefix(subject, "fix: login fails") {
+		t.Errorf("expected a fix: subject, got %q", subject)
+	}
+	if n := utf8.RuneCountInString(subject); n > MaxSubjectLength {
+		t.Errorf("subject is %d characters: %q", n, subject)
+	}
+	if strings.HasSuffix(subject, " ") || strings.HasSuffix(subject, "redirec") {
+		t.Errorf("expected the subject cut at a word boundary, got %q", subject)
+	}
+	if err := Validate(subject); err != nil {
+		t.Errorf("Validate(%q): %v", subject, err)
+	}
+
+	if got := Subject("docs", "API reference is missing the pagination parameters."); got != "docs: API reference is missing the pagination parameters" {
+		t.Errorf("unexpected subject %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, ok := range []string{"fix: handle expired sessions", "feat(auth)!: drop legacy tokens", "ci(github/actions): cache modules"} {
+		if err := Validate(ok); err != nil {
+			t.Errorf("Validate(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{
+		"Fix issue #7: Crash on start",
+		"fix:missing space",
+		"fixed: past tense type",
+		"fix(Auth): upper case scope",
+		"fix: " + strings.Repeat("x", MaxSubjectLength),
+		"fix: two\nlines",
+	} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ctxloader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile lists files that are never sent to the model, in gitignore syntax,
+// relative to the repo root. It applies even to files git tracks.
+const IgnoreFile = ".vibe-git/ignore"
+
+// excludedReason is the FileReference.Reason for references blocked by IgnoreFile
+const excludedReason = "excluded by policy (" + IgnoreFile + ")"
+
+// IgnoreRules are the patterns read from IgnoreFile. The zero value ignores nothing.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes what earlier patterns excluded
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// LoadIgnoreRules reads IgnoreFile under root. A missing file means no rules.
+func LoadIgnoreRules(root string) (*IgnoreRules, error) {
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &IgnoreRules{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", IgnoreFile, err)
+	}
+	defer f.Close()
+
+	rules, err := ParseIgnoreRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", IgnoreFile, err)
+	}
+	return rules, nil
+}
+
+// ParseIgnoreRules parses gitignore-style lines: blank lines and # comments are
+// skipped, ! negates, a trailing / matches only directories and a / anywhere else
+// anchors the pattern to the root. *, ?, [...] and ** work as in .gitignore.
+func ParseIgnoreRules(r io.Reader) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		switch {
+		case strings.HasPrefix(line, "!"):
+			p.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(ignorePatternRegexp(line))
+		if err != nil {
+			// An unbalanced [ and the like; treat the pattern as a literal path
+			re = regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimPrefix(line, "/")) + "$")
+		}
+		p.re = re
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, scanner.Err()
+}
+
+// ignorePatternRegexp translates a gitignore pattern to a regexp over slash-separated repo-relative paths
+func ignorePatternRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	// Without a slash before the end, the pattern matches at any depth
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// Excluded reports whether the repo-relative path rel is excluded, either itself
+// or through one of its parent directories. As with git, a file inside an
+// excluded directory can't be re-included.
+func (r *IgnoreRules) Excluded(rel string, isDir bool) bool {
+	if r == nil || len(r.patterns) == 0 {
+		return false
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(rel, isDir)
+}
+
+// match applies the patterns to rel alone; the last matching pattern wins
+func (r *IgnoreRules) match(rel string, isDir bool) bool {
+	excluded := false
+	for _, p := range r.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// excludesPath reports whether path, as opened from the working directory, is a
+// file under repoRoot that the rules exclude
+func (r *IgnoreRules) excludesPath(repoRoot, path string) bool {
+	absRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return false
+	}
+	return r.Excluded(rel, false)
+}
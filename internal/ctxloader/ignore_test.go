@@ -0,0 +1,75 @@
+package ctxloader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRulesExcluded(t *testing.T) {
+	rules, err := ParseIgnoreRules(strings.NewReader(`
+# secrets and vendored code
+*.pem
+/secrets.env
+third_party/
+docs/**/*.pdf
+!keep.pem
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]bool{
+		"server.pem":               true,
+		"certs/server.pem":         true,
+		"keep.pem":                 false,
+		"secrets.env":              true,
+		"config/secrets.env":       false,
+		"third_party/lib/lib.go":   true,
+		"pkg/third_party/x.go":     true,
+		"docs/manual.pdf":          true,
+		"docs/guides/setup.pdf":    true,
+		"docs/guides/setup.md":     false,
+		"main.go":                  false,
+		"third_party_notes.txt":    false,
+		"../outside/server.pem":    false,
+		"third_party/sub/keep.pem": true, // can't re-include inside an excluded directory
+	} {
+		if got := rules.Excluded(path, false); got != want {
+			t.Errorf("Excluded(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestIgnoreFileBlocksSecret(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		".vibe-git/ignore":    "config/secrets.yaml\n",
+		"config/secrets.yaml": "api_key: hunter2",
+		"config/app.yaml":     "port: 8080",
+	})
+
+	files := LoadReferencedFiles([]string{"config/secrets.yaml", "secrets.yaml", "config/app.yaml"}, root, DefaultLoadOptions())
+	for _, f := range files[:2] {
+		if f.Found || f.Content != "" || f.Reason != excludedReason {
+			t.Errorf("expected %s to be excluded by policy, got %+v", f.Path, f)
+		}
+	}
+	if !files[2].Found {
+		t.Errorf("expected config/app.yaml to load, got %+v", files[2])
+	}
+
+	section := BuildReferencedFilesSection(files)
+	if !strings.Contains(section, "### config/secrets.yaml\n**File skipped: excluded by policy") {
+		t.Errorf("expected an excluded-by-policy note, got:\n%s", section)
+	}
+
+	codebase, err := BuildCodebaseSection(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(codebase, "hunter2") || strings.Contains(codebase, "secrets.yaml") {
+		t.Error("codebase section included the ignored secret")
+	}
+	if !strings.Contains(codebase, "port: 8080") {
+		t.Error("expected the codebase section to keep other files")
+	}
+}
@@ -6,50 +6,140 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// DefaultMaxFileSize is the default cap on how much of a referenced file is loaded
+const DefaultMaxFileSize = 64 * 1024
+
 // FileReference represents a file referenced in an issue
 type FileReference struct {
-	Path    string
-	Content string
-	Found   bool
+	Path       string
+	Content    string
+	Found      bool
+	Size       int64  // Size of the file on disk, before any truncation
+	Truncated  bool   // Content was cut at the size limit
+	Reason     string // Why the reference was not loaded (e.g. "not found", "is a directory")
+	Redactions int    // Secrets masked in Content
+
+	ResolvedPath string   // Repo-relative path found by searching, if the reference wasn't an exact path
+	Matches      []string // All search matches when the reference was ambiguous
+}
+
+// fileRefPattern matches @"file with spaces", @filename and @path/to/file in a
+// single pass. The quoted form is tried first, so a quoted reference is never
+// also read as an unquoted one.
+var fileRefPattern = regexp.MustCompile(`@(?:"([^"]+)"|([a-zA-Z0-9_./-]+))`)
+
+// fileExtensions are the extensions that mark an unquoted @reference without a
+// slash as a file rather than a GitHub @mention or a domain
+var fileExtensions = map[string]bool{
+	".c": true, ".cc": true, ".cfg": true, ".conf": true, ".cpp": true, ".cs": true,
+	".css": true, ".csv": true, ".dart": true, ".env": true, ".ex": true, ".exs": true,
+	".go": true, ".gradle": true, ".h": true, ".hpp": true, ".html": true, ".ini": true,
+	".java": true, ".js": true, ".json": true, ".jsx": true, ".kt": true, ".lock": true,
+	".lua": true, ".md": true, ".mod": true, ".php": true, ".proto": true, ".py": true,
+	".rb": true, ".rs": true, ".scss": true, ".sh": true, ".sql": true, ".sum": true,
+	".svelte": true, ".swift": true, ".tf": true, ".toml": true, ".ts": true, ".tsx": true,
+	".txt": true, ".vue": true, ".xml": true, ".yaml": true, ".yml": true,
 }
 
 // ExtractFileReferences extracts @ mentions from text
 // Supports formats: @filename, @path/to/file, @"file with spaces"
+// Each path is returned once, in the order it first appears. Email addresses
+// are skipped, and so are unquoted references that look like GitHub @mentions:
+// no slash and no known file extension. Use ExtractFileReferencesIn to keep
+// those that name a file in the repository.
 func ExtractFileReferences(text string) []string {
+	return extractFileReferences(text, func(string) bool { return false })
+}
+
+// ExtractFileReferencesIn is ExtractFileReferences that also keeps unquoted
+// references without a slash or known extension, like @Makefile, when they
+// exist at root
+func ExtractFileReferencesIn(text, root string) []string {
+	return extractFileReferences(text, func(ref string) bool {
+		_, err := os.Stat(filepath.Join(root, ref))
+		return err == nil
+	})
+}
+
+// extractFileReferences extracts the @references of text; exists decides
+// whether a reference that doesn't look like a path is a file after all
+func extractFileReferences(text string, exists func(ref string) bool) []string {
 	var refs []string
 
-	// Pattern: @"file with spaces" or @filename or @path/to/file
-	// Capture quoted strings or unquoted path-like strings
-	patterns := []string{
-		`@"([^"]+)"`,           // @"file with spaces"
-		`@([a-zA-Z0-9_./-]+)`, // @filename or @path/to/file
-	}
-
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindAllStringSubmatch(text, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				path := strings.TrimSpace(match[1])
-				if path != "" && !contains(refs, path) {
-					refs = append(refs, path)
-				}
+	for _, loc := range fileRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		// user@example.com: the @ continues a word
+		if loc[0] > 0 && isWordByte(text[loc[0]-1]) {
+			continue
+		}
+
+		var path string
+		if loc[2] >= 0 {
+			// Quoted references are always meant as files
+			path = strings.TrimSpace(text[loc[2]:loc[3]])
+		} else {
+			// A sentence may end right after the reference
+			path = strings.TrimRight(text[loc[4]:loc[5]], ".")
+			if !looksLikePath(path) && !exists(path) {
+				continue
 			}
 		}
+		if path != "" && !contains(refs, path) {
+			refs = append(refs, path)
+		}
 	}
 
 	return refs
 }
 
-// LoadReferencedFiles loads the content of referenced files
-func LoadReferencedFiles(refs []string, repoRoot string) []*FileReference {
+// looksLikePath reports whether an unquoted reference has a slash or a known
+// file extension, unlike @octocat or @example.com
+func looksLikePath(ref string) bool {
+	return strings.Contains(ref, "/") || fileExtensions[strings.ToLower(filepath.Ext(ref))]
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// LoadOptions controls how referenced files are resolved and loaded
+type LoadOptions struct {
+	// MaxFileSize truncates files larger than this many bytes; <= 0 disables the limit
+	MaxFileSize int64
+	// SearchRoots are directories, relative to the repo root, searched recursively
+	// for references that don't resolve to an exact path. Empty means the repo root.
+	SearchRoots []string
+	// RedactSecrets masks credentials in loaded content (see RedactSecrets)
+	RedactSecrets bool
+}
+
+// DefaultLoadOptions returns the options used when nothing is configured
+func DefaultLoadOptions() LoadOptions {
+	return LoadOptions{MaxFileSize: DefaultMaxFileSize, RedactSecrets: true}
+}
+
+// LoadReferencedFiles loads the content of referenced files.
+// Exact paths are preferred; otherwise the search roots are searched by file name.
+// Files excluded by IgnoreFile are never loaded.
+func LoadReferencedFiles(refs []string, repoRoot string, opts LoadOptions) []*FileReference {
 	var files []*FileReference
 
+	ignore, ignoreErr := LoadIgnoreRules(repoRoot)
+
 	for _, ref := range refs {
 		file := &FileReference{
-			Path: ref,
+			Path:   ref,
+			Reason: "not found",
+		}
+
+		// Without readable rules nothing can be shown to be allowed
+		if ignoreErr != nil {
+			file.Reason = fmt.Sprintf("excluded by policy: %v", ignoreErr)
+			files = append(files, file)
+			continue
 		}
 
 		// Try different path resolutions
@@ -61,20 +151,78 @@ func LoadReferencedFiles(refs []string, repoRoot string) []*FileReference {
 		}
 
 		for _, path := range pathsToTry {
-			content, err := os.ReadFile(path)
-			if err == nil {
-				file.Content = string(content)
-				file.Found = true
+			if ignore.excludesPath(repoRoot, path) {
+				if _, err := os.Stat(path); err == nil {
+					file.Reason = excludedReason
+				}
+				continue
+			}
+			if loadFile(file, path, opts) {
 				break
 			}
 		}
 
+		// Fall back to searching nested directories for the file name
+		if !file.Found {
+			var matches []string
+			for _, match := range searchReference(repoRoot, ref, opts.SearchRoots) {
+				if ignore.Excluded(match, false) {
+					file.Reason = excludedReason
+					continue
+				}
+				matches = append(matches, match)
+			}
+			if len(matches) > 1 {
+				file.Matches = matches
+			}
+			for _, match := range matches {
+				if loadFile(file, filepath.Join(repoRoot, match), opts) {
+					file.ResolvedPath = match
+					break
+				}
+			}
+		}
+
 		files = append(files, file)
 	}
 
 	return files
 }
 
+// loadFile reads path into file, recording why it was skipped if it can't be used
+func loadFile(file *FileReference, path string, opts LoadOptions) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		// Keep looking, a later resolution may be a regular file
+		file.Reason = "is a directory"
+		return false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		file.Reason = fmt.Sprintf("unreadable: %v", err)
+		return false
+	}
+
+	file.Size = int64(len(content))
+	// Redact before truncating so a secret cut at the limit is still recognised
+	if opts.RedactSecrets {
+		redacted, n := RedactFileSecrets(path, string(content))
+		content, file.Redactions = []byte(redacted), n
+	}
+	if maxSize := opts.MaxFileSize; maxSize > 0 && int64(len(content)) > maxSize {
+		content = content[:truncateIndex(content, int(maxSize))]
+		file.Truncated = true
+	}
+	file.Content = string(content)
+	file.Found = true
+	file.Reason = ""
+	return true
+}
+
 // BuildReferencedFilesSection builds the prompt section for referenced files
 func BuildReferencedFilesSection(files []*FileReference) string {
 	if len(files) == 0 {
@@ -85,32 +233,180 @@ func BuildReferencedFilesSection(files []*FileReference) string {
 	sb.WriteString("\n## Referenced Files (from issue @mentions)\n\n")
 
 	for _, f := range files {
-		if f.Found {
-			sb.WriteString(fmt.Sprintf("### %s\n```\n%s\n```\n\n", f.Path, f.Content))
-		} else {
-			sb.WriteString(fmt.Sprintf("### %s\n**File not found**\n\n", f.Path))
+		if !f.Found {
+			if f.Reason == "" || f.Reason == "not found" {
+				sb.WriteString(fmt.Sprintf("### %s\n**File not found**\n\n", f.Path))
+			} else {
+				sb.WriteString(fmt.Sprintf("### %s\n**File skipped: %s**\n\n", f.Path, f.Reason))
+			}
+			continue
+		}
+
+		header := f.Path
+		if f.ResolvedPath != "" {
+			header += fmt.Sprintf(" (resolved to %s)", f.ResolvedPath)
 		}
+		if f.Truncated {
+			header += fmt.Sprintf(" (truncated at %d bytes)", len(f.Content))
+		}
+		sb.WriteString(fmt.Sprintf("### %s\n```\n%s\n```\n", header, f.Content))
+
+		if f.Truncated {
+			sb.WriteString(fmt.Sprintf("*File is %d bytes; only the first %d bytes are shown.*\n", f.Size, len(f.Content)))
+		}
+		if others := otherMatches(f); len(others) > 0 {
+			sb.WriteString(fmt.Sprintf("*Ambiguous reference, also matched: %s*\n", strings.Join(others, ", ")))
+		}
+		sb.WriteString("\n")
 	}
 
 	return sb.String()
 }
 
-// BuildCodebaseSection builds the codebase context section
+// CodebaseOptions controls which files the codebase section includes
+type CodebaseOptions struct {
+	// Dirs limits the section to these directories, relative to the root. Empty means the whole tree.
+	Dirs []string
+	// ExcludeFiles are left out, typically because they are shown as referenced files
+	ExcludeFiles []string
+	// RedactSecrets masks credentials in file contents (see RedactSecrets)
+	RedactSecrets bool
+	// MaxFiles caps how many files are included; <= 0 means no cap. Over the cap,
+	// the most relevant files are kept (see selectRelevant).
+	MaxFiles int
+	// Keywords from the issue make files whose path mentions them more relevant
+	Keywords []string
+}
+
+// CodebaseStats describes what went into a codebase section
+type CodebaseStats struct {
+	FilesIncluded int
+	FilesOmitted  int   // Files left out because of MaxFiles
+	Bytes         int64 // Size of the included file contents
+	Redactions    int   // Secrets masked in the included files
+}
+
+// BuildCodebaseSection builds the codebase context section, with secrets redacted
 func BuildCodebaseSection(root string, excludeFiles []string) (string, error) {
+	section, _, err := BuildCodebaseSectionWithOptions(root, CodebaseOptions{ExcludeFiles: excludeFiles, RedactSecrets: true})
+	return section, err
+}
+
+// BuildCodebaseSectionWithOptions builds the codebase context section and reports
+// what it contains. Files excluded by IgnoreFile are left out.
+func BuildCodebaseSectionWithOptions(root string, opts CodebaseOptions) (string, CodebaseStats, error) {
+	w := &codebaseWalker{
+		root:       root,
+		excludeMap: make(map[string]bool),
+	}
+	// Referenced files are matched by their path relative to the root
+	excludes := make([]string, len(opts.ExcludeFiles))
+	for i, f := range opts.ExcludeFiles {
+		if rel, err := filepath.Rel(root, f); err == nil && filepath.IsAbs(f) {
+			f = rel
+		}
+		excludes[i] = filepath.Clean(f)
+		w.excludeMap[excludes[i]] = true
+	}
+
+	var err error
+	if w.ignore, err = LoadIgnoreRules(root); err != nil {
+		return "", CodebaseStats{}, err
+	}
+
+	if len(opts.Dirs) == 0 {
+		if err := w.walk(root); err != nil {
+			return "", CodebaseStats{}, err
+		}
+	}
+	for _, dir := range opts.Dirs {
+		if err := w.walk(filepath.Join(root, dir)); err != nil {
+			return "", CodebaseStats{}, fmt.Errorf("codebase dir %s: %w", dir, err)
+		}
+	}
+
+	files := w.files
+	var stats CodebaseStats
+	if opts.MaxFiles > 0 && len(files) > opts.MaxFiles {
+		files = selectRelevant(files, opts.MaxFiles, excludes, opts.Keywords)
+		stats.FilesOmitted = len(w.files) - len(files)
+	}
+
 	var result strings.Builder
+	for _, f := range files {
+		stats.FilesIncluded++
+
+		// Skip large files
+		if f.size > 100*1024 {
+			result.WriteString(fmt.Sprintf("\n// File: %s (skipped - too large)\n", f.rel))
+			continue
+		}
+
+		content, err := os.ReadFile(f.path)
+		if err != nil {
+			continue
+		}
+
+		text := string(content)
+		if opts.RedactSecrets {
+			var n int
+			text, n = RedactFileSecrets(f.path, text)
+			stats.Redactions += n
+		}
+		stats.Bytes += int64(len(text))
+
+		result.WriteString(fmt.Sprintf("\n// File: %s\n", f.rel))
+		result.WriteString(text)
+		result.WriteString("\n")
+	}
 
-	excludeMap := make(map[string]bool)
-	for _, f := range excludeFiles {
-		excludeMap[f] = true
+	if stats.FilesOmitted > 0 {
+		result.WriteString(fmt.Sprintf("\n// %d more files were omitted to keep the prompt small; ask for them by path if they matter.\n", stats.FilesOmitted))
 	}
+	return result.String(), stats, nil
+}
+
+// codebaseFile is a candidate for the codebase section
+type codebaseFile struct {
+	path    string
+	rel     string // path relative to the root, as the prompt shows it
+	size    int64
+	modTime time.Time
+}
+
+// codebaseWalker collects candidate source files for the codebase section
+type codebaseWalker struct {
+	root       string
+	excludeMap map[string]bool
+	ignore     *IgnoreRules
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	files []codebaseFile
+}
+
+// walk collects every source file under start, skipping what the ignore rules
+// exclude relative to the root
+func (w *codebaseWalker) walk(start string) error {
+	return filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip directories
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			rel = path
+		}
+		if w.ignore.Excluded(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip directories; the starting directory itself is always walked, even "."
 		if info.IsDir() {
+			if path == start {
+				return nil
+			}
 			name := info.Name()
 			if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" ||
 				name == "dist" || name == "build" || name == ".git" {
@@ -120,7 +416,7 @@ func BuildCodebaseSection(root string, excludeFiles []string) (string, error) {
 		}
 
 		// Skip if in referenced files (will be shown separately)
-		if excludeMap[path] {
+		if w.excludeMap[rel] {
 			return nil
 		}
 
@@ -131,29 +427,20 @@ func BuildCodebaseSection(root string, excludeFiles []string) (string, error) {
 			return nil
 		}
 
-		// Skip large files
-		if info.Size() > 100*1024 {
-			result.WriteString(fmt.Sprintf("\n// File: %s (skipped - too large)\n", path))
-			return nil
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		result.WriteString(fmt.Sprintf("\n// File: %s\n", path))
-		result.WriteString(string(content))
-		result.WriteString("\n")
-
+		w.files = append(w.files, codebaseFile{path: path, rel: rel, size: info.Size(), modTime: info.ModTime()})
 		return nil
 	})
+}
 
-	if err != nil {
-		return "", err
+// truncateIndex returns the largest cut point <= limit that does not split a UTF-8 rune
+func truncateIndex(content []byte, limit int) int {
+	for n := limit; n > limit-utf8.UTFMax && n > 0; n-- {
+		if utf8.RuneStart(content[n]) {
+			return n
+		}
 	}
-
-	return result.String(), nil
+	// Not valid UTF-8 near the limit, cut at the byte boundary
+	return limit
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,181 @@
+package ctxloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractFileReferences(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// Mixed forms come back in the order they appear
+		{`See @main.go and @"docs/user guide.md", then @cmd/root.go`, []string{"main.go", "docs/user guide.md", "cmd/root.go"}},
+		{`@"foo bar.md" before @foo.md`, []string{"foo bar.md", "foo.md"}},
+		{`@foo.md before @"foo bar.md"`, []string{"foo.md", "foo bar.md"}},
+		// A quoted reference isn't also read as an unquoted one
+		{`@"notes @draft.md"`, []string{"notes @draft.md"}},
+		// Duplicates, quoted or not, are dropped
+		{`@a.go @"a.go" @b.go @a.go`, []string{"a.go", "b.go"}},
+		{`@"  spaced.md  "`, []string{"spaced.md"}},
+		{`no references`, nil},
+	}
+	for _, tt := range tests {
+		if got := ExtractFileReferences(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractFileReferences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtractFileReferencesSkipsMentionsAndEmails(t *testing.T) {
+	text := "Reported by jane@example.com, cc @octocat and @acme-bot.\n" +
+		"Mail ops@corp.io about @internal/api/handler.go and @README.md.\n" +
+		"Also see @Makefile and @\"My Notes\"."
+	want := []string{"internal/api/handler.go", "README.md", "My Notes"}
+	if got := ExtractFileReferences(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractFileReferences = %q, want %q", got, want)
+	}
+
+	// In a repository, bare names that exist there are files after all
+	root := t.TempDir()
+	writeTestFile(t, root, "Makefile", "all:\n")
+	want = []string{"internal/api/handler.go", "README.md", "Makefile", "My Notes"}
+	if got := ExtractFileReferencesIn(text, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractFileReferencesIn = %q, want %q", got, want)
+	}
+}
+
+func TestLoadReferencedFilesTruncationBoundary(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "exact.txt", "0123456789")
+	writeTestFile(t, root, "over.txt", "0123456789A")
+
+	files := LoadReferencedFiles([]string{"exact.txt", "over.txt"}, root, LoadOptions{MaxFileSize: 10})
+
+	exact, over := files[0], files[1]
+	if !exact.Found || exact.Truncated || exact.Content != "0123456789" {
+		t.Errorf("file at the limit should load fully, got %+v", exact)
+	}
+	if !over.Found || !over.Truncated || over.Content != "0123456789" {
+		t.Errorf("file over the limit should be truncated, got %+v", over)
+	}
+	if over.Size != 11 {
+		t.Errorf("expected original size 11, got %d", over.Size)
+	}
+}
+
+func TestLoadReferencedFilesUnlimited(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "big.txt", strings.Repeat("x", 1000))
+
+	files := LoadReferencedFiles([]string{"big.txt"}, root, LoadOptions{})
+	if files[0].Truncated || len(files[0].Content) != 1000 {
+		t.Errorf("expected no truncation with limit 0, got %d bytes", len(files[0].Content))
+	}
+}
+
+func TestLoadReferencedFilesTruncatesOnRuneBoundary(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "utf8.txt", "abc世界")
+
+	// The limit falls inside the three-byte encoding of 世
+	files := LoadReferencedFiles([]string{"utf8.txt"}, root, LoadOptions{MaxFileSize: 4})
+	if files[0].Content != "abc" {
+		t.Errorf("expected truncation before the split rune, got %q", files[0].Content)
+	}
+}
+
+func TestLoadReferencedFilesDirectoryAndMissing(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "pkgdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := LoadReferencedFiles([]string{"pkgdir", "missing.go"}, root, DefaultLoadOptions())
+
+	if files[0].Found || files[0].Reason != "is a directory" {
+		t.Errorf("expected directory to be skipped, got %+v", files[0])
+	}
+	if files[1].Found || files[1].Reason != "not found" {
+		t.Errorf("expected missing file to be reported, got %+v", files[1])
+	}
+}
+
+func TestBuildReferencedFilesSectionNotes(t *testing.T) {
+	section := BuildReferencedFilesSection([]*FileReference{
+		{Path: "big.go", Content: "package big", Found: true, Size: 5000, Truncated: true},
+		{Path: "small.go", Content: "package small", Found: true, Size: 13},
+		{Path: "pkgdir", Reason: "is a directory"},
+		{Path: "missing.go", Reason: "not found"},
+	})
+
+	for _, want := range []string{
+		"### big.go (truncated at 11 bytes)",
+		"File is 5000 bytes",
+		"### small.go\n```\npackage small\n```",
+		"### pkgdir\n**File skipped: is a directory**",
+		"### missing.go\n**File not found**",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("expected section to contain %q, got:\n%s", want, section)
+		}
+	}
+	if strings.Contains(section, "small.go (truncated") {
+		t.Error("untruncated file should not carry a truncation note")
+	}
+}
+
+func TestBuildCodebaseSectionForDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"cmd", "internal", "docs", ".git"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, root, "main.go", "package main")
+	writeTestFile(t, root, "cmd/root.go", "package cmd")
+	writeTestFile(t, root, "internal/util.go", "package internal")
+	writeTestFile(t, root, "docs/guide.md", "# Guide")
+	writeTestFile(t, root, ".git/config", "[core]")
+
+	all, err := BuildCodebaseSection(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"main.go", "cmd/root.go", "internal/util.go", "docs/guide.md"} {
+		if !strings.Contains(all, "// File: "+filepath.FromSlash(want)+"\n") {
+			t.Errorf("expected the whole tree to include %s", want)
+		}
+	}
+	if strings.Contains(all, ".git") {
+		t.Error("hidden directories should be skipped")
+	}
+
+	scoped, _, err := BuildCodebaseSectionWithOptions(root, CodebaseOptions{Dirs: []string{"cmd", "internal"}, ExcludeFiles: []string{filepath.Join(root, "internal/util.go")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(scoped, "cmd/root.go") {
+		t.Error("expected cmd/root.go in the scoped section")
+	}
+	for _, unwanted := range []string{"main.go", "docs/guide.md", "internal/util.go"} {
+		if strings.Contains(scoped, unwanted) {
+			t.Errorf("scoped section should not include %s", unwanted)
+		}
+	}
+
+	if _, _, err := BuildCodebaseSectionWithOptions(root, CodebaseOptions{Dirs: []string{"missing"}}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,192 @@
+package ctxloader
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces a secret found in content sent to the model. Every pattern
+// uses it, so claude.CheckRedacted can spot any masked secret written back.
+const Redacted = "[REDACTED]"
+
+// secretPattern is a known secret format; replace may refer to groups of re
+type secretPattern struct {
+	kind    string
+	re      *regexp.Regexp
+	replace string
+}
+
+// secretPatterns are checked in order, so specific formats are masked before
+// the generic high-entropy check sees them
+var secretPatterns = []secretPattern{
+	// PEM private keys of any type (RSA, EC, OPENSSH, ...)
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?-----END [A-Z0-9 ]*PRIVATE KEY-----`), Redacted},
+	// AWS access key IDs
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), Redacted},
+	// AWS secret access keys, recognised by their key name
+	{"AWS secret access key", regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`), "${1}" + Redacted},
+	// Authorization: Bearer <token>
+	{"bearer token", regexp.MustCompile(`(\b[Bb]earer\s+)[A-Za-z0-9\-._~+/]{16,}=*`), "${1}" + Redacted},
+	// GitHub, Anthropic and Slack tokens
+	{"API token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|sk-ant-[A-Za-z0-9_\-]{20,}|xox[abprs]-[A-Za-z0-9\-]{10,})`), Redacted},
+}
+
+// highEntropyCandidate matches long base64/base64url-looking words
+var highEntropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_\-]{32,}={0,2}`)
+
+// minSecretEntropy is the Shannon entropy, in bits per character, above which a
+// candidate counts as random. Hex digests stay below it at 4 bits.
+const minSecretEntropy = 4.3
+
+// RedactSecrets masks things that look like credentials in content (private
+// keys, cloud and API tokens, bearer tokens and long random strings) and returns
+// the result along with how many were masked.
+func RedactSecrets(content string) (string, int) {
+	return redactSecrets(content, true)
+}
+
+// RedactFileSecrets is RedactSecrets for the content of the file at path.
+// Lockfiles and checksum files are full of hashes that only look random, so
+// just the known secret formats are masked in them.
+func RedactFileSecrets(path, content string) (string, int) {
+	return redactSecrets(content, !isChecksumFile(path))
+}
+
+func redactSecrets(content string, entropy bool) (string, int) {
+	count := 0
+	for _, p := range secretPatterns {
+		content = p.re.ReplaceAllStringFunc(content, func(match string) string {
+			count++
+			return p.re.ReplaceAllString(match, p.replace)
+		})
+	}
+	if !entropy {
+		return content, count
+	}
+	content = highEntropyCandidate.ReplaceAllStringFunc(content, func(match string) string {
+		if !looksRandom(match) {
+			return match
+		}
+		count++
+		return Redacted
+	})
+	return content, count
+}
+
+// Secret is a likely credential found by FindSecrets
+type Secret struct {
+	Kind string // What it looks like, e.g. "AWS access key ID"
+	Line int    // 1-based line of content it starts on
+}
+
+// FindSecrets reports what RedactSecrets would mask in content, in order of
+// appearance, without masking it
+func FindSecrets(content string) []Secret {
+	return findSecrets(content, true)
+}
+
+// FindFileSecrets reports what RedactFileSecrets would mask in the content of
+// the file at path
+func FindFileSecrets(path, content string) []Secret {
+	return findSecrets(content, !isChecksumFile(path))
+}
+
+func findSecrets(content string, entropy bool) []Secret {
+	var (
+		found []Secret
+		spans [][]int
+	)
+	for _, p := range secretPatterns {
+		for _, loc := range p.re.FindAllStringIndex(content, -1) {
+			found = append(found, Secret{Kind: p.kind, Line: lineOf(content, loc[0])})
+			spans = append(spans, loc)
+		}
+	}
+	if entropy {
+		for _, loc := range highEntropyCandidate.FindAllStringIndex(content, -1) {
+			if overlaps(spans, loc) || !looksRandom(content[loc[0]:loc[1]]) {
+				continue
+			}
+			found = append(found, Secret{Kind: "high-entropy string", Line: lineOf(content, loc[0])})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Line < found[j].Line })
+	return found
+}
+
+// checksumFiles are lockfiles whose names don't end in .lock or .sum
+var checksumFiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+}
+
+// isChecksumFile reports whether path is a lockfile or checksum file, such as
+// go.sum or package-lock.json, whose hashes would pass for random secrets
+func isChecksumFile(path string) bool {
+	name := filepath.Base(path)
+	switch filepath.Ext(name) {
+	case ".sum", ".lock":
+		return true
+	}
+	return checksumFiles[name]
+}
+
+// lineOf returns the 1-based line of content that offset falls on
+func lineOf(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// overlaps reports whether loc overlaps any of spans, a specific format
+// already counted
+func overlaps(spans [][]int, loc []int) bool {
+	for _, s := range spans {
+		if loc[0] < s[1] && s[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandom reports whether s mixes upper case, lower case and digits with
+// high entropy, which identifiers, words and paths rarely do
+func looksRandom(s string) bool {
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	return shannonEntropy(strings.TrimRight(s, "=")) >= minSecretEntropy
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
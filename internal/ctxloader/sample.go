@@ -0,0 +1,94 @@
+package ctxloader
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxKeywords bounds how many issue words are used to rank codebase files
+const maxKeywords = 20
+
+var keywordPattern = regexp.MustCompile(`[a-z0-9_]+`)
+
+// stopWords are common issue words that say nothing about which files matter
+var stopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "because": true, "before": true,
+	"been": true, "could": true, "does": true, "doesn": true,
+	"each": true, "error": true, "file": true, "files": true, "from": true,
+	"have": true, "into": true, "issue": true, "like": true, "make": true,
+	"more": true, "need": true, "only": true, "please": true, "should": true,
+	"some": true, "than": true, "that": true, "then": true, "there": true,
+	"these": true, "this": true, "when": true, "where": true, "which": true,
+	"will": true, "with": true, "would": true,
+}
+
+// IssueKeywords picks the distinct, lower-cased words of text that are long
+// enough to be meaningful in a file path, for CodebaseOptions.Keywords
+func IssueKeywords(text string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, word := range keywordPattern.FindAllString(strings.ToLower(text), -1) {
+		if len(word) < 4 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+		if len(keywords) == maxKeywords {
+			break
+		}
+	}
+	return keywords
+}
+
+// selectRelevant keeps the max most relevant files. Files next to a referenced
+// file rank first, then files whose path mentions more issue keywords, then the
+// most recently modified. The kept files stay in walk order.
+func selectRelevant(files []codebaseFile, max int, referenced, keywords []string) []codebaseFile {
+	refDirs := make(map[string]bool)
+	for _, ref := range referenced {
+		refDirs[filepath.Dir(filepath.Clean(ref))] = true
+	}
+
+	type ranked struct {
+		codebaseFile
+		index int
+		score int
+	}
+	candidates := make([]ranked, len(files))
+	for i, f := range files {
+		// A referenced directory outweighs any number of keyword matches
+		score := 0
+		if refDirs[filepath.Dir(f.rel)] {
+			score += len(keywords) + 1
+		}
+		path := strings.ToLower(filepath.ToSlash(f.rel))
+		for _, k := range keywords {
+			if strings.Contains(path, k) {
+				score++
+			}
+		}
+		candidates[i] = ranked{codebaseFile: f, index: i, score: score}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.modTime.Equal(b.modTime) {
+			return a.modTime.After(b.modTime)
+		}
+		return a.index < b.index
+	})
+
+	kept := candidates[:max]
+	sort.Slice(kept, func(i, j int) bool { return kept[i].index < kept[j].index })
+
+	selected := make([]codebaseFile, len(kept))
+	for i, c := range kept {
+		selected[i] = c.codebaseFile
+	}
+	return selected
+}
@@ -0,0 +1,109 @@
+package ctxloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLargeTree creates count filler files spread over a few packages
+func newLargeTree(t *testing.T, count int) string {
+	t.Helper()
+	files := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		files[fmt.Sprintf("pkg%d/file%03d.go", i%10, i)] = fmt.Sprintf("package pkg%d", i%10)
+	}
+	root := newFixtureTree(t, files)
+
+	// Give the filler an old mtime so recency only decides where it should
+	old := time.Now().Add(-48 * time.Hour)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			os.Chtimes(path, old, old)
+		}
+		return nil
+	})
+	return root
+}
+
+func includedFiles(section string) []string {
+	var files []string
+	for _, line := range strings.Split(section, "\n") {
+		if name, ok := strings.CutPrefix(line, "// File: "); ok {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+func TestBuildCodebaseSectionMaxFiles(t *testing.T) {
+	root := newLargeTree(t, 500)
+	writeTestFile(t, root, "pkg3/payment_gateway.go", "package pkg3")
+	writeTestFile(t, root, "pkg7/recent.go", "package pkg7")
+	if err := os.MkdirAll(filepath.Join(root, "auth"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, root, "auth/login.go", "package auth")
+	writeTestFile(t, root, "auth/session.go", "package auth")
+
+	section, stats, err := BuildCodebaseSectionWithOptions(root, CodebaseOptions{
+		MaxFiles:     20,
+		ExcludeFiles: []string{filepath.Join(root, "auth/login.go")},
+		Keywords:     IssueKeywords("Payment gateway times out after login"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	included := includedFiles(section)
+	if len(included) != 20 || stats.FilesIncluded != 20 {
+		t.Fatalf("expected 20 files, got %d (stats %+v)", len(included), stats)
+	}
+	if stats.FilesOmitted != 503-20 {
+		t.Errorf("expected %d omitted files, got %d", 503-20, stats.FilesOmitted)
+	}
+	if !strings.Contains(section, fmt.Sprintf("%d more files were omitted", stats.FilesOmitted)) {
+		t.Error("expected the section to note the omitted files")
+	}
+	if stats.Bytes == 0 {
+		t.Error("expected the included bytes to be counted")
+	}
+
+	for _, want := range []string{"auth/session.go", "pkg3/payment_gateway.go", "pkg7/recent.go"} {
+		if !contains(included, filepath.FromSlash(want)) {
+			t.Errorf("expected relevant file %s to be kept, got %v", want, included)
+		}
+	}
+	// The next-door file comes first by relevance, but the output stays in tree order
+	if !strings.HasSuffix(included[0], "auth/session.go") {
+		t.Errorf("expected walk order to be kept, got %v", included[:3])
+	}
+}
+
+func TestBuildCodebaseSectionUnderMaxFiles(t *testing.T) {
+	root := newLargeTree(t, 30)
+
+	capped, stats, err := BuildCodebaseSectionWithOptions(root, CodebaseOptions{MaxFiles: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := BuildCodebaseSection(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capped != all || stats.FilesIncluded != 30 || stats.FilesOmitted != 0 {
+		t.Errorf("a cap that isn't reached should change nothing, got %+v", stats)
+	}
+}
+
+func TestIssueKeywords(t *testing.T) {
+	got := IssueKeywords("Fix the Payment gateway: it should retry when the gateway returns 502")
+	want := []string{"payment", "gateway", "retry", "returns"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueKeywords = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package ctxloader
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxSearchDepth bounds how many directories deep the reference search descends
+	maxSearchDepth = 12
+	// maxSearchEntries bounds how many directory entries a single search visits
+	maxSearchEntries = 50000
+)
+
+// errSearchLimit stops a walk once maxSearchEntries have been visited
+var errSearchLimit = errors.New("search limit reached")
+
+// searchReference finds files under the search roots whose path ends with ref.
+// A bare file name matches by basename; a path like "api/client.go" must match
+// whole trailing path components. Results are repo-relative, shallowest first.
+func searchReference(repoRoot, ref string, searchRoots []string) []string {
+	ref = filepath.ToSlash(filepath.Clean(ref))
+	if ref == "." || strings.HasPrefix(ref, "../") || filepath.IsAbs(ref) {
+		return nil
+	}
+	if len(searchRoots) == 0 {
+		searchRoots = []string{"."}
+	}
+
+	seen := make(map[string]bool)
+	var matches []string
+	visited := 0
+
+	for _, root := range searchRoots {
+		start := filepath.Join(repoRoot, root)
+		err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are skipped rather than failing the search
+				if d != nil && d.IsDir() && path != start {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			visited++
+			if visited > maxSearchEntries {
+				return errSearchLimit
+			}
+
+			rel, relErr := filepath.Rel(repoRoot, path)
+			if relErr != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+
+			if d.IsDir() {
+				if path != start && (skipSearchDir(d.Name()) || strings.Count(rel, "/") >= maxSearchDepth) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if (rel == ref || strings.HasSuffix(rel, "/"+ref)) && !seen[rel] {
+				seen[rel] = true
+				matches = append(matches, rel)
+			}
+			return nil
+		})
+		if errors.Is(err, errSearchLimit) {
+			break
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		di, dj := strings.Count(matches[i], "/"), strings.Count(matches[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return matches[i] < matches[j]
+	})
+
+	return matches
+}
+
+// skipSearchDir reports whether a directory is never searched for references
+func skipSearchDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" ||
+		name == "dist" || name == "build"
+}
+
+// otherMatches returns the ambiguous matches that were not loaded
+func otherMatches(f *FileReference) []string {
+	var others []string
+	for _, m := range f.Matches {
+		if m != f.ResolvedPath {
+			others = append(others, m)
+		}
+	}
+	return others
+}
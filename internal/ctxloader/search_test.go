@@ -0,0 +1,103 @@
+package ctxloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFixtureTree creates files (slash-separated paths) under a temp root
+func newFixtureTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLoadReferencedFilesFindsNestedFile(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		"services/billing/internal/api/client.go": "package api",
+	})
+
+	files := LoadReferencedFiles([]string{"client.go", "api/client.go"}, root, DefaultLoadOptions())
+
+	for _, f := range files {
+		if !f.Found || f.Content != "package api" {
+			t.Errorf("expected %s to resolve, got %+v", f.Path, f)
+		}
+		if f.ResolvedPath != "services/billing/internal/api/client.go" {
+			t.Errorf("unexpected resolved path for %s: %s", f.Path, f.ResolvedPath)
+		}
+		if len(f.Matches) != 0 {
+			t.Errorf("expected unambiguous match for %s, got %v", f.Path, f.Matches)
+		}
+	}
+}
+
+func TestLoadReferencedFilesPrefersExactPath(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		"client.go":             "root",
+		"deep/nested/client.go": "nested",
+	})
+
+	files := LoadReferencedFiles([]string{"client.go"}, root, DefaultLoadOptions())
+	if files[0].Content != "root" || files[0].ResolvedPath != "" {
+		t.Errorf("expected exact path to win, got %+v", files[0])
+	}
+}
+
+func TestLoadReferencedFilesReportsAmbiguousMatches(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		"a/b/c/client.go": "deeper",
+		"x/client.go":     "shallow",
+		"y/clientx.go":    "not a match",
+	})
+
+	files := LoadReferencedFiles([]string{"client.go"}, root, DefaultLoadOptions())
+	f := files[0]
+	if f.ResolvedPath != "x/client.go" || f.Content != "shallow" {
+		t.Errorf("expected shallowest match to be loaded, got %+v", f)
+	}
+	if strings.Join(f.Matches, ",") != "x/client.go,a/b/c/client.go" {
+		t.Errorf("unexpected matches: %v", f.Matches)
+	}
+
+	section := BuildReferencedFilesSection(files)
+	if !strings.Contains(section, "also matched: a/b/c/client.go") {
+		t.Errorf("expected ambiguity note in section, got:\n%s", section)
+	}
+}
+
+func TestLoadReferencedFilesSearchRoots(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		"apps/web/util.go":  "web",
+		"tools/gen/util.go": "tools",
+	})
+
+	files := LoadReferencedFiles([]string{"util.go"}, root, LoadOptions{SearchRoots: []string{"tools"}})
+	if files[0].ResolvedPath != "tools/gen/util.go" || len(files[0].Matches) != 0 {
+		t.Errorf("expected search limited to tools/, got %+v", files[0])
+	}
+}
+
+func TestSearchReferenceSkipsIgnoredDirs(t *testing.T) {
+	root := newFixtureTree(t, map[string]string{
+		"node_modules/pkg/index.js": "",
+		".git/index.js":             "",
+		"src/index.js":              "",
+	})
+
+	matches := searchReference(root, "index.js", nil)
+	if strings.Join(matches, ",") != "src/index.js" {
+		t.Errorf("unexpected matches: %v", matches)
+	}
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Author is the identity a commit is attributed to
+type Author struct {
+	Name  string
+	Email string
+}
+
+// ParseAuthor parses "Name <email>", the form git prints authors in
+func ParseAuthor(s string) (Author, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(s))
+	if err != nil || addr.Name == "" {
+		return Author{}, fmt.Errorf("%q is not of the form \"Name <email>\"", s)
+	}
+	return Author{Name: addr.Name, Email: addr.Address}, nil
+}
+
+// String formats the author as "Name <email>"
+func (a Author) String() string {
+	return fmt.Sprintf("%s <%s>", a.Name, a.Email)
+}
+
+// GitHubUser is the identity GitHub attributes to a user's web commits. Commits
+// crediting it are linked to the account without exposing a private email.
+func GitHubUser(login string, id int64) Author {
+	email := login + "@users.noreply.github.com"
+	if id > 0 {
+		email = fmt.Sprintf("%d+%s", id, email)
+	}
+	return Author{Name: login, Email: email}
+}
+
+// WithCoAuthor appends a Co-authored-by trailer crediting a to message
+func WithCoAuthor(message string, a Author) string {
+	return strings.TrimRight(message, "\n") + "\n\nCo-authored-by: " + a.String()
+}
@@ -1,31 +1,49 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"vibe-git/internal/claude"
 )
 
 // Client handles git operations
 type Client struct {
-	owner string
-	repo  string
-	token string
-	dir   string
+	owner  string
+	repo   string
+	token  string
+	dir    string
+	output io.Writer
+	author Author // Commit identity; zero means the repository's own
+	proxy  string // http.proxy for fetches and pushes; empty leaves it to git's config and environment
+
+	tokenSource func(context.Context) (string, error) // Supplies short-lived push tokens; overrides token
+
+	worktreeMu sync.Mutex // Serializes fetch and worktree add across concurrent issues
+	mainDir    string     // Repository that owns this client's worktree; empty if not a worktree
 }
 
 // NewClient creates a new git client
 func NewClient(owner, repo, token string) *Client {
 	return &Client{
-		owner: owner,
-		repo:  repo,
-		token: token,
-		dir:   ".",
+		owner:  owner,
+		repo:   repo,
+		token:  token,
+		dir:    ".",
+		output: os.Stdout,
 	}
 }
 
@@ -34,71 +52,382 @@ func (c *Client) SetDir(dir string) {
 	c.dir = dir
 }
 
+// Dir returns the working directory
+func (c *Client) Dir() string {
+	return c.dir
+}
+
+// SetProxy makes git's fetches and pushes go through the proxy at url
+func (c *Client) SetProxy(url string) {
+	c.proxy = url
+}
+
+// SetCommitAuthor makes Commit author and commit as a instead of the identity
+// configured in the repository
+func (c *Client) SetCommitAuthor(a Author) {
+	c.author = a
+}
+
+// SetTokenSource makes pushes authenticate with a token fetched from source at
+// push time, such as a GitHub App installation token that is refreshed before it expires
+func (c *Client) SetTokenSource(source func(context.Context) (string, error)) {
+	c.tokenSource = source
+}
+
+// SetOutput sets where git command output is echoed (nil disables echoing)
+func (c *Client) SetOutput(w io.Writer) {
+	c.output = w
+}
+
 // CreateBranch creates a new branch from the base branch
 func (c *Client) CreateBranch(ctx context.Context, baseBranch, newBranch string) error {
 	// Fetch latest changes
-	if err := c.run("fetch", "origin"); err != nil {
+	if err := c.run(ctx, "fetch", "origin"); err != nil {
 		return fmt.Errorf("fetching: %w", err)
 	}
 
 	// Checkout base branch
-	if err := c.run("checkout", baseBranch); err != nil {
+	if err := c.run(ctx, "checkout", baseBranch); err != nil {
 		return fmt.Errorf("checking out base branch: %w", err)
 	}
 
 	// Pull latest changes
-	if err := c.run("pull", "origin", baseBranch); err != nil {
+	if err := c.run(ctx, "pull", "origin", baseBranch); err != nil {
 		return fmt.Errorf("pulling base branch: %w", err)
 	}
 
-	// Create and checkout new branch
-	if err := c.run("checkout", "-b", newBranch); err != nil {
+	// Create and checkout new branch; -B resets a branch left over from an
+	// earlier run of the same issue
+	if err := c.run(ctx, "checkout", "-B", newBranch); err != nil {
 		return fmt.Errorf("creating branch: %w", err)
 	}
 
 	return nil
 }
 
+// CreateWorktree checks out newBranch, starting at the latest origin/baseBranch,
+// in a new temporary worktree that shares the repository's object storage. It
+// leaves the client's own checkout untouched, so several issues can be processed
+// at once. The returned client works in the worktree; call RemoveWorktree on it
+// when done.
+func (c *Client) CreateWorktree(ctx context.Context, baseBranch, newBranch string) (*Client, error) {
+	dir, err := os.MkdirTemp("", "vibe-git-worktree-")
+	if err != nil {
+		return nil, fmt.Errorf("creating worktree directory: %w", err)
+	}
+
+	c.worktreeMu.Lock()
+	defer c.worktreeMu.Unlock()
+
+	if err := c.run(ctx, "fetch", "origin"); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("fetching: %w", err)
+	}
+
+	// -B resets a branch left over from an earlier run of the same issue
+	if err := c.run(ctx, "worktree", "add", "-B", newBranch, dir, "origin/"+baseBranch); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("adding worktree: %w", err)
+	}
+
+	return &Client{
+		owner:   c.owner,
+		repo:    c.repo,
+		token:   c.token,
+		dir:     dir,
+		output:  c.output,
+		author:  c.author,
+		proxy:   c.proxy,
+		mainDir: c.dir,
+
+		tokenSource: c.tokenSource,
+	}, nil
+}
+
+// RemoveWorktree deletes a worktree created by CreateWorktree. The branch and
+// its commits are kept.
+func (c *Client) RemoveWorktree(ctx context.Context) error {
+	if c.mainDir == "" {
+		return fmt.Errorf("%s is not a worktree", c.dir)
+	}
+
+	parent := &Client{token: c.token, dir: c.mainDir}
+	err := parent.run(ctx, "worktree", "remove", "--force", c.dir)
+	if err != nil {
+		// Fall back to deleting the directory and letting git forget it
+		os.RemoveAll(c.dir)
+		if perr := parent.run(ctx, "worktree", "prune"); perr != nil {
+			return fmt.Errorf("removing worktree: %w", err)
+		}
+	}
+	return nil
+}
+
 // ApplyChanges applies file changes to the repository
-func (c *Client) ApplyChanges(changes []claude.FileChange) error {
+func (c *Client) ApplyChanges(ctx context.Context, changes []claude.FileChange) error {
 	for _, change := range changes {
-		fullPath := filepath.Join(c.dir, change.Path)
-
-		switch change.Operation {
-		case "create", "modify":
-			// Ensure directory exists
-			dir := filepath.Dir(fullPath)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("creating directory %s: %w", dir, err)
-			}
+		if err := c.applyChange(ctx, change); err != nil {
+			return err
+		}
+	}
 
-			// Write file
-			if err := os.WriteFile(fullPath, []byte(change.Content), 0644); err != nil {
-				return fmt.Errorf("writing file %s: %w", change.Path, err)
-			}
+	return nil
+}
 
-		case "delete":
-			if err := os.Remove(fullPath); err != nil {
-				return fmt.Errorf("deleting file %s: %w", change.Path, err)
-			}
+// applyChange writes or deletes one file and stages it
+func (c *Client) applyChange(ctx context.Context, change claude.FileChange) error {
+	fullPath := filepath.Join(c.dir, change.Path)
 
-		default:
-			return fmt.Errorf("unknown operation: %s", change.Operation)
+	switch change.Operation {
+	case "create", "modify":
+		// Ensure directory exists
+		dir := filepath.Dir(fullPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
 		}
 
-		// Stage the file
-		if err := c.run("add", change.Path); err != nil {
-			return fmt.Errorf("staging file %s: %w", change.Path, err)
+		// Write file
+		if err := writeFileAtomic(fullPath, change.Content); err != nil {
+			return fmt.Errorf("writing file %s: %w", change.Path, err)
 		}
+
+	case "delete":
+		return c.deletePath(ctx, change.Path)
+
+	default:
+		return fmt.Errorf("unknown operation: %s", change.Operation)
 	}
 
+	// Stage the file
+	if err := c.run(ctx, "add", change.Path); err != nil {
+		return fmt.Errorf("staging file %s: %w", change.Path, err)
+	}
 	return nil
 }
 
+// deletePath deletes a file, or a directory and everything in it, and stages
+// the removal. A path that doesn't exist is skipped: Claude sometimes deletes
+// files that were never there, and the result is the same.
+func (c *Client) deletePath(ctx context.Context, p string) error {
+	fullPath := filepath.Join(c.dir, p)
+	info, err := os.Lstat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if c.output != nil {
+			fmt.Fprintf(c.output, "Skipping delete of %s: it doesn't exist\n", p)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting file %s: %w", p, err)
+	}
+
+	if info.IsDir() {
+		// Never the checkout itself, or anything outside it, or its .git
+		rel := filepath.Clean(p)
+		if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) || strings.SplitN(filepath.ToSlash(rel), "/", 2)[0] == ".git" {
+			return fmt.Errorf("deleting directory %s: not a directory inside the repository", p)
+		}
+		if err := os.RemoveAll(fullPath); err != nil {
+			return fmt.Errorf("deleting directory %s: %w", p, err)
+		}
+	} else if err := os.Remove(fullPath); err != nil {
+		return fmt.Errorf("deleting file %s: %w", p, err)
+	}
+
+	// Untracked files have nothing to stage, which `git add` would reject
+	if err := c.run(ctx, "rm", "-r", "-q", "--cached", "--ignore-unmatch", "--", p); err != nil {
+		return fmt.Errorf("staging deletion of %s: %w", p, err)
+	}
+	return nil
+}
+
+// ErrOutOfScope is returned by ApplyChangesInScope for a change outside its scope
+var ErrOutOfScope = errors.New("change outside the issue's path scope")
+
+// ApplyChangesInScope is ApplyChanges for changes confined to the directory
+// scope, relative to the repository root. Nothing is written if any change
+// falls outside it. An empty scope allows every path.
+func (c *Client) ApplyChangesInScope(ctx context.Context, scope string, changes []claude.FileChange) error {
+	if err := checkScope(scope, changes); err != nil {
+		return err
+	}
+	return c.ApplyChanges(ctx, changes)
+}
+
+// ChangeError is a change ApplyChangesLenient could not apply
+type ChangeError struct {
+	Change claude.FileChange
+	Err    error
+}
+
+func (e *ChangeError) Error() string { return e.Err.Error() }
+func (e *ChangeError) Unwrap() error { return e.Err }
+
+// ApplyChangesLenient is ApplyChangesInScope that skips the changes it can't
+// apply, such as deleting a file that doesn't exist, instead of stopping at the
+// first. It returns the changes it applied and why each of the others failed;
+// only the applied ones are staged. The scope is still all or nothing.
+func (c *Client) ApplyChangesLenient(ctx context.Context, scope string, changes []claude.FileChange) ([]claude.FileChange, []*ChangeError, error) {
+	if err := checkScope(scope, changes); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		applied []claude.FileChange
+		failed  []*ChangeError
+	)
+	for _, change := range changes {
+		if err := ctx.Err(); err != nil {
+			return applied, failed, err
+		}
+		if err := c.applyChange(ctx, change); err != nil {
+			failed = append(failed, &ChangeError{Change: change, Err: err})
+			continue
+		}
+		applied = append(applied, change)
+	}
+	return applied, failed, nil
+}
+
+// checkScope returns ErrOutOfScope for the first change outside the directory scope
+func checkScope(scope string, changes []claude.FileChange) error {
+	if scope == "" {
+		return nil
+	}
+	for _, change := range changes {
+		if !inScope(scope, change.Path) {
+			return fmt.Errorf("%w: %s is not under %s/", ErrOutOfScope, change.Path, scope)
+		}
+	}
+	return nil
+}
+
+// ChangedLines counts the lines changes would add and remove in the working
+// tree. Lines are compared as a multiset, so a moved line counts as unchanged;
+// that's close enough to a diff for judging how large a change set is.
+func (c *Client) ChangedLines(changes []claude.FileChange) (int, error) {
+	total := 0
+	for _, change := range changes {
+		if change.Operation == "delete" {
+			n, err := c.deletedLines(change.Path)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			continue
+		}
+		existing, err := os.ReadFile(filepath.Join(c.dir, change.Path))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("reading %s: %w", change.Path, err)
+		}
+		total += lineDelta(string(existing), change.Content)
+	}
+	return total, nil
+}
+
+// deletedLines counts the lines deleting p removes: those of the file, or of
+// every file under the directory, as deletePath would remove it
+func (c *Client) deletedLines(p string) (int, error) {
+	total := 0
+	err := filepath.WalkDir(filepath.Join(c.dir, p), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		total += len(splitLines(string(content)))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reading %s: %w", p, err)
+	}
+	return total, nil
+}
+
+// lineDelta counts the lines of old missing from new plus those of new missing from old
+func lineDelta(old, new string) int {
+	counts := make(map[string]int)
+	for _, line := range splitLines(old) {
+		counts[strings.TrimRight(line, "\r\n")]++
+	}
+	added := 0
+	for _, line := range splitLines(new) {
+		key := strings.TrimRight(line, "\r\n")
+		if counts[key] > 0 {
+			counts[key]--
+		} else {
+			added++
+		}
+	}
+	removed := 0
+	for _, n := range counts {
+		removed += n
+	}
+	return added + removed
+}
+
+// inScope reports whether the relative file path p lies inside the directory scope
+func inScope(scope, p string) bool {
+	p = path.Clean(filepath.ToSlash(p))
+	return strings.HasPrefix(p, path.Clean(scope)+"/")
+}
+
+// writeChunkSize is how much of a file's content is written at a time
+const writeChunkSize = 64 * 1024
+
+// writeFileAtomic writes content to a temporary file next to path and renames
+// it into place, so an interrupted write never leaves a partial file. Content
+// is written in chunks, so a very large generated file isn't copied into a
+// second buffer of its full size first. Existing files keep their permissions.
+func writeFileAtomic(path, content string) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Once renamed, the temp name no longer exists and this is a no-op
+	defer os.Remove(f.Name())
+
+	for len(content) > 0 {
+		n := writeChunkSize
+		if n > len(content) {
+			n = len(content)
+		}
+		if _, err := f.WriteString(content[:n]); err != nil {
+			f.Close()
+			return err
+		}
+		content = content[n:]
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
 // Commit creates a commit with the staged changes
-func (c *Client) Commit(message string) error {
+func (c *Client) Commit(ctx context.Context, message string) error {
 	// Check if there are changes to commit
-	status, err := c.runOutput("status", "--porcelain")
+	status, err := c.runOutput(ctx, "status", "--porcelain")
 	if err != nil {
 		return fmt.Errorf("checking status: %w", err)
 	}
@@ -107,60 +436,210 @@ func (c *Client) Commit(message string) error {
 		return fmt.Errorf("no changes to commit")
 	}
 
-	// Configure git user if not set
-	if err := c.configureGitUser(); err != nil {
+	return c.commit(ctx, message)
+}
+
+// CommitEach commits the staged changes one commit per change, in order, each
+// with message(change). A change that left nothing staged, like the delete of
+// a file that didn't exist, gets no commit. It returns how many it made.
+func (c *Client) CommitEach(ctx context.Context, changes []claude.FileChange, message func(claude.FileChange) string) (int, error) {
+	commits := 0
+	for _, change := range changes {
+		staged, err := c.runOutput(ctx, "diff", "--cached", "--name-only", "--", change.Path)
+		if err != nil {
+			return commits, fmt.Errorf("checking status of %s: %w", change.Path, err)
+		}
+		if strings.TrimSpace(staged) == "" {
+			continue
+		}
+		// A pathspec commits that path alone and leaves the rest staged
+		if err := c.commit(ctx, message(change), "--", change.Path); err != nil {
+			return commits, err
+		}
+		commits++
+	}
+	if commits == 0 {
+		return 0, fmt.Errorf("no changes to commit")
+	}
+	return commits, nil
+}
+
+// commit runs git commit with message and any extra arguments as the
+// configured author
+func (c *Client) commit(ctx context.Context, message string, extra ...string) error {
+	args := []string{"commit", "-m", message}
+	if c.author.Name != "" {
+		// -c sets the committer for this commit only; --author wins over GIT_AUTHOR_* in the environment
+		args = append([]string{"-c", "user.name=" + c.author.Name, "-c", "user.email=" + c.author.Email}, args...)
+		args = append(args, "--author", c.author.String())
+	} else if err := c.configureGitUser(ctx); err != nil {
+		// Configure git user if not set
 		return err
 	}
+	args = append(args, extra...)
 
 	// Commit
-	if err := c.run("commit", "-m", message); err != nil {
+	if err := c.run(ctx, args...); err != nil {
 		return fmt.Errorf("committing: %w", err)
 	}
 
 	return nil
 }
 
-// PushBranch pushes the current branch to origin
+// PushBranch pushes the current branch to origin. A reprocessed issue's branch
+// starts over from its base, so it replaces what an earlier run pushed, but
+// not commits someone else pushed since the last fetch.
 func (c *Client) PushBranch(ctx context.Context, branch string) error {
 	// Set up remote URL with token for authentication
-	remoteURL := fmt.Sprintf("https://%s@github.com/%s/%s.git", c.token, c.owner, c.repo)
+	remoteURL, err := c.remoteURL(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Configure remote
-	if err := c.run("remote", "set-url", "origin", remoteURL); err != nil {
+	if err := c.run(ctx, "remote", "set-url", "origin", remoteURL); err != nil {
 		return fmt.Errorf("setting remote: %w", err)
 	}
 
 	// Push branch
-	if err := c.run("push", "-u", "origin", branch); err != nil {
+	if err := c.run(ctx, "push", "--force-with-lease", "-u", "origin", branch); err != nil {
 		return fmt.Errorf("pushing: %w", err)
 	}
 
 	return nil
 }
 
+// FileStat is how many lines changed in one file
+type FileStat struct {
+	Path       string
+	Insertions int
+	Deletions  int
+	Binary     bool // Line counts are unknown for binary files
+}
+
+// DiffStat summarizes the changes of a branch
+type DiffStat struct {
+	Files      []FileStat
+	Insertions int
+	Deletions  int
+}
+
+// DiffStat returns the changes committed on HEAD since it diverged from base
+// (e.g. "origin/main")
+func (c *Client) DiffStat(ctx context.Context, base string) (*DiffStat, error) {
+	out, err := c.runOutput(ctx, "-c", "core.quotePath=false", "diff", "--numstat", "--no-renames", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("diffing against %s: %w", base, err)
+	}
+	return parseNumstat(out)
+}
+
+// parseNumstat parses `git diff --numstat` output: "<added>\t<deleted>\t<path>",
+// with "-" counts for binary files
+func parseNumstat(out string) (*DiffStat, error) {
+	stat := &DiffStat{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected numstat line %q", line)
+		}
+
+		file := FileStat{Path: fields[2]}
+		if fields[0] == "-" && fields[1] == "-" {
+			file.Binary = true
+		} else {
+			added, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected numstat line %q", line)
+			}
+			deleted, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected numstat line %q", line)
+			}
+			file.Insertions, file.Deletions = added, deleted
+		}
+
+		stat.Files = append(stat.Files, file)
+		stat.Insertions += file.Insertions
+		stat.Deletions += file.Deletions
+	}
+	return stat, nil
+}
+
+// DiffAgainst returns the patch committed on HEAD since it diverged from base
+// (`git diff base...HEAD`), limited to paths when any are given
+func (c *Client) DiffAgainst(ctx context.Context, base string, paths ...string) (string, error) {
+	args := []string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", base + "...HEAD"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	out, err := c.runOutput(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("diffing against %s: %w", base, err)
+	}
+	return out, nil
+}
+
+// ChangedFiles lists the files added or modified on HEAD since it diverged from
+// base, relative to the repository root. Deleted files are left out since there
+// is no content left to show.
+func (c *Client) ChangedFiles(ctx context.Context, base string) ([]string, error) {
+	out, err := c.runOutput(ctx, "-c", "core.quotePath=false", "diff", "--name-only", "--no-renames", "--diff-filter=d", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("listing changes against %s: %w", base, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ContinueRemoteBranch moves the current branch to origin/branch when that
+// exists, so new commits build on work already pushed for it. It reports
+// whether the remote branch was found.
+func (c *Client) ContinueRemoteBranch(ctx context.Context, branch string) (bool, error) {
+	if _, err := c.runOutput(ctx, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch); err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, nil
+	}
+	if err := c.run(ctx, "reset", "--hard", "origin/"+branch); err != nil {
+		return false, fmt.Errorf("continuing origin/%s: %w", branch, err)
+	}
+	return true, nil
+}
+
 // HasConflicts checks if the current branch has merge conflicts with base
 func (c *Client) HasConflicts(ctx context.Context, baseBranch string) (bool, error) {
 	// Fetch latest
-	if err := c.run("fetch", "origin"); err != nil {
+	if err := c.run(ctx, "fetch", "origin"); err != nil {
 		return false, fmt.Errorf("fetching: %w", err)
 	}
 
 	// Try a test merge to detect conflicts
-	if err := c.run("merge", "--no-commit", "--no-ff", "origin/"+baseBranch); err != nil {
+	if err := c.run(ctx, "merge", "--no-commit", "--no-ff", "origin/"+baseBranch); err != nil {
 		// Check if it's due to conflicts
-		status, _ := c.runOutput("status", "--porcelain")
+		status, _ := c.runOutput(ctx, "status", "--porcelain")
 		if strings.Contains(status, "UU") || strings.Contains(status, "AA") ||
 			strings.Contains(status, "DD") || strings.Contains(status, "AU") ||
 			strings.Contains(status, "UA") || strings.Contains(status, "DU") ||
 			strings.Contains(status, "UD") {
 			// Abort the merge attempt
-			c.run("merge", "--abort")
+			c.run(ctx, "merge", "--abort")
 			return true, nil
 		}
 	}
 
 	// Abort the test merge
-	c.run("merge", "--abort")
+	c.run(ctx, "merge", "--abort")
 	return false, nil
 }
 
@@ -169,14 +648,14 @@ func (c *Client) ResolveConflicts(ctx context.Context, baseBranch string, issueT
 	fmt.Println("  Detected merge conflicts, attempting to resolve...")
 
 	// Fetch latest
-	if err := c.run("fetch", "origin"); err != nil {
+	if err := c.run(ctx, "fetch", "origin"); err != nil {
 		return fmt.Errorf("fetching: %w", err)
 	}
 
 	// Attempt to merge base branch
-	if err := c.run("merge", "origin/"+baseBranch); err != nil {
+	if err := c.run(ctx, "merge", "origin/"+baseBranch); err != nil {
 		// Check if there are actual conflicts
-		conflictFiles, err := c.getConflictFiles()
+		conflictFiles, err := c.getConflictFiles(ctx)
 		if err != nil {
 			return fmt.Errorf("getting conflict files: %w", err)
 		}
@@ -190,13 +669,13 @@ func (c *Client) ResolveConflicts(ctx context.Context, baseBranch string, issueT
 
 		// Resolve each conflicted file
 		for _, file := range conflictFiles {
-			if err := c.resolveFileConflict(file, issueTitle, resolveFn); err != nil {
+			if err := c.resolveFileConflict(ctx, file, issueTitle, resolveFn); err != nil {
 				return fmt.Errorf("resolving conflict in %s: %w", file, err)
 			}
 		}
 
 		// Complete the merge
-		if err := c.Commit("Resolve merge conflicts\n\n" + issueTitle); err != nil {
+		if err := c.Commit(ctx, "Resolve merge conflicts\n\n"+issueTitle); err != nil {
 			return fmt.Errorf("committing resolved conflicts: %w", err)
 		}
 
@@ -206,9 +685,85 @@ func (c *Client) ResolveConflicts(ctx context.Context, baseBranch string, issueT
 	return nil
 }
 
+// maxRebaseSteps bounds the resolve/continue loop when rebasing a multi-commit branch
+const maxRebaseSteps = 100
+
+// RebaseResolveConflicts rebases the current branch onto the latest base branch,
+// resolving conflicts commit by commit. Unlike ResolveConflicts it produces a linear
+// history without merge commits; the branch must then be force-pushed.
+func (c *Client) RebaseResolveConflicts(ctx context.Context, baseBranch string, issueTitle string, resolveFn ConflictResolver) error {
+	fmt.Println("  Rebasing onto latest base branch...")
+
+	// Fetch latest
+	if err := c.run(ctx, "fetch", "origin"); err != nil {
+		return fmt.Errorf("fetching: %w", err)
+	}
+
+	// Continuing a rebase creates commits, so make sure an identity is configured
+	if err := c.configureGitUser(ctx); err != nil {
+		return err
+	}
+
+	err := c.run(ctx, "rebase", "origin/"+baseBranch)
+	for step := 0; err != nil; step++ {
+		if step >= maxRebaseSteps {
+			c.run(ctx, "rebase", "--abort")
+			return fmt.Errorf("rebase did not complete after %d steps", maxRebaseSteps)
+		}
+
+		conflictFiles, cerr := c.getConflictFiles(ctx)
+		if cerr != nil {
+			c.run(ctx, "rebase", "--abort")
+			return fmt.Errorf("getting conflict files: %w", cerr)
+		}
+
+		if len(conflictFiles) == 0 {
+			if !c.rebaseInProgress(ctx) {
+				return fmt.Errorf("rebasing: %w", err)
+			}
+			// The resolved commit ended up empty; drop it and move on
+			err = c.run(ctx, "rebase", "--skip")
+			continue
+		}
+
+		fmt.Printf("  Found %d conflicted file(s): %v\n", len(conflictFiles), conflictFiles)
+
+		for _, file := range conflictFiles {
+			if rerr := c.resolveFileConflict(ctx, file, issueTitle, resolveFn); rerr != nil {
+				c.run(ctx, "rebase", "--abort")
+				return fmt.Errorf("resolving conflict in %s: %w", file, rerr)
+			}
+		}
+
+		// Keep the original commit message without opening an editor
+		err = c.run(ctx, "-c", "core.editor=true", "rebase", "--continue")
+	}
+
+	fmt.Println("  ✓ Rebased onto origin/" + baseBranch)
+	return nil
+}
+
+// rebaseInProgress reports whether a rebase is currently stopped
+func (c *Client) rebaseInProgress(ctx context.Context) bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		path, err := c.runOutput(ctx, "rev-parse", "--git-path", name)
+		if err != nil {
+			continue
+		}
+		path = strings.TrimSpace(path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.dir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // getConflictFiles returns list of files with merge conflicts
-func (c *Client) getConflictFiles() ([]string, error) {
-	status, err := c.runOutput("status", "--porcelain")
+func (c *Client) getConflictFiles(ctx context.Context) ([]string, error) {
+	status, err := c.runOutput(ctx, "status", "--porcelain")
 	if err != nil {
 		return nil, err
 	}
@@ -231,30 +786,94 @@ func (c *Client) getConflictFiles() ([]string, error) {
 	return files, nil
 }
 
-// ConflictResolver is a function that resolves a conflict given the conflicted content
-type ConflictResolver func(filePath string, conflictContent string, issueTitle string) (string, error)
+// ConflictResolver is a function that resolves a conflict given the conflicted
+// content and the file's full versions, which are nil if they couldn't be read
+type ConflictResolver func(filePath string, conflictContent string, issueTitle string, versions *claude.ConflictVersions) (string, error)
+
+// ConflictVersions reads the base, ours and theirs versions of a conflicted
+// file from index stages 1, 2 and 3. Stages missing from the index, such as
+// the deleted side of a modify/delete conflict, are left empty.
+func (c *Client) ConflictVersions(ctx context.Context, file string) (*claude.ConflictVersions, error) {
+	entries, err := c.runOutput(ctx, "ls-files", "-u", "--", file)
+	if err != nil {
+		return nil, fmt.Errorf("listing index stages of %s: %w", file, err)
+	}
+
+	versions := &claude.ConflictVersions{}
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(entries), "\n") {
+		// <mode> <object> <stage>\t<path>
+		meta, _, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			continue
+		}
+
+		content, err := c.runOutput(ctx, "cat-file", "blob", fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("reading stage %s of %s: %w", fields[2], file, err)
+		}
+		switch fields[2] {
+		case "1":
+			versions.Base = content
+		case "2":
+			versions.Ours = content
+		case "3":
+			versions.Theirs = content
+		}
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not conflicted", file)
+	}
+	return versions, nil
+}
 
 // resolveFileConflict resolves a single file conflict
-func (c *Client) resolveFileConflict(file string, issueTitle string, resolveFn ConflictResolver) error {
+func (c *Client) resolveFileConflict(ctx context.Context, file string, issueTitle string, resolveFn ConflictResolver) error {
 	// Read the conflicted file
 	content, err := os.ReadFile(filepath.Join(c.dir, file))
 	if err != nil {
 		return fmt.Errorf("reading conflicted file: %w", err)
 	}
 
-	// Use the resolver function to resolve
-	resolved, err := resolveFn(file, string(content), issueTitle)
+	hunks, err := ParseConflictHunks(string(content))
+	if err != nil {
+		return fmt.Errorf("parsing conflict markers: %w", err)
+	}
+
+	// The full versions help with hunks whose context is outside the excerpt
+	versions, err := c.ConflictVersions(ctx, file)
+	if err != nil {
+		fmt.Printf("    Resolving %s without its full versions: %v\n", file, err)
+	}
+
+	var resolved string
+	if len(hunks) == 0 {
+		// No textual markers (e.g. modify/delete conflict), so resolve the whole file
+		resolved, err = resolveFn(file, string(content), issueTitle, versions)
+	} else {
+		// Only send the conflicted regions, leaving the rest of the file untouched
+		fmt.Printf("    Resolving %d conflict hunk(s) in %s\n", len(hunks), file)
+		resolved, err = ResolveConflictHunks(string(content), func(snippet string) (string, error) {
+			return resolveFn(file, snippet, issueTitle, versions)
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("conflict resolution failed: %w", err)
 	}
 
-	// Write resolved content
-	if err := os.WriteFile(filepath.Join(c.dir, file), []byte(resolved), 0644); err != nil {
+	if HasConflictMarkers(resolved) {
+		return fmt.Errorf("resolution of %s still contains conflict markers", file)
+	}
+
+	// Write resolved content, keeping the file's mode
+	if err := writeFileAtomic(filepath.Join(c.dir, file), resolved); err != nil {
 		return fmt.Errorf("writing resolved file: %w", err)
 	}
 
 	// Stage the resolved file
-	if err := c.run("add", file); err != nil {
+	if err := c.run(ctx, "add", file); err != nil {
 		return fmt.Errorf("staging resolved file: %w", err)
 	}
 
@@ -264,33 +883,49 @@ func (c *Client) resolveFileConflict(file string, issueTitle string, resolveFn C
 
 // ForcePushWithLease pushes with force-with-lease (safer force push)
 func (c *Client) ForcePushWithLease(ctx context.Context, branch string) error {
-	remoteURL := fmt.Sprintf("https://%s@github.com/%s/%s.git", c.token, c.owner, c.repo)
+	remoteURL, err := c.remoteURL(ctx)
+	if err != nil {
+		return err
+	}
 
-	if err := c.run("remote", "set-url", "origin", remoteURL); err != nil {
+	if err := c.run(ctx, "remote", "set-url", "origin", remoteURL); err != nil {
 		return fmt.Errorf("setting remote: %w", err)
 	}
 
-	if err := c.run("push", "--force-with-lease", "-u", "origin", branch); err != nil {
+	if err := c.run(ctx, "push", "--force-with-lease", "-u", "origin", branch); err != nil {
 		return fmt.Errorf("force pushing: %w", err)
 	}
 
 	return nil
 }
 
+// remoteURL is the authenticated origin URL pushes go to
+func (c *Client) remoteURL(ctx context.Context) (string, error) {
+	if c.tokenSource == nil {
+		return fmt.Sprintf("https://%s@github.com/%s/%s.git", c.token, c.owner, c.repo), nil
+	}
+	token, err := c.tokenSource(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting push token: %w", err)
+	}
+	// Installation tokens must be given as the password of x-access-token
+	return fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, c.owner, c.repo), nil
+}
+
 // configureGitUser sets up git user config for commits
-func (c *Client) configureGitUser() error {
+func (c *Client) configureGitUser(ctx context.Context) error {
 	// Check if user.name is set
-	name, _ := c.runOutput("config", "user.name")
+	name, _ := c.runOutput(ctx, "config", "user.name")
 	if strings.TrimSpace(name) == "" {
-		if err := c.run("config", "user.name", "Vibe Git"); err != nil {
+		if err := c.run(ctx, "config", "user.name", "Vibe Git"); err != nil {
 			return fmt.Errorf("setting git user.name: %w", err)
 		}
 	}
 
 	// Check if user.email is set
-	email, _ := c.runOutput("config", "user.email")
+	email, _ := c.runOutput(ctx, "config", "user.email")
 	if strings.TrimSpace(email) == "" {
-		if err := c.run("config", "user.email", "vibe-git@localhost"); err != nil {
+		if err := c.run(ctx, "config", "user.email", "vibe-git@localhost"); err != nil {
 			return fmt.Errorf("setting git user.email: %w", err)
 		}
 	}
@@ -298,19 +933,81 @@ func (c *Client) configureGitUser() error {
 	return nil
 }
 
-// run executes a git command
-func (c *Client) run(args ...string) error {
-	cmd := exec.Command("git", args...)
+// accessTokenInURL matches the token in an x-access-token remote URL, which
+// changes too often to be redacted by value
+var accessTokenInURL = regexp.MustCompile(`(x-access-token:)[^@\s]+@`)
+
+// maxErrorOutput caps how much git output is kept in an error message
+const maxErrorOutput = 4096
+
+// run executes a git command; the process is killed if ctx is cancelled.
+// Output is captured into the returned error and echoed to the client's output.
+func (c *Client) run(ctx context.Context, args ...string) error {
+	var captured bytes.Buffer
+	var w io.Writer = &captured
+	if c.output != nil {
+		w = io.MultiWriter(&captured, c.output)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", c.withProxy(args)...)
 	cmd.Dir = c.dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Don't wait on children of a killed git, such as a credential helper, that still hold its output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("git %s: %w", subcommand(args), ctx.Err())
+		}
+		if out := c.errorOutput(captured.String()); out != "" {
+			return fmt.Errorf("git %s: %w: %s", subcommand(args), err, out)
+		}
+		return fmt.Errorf("git %s: %w", subcommand(args), err)
+	}
+	return nil
+}
+
+// errorOutput trims and redacts command output for inclusion in an error
+func (c *Client) errorOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if c.token != "" {
+		output = strings.ReplaceAll(output, c.token, "***")
+	}
+	output = accessTokenInURL.ReplaceAllString(output, "${1}***@")
+	if len(output) > maxErrorOutput {
+		output = "..." + output[len(output)-maxErrorOutput:]
+	}
+	return output
 }
 
 // runOutput executes a git command and returns the output
-func (c *Client) runOutput(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+func (c *Client) runOutput(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", c.withProxy(args)...)
 	cmd.Dir = c.dir
+	cmd.WaitDelay = time.Second
 	output, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return string(output), fmt.Errorf("git %s: %w", subcommand(args), ctx.Err())
+	}
 	return string(output), err
 }
+
+// withProxy prefixes args with the client's http.proxy, if it has one
+func (c *Client) withProxy(args []string) []string {
+	if c.proxy == "" {
+		return args
+	}
+	return append([]string{"-c", "http.proxy=" + c.proxy}, args...)
+}
+
+// subcommand returns the git subcommand name from args, skipping leading `-c key=value` options
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conflictContextLines is how many lines around a hunk are sent to the resolver
+const conflictContextLines = 3
+
+// ConflictHunk is a single `<<<<<<< / ======= / >>>>>>>` region of a file
+type ConflictHunk struct {
+	Ours        string // Lines between <<<<<<< and ======= (or |||||||)
+	Base        string // Lines between ||||||| and ======= (diff3 style only)
+	Theirs      string // Lines between ======= and >>>>>>>
+	OursLabel   string // Label after <<<<<<< (e.g. HEAD)
+	TheirsLabel string // Label after >>>>>>> (e.g. origin/main)
+	StartLine   int    // 0-based line index of the <<<<<<< marker
+	EndLine     int    // 0-based line index of the >>>>>>> marker
+}
+
+// ParseConflictHunks extracts the conflicted regions of a file in order
+func ParseConflictHunks(content string) ([]ConflictHunk, error) {
+	const (
+		outside = iota
+		inOurs
+		inBase
+		inTheirs
+	)
+
+	var hunks []ConflictHunk
+	var cur ConflictHunk
+	state := outside
+
+	for i, line := range splitLines(content) {
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case isConflictMarker(trimmed, "<<<<<<<"):
+			if state != outside {
+				return nil, fmt.Errorf("nested conflict marker at line %d", i+1)
+			}
+			cur = ConflictHunk{StartLine: i, OursLabel: markerLabel(trimmed)}
+			state = inOurs
+		case state == inOurs && isConflictMarker(trimmed, "|||||||"):
+			state = inBase
+		case (state == inOurs || state == inBase) && trimmed == "=======":
+			state = inTheirs
+		case state == inTheirs && isConflictMarker(trimmed, ">>>>>>>"):
+			cur.EndLine = i
+			cur.TheirsLabel = markerLabel(trimmed)
+			hunks = append(hunks, cur)
+			state = outside
+		case state == inOurs:
+			cur.Ours += line
+		case state == inBase:
+			cur.Base += line
+		case state == inTheirs:
+			cur.Theirs += line
+		}
+	}
+
+	if state != outside {
+		return nil, fmt.Errorf("unterminated conflict starting at line %d", cur.StartLine+1)
+	}
+
+	return hunks, nil
+}
+
+// HasConflictMarkers reports whether content still contains conflict markers.
+// A bare ======= line is not counted since it is common in Markdown and RST.
+func HasConflictMarkers(content string) bool {
+	for _, line := range splitLines(content) {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if isConflictMarker(trimmed, "<<<<<<<") || isConflictMarker(trimmed, ">>>>>>>") {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveConflictHunks resolves each conflicted region separately and splices the
+// results back into content. resolve receives the hunk (with markers) surrounded by
+// up to conflictContextLines of context and must return that same region resolved.
+// Lines outside those regions are left untouched.
+func ResolveConflictHunks(content string, resolve func(snippet string) (string, error)) (string, error) {
+	hunks, err := ParseConflictHunks(content)
+	if err != nil {
+		return "", err
+	}
+	if len(hunks) == 0 {
+		return content, nil
+	}
+
+	lines := splitLines(content)
+	var out strings.Builder
+	written := 0 // index of the first line not yet written
+
+	for i, h := range hunks {
+		nextStart := len(lines)
+		if i+1 < len(hunks) {
+			nextStart = hunks[i+1].StartLine
+		}
+
+		start := h.StartLine - conflictContextLines
+		if start < written {
+			start = written
+		}
+		end := h.EndLine + 1 + conflictContextLines
+		if end > nextStart {
+			end = nextStart
+		}
+
+		out.WriteString(strings.Join(lines[written:start], ""))
+
+		region := strings.Join(lines[start:end], "")
+		resolved, err := resolve(region)
+		if err != nil {
+			return "", fmt.Errorf("resolving hunk %d (line %d): %w", i+1, h.StartLine+1, err)
+		}
+
+		// Keep the line structure of the surrounding file intact
+		if strings.HasSuffix(region, "\n") && !strings.HasSuffix(resolved, "\n") {
+			resolved += "\n"
+		}
+		out.WriteString(resolved)
+
+		written = end
+	}
+
+	out.WriteString(strings.Join(lines[written:], ""))
+	return out.String(), nil
+}
+
+// splitLines splits content into lines, keeping line endings
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isConflictMarker reports whether line is the given marker, optionally followed by a label
+func isConflictMarker(line, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// markerLabel returns the label following a conflict marker
+func markerLabel(line string) string {
+	if len(line) <= 8 {
+		return ""
+	}
+	return strings.TrimSpace(line[8:])
+}
@@ -0,0 +1,194 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+)
+
+const singleHunk = `package main
+
+import "fmt"
+
+func main() {
+<<<<<<< HEAD
+	fmt.Println("ours")
+=======
+	fmt.Println("theirs")
+>>>>>>> origin/main
+}
+`
+
+const multiHunk = `line 1
+line 2
+line 3
+line 4
+<<<<<<< HEAD
+first ours
+=======
+first theirs
+>>>>>>> origin/main
+line 10
+line 11
+line 12
+line 13
+line 14
+line 15
+line 16
+line 17
+line 18
+line 19
+<<<<<<< HEAD
+second ours
+||||||| base
+second base
+=======
+second theirs
+>>>>>>> origin/main
+line 27
+`
+
+func TestParseConflictHunksSingle(t *testing.T) {
+	hunks, err := ParseConflictHunks(singleHunk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+
+	h := hunks[0]
+	if h.Ours != "\tfmt.Println(\"ours\")\n" || h.Theirs != "\tfmt.Println(\"theirs\")\n" {
+		t.Errorf("unexpected hunk sides: %+v", h)
+	}
+	if h.OursLabel != "HEAD" || h.TheirsLabel != "origin/main" {
+		t.Errorf("unexpected labels: %q / %q", h.OursLabel, h.TheirsLabel)
+	}
+	if h.StartLine != 5 || h.EndLine != 9 {
+		t.Errorf("unexpected line range: %d-%d", h.StartLine, h.EndLine)
+	}
+}
+
+func TestParseConflictHunksMultiWithBase(t *testing.T) {
+	hunks, err := ParseConflictHunks(multiHunk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	if hunks[1].Base != "second base\n" || hunks[1].Ours != "second ours\n" || hunks[1].Theirs != "second theirs\n" {
+		t.Errorf("unexpected diff3 hunk: %+v", hunks[1])
+	}
+}
+
+func TestParseConflictHunksUnterminated(t *testing.T) {
+	if _, err := ParseConflictHunks("a\n<<<<<<< HEAD\nours\n=======\ntheirs\n"); err == nil {
+		t.Fatal("expected error for unterminated conflict")
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	if !HasConflictMarkers(singleHunk) {
+		t.Error("expected markers to be detected")
+	}
+	if HasConflictMarkers("Title\n=======\n\nBody text\n") {
+		t.Error("a Markdown heading underline should not count as a conflict marker")
+	}
+	if HasConflictMarkers("x := a << 2\n") {
+		t.Error("shift operators should not count as conflict markers")
+	}
+}
+
+func TestResolveConflictHunksSingle(t *testing.T) {
+	var snippets []string
+	resolved, err := ResolveConflictHunks(singleHunk, func(snippet string) (string, error) {
+		snippets = append(snippets, snippet)
+		return "import \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"merged\")\n}", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(snippets) != 1 {
+		t.Fatalf("expected 1 resolver call, got %d", len(snippets))
+	}
+	// Three lines of context before the hunk, one (all that's left) after
+	if !strings.HasPrefix(snippets[0], "import \"fmt\"\n\nfunc main() {\n<<<<<<< HEAD") {
+		t.Errorf("unexpected snippet context: %q", snippets[0])
+	}
+
+	expected := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"merged\")\n}\n"
+	if resolved != expected {
+		t.Errorf("unexpected result:\n%s", resolved)
+	}
+}
+
+func TestResolveConflictHunksMultiLeavesRestUntouched(t *testing.T) {
+	calls := 0
+	resolved, err := ResolveConflictHunks(multiHunk, func(snippet string) (string, error) {
+		calls++
+		if strings.Count(snippet, "<<<<<<<") != 1 {
+			t.Errorf("each snippet should contain exactly one hunk: %q", snippet)
+		}
+		if strings.Contains(snippet, "line 14") {
+			t.Errorf("snippet should not include lines far from the hunk: %q", snippet)
+		}
+		// Replace the markers with a single resolved line, keeping context as given
+		var out []string
+		inHunk := false
+		for _, line := range strings.SplitAfter(snippet, "\n") {
+			switch {
+			case strings.HasPrefix(line, "<<<<<<<"):
+				inHunk = true
+				out = append(out, "resolved\n")
+			case strings.HasPrefix(line, ">>>>>>>"):
+				inHunk = false
+			case !inHunk:
+				out = append(out, line)
+			}
+		}
+		return strings.Join(out, ""), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected one resolver call per hunk, got %d", calls)
+	}
+	if HasConflictMarkers(resolved) {
+		t.Errorf("markers remain after resolution:\n%s", resolved)
+	}
+	for _, want := range []string{"line 1\n", "line 4\n", "line 10\n", "line 14\n", "line 19\n", "line 27\n"} {
+		if !strings.Contains(resolved, want) {
+			t.Errorf("untouched line %q missing from result", want)
+		}
+	}
+	if strings.Count(resolved, "resolved\n") != 2 {
+		t.Errorf("expected both hunks to be replaced:\n%s", resolved)
+	}
+}
+
+func TestResolveFileConflictRejectsRemainingMarkers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(singleHunk), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("owner", "repo", "")
+	client.SetDir(dir)
+	client.SetOutput(nil)
+
+	// The resolver echoes the markers back unchanged
+	err := client.resolveFileConflict(context.Background(), "main.go", "Fix", func(path, content, title string, versions *claude.ConflictVersions) (string, error) {
+		return content, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "conflict markers") {
+		t.Fatalf("expected conflict marker error, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseRemoteURL extracts the owner and repository name from a GitHub remote
+// URL in SSH (git@github.com:owner/repo.git, ssh://git@github.com/owner/repo)
+// or HTTPS (https://github.com/owner/repo.git) form
+func ParseRemoteURL(url string) (owner, repo string, err error) {
+	var path string
+	switch {
+	case strings.Contains(url, "://"):
+		rest := url[strings.Index(url, "://")+3:]
+		slash := strings.Index(rest, "/")
+		if slash < 0 {
+			return "", "", fmt.Errorf("remote %q has no repository path", url)
+		}
+		path = rest[slash+1:]
+	case strings.Contains(url, ":"):
+		// scp-like syntax: [user@]host:owner/repo
+		path = url[strings.Index(url, ":")+1:]
+	default:
+		return "", "", fmt.Errorf("remote %q is not a GitHub URL", url)
+	}
+
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("remote %q is not of the form owner/repo", url)
+	}
+	return parts[0], parts[1], nil
+}
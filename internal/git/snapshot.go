@@ -0,0 +1,46 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Clean reports whether the working tree has no changes, untracked files
+// included, against HEAD
+func (c *Client) Clean(ctx context.Context) (bool, error) {
+	status, err := c.runOutput(ctx, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("checking status: %w", err)
+	}
+	return strings.TrimSpace(status) == "", nil
+}
+
+// Snapshot records the index, where ApplyChanges stages its changes, as a
+// tree and returns the tree's ID. Nothing is committed.
+func (c *Client) Snapshot(ctx context.Context) (string, error) {
+	out, err := c.runOutput(ctx, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("recording the index: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// DiffSnapshots returns the patch that turns snapshot from into snapshot to
+func (c *Client) DiffSnapshots(ctx context.Context, from, to string) (string, error) {
+	out, err := c.runOutput(ctx, "-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", from, to)
+	if err != nil {
+		return "", fmt.Errorf("diffing snapshots: %w", err)
+	}
+	return out, nil
+}
+
+// RestoreSnapshot sets the index and the files it tracks back to snapshot:
+// files staged since are removed and changed ones rewritten. Untracked files
+// are left alone.
+func (c *Client) RestoreSnapshot(ctx context.Context, snapshot string) error {
+	if err := c.run(ctx, "read-tree", "--reset", "-u", snapshot); err != nil {
+		return fmt.Errorf("restoring snapshot: %w", err)
+	}
+	return nil
+}
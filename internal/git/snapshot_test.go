@@ -0,0 +1,69 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vibe-git/internal/claude"
+)
+
+func TestSnapshotDiffAndRestore(t *testing.T) {
+	clone, _ := newTestRepo(t, map[string]string{"keep.go": "package a\n", "old.go": "package old\n"})
+	c := NewClient("owner", "repo", "")
+	c.SetDir(clone)
+	c.SetOutput(nil)
+	ctx := context.Background()
+
+	if clean, err := c.Clean(ctx); err != nil || !clean {
+		t.Fatalf("expected a clean checkout, got %v (err %v)", clean, err)
+	}
+	before, err := c.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.ApplyChanges(ctx, []claude.FileChange{
+		{Path: "keep.go", Operation: "modify", Content: "package b\n"},
+		{Path: "new.go", Operation: "create", Content: "package new\n"},
+		{Path: "old.go", Operation: "delete"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := c.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean, _ := c.Clean(ctx); clean {
+		t.Error("expected applied changes to make the checkout dirty")
+	}
+
+	diff, err := c.DiffSnapshots(ctx, before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"-package a\n+package b", "+package new", "-package old"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", want, diff)
+		}
+	}
+
+	if err := c.RestoreSnapshot(ctx, before); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(clone, "keep.go")); string(content) != "package a\n" {
+		t.Errorf("expected keep.go restored, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "new.go")); !os.IsNotExist(err) {
+		t.Errorf("expected new.go removed, got %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(clone, "old.go")); string(content) != "package old\n" {
+		t.Errorf("expected old.go restored, got %q", content)
+	}
+	if clean, _ := c.Clean(ctx); !clean {
+		t.Error("expected the checkout to be clean after restoring")
+	}
+}
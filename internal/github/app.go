@@ -0,0 +1,232 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vibe-git/internal/httpclient"
+)
+
+const (
+	// appJWTLifetime is how long app JWTs are valid; GitHub accepts at most 10 minutes
+	appJWTLifetime = 9 * time.Minute
+	// appClockSkew backdates JWTs so a slightly fast GitHub clock still accepts them
+	appClockSkew = time.Minute
+	// tokenRefreshMargin is how long before expiry an installation token is replaced,
+	// so a token never expires in the middle of a push or a wait for checks
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// AppAuth authenticates as a GitHub App installation. It signs JWTs with the
+// app's private key, exchanges them for installation tokens and refreshes the
+// token shortly before it expires.
+type AppAuth struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	baseURL        string
+	http           *http.Client
+	now            func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAppAuth creates installation authentication for a GitHub App from its
+// PEM-encoded private key (PKCS#1 as GitHub issues it, or PKCS#8)
+func NewAppAuth(appID, installationID int64, privateKeyPEM []byte) (*AppAuth, error) {
+	if appID <= 0 || installationID <= 0 {
+		return nil, fmt.Errorf("app ID and installation ID are required")
+	}
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &AppAuth{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		baseURL:        githubAPIURL,
+		http:           &http.Client{Timeout: 30 * time.Second, Transport: httpclient.Transport()},
+		now:            time.Now,
+	}, nil
+}
+
+// NewAppClient creates a client that authenticates as a GitHub App installation
+func NewAppClient(appID, installationID int64, privateKeyPEM []byte, owner, repo string) (*Client, error) {
+	auth, err := NewAppAuth(appID, installationID, privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithApp(auth, owner, repo), nil
+}
+
+// NewClientWithApp creates a client for owner/repo authenticating with auth,
+// so clients for several repositories can share one installation token
+func NewClientWithApp(auth *AppAuth, owner, repo string) *Client {
+	c := NewClient("", owner, repo)
+	c.app = auth
+	c.http.Transport = &appTransport{auth: auth, base: c.http.Transport}
+	return c
+}
+
+// AppID returns the GitHub App's ID
+func (a *AppAuth) AppID() int64 {
+	return a.appID
+}
+
+// InstallationID returns the ID of the installation tokens are requested for
+func (a *AppAuth) InstallationID() int64 {
+	return a.installationID
+}
+
+// AppAuth returns the client's app authentication, or nil for token clients
+func (c *Client) AppAuth() *AppAuth {
+	return c.app
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("app private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("app private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns a token identifying the app itself, signed with RS256
+func (a *AppAuth) JWT() (string, error) {
+	now := a.now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing app JWT: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+// Token returns an installation token, requesting a new one when there is
+// none yet or the current one expires within tokenRefreshMargin
+func (a *AppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && a.now().Add(tokenRefreshMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	token, expires, err := a.requestToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.expires = token, expires
+	return token, nil
+}
+
+// requestToken exchanges a fresh JWT for an installation token
+func (a *AppAuth) requestToken(ctx context.Context) (string, time.Time, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.baseURL, a.installationID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("requesting installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("requesting installation token: %w", &APIError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("decoding installation token: %w", err)
+	}
+	if result.Token == "" {
+		return "", time.Time{}, errors.New("GitHub returned an empty installation token")
+	}
+	return result.Token, result.ExpiresAt, nil
+}
+
+// appTransport authenticates every API request with the current installation token
+type appTransport struct {
+	auth *AppAuth
+	base http.RoundTripper
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.auth.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	authed := req.Clone(req.Context())
+	authed.Header.Set("Authorization", "Bearer "+token)
+
+	base := t.base
+	if base == nil {
+		base = httpclient.Transport()
+	}
+	return base.RoundTrip(authed)
+}
+
+// setBaseURL points token requests at the same API as the client
+func (a *AppAuth) setBaseURL(baseURL string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.baseURL = strings.TrimSuffix(baseURL, "/")
+}
@@ -0,0 +1,202 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAppKey returns a freshly generated RSA key and its PKCS#1 PEM encoding
+func testAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppJWT(t *testing.T) {
+	key, keyPEM := testAppKey(t)
+	auth, err := NewAppAuth(42, 7, keyPEM)
+	if err != nil {
+		t.Fatalf("NewAppAuth: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	jwt, err := auth.JWT()
+	if err != nil {
+		t.Fatalf("JWT: %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 JWT segments, got %d", len(parts))
+	}
+
+	enc := base64.RawURLEncoding
+	var header map[string]string
+	decodeSegment(t, parts[0], &header)
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		t.Errorf("unexpected header %v", header)
+	}
+
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	decodeSegment(t, parts[1], &claims)
+	if claims.Iss != "42" {
+		t.Errorf("iss = %q, want %q", claims.Iss, "42")
+	}
+	if claims.Iat != now.Add(-time.Minute).Unix() {
+		t.Errorf("iat should be backdated a minute, got %d", claims.Iat)
+	}
+	if claims.Exp != now.Add(9*time.Minute).Unix() {
+		t.Errorf("exp should be 9 minutes out, got %d", claims.Exp)
+	}
+
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decoding signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func decodeSegment(t *testing.T, segment string, v interface{}) {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		t.Fatalf("decoding segment: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("unmarshaling segment: %v", err)
+	}
+}
+
+func TestNewAppAuthAcceptsPKCS8(t *testing.T) {
+	key, _ := testAppKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if _, err := NewAppAuth(1, 2, keyPEM); err != nil {
+		t.Errorf("expected PKCS#8 key to be accepted: %v", err)
+	}
+	if _, err := NewAppAuth(1, 2, []byte("not a key")); err == nil {
+		t.Error("expected error for a non-PEM key")
+	}
+}
+
+func TestAppTokenRefreshesBeforeExpiry(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+			t.Errorf("expected a JWT bearer, got %q", r.Header.Get("Authorization"))
+		}
+		requests++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, requests, now.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	auth, err := NewAppAuth(42, 7, keyPEM)
+	if err != nil {
+		t.Fatalf("NewAppAuth: %v", err)
+	}
+	auth.setBaseURL(server.URL)
+	clock := now
+	auth.now = func() time.Time { return clock }
+
+	ctx := context.Background()
+	token := func() string {
+		t.Helper()
+		tok, err := auth.Token(ctx)
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		return tok
+	}
+
+	if got := token(); got != "ghs_1" {
+		t.Fatalf("first token = %q", got)
+	}
+	// Still well within the token's lifetime: the cached token is reused
+	clock = now.Add(50 * time.Minute)
+	if got := token(); got != "ghs_1" || requests != 1 {
+		t.Errorf("expected cached token, got %q after %d requests", got, requests)
+	}
+	// Within five minutes of expiry: a new token is requested
+	clock = now.Add(56 * time.Minute)
+	if got := token(); got != "ghs_2" || requests != 2 {
+		t.Errorf("expected refreshed token, got %q after %d requests", got, requests)
+	}
+}
+
+func TestAppClientUsesInstallationToken(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/app/installations/7/access_tokens" {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token":"ghs_install","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer ghs_install" {
+			t.Errorf("Authorization = %q, want the installation token", got)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"number": 3, "title": "t", "html_url": "u"})
+	}))
+	defer server.Close()
+
+	client, err := NewAppClient(42, 7, keyPEM, "owner", "repo")
+	if err != nil {
+		t.Fatalf("NewAppClient: %v", err)
+	}
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.GetPullRequest(context.Background(), 3); err != nil {
+		t.Fatalf("GetPullRequest: %v", err)
+	}
+}
+
+func TestAppTokenRequestError(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"A JSON web token could not be decoded"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client, err := NewAppClient(42, 7, keyPEM, "owner", "repo")
+	if err != nil {
+		t.Fatalf("NewAppClient: %v", err)
+	}
+	client.SetBaseURL(server.URL)
+
+	_, err = client.GetPullRequest(context.Background(), 3)
+	if err == nil || !strings.Contains(err.Error(), "installation token") {
+		t.Errorf("expected installation token error, got %v", err)
+	}
+}
@@ -7,42 +7,123 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
+
+	"vibe-git/internal/breaker"
+	"vibe-git/internal/httpclient"
 )
 
 const githubAPIURL = "https://api.github.com"
 
 // Client wraps the GitHub API
 type Client struct {
-	token  string
-	owner  string
-	repo   string
-	http   *http.Client
+	token   string
+	owner   string
+	repo    string
+	baseURL string
+	http    *http.Client
+	app     *AppAuth // Set for GitHub App clients; the token comes from it instead
+
+	mergePollInterval time.Duration // How often WaitForMergeable checks the PR
+}
+
+// APIError is returned when the API answers with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// IsAuthError reports whether the API rejected the credentials
+func (e *APIError) IsAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
 }
 
 // Issue represents a GitHub issue
 type Issue struct {
-	Number  int
-	Title   string
-	Body    string
-	URL     string
-	State   string
-	Labels  []string
+	Number int
+	Title  string
+	Body   string
+	URL    string
+	State  string
+	Labels []string
+
+	Author   string // Login of the user who opened the issue
+	AuthorID int64  // GitHub account ID of Author
+
+	UpdatedAt   time.Time
+	PullRequest bool // GitHub lists pull requests as issues too
+}
+
+// Permissions are the token's permissions on the repository
+type Permissions struct {
+	Admin    bool `json:"admin"`
+	Maintain bool `json:"maintain"`
+	Push     bool `json:"push"`
+	Triage   bool `json:"triage"`
+	Pull     bool `json:"pull"`
 }
 
-// NewClient creates a new GitHub client
+// DefaultTimeout bounds each API request of a client from NewClient, so a hung
+// connection can't block forever. Long waits like WaitForMergeable are made of
+// many short requests.
+const DefaultTimeout = 30 * time.Second
+
+// NewClient creates a new GitHub client whose requests time out after DefaultTimeout
 func NewClient(token, owner, repo string) *Client {
+	return NewClientWithTimeout(token, owner, repo, DefaultTimeout)
+}
+
+// NewClientWithTimeout creates a new GitHub client whose requests fail after
+// timeout, including reading the response; 0 means no timeout
+func NewClientWithTimeout(token, owner, repo string, timeout time.Duration) *Client {
 	return &Client{
-		token: token,
-		owner: owner,
-		repo:  repo,
-		http:  &http.Client{},
+		token:   token,
+		owner:   owner,
+		repo:    repo,
+		baseURL: githubAPIURL,
+		http:    &http.Client{Transport: httpclient.Transport(), Timeout: timeout},
+
+		mergePollInterval: 10 * time.Second,
+	}
+}
+
+// SetTimeout changes how long each request may take; 0 means no timeout
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.http.Timeout = timeout
+}
+
+// SetBaseURL overrides the API base URL (e.g. for GitHub Enterprise or tests)
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	if c.app != nil {
+		c.app.setBaseURL(baseURL)
 	}
 }
 
+// SetHTTPClient replaces the HTTP client API requests are sent with (e.g. for tests)
+func (c *Client) SetHTTPClient(h *http.Client) {
+	c.http = h
+}
+
+// SetMergePollInterval changes how often WaitForMergeable checks the PR (e.g. for tests)
+func (c *Client) SetMergePollInterval(d time.Duration) {
+	c.mergePollInterval = d
+}
+
+// SetBreaker routes every API request through the circuit breaker b
+func (c *Client) SetBreaker(b *breaker.Breaker) {
+	c.http.Transport = b.Transport(c.http.Transport)
+}
+
 // GetIssue fetches a single issue by number
 func (c *Client) GetIssue(ctx context.Context, number int) (*Issue, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", githubAPIURL, c.owner, c.repo, number)
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.baseURL, c.owner, c.repo, number)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -61,7 +142,7 @@ func (c *Client) GetIssue(ctx context.Context, number int) (*Issue, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result struct {
@@ -73,6 +154,12 @@ func (c *Client) GetIssue(ctx context.Context, number int) (*Issue, error) {
 		Labels  []struct {
 			Name string `json:"name"`
 		} `json:"labels"`
+		User struct {
+			Login string `json:"login"`
+			ID    int64  `json:"id"`
+		} `json:"user"`
+		UpdatedAt   time.Time `json:"updated_at"`
+		PullRequest *struct{} `json:"pull_request"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -91,12 +178,18 @@ func (c *Client) GetIssue(ctx context.Context, number int) (*Issue, error) {
 		URL:    result.HTMLURL,
 		State:  result.State,
 		Labels: labels,
+
+		Author:   result.User.Login,
+		AuthorID: result.User.ID,
+
+		UpdatedAt:   result.UpdatedAt,
+		PullRequest: result.PullRequest != nil,
 	}, nil
 }
 
 // CreatePullRequest creates a new pull request
 func (c *Client) CreatePullRequest(ctx context.Context, base, head, title, body string) (string, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls", githubAPIURL, c.owner, c.repo)
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls", c.baseURL, c.owner, c.repo)
 
 	requestBody := map[string]interface{}{
 		"title": title,
@@ -128,7 +221,7 @@ func (c *Client) CreatePullRequest(ctx context.Context, base, head, title, body
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result struct {
@@ -143,8 +236,8 @@ func (c *Client) CreatePullRequest(ctx context.Context, base, head, title, body
 }
 
 // CreatePullRequestWithNumber creates a new pull request and returns PR number and URL
-func (c *Client) CreatePullRequestWithNumber(ctx context.Context, base, head, title, body string) (int, string, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls", githubAPIURL, c.owner, c.repo)
+func (c *Client) CreatePullRequestWithNumber(ctx context.Context, base, head, title, body string, draft bool) (int, string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls", c.baseURL, c.owner, c.repo)
 
 	requestBody := map[string]interface{}{
 		"title": title,
@@ -152,6 +245,9 @@ func (c *Client) CreatePullRequestWithNumber(ctx context.Context, base, head, ti
 		"base":  base,
 		"body":  body,
 	}
+	if draft {
+		requestBody["draft"] = true
+	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
@@ -176,7 +272,7 @@ func (c *Client) CreatePullRequestWithNumber(ctx context.Context, base, head, ti
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return 0, "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return 0, "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result struct {
@@ -191,220 +287,1042 @@ func (c *Client) CreatePullRequestWithNumber(ctx context.Context, base, head, ti
 	return result.Number, result.HTMLURL, nil
 }
 
-// MergePullRequest merges a pull request
-func (c *Client) MergePullRequest(ctx context.Context, prNumber int, commitTitle, commitMessage string) error {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/merge", githubAPIURL, c.owner, c.repo, prNumber)
+// PullRequest represents a GitHub pull request
+type PullRequest struct {
+	Number         int
+	Title          string
+	Body           string
+	URL            string
+	State          string
+	Head           string
+	Base           string
+	Merged         bool
+	Draft          bool
+	NodeID         string // GraphQL ID, needed for draft conversion
+	Mergeable      *bool
+	MergeableState string // "clean", "dirty" (conflicts), "blocked", "behind", "unstable", "unknown", ...
+}
 
-	requestBody := map[string]interface{}{
-		"commit_title":   commitTitle,
-		"commit_message": commitMessage,
-		"merge_method":   "squash",
+// GetPullRequest fetches a single pull request by number
+func (c *Client) GetPullRequest(ctx context.Context, prNumber int) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, c.owner, c.repo, prNumber)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonBody, err := json.Marshal(requestBody)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
 	if err != nil {
-		return fmt.Errorf("marshaling request: %w", err)
+		return nil, fmt.Errorf("fetching PR %d: %w", prNumber, err)
 	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(jsonBody))
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result pullRequestJSON
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return result.toPullRequest(), nil
+}
+
+// GetPullRequestForBranch returns the open pull request whose head is the given branch,
+// or nil if there is none. head may be "branch" (in this repository's owner) or "owner:branch".
+func (c *Client) GetPullRequestForBranch(ctx context.Context, head string) (*PullRequest, error) {
+	if !strings.Contains(head, ":") {
+		head = c.owner + ":" + head
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s", c.baseURL, c.owner, c.repo, neturl.QueryEscape(head))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return fmt.Errorf("merging PR: %w", err)
+		return nil, fmt.Errorf("listing PRs for %s: %w", head, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	return nil
-}
-
-// CloseIssue closes an issue
-func (c *Client) CloseIssue(ctx context.Context, issueNumber int) error {
-	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", githubAPIURL, c.owner, c.repo, issueNumber)
+	var results []pullRequestJSON
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
 
-	requestBody := map[string]interface{}{
-		"state": "closed",
+	if len(results) == 0 {
+		return nil, nil
 	}
+	return results[0].toPullRequest(), nil
+}
 
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return fmt.Errorf("marshaling request: %w", err)
+// ListPullRequestsForBranch returns the pull requests in any state whose head
+// is the given branch, newest first. head may be "branch" or "owner:branch".
+func (c *Client) ListPullRequestsForBranch(ctx context.Context, head string) ([]*PullRequest, error) {
+	if !strings.Contains(head, ":") {
+		head = c.owner + ":" + head
 	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=all&head=%s&per_page=%d", c.baseURL, c.owner, c.repo, neturl.QueryEscape(head), pullRequestsPerPage)
 
-	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return fmt.Errorf("closing issue: %w", err)
+		return nil, fmt.Errorf("listing PRs for %s: %w", head, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	return nil
-}
-
-// WaitForMergeable waits for PR to be mergeable
-func (c *Client) WaitForMergeable(ctx context.Context, prNumber int, timeout time.Duration) error {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPIURL, c.owner, c.repo, prNumber)
+	var results []pullRequestJSON
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	prs := make([]*PullRequest, 0, len(results))
+	for i := range results {
+		prs = append(prs, results[i].toPullRequest())
+	}
+	return prs, nil
+}
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+// pullRequestsPerPage is the page size used when listing pull requests
+const pullRequestsPerPage = 100
 
-	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("timeout waiting for PR to be mergeable")
-		case <-ticker.C:
-			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-			if err != nil {
-				return err
-			}
+// ListOpenPullRequests returns every open pull request in the repository,
+// following pages until GitHub returns a short one
+func (c *Client) ListOpenPullRequests(ctx context.Context) ([]*PullRequest, error) {
+	var prs []*PullRequest
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&per_page=%d&page=%d", c.baseURL, c.owner, c.repo, pullRequestsPerPage, page)
 
-			req.Header.Set("Authorization", "Bearer "+c.token)
-			req.Header.Set("Accept", "application/vnd.github+json")
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
 
-			resp, err := c.http.Do(req)
-			if err != nil {
-				resp.Body.Close()
-				continue
-			}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-			var result struct {
-				Mergeable *bool  `json:"mergeable"`
-				State     string `json:"state"`
-			}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing open PRs: %w", err)
+		}
 
-			json.NewDecoder(resp.Body).Decode(&result)
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
 
-			if result.State == "closed" {
-				return fmt.Errorf("PR was closed")
-			}
+		var results []pullRequestJSON
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
 
-			if result.Mergeable != nil && *result.Mergeable {
-				return nil
-			}
+		for i := range results {
+			prs = append(prs, results[i].toPullRequest())
+		}
+		if len(results) < pullRequestsPerPage {
+			return prs, nil
 		}
 	}
 }
 
-// GetDefaultBranch returns the default branch for the repository
-func (c *Client) GetDefaultBranch(ctx context.Context) (string, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s", githubAPIURL, c.owner, c.repo)
+// pullRequestJSON is the subset of the GitHub pull request payload we use
+type pullRequestJSON struct {
+	Number         int    `json:"number"`
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	HTMLURL        string `json:"html_url"`
+	State          string `json:"state"`
+	Merged         bool   `json:"merged"`
+	MergedAt       string `json:"merged_at"` // Set in listings, which leave out merged
+	Draft          bool   `json:"draft"`
+	NodeID         string `json:"node_id"`
+	Mergeable      *bool  `json:"mergeable"`
+	MergeableState string `json:"mergeable_state"`
+	Head           struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+func (p *pullRequestJSON) toPullRequest() *PullRequest {
+	return &PullRequest{
+		Number:         p.Number,
+		Title:          p.Title,
+		Body:           p.Body,
+		URL:            p.HTMLURL,
+		State:          p.State,
+		Head:           p.Head.Ref,
+		Base:           p.Base.Ref,
+		Merged:         p.Merged || p.MergedAt != "",
+		Draft:          p.Draft,
+		NodeID:         p.NodeID,
+		Mergeable:      p.Mergeable,
+		MergeableState: p.MergeableState,
+	}
+}
+
+// UpdatePullRequest changes the title and body of a pull request. Empty values are left unchanged.
+func (c *Client) UpdatePullRequest(ctx context.Context, prNumber int, title, body string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, c.owner, c.repo, prNumber)
+
+	requestBody := map[string]interface{}{}
+	if title != "" {
+		requestBody["title"] = title
+	}
+	if body != "" {
+		requestBody["body"] = body
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("fetching repo: %w", err)
+		return fmt.Errorf("updating PR %d: %w", prNumber, err)
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		DefaultBranch string `json:"default_branch"`
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// SetPullRequestDraft converts a pull request to a draft or marks it ready for review.
+// The REST API can't change the draft state, so this goes through GraphQL.
+func (c *Client) SetPullRequestDraft(ctx context.Context, prNumber int, draft bool) error {
+	pr, err := c.GetPullRequest(ctx, prNumber)
+	if err != nil {
+		return err
+	}
+	if pr.Draft == draft {
+		return nil
+	}
+
+	mutation := "mutation($id: ID!) { markPullRequestReadyForReview(input: {pullRequestId: $id}) { clientMutationId } }"
+	if draft {
+		mutation = "mutation($id: ID!) { convertPullRequestToDraft(input: {pullRequestId: $id}) { clientMutationId } }"
+	}
+
+	requestBody := map[string]interface{}{
+		"query":     mutation,
+		"variables": map[string]string{"id": pr.NodeID},
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/graphql", bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("changing draft state of PR %d: %w", prNumber, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
+	// GraphQL reports failures in the body with a 200 status
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("changing draft state of PR %d: %s", prNumber, result.Errors[0].Message)
 	}
 
-	return result.DefaultBranch, nil
+	return nil
 }
 
-// ListRecentIssues lists issues created after the given time
-func (c *Client) ListRecentIssues(ctx context.Context, since time.Time) ([]*Issue, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/issues?state=open&sort=created&direction=desc&since=%s",
-		githubAPIURL, c.owner, c.repo, since.Format(time.RFC3339))
+// MergePullRequest merges a pull request
+func (c *Client) MergePullRequest(ctx context.Context, prNumber int, commitTitle, commitMessage string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/merge", c.baseURL, c.owner, c.repo, prNumber)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	requestBody := map[string]interface{}{
+		"commit_title":   commitTitle,
+		"commit_message": commitMessage,
+		"merge_method":   "squash",
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("fetching issues: %w", err)
+		return fmt.Errorf("merging PR: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	var results []struct {
-		Number  int    `json:"number"`
-		Title   string `json:"title"`
-		Body    string `json:"body"`
-		HTMLURL string `json:"html_url"`
-		State   string `json:"state"`
-		Labels  []struct {
-			Name string `json:"name"`
-		} `json:"labels"`
-		PullRequest *struct{} `json:"pull_request"`
+	return nil
+}
+
+// CloseIssue closes an issue
+func (c *Client) CloseIssue(ctx context.Context, issueNumber int) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.baseURL, c.owner, c.repo, issueNumber)
+
+	requestBody := map[string]interface{}{
+		"state": "closed",
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
 	}
 
-	var issues []*Issue
-	for _, r := range results {
-		// Skip pull requests (GitHub API returns them as issues too)
-		if r.PullRequest != nil {
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
 
-		labels := make([]string, len(r.Labels))
-		for i, label := range r.Labels {
-			labels[i] = label.Name
-		}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-		issues = append(issues, &Issue{
-			Number: r.Number,
-			Title:  r.Title,
-			Body:   r.Body,
-			URL:    r.HTMLURL,
-			State:  r.State,
-			Labels: labels,
-		})
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("closing issue: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	return issues, nil
+	return nil
+}
+
+// AddIssueComment posts a comment on an issue or pull request
+func (c *Client) AddIssueComment(ctx context.Context, number int, body string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", c.baseURL, c.owner, c.repo, number)
+
+	requestBody := map[string]interface{}{
+		"body": body,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("adding comment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// Comment is a comment on an issue or pull request
+type Comment struct {
+	ID          int64
+	Body        string
+	Author      string // Login of the commenter
+	Association string // The commenter's role in the repository: OWNER, MEMBER, COLLABORATOR, NONE, ...
+	CreatedAt   time.Time
+}
+
+// commentsPerPage is the page size used when listing comments
+const commentsPerPage = 100
+
+// ListIssueComments returns every comment on an issue or pull request, oldest
+// first, following pages until GitHub returns a short one
+func (c *Client) ListIssueComments(ctx context.Context, number int) ([]*Comment, error) {
+	var comments []*Comment
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=%d&page=%d", c.baseURL, c.owner, c.repo, number, commentsPerPage, page)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+
+		var results []struct {
+			ID        int64     `json:"id"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+			User      struct {
+				Login string `json:"login"`
+			} `json:"user"`
+			AuthorAssociation string `json:"author_association"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+
+		for _, r := range results {
+			comments = append(comments, &Comment{
+				ID:          r.ID,
+				Body:        r.Body,
+				Author:      r.User.Login,
+				Association: r.AuthorAssociation,
+				CreatedAt:   r.CreatedAt,
+			})
+		}
+		if len(results) < commentsPerPage {
+			return comments, nil
+		}
+	}
+}
+
+// AddLabelsToIssue adds labels to an issue or pull request (PRs are issues for labeling)
+func (c *Client) AddLabelsToIssue(ctx context.Context, number int, labels []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels", c.baseURL, c.owner, c.repo, number)
+
+	requestBody := map[string]interface{}{
+		"labels": labels,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("adding labels: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// RequestReviewers requests review on a pull request from users and teams (team slugs)
+func (c *Client) RequestReviewers(ctx context.Context, prNumber int, users, teams []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", c.baseURL, c.owner, c.repo, prNumber)
+
+	requestBody := map[string]interface{}{}
+	if len(users) > 0 {
+		requestBody["reviewers"] = users
+	}
+	if len(teams) > 0 {
+		requestBody["team_reviewers"] = teams
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting reviewers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// AddAssignees assigns users to an issue or pull request and returns the logins
+// actually assigned. GitHub silently ignores users who can't be assigned.
+func (c *Client) AddAssignees(ctx context.Context, number int, users []string) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", c.baseURL, c.owner, c.repo, number)
+
+	requestBody := map[string]interface{}{
+		"assignees": users,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("adding assignees: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		Assignees []struct {
+			Login string `json:"login"`
+		} `json:"assignees"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	assigned := make([]string, len(result.Assignees))
+	for i, a := range result.Assignees {
+		assigned[i] = a.Login
+	}
+
+	return assigned, nil
+}
+
+// defaultLabelColor is used for labels created by EnsureLabel
+const defaultLabelColor = "ededed"
+
+// EnsureLabel creates the label in the repository if it doesn't exist yet
+func (c *Client) EnsureLabel(ctx context.Context, name string) error {
+	labelURL := fmt.Sprintf("%s/repos/%s/%s/labels/%s", c.baseURL, c.owner, c.repo, neturl.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", labelURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching label: %w", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		// Create it below
+	default:
+		return fmt.Errorf("fetching label %q: %w", name, &APIError{StatusCode: resp.StatusCode})
+	}
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"name":  name,
+		"color": defaultLabelColor,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/repos/%s/%s/labels", c.baseURL, c.owner, c.repo), bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err = c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("creating label: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// WaitForMergeable waits for PR to be mergeable
+func (c *Client) WaitForMergeable(ctx context.Context, prNumber int, timeout time.Duration) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, c.owner, c.repo, prNumber)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(c.mergePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for PR to be mergeable")
+		case <-ticker.C:
+			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			req.Header.Set("Authorization", "Bearer "+c.token)
+			req.Header.Set("Accept", "application/vnd.github+json")
+
+			resp, err := c.http.Do(req)
+			if err != nil {
+				continue
+			}
+
+			var result struct {
+				Mergeable *bool  `json:"mergeable"`
+				State     string `json:"state"`
+			}
+
+			json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+
+			if result.State == "closed" {
+				return fmt.Errorf("PR was closed")
+			}
+
+			if result.Mergeable != nil && *result.Mergeable {
+				return nil
+			}
+		}
+	}
+}
+
+// GetAuthenticatedUser returns the login of the user the token belongs to
+func (c *Client) GetAuthenticatedUser(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/user", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		Login string `json:"login"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+
+	return result.Login, nil
+}
+
+// GetRepoPermissions returns what the token is allowed to do on the repository
+func (c *Client) GetRepoPermissions(ctx context.Context) (Permissions, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, c.owner, c.repo)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return Permissions{}, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("fetching repo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return Permissions{}, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		Permissions *Permissions `json:"permissions"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Permissions{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	// GitHub omits permissions for unauthenticated requests
+	if result.Permissions == nil {
+		return Permissions{}, fmt.Errorf("repository response has no permissions (is the token valid?)")
+	}
+
+	return *result.Permissions, nil
+}
+
+// GetDefaultBranch returns the default branch for the repository
+func (c *Client) GetDefaultBranch(ctx context.Context) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, c.owner, c.repo)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching repo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+
+	return result.DefaultBranch, nil
+}
+
+// BranchExists reports whether the repository has a branch called name
+func (c *Client) BranchExists(ctx context.Context, name string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/branches/%s", c.baseURL, c.owner, c.repo, neturl.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fetching branch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+}
+
+// ListBranches returns the names of the branches starting with prefix
+func (c *Client) ListBranches(ctx context.Context, prefix string) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/matching-refs/heads/%s", c.baseURL, c.owner, c.repo, prefix)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var refs []struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&refs); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	branches := make([]string, 0, len(refs))
+	for _, r := range refs {
+		branches = append(branches, strings.TrimPrefix(r.Ref, "refs/heads/"))
+	}
+	return branches, nil
+}
+
+// DeleteBranch deletes a branch from the repository
+func (c *Client) DeleteBranch(ctx context.Context, branch string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", c.baseURL, c.owner, c.repo, branch)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("deleting branch %s: %w", branch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// issuesPerPage is the page size used when listing issues
+const issuesPerPage = 100
+
+// ListRecentIssues lists the issues in state ("open", "closed" or "all")
+// updated at or after since, which includes those created since then, most
+// recently updated first. Pull requests, which GitHub lists as issues too, are
+// left out unless includePRs.
+//
+// GitHub's since filters on the update time, so the listing is sorted by it
+// as well: an issue updated while the pages are read moves to the front and
+// shows up twice rather than pushing another one off a page. Duplicates are
+// dropped.
+func (c *Client) ListRecentIssues(ctx context.Context, since time.Time, state string, includePRs bool) ([]*Issue, error) {
+	var issues []*Issue
+	seen := make(map[int]bool)
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/issues?state=%s&sort=updated&direction=desc&since=%s&per_page=%d&page=%d",
+			c.baseURL, c.owner, c.repo, neturl.QueryEscape(state), neturl.QueryEscape(since.UTC().Format(time.RFC3339)), issuesPerPage, page)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching issues: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+
+		var results []struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+			State   string `json:"state"`
+			Labels  []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+			User struct {
+				Login string `json:"login"`
+				ID    int64  `json:"id"`
+			} `json:"user"`
+			UpdatedAt   time.Time `json:"updated_at"`
+			PullRequest *struct{} `json:"pull_request"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+
+		for _, r := range results {
+			if seen[r.Number] {
+				continue
+			}
+			seen[r.Number] = true
+
+			if r.PullRequest != nil && !includePRs {
+				continue
+			}
+			// since is GitHub's filter; don't rely on it alone for the boundary.
+			// Both are whole seconds, so an update in since's second counts.
+			// Entries without an update time are kept.
+			if !r.UpdatedAt.IsZero() && r.UpdatedAt.Before(since.Truncate(time.Second)) {
+				continue
+			}
+
+			labels := make([]string, len(r.Labels))
+			for i, label := range r.Labels {
+				labels[i] = label.Name
+			}
+
+			issues = append(issues, &Issue{
+				Number: r.Number,
+				Title:  r.Title,
+				Body:   r.Body,
+				URL:    r.HTMLURL,
+				State:  r.State,
+				Labels: labels,
+
+				Author:   r.User.Login,
+				AuthorID: r.User.ID,
+
+				UpdatedAt:   r.UpdatedAt,
+				PullRequest: r.PullRequest != nil,
+			})
+		}
+
+		if len(results) < issuesPerPage {
+			return issues, nil
+		}
+	}
 }
@@ -0,0 +1,650 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client pointed at a stub GitHub API
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("test-token", "owner", "repo")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func TestGetPullRequest(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls/7" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("expected bearer token")
+		}
+		w.Write([]byte(`{
+			"number": 7,
+			"title": "Fix #3: bug",
+			"html_url": "https://github.com/owner/repo/pull/7",
+			"state": "open",
+			"mergeable": false,
+			"mergeable_state": "dirty",
+			"head": {"ref": "vibe-git/issue-3"},
+			"base": {"ref": "main"}
+		}`))
+	})
+
+	pr, err := client.GetPullRequest(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.Number != 7 || pr.MergeableState != "dirty" {
+		t.Errorf("unexpected PR: %+v", pr)
+	}
+	if pr.Mergeable == nil || *pr.Mergeable {
+		t.Errorf("expected mergeable=false")
+	}
+	if pr.Head != "vibe-git/issue-3" || pr.Base != "main" {
+		t.Errorf("unexpected head/base: %s/%s", pr.Head, pr.Base)
+	}
+}
+
+func TestGetPullRequestNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+
+	if _, err := client.GetPullRequest(context.Background(), 99); err == nil {
+		t.Fatal("expected error for missing PR")
+	}
+}
+
+func TestGetPullRequestForBranch(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("head"); got != "owner:vibe-git/issue-3" {
+			t.Errorf("unexpected head filter %q", got)
+		}
+		if got := r.URL.Query().Get("state"); got != "open" {
+			t.Errorf("expected open PRs only, got %q", got)
+		}
+		w.Write([]byte(`[{"number": 9, "html_url": "https://github.com/owner/repo/pull/9", "head": {"ref": "vibe-git/issue-3"}}]`))
+	})
+
+	pr, err := client.GetPullRequestForBranch(context.Background(), "vibe-git/issue-3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr == nil || pr.Number != 9 || pr.URL != "https://github.com/owner/repo/pull/9" {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+}
+
+func TestGetPullRequestForBranchNone(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+
+	pr, err := client.GetPullRequestForBranch(context.Background(), "fork:feature")
+	if err != nil || pr != nil {
+		t.Errorf("expected no PR and no error, got %+v, %v", pr, err)
+	}
+}
+
+func TestListOpenPullRequestsPaginates(t *testing.T) {
+	var pages []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "open" {
+			t.Errorf("expected open PRs only, got %q", got)
+		}
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+
+		// A full first page, then a short second one
+		n := pullRequestsPerPage
+		if page != "1" {
+			n = 1
+		}
+		var prs []string
+		for i := 0; i < n; i++ {
+			prs = append(prs, fmt.Sprintf(`{"number": %s%d, "head": {"ref": "branch-%d"}}`, page, i, i))
+		}
+		w.Write([]byte("[" + strings.Join(prs, ",") + "]"))
+	})
+
+	prs, err := client.ListOpenPullRequests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prs) != pullRequestsPerPage+1 {
+		t.Errorf("expected %d PRs, got %d", pullRequestsPerPage+1, len(prs))
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("expected pages 1,2, got %v", pages)
+	}
+}
+
+func TestListOpenPullRequestsError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource not accessible"}`))
+	})
+
+	if _, err := client.ListOpenPullRequests(context.Background()); err == nil {
+		t.Fatal("expected error listing PRs")
+	}
+}
+
+// recentIssuesHandler serves a full first page of issues 1..100 updated
+// after since, then a second page repeating issue 100 and adding PR 101,
+// issue 102 updated before since and issue 103
+func recentIssuesHandler(t *testing.T, since time.Time, pages *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("since") != since.Format(time.RFC3339) {
+			t.Errorf("expected since=%s, got %q", since.Format(time.RFC3339), q.Get("since"))
+		}
+		if q.Get("sort") != "updated" || q.Get("per_page") != "100" {
+			t.Errorf("expected sort=updated&per_page=100, got %s", r.URL.RawQuery)
+		}
+		*pages = append(*pages, q.Get("page"))
+
+		after := since.Add(time.Minute).Format(time.RFC3339)
+		var items []string
+		if q.Get("page") == "1" {
+			for i := 1; i <= issuesPerPage; i++ {
+				items = append(items, fmt.Sprintf(`{"number": %d, "updated_at": %q}`, i, after))
+			}
+		} else {
+			items = []string{
+				fmt.Sprintf(`{"number": 100, "updated_at": %q}`, after),
+				fmt.Sprintf(`{"number": 101, "updated_at": %q, "pull_request": {"url": "x"}}`, after),
+				fmt.Sprintf(`{"number": 102, "updated_at": %q}`, since.Add(-time.Minute).Format(time.RFC3339)),
+				fmt.Sprintf(`{"number": 103, "updated_at": %q}`, since.Format(time.RFC3339)),
+			}
+		}
+		w.Write([]byte("[" + strings.Join(items, ",") + "]"))
+	}
+}
+
+func TestListRecentIssuesPaginatesAndFilters(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var pages []string
+	client := newTestClient(t, recentIssuesHandler(t, since, &pages))
+
+	issues, err := client.ListRecentIssues(context.Background(), since, "open", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("expected pages 1,2, got %v", pages)
+	}
+	// 1..100 once each, not the PR nor the issue updated before since; an
+	// issue updated exactly at since is kept
+	if len(issues) != issuesPerPage+1 {
+		t.Fatalf("expected %d issues, got %d", issuesPerPage+1, len(issues))
+	}
+	if last := issues[len(issues)-1]; last.Number != 103 {
+		t.Errorf("expected issue #103 last, got #%d", last.Number)
+	}
+	for _, issue := range issues {
+		if issue.PullRequest || issue.Number == 102 {
+			t.Errorf("issue #%d should have been filtered out", issue.Number)
+		}
+	}
+}
+
+func TestListRecentIssuesIncludesPRs(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var pages []string
+	client := newTestClient(t, recentIssuesHandler(t, since, &pages))
+
+	issues, err := client.ListRecentIssues(context.Background(), since, "open", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var found bool
+	for _, issue := range issues {
+		if issue.Number == 101 {
+			found = issue.PullRequest
+		}
+	}
+	if !found {
+		t.Error("expected PR #101 to be listed and marked as a pull request")
+	}
+}
+
+func TestListRecentIssuesState(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("state"); got != "all" {
+			t.Errorf("expected state=all, got %q", got)
+		}
+		w.Write([]byte(`[{"number": 1, "state": "closed"}]`))
+	})
+
+	issues, err := client.ListRecentIssues(context.Background(), time.Time{}, "all", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].State != "closed" {
+		t.Errorf("expected closed issue #1, got %+v", issues)
+	}
+}
+
+func TestListIssueComments(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues/7/comments" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") != "1" {
+			t.Errorf("expected a single page, got page %s", r.URL.Query().Get("page"))
+		}
+		w.Write([]byte(`[{"id": 11, "body": "/cancel", "created_at": "2024-05-01T10:00:00Z", "user": {"login": "octocat"}, "author_association": "MEMBER"}]`))
+	})
+
+	comments, err := client.ListIssueComments(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &Comment{ID: 11, Body: "/cancel", Author: "octocat", Association: "MEMBER", CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	if len(comments) != 1 || *comments[0] != *want {
+		t.Errorf("unexpected comments %+v", comments)
+	}
+}
+
+func TestDeleteBranch(t *testing.T) {
+	var method, path string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := client.DeleteBranch(context.Background(), "vibe-git/issue-4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != http.MethodDelete || path != "/repos/owner/repo/git/refs/heads/vibe-git/issue-4" {
+		t.Errorf("unexpected request %s %s", method, path)
+	}
+
+	missing := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"Reference does not exist"}`))
+	})
+	var apiErr *APIError
+	if err := missing.DeleteBranch(context.Background(), "gone"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected a 422 APIError, got %v", err)
+	}
+}
+
+func TestListBranchesAndTheirPullRequests(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/git/matching-refs/heads/vibe-git/":
+			w.Write([]byte(`[{"ref": "refs/heads/vibe-git/issue-1"}, {"ref": "refs/heads/vibe-git/issue-2"}]`))
+		case "/repos/owner/repo/pulls":
+			if q := r.URL.Query(); q.Get("state") != "all" || q.Get("head") != "owner:vibe-git/issue-1" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"number": 3, "state": "closed", "merged_at": "2024-05-01T10:00:00Z"}, {"number": 2, "state": "closed", "merged_at": null}]`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	branches, err := client.ListBranches(context.Background(), "vibe-git/")
+	if err != nil {
+		t.Fatalf("ListBranches: %v", err)
+	}
+	if strings.Join(branches, ",") != "vibe-git/issue-1,vibe-git/issue-2" {
+		t.Errorf("unexpected branches %v", branches)
+	}
+
+	prs, err := client.ListPullRequestsForBranch(context.Background(), "vibe-git/issue-1")
+	if err != nil {
+		t.Fatalf("ListPullRequestsForBranch: %v", err)
+	}
+	if len(prs) != 2 || !prs[0].Merged || prs[1].Merged {
+		t.Errorf("expected merged_at to mark only PR #3 merged, got %+v %+v", prs[0], prs[1])
+	}
+}
+
+func TestUpdatePullRequest(t *testing.T) {
+	var payload map[string]interface{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/repos/owner/repo/pulls/7" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"number": 7}`))
+	})
+
+	if err := client.UpdatePullRequest(context.Background(), 7, "New title", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["title"] != "New title" {
+		t.Errorf("expected title in payload, got %v", payload)
+	}
+	if _, ok := payload["body"]; ok {
+		t.Errorf("empty body should be omitted, got %v", payload)
+	}
+}
+
+func TestUpdatePullRequestError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource not accessible"}`))
+	})
+
+	err := client.UpdatePullRequest(context.Background(), 7, "New title", "New body")
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a 403 APIError, got %v", err)
+	}
+}
+
+func TestSetPullRequestDraft(t *testing.T) {
+	var query map[string]interface{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls/7":
+			w.Write([]byte(`{"number": 7, "draft": true, "node_id": "PR_abc"}`))
+		case "/graphql":
+			json.NewDecoder(r.Body).Decode(&query)
+			w.Write([]byte(`{"data": {}}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	if err := client.SetPullRequestDraft(context.Background(), 7, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(query["query"].(string), "markPullRequestReadyForReview") {
+		t.Errorf("expected ready-for-review mutation, got %v", query["query"])
+	}
+	if vars := query["variables"].(map[string]interface{}); vars["id"] != "PR_abc" {
+		t.Errorf("expected node ID variable, got %v", vars)
+	}
+}
+
+func TestSetPullRequestDraftGraphQLError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			w.Write([]byte(`{"errors": [{"message": "Draft pull requests are not supported"}]}`))
+			return
+		}
+		w.Write([]byte(`{"number": 7, "draft": false, "node_id": "PR_abc"}`))
+	})
+
+	err := client.SetPullRequestDraft(context.Background(), 7, true)
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected GraphQL error, got %v", err)
+	}
+}
+
+func TestGetRepoPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Permissions
+	}{
+		{
+			name:    "admin",
+			payload: `{"permissions": {"admin": true, "maintain": true, "push": true, "triage": true, "pull": true}}`,
+			want:    Permissions{Admin: true, Maintain: true, Push: true, Triage: true, Pull: true},
+		},
+		{
+			name:    "read only",
+			payload: `{"permissions": {"admin": false, "maintain": false, "push": false, "triage": false, "pull": true}}`,
+			want:    Permissions{Pull: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/repo" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				w.Write([]byte(tt.payload))
+			})
+
+			perms, err := client.GetRepoPermissions(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if perms != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, perms)
+			}
+		})
+	}
+}
+
+func TestGetRepoPermissionsMissing(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"default_branch": "main"}`))
+	})
+
+	if _, err := client.GetRepoPermissions(context.Background()); err == nil {
+		t.Fatal("expected error when permissions are absent")
+	}
+}
+
+func TestAddLabelsToIssue(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/issues/12/labels" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Labels []string `json:"labels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if strings.Join(body.Labels, ",") != "ai-generated,needs-review" {
+			t.Errorf("unexpected labels %v", body.Labels)
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	if err := client.AddLabelsToIssue(context.Background(), 12, []string{"ai-generated", "needs-review"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEnsureLabelCreatesMissingLabel(t *testing.T) {
+	var created map[string]string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/repos/owner/repo/labels/ai%20generated":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/repo/labels":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	})
+
+	if err := client.EnsureLabel(context.Background(), "ai generated"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created["name"] != "ai generated" || created["color"] == "" {
+		t.Errorf("unexpected create payload %v", created)
+	}
+}
+
+func TestEnsureLabelExisting(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("existing label should not be created")
+		}
+		w.Write([]byte(`{"name": "bug"}`))
+	})
+
+	if err := client.EnsureLabel(context.Background(), "bug"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRequestReviewers(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/pulls/9/requested_reviewers" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string][]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if strings.Join(body["reviewers"], ",") != "alice,bob" || strings.Join(body["team_reviewers"], ",") != "backend" {
+			t.Errorf("unexpected payload %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+
+	if err := client.RequestReviewers(context.Background(), 9, []string{"alice", "bob"}, []string{"backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRequestReviewersOmitsEmptyTeams(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["team_reviewers"]; ok {
+			t.Errorf("team_reviewers should be omitted when empty: %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := client.RequestReviewers(context.Background(), 9, []string{"alice"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAddAssignees(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/issues/9/assignees" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Assignees []string `json:"assignees"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.Join(body.Assignees, ",") != "alice,ghost" {
+			t.Errorf("unexpected assignees %v", body.Assignees)
+		}
+		// GitHub drops users that can't be assigned
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"assignees": [{"login": "alice"}]}`))
+	})
+
+	assigned, err := client.AddAssignees(context.Background(), 9, []string{"alice", "ghost"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(assigned, ",") != "alice" {
+		t.Errorf("unexpected assigned %v", assigned)
+	}
+}
+
+func TestAddIssueComment(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/issues/4/comments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["body"] != "hello" {
+			t.Errorf("unexpected comment body %q", body["body"])
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := client.AddIssueComment(context.Background(), 4, "hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// roundTripFunc lets a function stand in for a transport
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSetHTTPClient(t *testing.T) {
+	client := NewClient("test-token", "owner", "repo")
+
+	var requested string
+	client.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = r.URL.String()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"number": 7, "title": "Injected"}`)),
+			Request:    r,
+		}, nil
+	})})
+
+	issue, err := client.GetIssue(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if issue.Title != "Injected" || requested != "https://api.github.com/repos/owner/repo/issues/7" {
+		t.Errorf("expected the request to go through the injected client, got %q from %s", issue.Title, requested)
+	}
+}
+
+func TestClientTimesOutOnSlowServer(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewClientWithTimeout("test-token", "owner", "repo", 100*time.Millisecond)
+	client.SetBaseURL(server.URL)
+
+	start := time.Now()
+	_, err := client.GetIssue(context.Background(), 7)
+	if err == nil {
+		t.Fatal("expected the slow request to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request to give up after about 100ms, took %v", elapsed)
+	}
+	var timeout interface{ Timeout() bool }
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	// A shorter context deadline wins over the client's timeout
+	client.SetTimeout(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := client.GetIssue(ctx, 7); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context deadline to end the request, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request to end with its context, took %v", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+// Package hooks runs user-supplied scripts around the issue processing pipeline.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long a hook may run when no timeout is given
+const DefaultTimeout = 5 * time.Minute
+
+// maxErrorOutput caps how much hook output is kept in an error message
+const maxErrorOutput = 4096
+
+// Env describes the pipeline state exposed to a hook as VIBE_GIT_* variables
+type Env struct {
+	IssueNumber  int
+	IssueTitle   string
+	Repo         string // owner/name the branch is pushed to
+	Branch       string
+	BaseBranch   string
+	PRURL        string   // Empty until the PR has been created
+	ChangedFiles []string // Paths touched by the generated changes
+}
+
+// Vars returns the environment variables passed to the hook
+func (e Env) Vars() []string {
+	return []string{
+		"VIBE_GIT_ISSUE_NUMBER=" + strconv.Itoa(e.IssueNumber),
+		"VIBE_GIT_ISSUE_TITLE=" + e.IssueTitle,
+		"VIBE_GIT_REPO=" + e.Repo,
+		"VIBE_GIT_BRANCH=" + e.Branch,
+		"VIBE_GIT_BASE_BRANCH=" + e.BaseBranch,
+		"VIBE_GIT_PR_URL=" + e.PRURL,
+		"VIBE_GIT_CHANGED_FILES=" + strings.Join(e.ChangedFiles, "\n"),
+	}
+}
+
+// Run executes command through the shell in dir (the current directory if empty)
+// with env added to the process environment. Output is echoed to out (if not nil)
+// and returned. A non-zero exit or timeout is an error that includes the tail of
+// the output.
+func Run(ctx context.Context, command, dir string, env Env, timeout time.Duration, out io.Writer) (string, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var captured bytes.Buffer
+	var w io.Writer = &captured
+	if out != nil {
+		w = io.MultiWriter(&captured, out)
+	}
+
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env.Vars()...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Don't wait for background children holding the pipes once the hook is killed
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	output := captured.String()
+	if err == nil {
+		return output, nil
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	if tail := errorOutput(output); tail != "" {
+		return output, fmt.Errorf("hook %q: %w: %s", command, err, tail)
+	}
+	return output, fmt.Errorf("hook %q: %w", command, err)
+}
+
+// shellCommand runs command through the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// errorOutput trims hook output for inclusion in an error
+func errorOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxErrorOutput {
+		output = "..." + output[len(output)-maxErrorOutput:]
+	}
+	return output
+}
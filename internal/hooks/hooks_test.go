@@ -0,0 +1,71 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use POSIX shell syntax")
+	}
+}
+
+func TestRunPassesEnvironment(t *testing.T) {
+	skipWithoutShell(t)
+
+	env := Env{
+		IssueNumber:  42,
+		IssueTitle:   "Fix login",
+		Repo:         "myorg/backend",
+		Branch:       "vibe-git/issue-42",
+		BaseBranch:   "main",
+		ChangedFiles: []string{"a.go", "dir/b.go"},
+	}
+
+	var echoed bytes.Buffer
+	out, err := Run(context.Background(),
+		`echo "$VIBE_GIT_ISSUE_NUMBER $VIBE_GIT_BRANCH $VIBE_GIT_REPO"; echo "$VIBE_GIT_CHANGED_FILES" | wc -l`,
+		"", env, time.Second*10, &echoed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Fields(out)
+	if len(lines) != 4 || lines[0] != "42" || lines[1] != "vibe-git/issue-42" || lines[2] != "myorg/backend" || lines[3] != "2" {
+		t.Errorf("unexpected hook output %q", out)
+	}
+	if echoed.String() != out {
+		t.Errorf("expected output to be echoed, got %q", echoed.String())
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	skipWithoutShell(t)
+
+	_, err := Run(context.Background(), `echo "lint failed: main.go" >&2; exit 3`, "", Env{}, 10*time.Second, nil)
+	if err == nil {
+		t.Fatal("expected error for non-zero exit")
+	}
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "lint failed: main.go") {
+		t.Errorf("expected exit status and output in error, got %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	skipWithoutShell(t)
+
+	start := time.Now()
+	_, err := Run(context.Background(), "sleep 30", "", Env{}, 200*time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("hook was not killed promptly")
+	}
+}
@@ -50,7 +50,7 @@ type RequestOptions struct {
 func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL:    strings.TrimSuffix(baseURL, "/"),
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: Transport()},
 		headers:    make(map[string]string),
 	}
 }
@@ -175,7 +175,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, opts *Reque
 	// Apply timeout if specified
 	client := c.httpClient
 	if opts.Timeout > 0 {
-		client = &http.Client{Timeout: opts.Timeout}
+		client = &http.Client{Timeout: opts.Timeout, Transport: client.Transport}
 	}
 
 	// Execute request
@@ -0,0 +1,167 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions tune the connection pool of the shared transport
+type TransportOptions struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts; 0 means no limit
+	MaxIdleConnsPerHost int           // Idle connections kept per host
+	MaxConnsPerHost     int           // Connections per host, in use or idle; 0 means no limit
+	IdleConnTimeout     time.Duration // How long an idle connection is kept; 0 means forever
+
+	// Proxy receives every request when set, except those NO_PROXY exempts
+	// and those to loopback hosts. Otherwise HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY decide, as for http.DefaultTransport.
+	Proxy *url.URL
+}
+
+// DefaultTransportOptions keep enough idle connections per host that watch
+// mode's bursts of API calls reuse them rather than dialing, and leaving
+// sockets in TIME_WAIT, for every request
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// NewTransport returns a transport with http.DefaultTransport's TLS and dial
+// settings and the proxy and connection pool given by opts. Every client
+// vibe-git makes gets its transport from here, so they all honor the proxy.
+func NewTransport(opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if opts.Proxy != nil {
+		t.Proxy = proxyExcept(opts.Proxy, noProxyEnv())
+	}
+	t.MaxIdleConns = opts.MaxIdleConns
+	t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	t.MaxConnsPerHost = opts.MaxConnsPerHost
+	t.IdleConnTimeout = opts.IdleConnTimeout
+	return t
+}
+
+// ParseProxy parses a proxy URL such as http://proxy.corp:3128 or
+// socks5://127.0.0.1:1080
+func ParseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%q needs an http, https or socks5 scheme", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", s)
+	}
+	return u, nil
+}
+
+// noProxyEnv returns NO_PROXY, or no_proxy if it isn't set
+func noProxyEnv() string {
+	if v := os.Getenv("NO_PROXY"); v != "" {
+		return v
+	}
+	return os.Getenv("no_proxy")
+}
+
+// proxyExcept sends requests through proxy unless they go to a loopback host
+// or one noProxy lists, the way http.ProxyFromEnvironment treats NO_PROXY
+func proxyExcept(proxy *url.URL, noProxy string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+}
+
+// bypassProxy reports whether u goes to a loopback host or matches an entry
+// of noProxy: "*", an IP, a CIDR range, or a domain that also covers its
+// subdomains, each optionally with a port
+func bypassProxy(u *url.URL, noProxy string) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	sharedMu        sync.Mutex
+	sharedTransport *http.Transport
+)
+
+// Transport returns the transport shared by the GitHub, Claude and webhook
+// clients, so they draw on one connection pool
+func Transport() *http.Transport {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if sharedTransport == nil {
+		sharedTransport = NewTransport(DefaultTransportOptions())
+	}
+	return sharedTransport
+}
+
+// ConfigureTransport replaces the shared transport with one tuned by opts.
+// Clients created before keep the old one, so call it at startup.
+func ConfigureTransport(opts TransportOptions) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if sharedTransport != nil {
+		sharedTransport.CloseIdleConnections()
+	}
+	sharedTransport = NewTransport(opts)
+}
@@ -0,0 +1,118 @@
+// Package issueprep cleans up issue bodies before they go into a prompt, so
+// template boilerplate and task-list syntax don't distract the model.
+package issueprep
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlComment matches template comments; an unterminated one runs to the end,
+	// as GitHub renders it
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?(?:-->|$)`)
+	// commentLine is a comment on lines of its own, removed with its line break
+	commentLine = regexp.MustCompile(`(?m)^[ \t]*<!--(?s:.*?)-->[ \t]*\n`)
+	// openTask matches an unchecked task-list item, e.g. "- [ ] Add tests"
+	openTask = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(.*\S)\s*$`)
+	// fence opens or closes a fenced code block
+	fence = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// Prepared is an issue body cleaned up for the prompt
+type Prepared struct {
+	Original     string   // The body as written
+	Body         string   // Body without template comments or open tasks, whitespace normalized
+	Requirements []string // Unchecked task-list items, in order
+}
+
+// Prepare strips HTML comments, moves unchecked tasks into Requirements and
+// normalizes whitespace: CRLF line endings, trailing spaces and runs of blank
+// lines. Fenced code blocks are kept as they are.
+func Prepare(body string) Prepared {
+	p := Prepared{Original: body}
+
+	var out []string
+	for _, block := range splitFences(strings.ReplaceAll(body, "\r\n", "\n")) {
+		if block.code {
+			out = append(out, block.lines...)
+			continue
+		}
+
+		text := strings.Join(block.lines, "\n") + "\n"
+		text = htmlComment.ReplaceAllString(commentLine.ReplaceAllString(text, ""), "")
+		text = strings.TrimSuffix(text, "\n")
+		for _, line := range strings.Split(text, "\n") {
+			if m := openTask.FindStringSubmatch(line); m != nil {
+				p.Requirements = append(p.Requirements, m[1])
+				continue
+			}
+			line = strings.TrimRight(line, " \t")
+			// Collapse runs of blank lines to one
+			if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+				continue
+			}
+			out = append(out, line)
+		}
+	}
+
+	p.Body = strings.TrimSpace(strings.Join(out, "\n"))
+	return p
+}
+
+// PromptBody is the body followed by the open tasks as an explicit requirements list
+func (p Prepared) PromptBody() string {
+	if len(p.Requirements) == 0 {
+		return p.Body
+	}
+
+	var sb strings.Builder
+	if p.Body != "" {
+		sb.WriteString(p.Body)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Requirements (open tasks from the issue):\n")
+	for i, r := range p.Requirements {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, r))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// block is a run of lines that is either inside a fenced code block or not
+type block struct {
+	lines []string
+	code  bool
+}
+
+// splitFences splits text into prose and fenced code blocks. The fence lines
+// belong to the code block; an unclosed fence runs to the end.
+func splitFences(text string) []block {
+	var blocks []block
+	current := block{}
+	var marker string
+
+	flush := func() {
+		if len(current.lines) > 0 {
+			blocks = append(blocks, current)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		m := fence.FindStringSubmatch(line)
+		switch {
+		case !current.code && m != nil:
+			flush()
+			current = block{lines: []string{line}, code: true}
+			marker = m[1]
+		case current.code && m != nil && m[1] == marker:
+			current.lines = append(current.lines, line)
+			flush()
+			current = block{}
+		default:
+			current.lines = append(current.lines, line)
+		}
+	}
+	flush()
+	return blocks
+}
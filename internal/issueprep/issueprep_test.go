@@ -0,0 +1,84 @@
+package issueprep
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const templatedBody = "<!--\r\n" +
+	"Thanks for filing an issue! Please fill in the sections below.\r\n" +
+	"Mention relevant files with @path/to/file.\r\n" +
+	"-->\r\n" +
+	"\r\n" +
+	"### Description   \r\n" +
+	"<!-- A clear and concise description of the feature. -->\r\n" +
+	"Exports time out for accounts with more than 10k rows.\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"### Acceptance criteria\r\n" +
+	"<!-- Use a task list so progress is tracked -->\r\n" +
+	"- [x] Reproduce on staging\r\n" +
+	"- [ ] Stream rows instead of loading them all\r\n" +
+	"  - [ ]   Add a progress callback  \r\n" +
+	"* [ ] Cover exports over 10k rows with a test\r\n" +
+	"\r\n" +
+	"### Logs\r\n" +
+	"```\r\n" +
+	"- [ ] not a task, just log output\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"<!-- kept -->\r\n" +
+	"```\r\n" +
+	"<!-- Anything else? -->\r\n"
+
+func TestPrepareTemplatedIssue(t *testing.T) {
+	p := Prepare(templatedBody)
+
+	if p.Original != templatedBody {
+		t.Error("expected the original body to be kept")
+	}
+
+	wantRequirements := []string{
+		"Stream rows instead of loading them all",
+		"Add a progress callback",
+		"Cover exports over 10k rows with a test",
+	}
+	if !reflect.DeepEqual(p.Requirements, wantRequirements) {
+		t.Errorf("Requirements = %q, want %q", p.Requirements, wantRequirements)
+	}
+
+	wantBody := "### Description\n" +
+		"Exports time out for accounts with more than 10k rows.\n" +
+		"\n" +
+		"### Acceptance criteria\n" +
+		"- [x] Reproduce on staging\n" +
+		"\n" +
+		"### Logs\n" +
+		"```\n" +
+		"- [ ] not a task, just log output\n" +
+		"\n" +
+		"\n" +
+		"<!-- kept -->\n" +
+		"```"
+	if p.Body != wantBody {
+		t.Errorf("Body =\n%s\nwant\n%s", p.Body, wantBody)
+	}
+}
+
+func TestPromptBodyListsRequirements(t *testing.T) {
+	p := Prepare("Make exports faster.\n\n- [ ] Stream rows\n- [ ] Add a test")
+	want := "Make exports faster.\n\nRequirements (open tasks from the issue):\n1. Stream rows\n2. Add a test"
+	if got := p.PromptBody(); got != want {
+		t.Errorf("PromptBody =\n%s\nwant\n%s", got, want)
+	}
+
+	plain := Prepare("  Just a bug report.  \n")
+	if got := plain.PromptBody(); got != "Just a bug report." {
+		t.Errorf("expected a plain body without a requirements list, got %q", got)
+	}
+	if strings.Contains(Prepare("<!-- unterminated\nhidden").Body, "hidden") {
+		t.Error("an unterminated comment should hide the rest of the body")
+	}
+}
@@ -0,0 +1,118 @@
+// Package notify posts run results to chat webhooks such as Slack and Discord.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vibe-git/internal/httpclient"
+)
+
+// EventType identifies what happened to an issue
+type EventType string
+
+const (
+	EventPRCreated EventType = "pr_created"
+	EventPRMerged  EventType = "pr_merged"
+	EventError     EventType = "error"
+)
+
+// Event describes the outcome of processing an issue
+type Event struct {
+	Type        EventType
+	Repo        string // owner/name of the repository the issue belongs to
+	IssueNumber int
+	IssueTitle  string
+	PRURL       string
+	Err         error // Set for EventError
+}
+
+// Notifier delivers events to an external service
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// New returns a notifier of the given type ("slack" or "discord") posting to webhookURL
+func New(kind, webhookURL string) (Notifier, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("webhook URL required")
+	}
+	switch strings.ToLower(kind) {
+	case "slack":
+		return NewSlack(webhookURL), nil
+	case "discord":
+		return NewDiscord(webhookURL), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type: %s (use 'slack' or 'discord')", kind)
+	}
+}
+
+// SlackNotifier posts events to a Slack incoming webhook
+type SlackNotifier struct {
+	client *httpclient.Client
+}
+
+// NewSlack creates a notifier for a Slack incoming webhook URL
+func NewSlack(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{client: httpclient.NewClient(webhookURL)}
+}
+
+// Notify posts the event as a Slack message
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	return post(ctx, n.client, map[string]string{"text": FormatMessage(event)})
+}
+
+// DiscordNotifier posts events to a Discord webhook
+type DiscordNotifier struct {
+	client *httpclient.Client
+}
+
+// NewDiscord creates a notifier for a Discord webhook URL
+func NewDiscord(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{client: httpclient.NewClient(webhookURL)}
+}
+
+// Notify posts the event as a Discord message
+func (n *DiscordNotifier) Notify(ctx context.Context, event Event) error {
+	return post(ctx, n.client, map[string]string{"content": FormatMessage(event)})
+}
+
+// post sends payload as JSON to the webhook and checks for a 2xx answer
+func post(ctx context.Context, client *httpclient.Client, payload interface{}) error {
+	resp, err := client.Post(ctx, "", &httpclient.RequestOptions{Body: payload})
+	if err != nil {
+		return fmt.Errorf("posting notification: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(resp.String()))
+	}
+	return nil
+}
+
+// FormatMessage renders an event as a short plain-text message
+func FormatMessage(event Event) string {
+	issue := fmt.Sprintf("%s#%d", event.Repo, event.IssueNumber)
+	if event.IssueTitle != "" {
+		issue += ": " + event.IssueTitle
+	}
+
+	var b strings.Builder
+	switch event.Type {
+	case EventPRCreated:
+		fmt.Fprintf(&b, "vibe-git opened a PR for %s", issue)
+	case EventPRMerged:
+		fmt.Fprintf(&b, "vibe-git merged the PR for %s", issue)
+	case EventError:
+		fmt.Fprintf(&b, "vibe-git failed to process %s", issue)
+	default:
+		fmt.Fprintf(&b, "vibe-git %s for %s", event.Type, issue)
+	}
+	if event.PRURL != "" {
+		fmt.Fprintf(&b, "\nPR: %s", event.PRURL)
+	}
+	if event.Err != nil {
+		fmt.Fprintf(&b, "\nError: %v", event.Err)
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureServer records the JSON payload of the last request it received
+func captureServer(t *testing.T, status int) (*httptest.Server, *map[string]string) {
+	t.Helper()
+	payload := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected JSON content type, got %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &payload
+}
+
+func TestSlackNotifierPostsText(t *testing.T) {
+	server, payload := captureServer(t, http.StatusOK)
+
+	err := NewSlack(server.URL).Notify(context.Background(), Event{
+		Type:        EventPRCreated,
+		Repo:        "myorg/myproject",
+		IssueNumber: 42,
+		IssueTitle:  "Fix login",
+		PRURL:       "https://github.com/myorg/myproject/pull/7",
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	text := (*payload)["text"]
+	for _, want := range []string{"opened a PR", "myorg/myproject#42: Fix login", "PR: https://github.com/myorg/myproject/pull/7"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected text to contain %q, got %q", want, text)
+		}
+	}
+}
+
+func TestDiscordNotifierPostsContent(t *testing.T) {
+	server, payload := captureServer(t, http.StatusNoContent)
+
+	err := NewDiscord(server.URL).Notify(context.Background(), Event{
+		Type:        EventError,
+		Repo:        "myorg/myproject",
+		IssueNumber: 42,
+		Err:         errors.New("generating code: boom"),
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	content := (*payload)["content"]
+	for _, want := range []string{"failed to process myorg/myproject#42", "Error: generating code: boom"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected content to contain %q, got %q", want, content)
+		}
+	}
+}
+
+func TestNotifyReportsWebhookError(t *testing.T) {
+	server, _ := captureServer(t, http.StatusNotFound)
+
+	err := NewSlack(server.URL).Notify(context.Background(), Event{Type: EventPRMerged, Repo: "o/r", IssueNumber: 1})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if n, err := New("Slack", "https://example.com"); err != nil {
+		t.Errorf("New(slack): %v", err)
+	} else if _, ok := n.(*SlackNotifier); !ok {
+		t.Errorf("expected a SlackNotifier, got %T", n)
+	}
+	if _, err := New("teams", "https://example.com"); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+	if _, err := New("discord", ""); err == nil {
+		t.Error("expected an error for a missing URL")
+	}
+}
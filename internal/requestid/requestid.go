@@ -0,0 +1,85 @@
+// Package requestid correlates one request across vibe-git, the worker, the
+// gateway and the Anthropic API with an X-Request-ID header.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+)
+
+// Header carries the request ID between services
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs taken from clients so they can't flood the logs
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a random 16-byte hex request ID
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithID returns a context carrying id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or ""
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ensure returns ctx and its request ID, adding a new ID if it has none
+func Ensure(ctx context.Context) (context.Context, string) {
+	if id := FromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := New()
+	return WithID(ctx, id), id
+}
+
+// Middleware keeps the caller's request ID, or assigns one when it is missing
+// or malformed. The ID is echoed in the response header, stored in the request
+// context and left on the request header so proxies forward it.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		r.Header.Set(Header, id)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
+
+// Logf logs like log.Printf, prefixed with the request's ID
+func Logf(r *http.Request, format string, args ...interface{}) {
+	if id := FromContext(r.Context()); id != "" {
+		format = "[" + id + "] " + format
+	}
+	log.Printf(format, args...)
+}
+
+// valid accepts IDs of letters, digits and - _ . : only, so they are safe to log
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+		if r.Header.Get(Header) != seen {
+			t.Errorf("request header %q should match context ID %q", r.Header.Get(Header), seen)
+		}
+	}))
+
+	tests := []struct {
+		name, id string
+		keep     bool
+	}{
+		{"provided", "run-42.issue_7", true},
+		{"missing", "", false},
+		{"unsafe characters", "abc\ndef", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.id != "" {
+			req.Header.Set(Header, tt.id)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		echoed := rec.Header().Get(Header)
+		if echoed == "" || echoed != seen {
+			t.Errorf("%s: response ID %q, handler saw %q", tt.name, echoed, seen)
+		}
+		if tt.keep != (echoed == tt.id) {
+			t.Errorf("%s: got ID %q for provided %q", tt.name, echoed, tt.id)
+		}
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	ctx, id := Ensure(context.Background())
+	if id == "" || FromContext(ctx) != id {
+		t.Fatalf("expected a new ID in the context, got %q", id)
+	}
+	if _, again := Ensure(ctx); again != id {
+		t.Errorf("expected existing ID %q to be kept, got %q", id, again)
+	}
+}
@@ -0,0 +1,104 @@
+// Package ui formats the status markers printed by the CLI, switching between
+// emoji and ASCII and adding ANSI color when the output is a terminal.
+package ui
+
+import (
+	"os"
+	"sync"
+)
+
+// Options controls how markers are rendered
+type Options struct {
+	Emoji bool // Use ✓/⚠/✗ and decorative emoji instead of ASCII markers
+	Color bool // Wrap markers in ANSI color codes
+}
+
+const (
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	red    = "\x1b[31m"
+	reset  = "\x1b[0m"
+)
+
+var (
+	mu      sync.RWMutex
+	current = Options{Emoji: true}
+)
+
+// Detect picks the options for the environment. TERM=dumb falls back to plain
+// ASCII, a non-empty NO_COLOR disables color, and color is only used on a terminal.
+func Detect(noEmoji bool, getenv func(string) string, terminal bool) Options {
+	dumb := getenv("TERM") == "dumb"
+	noColor := getenv("NO_COLOR") != ""
+	return Options{
+		Emoji: !noEmoji && !dumb,
+		Color: terminal && !noColor && !dumb,
+	}
+}
+
+// Configure sets the options for this process from --no-emoji and the environment
+func Configure(noEmoji bool) {
+	Set(Detect(noEmoji, os.Getenv, isTerminal(os.Stdout) && isTerminal(os.Stderr)))
+}
+
+// Set replaces the current options
+func Set(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = o
+}
+
+// Current returns the current options
+func Current() Options {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Success returns the marker for a step that worked
+func Success() string {
+	return marker("✓", "[ok]", green)
+}
+
+// Warn returns the marker for a problem that doesn't stop the run
+func Warn() string {
+	return marker("⚠", "[warn]", yellow)
+}
+
+// Error returns the marker for a failed step
+func Error() string {
+	return marker("✗", "[error]", red)
+}
+
+// Arrow returns the marker that introduces a hint or follow-up line
+func Arrow() string {
+	if Current().Emoji {
+		return "→"
+	}
+	return "->"
+}
+
+// Emoji returns the decorative prefix s (e.g. "📥 "), or nothing in ASCII mode
+func Emoji(s string) string {
+	if Current().Emoji {
+		return s
+	}
+	return ""
+}
+
+func marker(emoji, ascii, color string) string {
+	o := Current()
+	m := ascii
+	if o.Emoji {
+		m = emoji
+	}
+	if o.Color {
+		return color + m + reset
+	}
+	return m
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+// withOptions sets o for the duration of the test
+func withOptions(t *testing.T, o Options) {
+	t.Helper()
+	orig := Current()
+	t.Cleanup(func() { Set(orig) })
+	Set(o)
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		noEmoji  bool
+		vars     map[string]string
+		terminal bool
+		want     Options
+	}{
+		{"terminal", false, nil, true, Options{Emoji: true, Color: true}},
+		{"piped", false, nil, false, Options{Emoji: true}},
+		{"no color", false, map[string]string{"NO_COLOR": "1"}, true, Options{Emoji: true}},
+		{"empty no color", false, map[string]string{"NO_COLOR": ""}, true, Options{Emoji: true, Color: true}},
+		{"no emoji", true, nil, true, Options{Color: true}},
+		{"dumb terminal", false, map[string]string{"TERM": "dumb"}, true, Options{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.noEmoji, env(tt.vars), tt.terminal); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkersPlainWithNoColorAndNoEmoji(t *testing.T) {
+	withOptions(t, Detect(true, env(map[string]string{"NO_COLOR": "1"}), true))
+
+	got := []string{Success(), Warn(), Error(), Arrow(), Emoji("📥 ")}
+	want := []string{"[ok]", "[warn]", "[error]", "->", ""}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("marker %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMarkersWithNoColorKeepEmoji(t *testing.T) {
+	withOptions(t, Detect(false, env(map[string]string{"NO_COLOR": "1"}), true))
+
+	if got := Success() + Warn() + Error(); got != "✓⚠✗" {
+		t.Errorf("expected bare emoji markers, got %q", got)
+	}
+}
+
+func TestMarkersColored(t *testing.T) {
+	withOptions(t, Options{Emoji: true, Color: true})
+
+	if got := Warn(); got != "\x1b[33m⚠\x1b[0m" {
+		t.Errorf("expected a yellow marker, got %q", got)
+	}
+	if got := Arrow(); strings.Contains(got, "\x1b[") {
+		t.Errorf("arrow should not be colored, got %q", got)
+	}
+}
@@ -6,17 +6,44 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"vibe-git/internal/claude"
+	"vibe-git/internal/httpclient"
+	"vibe-git/internal/requestid"
+)
+
+var (
+	// ErrUnreachable is returned when the worker cannot be contacted at all
+	ErrUnreachable = errors.New("worker unreachable")
+	// ErrUnauthorized is returned when the worker rejects the token (HTTP 401)
+	ErrUnauthorized = errors.New("worker rejected token")
+	// ErrUnhealthy is returned when the worker responds but does not report healthy
+	ErrUnhealthy = errors.New("worker unhealthy")
+	// ErrUnknownProject is returned when the worker has no project by the name set with SetProject
+	ErrUnknownProject = errors.New("worker has no such project")
+	// ErrClaudeNotStarted is returned when the worker could not start claude at all (HTTP 422)
+	ErrClaudeNotStarted = errors.New("worker could not start claude")
+	// ErrClaudeFailed is returned with the response when claude ran but exited non-zero
+	ErrClaudeFailed = errors.New("claude exited with an error")
 )
 
+// healthPollInterval is how often WaitHealthy re-checks the worker
+var healthPollInterval = 2 * time.Second
+
 // Client provides methods to interact with the Claude Worker container
 type Client struct {
 	baseURL string
 	token   string
+	project string
 	client  *http.Client
+	stream  *http.Client // For streamed responses, which only the request's context bounds
 }
 
 // NewClient creates a new Worker client
@@ -27,32 +54,62 @@ func NewClient(baseURL, token string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		token:   token,
-		client:  &http.Client{Timeout: 300 * time.Second},
+		client:  &http.Client{Timeout: 300 * time.Second, Transport: httpclient.Transport()},
+		stream:  &http.Client{Transport: httpclient.Transport()},
+	}
+}
+
+// SetProject makes requests work on the named project from the worker's
+// WORKER_PROJECTS instead of its default PROJECT_PATH
+func (c *Client) SetProject(name string) {
+	c.project = name
+}
+
+// NewClientWithHealthCheck creates a new Worker client for project, "" for the
+// default, and waits until it is reachable
+func NewClientWithHealthCheck(ctx context.Context, baseURL, token, project string, timeout time.Duration) (*Client, error) {
+	c := NewClient(baseURL, token)
+	c.SetProject(project)
+	if err := c.WaitHealthy(ctx, timeout); err != nil {
+		return nil, err
 	}
+	return c, nil
 }
 
 // ClaudeRunRequest represents a request to run Claude
 type ClaudeRunRequest struct {
-	Command string   `json:"command"`
-	Args    []string `json:"args"`
-	Timeout int      `json:"timeout"`
-	Stdin   string   `json:"stdin,omitempty"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Timeout   int               `json:"timeout"`
+	Stdin     string            `json:"stdin,omitempty"`
+	MaxOutput int64             `json:"max_output,omitempty"` // Bytes each of stdout and stderr, below the worker's own limit
+	Env       map[string]string `json:"env,omitempty"`
 }
 
 // ClaudeRunResponse represents the response from running Claude
 type ClaudeRunResponse struct {
-	Stdout   string `json:"stdout"`
-	Stderr   string `json:"stderr"`
-	ExitCode int    `json:"exit_code"`
-	Duration string `json:"duration"`
+	Success     bool     `json:"success"` // claude exited 0
+	Stdout      string   `json:"stdout"`
+	Stderr      string   `json:"stderr"`
+	ExitCode    int      `json:"exit_code"`
+	Duration    string   `json:"duration"`
+	Truncated   bool     `json:"truncated"`    // The worker cut off stdout or stderr
+	StdoutBytes int64    `json:"stdout_bytes"` // Length before truncation
+	StderrBytes int64    `json:"stderr_bytes"`
+	DroppedEnv  []string `json:"dropped_env,omitempty"` // env names the worker refused, such as tokens
 }
 
-// RunClaude executes a Claude command in the worker container
-func (c *Client) RunClaude(ctx context.Context, command string, args []string, timeout int) (*ClaudeRunResponse, error) {
+// RunClaude executes a Claude command in the worker container. env is added
+// to the worker's environment, except for names it refuses such as tokens.
+// If claude exits non-zero, the response is returned along with an error
+// wrapping ErrClaudeFailed; if it can't be started, the error wraps
+// ErrClaudeNotStarted.
+func (c *Client) RunClaude(ctx context.Context, command string, args []string, timeout int, env map[string]string) (*ClaudeRunResponse, error) {
 	reqBody := ClaudeRunRequest{
 		Command: command,
 		Args:    args,
 		Timeout: timeout,
+		Env:     env,
 	}
 
 	resp, err := c.doRequest(ctx, "POST", "/claude/run", reqBody)
@@ -61,7 +118,15 @@ func (c *Client) RunClaude(ctx context.Context, command string, args []string, t
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnprocessableEntity:
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("%w: %s", ErrClaudeNotStarted, body.Error)
+	default:
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("claude run failed: %s", string(body))
 	}
@@ -71,6 +136,9 @@ func (c *Client) RunClaude(ctx context.Context, command string, args []string, t
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
+	if !result.Success {
+		return &result, fmt.Errorf("%w (exit code %d)", ErrClaudeFailed, result.ExitCode)
+	}
 	return &result, nil
 }
 
@@ -99,6 +167,89 @@ func (c *Client) GitStatus(ctx context.Context) (string, error) {
 	return result.Output, nil
 }
 
+// FileStatus represents a single entry from `git status --porcelain`
+type FileStatus struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"` // Source path for renames/copies
+	Staged   string `json:"staged"`              // Index status code (X)
+	Unstaged string `json:"unstaged"`            // Worktree status code (Y)
+	Status   string `json:"status"`              // "modified", "added", "deleted", "renamed", "copied", "untracked", "ignored", "conflict"
+}
+
+// GitStatusParsed returns the git status of the project as structured entries
+func (c *Client) GitStatusParsed(ctx context.Context) ([]FileStatus, error) {
+	output, err := c.GitStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parsePorcelainStatus(output), nil
+}
+
+// parsePorcelainStatus parses `git status --porcelain` (v1) output
+func parsePorcelainStatus(output string) []FileStatus {
+	var files []FileStatus
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) < 4 {
+			continue
+		}
+
+		x, y := line[0], line[1]
+		fs := FileStatus{
+			Path:     unquotePath(line[3:]),
+			Staged:   string(x),
+			Unstaged: string(y),
+		}
+
+		// Renames and copies are reported as "orig -> new"
+		if x == 'R' || x == 'C' || y == 'R' || y == 'C' {
+			if parts := strings.SplitN(line[3:], " -> ", 2); len(parts) == 2 {
+				fs.OrigPath = unquotePath(parts[0])
+				fs.Path = unquotePath(parts[1])
+			}
+		}
+
+		fs.Status = describeStatus(x, y)
+		files = append(files, fs)
+	}
+	return files
+}
+
+// describeStatus maps a porcelain XY code to a readable status
+func describeStatus(x, y byte) string {
+	switch {
+	case x == '?' && y == '?':
+		return "untracked"
+	case x == '!' && y == '!':
+		return "ignored"
+	case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+		return "conflict"
+	case x == 'R' || y == 'R':
+		return "renamed"
+	case x == 'C' || y == 'C':
+		return "copied"
+	case x == 'A':
+		return "added"
+	case x == 'D' || y == 'D':
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
+
+// unquotePath undoes the C-style quoting git applies to paths with special
+// characters, e.g. "caf\303\251.txt" or "tab\there". A path git didn't
+// quote, or one that can't be unquoted, is returned as is.
+func unquotePath(path string) string {
+	if !strings.HasPrefix(path, "\"") {
+		return path
+	}
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
+
 // GitDiff returns the git diff
 func (c *Client) GitDiff(ctx context.Context, cached bool, file string) (string, error) {
 	url := "/git/diff"
@@ -182,6 +333,43 @@ func (c *Client) FileWrite(ctx context.Context, path string, content string) err
 	return nil
 }
 
+// FileDelete deletes a file, or an empty directory, in the project
+func (c *Client) FileDelete(ctx context.Context, path string) error {
+	return c.fileOp(ctx, "/file/delete", map[string]string{"path": path})
+}
+
+// FileRename moves a file within the project. It fails if to exists unless
+// overwrite is set.
+func (c *Client) FileRename(ctx context.Context, from, to string, overwrite bool) error {
+	return c.fileOp(ctx, "/file/rename", map[string]interface{}{
+		"from":      from,
+		"to":        to,
+		"overwrite": overwrite,
+	})
+}
+
+// fileOp posts a file operation and turns an unsuccessful result into an error
+// carrying the worker's message
+func (c *Client) fileOp(ctx context.Context, path string, body interface{}) error {
+	resp, err := c.doRequest(ctx, "POST", path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%s failed (HTTP %d): %s", strings.TrimPrefix(path, "/"), resp.StatusCode, result.Error)
+	}
+	return nil
+}
+
 // FileList lists files in a directory
 func (c *Client) FileList(ctx context.Context, dir string) ([]map[string]interface{}, error) {
 	url := "/file/list"
@@ -223,14 +411,106 @@ func (c *Client) ProjectInfo(ctx context.Context) (map[string]interface{}, error
 	return result, nil
 }
 
+// TreeNode is a file or directory in the project tree
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"` // Slash-separated, relative to the project
+	Type     string      `json:"type"` // "file", "dir" or "symlink"
+	Size     int64       `json:"size,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// TreeResult holds the project tree
+type TreeResult struct {
+	Tree      *TreeNode `json:"tree"`
+	Depth     int       `json:"depth"`
+	Truncated bool      `json:"truncated"` // The worker's entry limit was reached
+}
+
+// ProjectTree returns the project's files and directories, depth levels deep
+// (0 for the worker's default). .git, vendor and node_modules are listed but
+// not expanded.
+func (c *Client) ProjectTree(ctx context.Context, depth int) (*TreeResult, error) {
+	path := "/project/tree"
+	if depth > 0 {
+		path += fmt.Sprintf("?depth=%d", depth)
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return nil, fmt.Errorf("project tree failed (HTTP %d): %s", resp.StatusCode, result.Error)
+	}
+
+	var result TreeResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &result, nil
+}
+
+// SearchMatch is a line of a project file matching a search
+type SearchMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"` // The matching line, cut at 200 bytes
+}
+
+// SearchResult holds the matches of a project search
+type SearchResult struct {
+	Matches   []SearchMatch `json:"matches"`
+	Truncated bool          `json:"truncated"` // More matches may exist past the result cap or timeout
+}
+
+// Search finds lines matching the regexp pattern in project files, optionally
+// only in files matching glob (e.g. "*.go" at any depth or "cmd/*.go")
+func (c *Client) Search(ctx context.Context, pattern, glob string) (*SearchResult, error) {
+	reqBody := map[string]string{"pattern": pattern, "glob": glob}
+
+	resp, err := c.doRequest(ctx, "POST", "/project/search", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return nil, fmt.Errorf("search failed (HTTP %d): %s", resp.StatusCode, result.Error)
+	}
+
+	var result SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &result, nil
+}
+
 // Health checks if the worker is healthy
 func (c *Client) Health(ctx context.Context) (map[string]interface{}, error) {
 	resp, err := c.doRequest(ctx, "GET", "/health", nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w at %s: %v", ErrUnreachable, c.baseURL, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrUnhealthy, resp.StatusCode)
+	}
+
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
@@ -239,8 +519,150 @@ func (c *Client) Health(ctx context.Context) (map[string]interface{}, error) {
 	return result, nil
 }
 
-// doRequest performs an HTTP request
+// Ping verifies the worker is reachable, reports healthy and accepts our token
+func (c *Client) Ping(ctx context.Context) error {
+	health, err := c.Health(ctx)
+	if err != nil {
+		return err
+	}
+	if status, _ := health["status"].(string); status != "healthy" {
+		return fmt.Errorf("%w: status %q", ErrUnhealthy, status)
+	}
+
+	// /health is unauthenticated, so confirm the token against a cheap endpoint
+	resp, err := c.doRequest(ctx, "GET", "/project/info", nil)
+	if err != nil {
+		return fmt.Errorf("%w at %s: %v", ErrUnreachable, c.baseURL, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode == http.StatusNotFound && c.project != "" {
+		return fmt.Errorf("%w: %s", ErrUnknownProject, c.project)
+	}
+
+	return nil
+}
+
+// WaitHealthy polls the worker until Ping succeeds or the timeout elapses.
+// An unauthorized response or unknown project is returned immediately since
+// retrying won't help.
+func (c *Client) WaitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		err := c.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrUnknownProject) {
+			return err
+		}
+		// Keep the last real failure rather than the deadline cutting a check short
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("worker not healthy after %v: %w", timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// IssueProcessRequest represents a request to process an issue end-to-end in the worker
+type IssueProcessRequest struct {
+	Number      int      `json:"number"`
+	Title       string   `json:"title"`
+	Body        string   `json:"body"`
+	URL         string   `json:"url"`
+	Refs        []string `json:"refs,omitempty"`
+	BaseBranch  string   `json:"base_branch"`
+	Branch      string   `json:"branch"`
+	Owner       string   `json:"owner"`
+	Repo        string   `json:"repo"`
+	GitHubToken string   `json:"github_token"`
+	Model       string   `json:"model,omitempty"`
+}
+
+// IssueProgressEvent is a progress update streamed by the worker while processing an issue
+type IssueProgressEvent struct {
+	Step    string              `json:"step"`
+	Message string              `json:"message,omitempty"`
+	Branch  string              `json:"branch,omitempty"`
+	Files   []string            `json:"files,omitempty"`
+	Error   string              `json:"error,omitempty"`
+	Changes []claude.FileChange `json:"changes,omitempty"` // Set on PlanIssue's "done" event
+	Diff    string              `json:"diff,omitempty"`    // Unified diff of Changes
+}
+
+// ProcessIssue runs create-branch → generate → commit → push inside the worker.
+// onProgress is called for every streamed event; the final "done" event is returned.
+func (c *Client) ProcessIssue(ctx context.Context, req IssueProcessRequest, onProgress func(IssueProgressEvent)) (*IssueProgressEvent, error) {
+	return c.processIssue(ctx, "/issue/process", req, onProgress)
+}
+
+// PlanIssue is a dry run of ProcessIssue: the worker generates the changes and
+// returns them with their diff in the "done" event, without creating a branch
+// or writing, committing or pushing anything
+func (c *Client) PlanIssue(ctx context.Context, req IssueProcessRequest, onProgress func(IssueProgressEvent)) (*IssueProgressEvent, error) {
+	return c.processIssue(ctx, "/issue/process?dry-run=true", req, onProgress)
+}
+
+func (c *Client) processIssue(ctx context.Context, path string, req IssueProcessRequest, onProgress func(IssueProgressEvent)) (*IssueProgressEvent, error) {
+	// A run streams its progress for as long as it takes; ctx bounds it
+	resp, err := c.doRequestWith(ctx, c.stream, "POST", path, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("issue process failed: %s", string(body))
+	}
+
+	// Decode the stream rather than scan it by line: a dry run's "done" event
+	// carries every planned file and can be any size
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event IssueProgressEvent
+		if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding progress event: %w", err)
+		}
+
+		if onProgress != nil {
+			onProgress(event)
+		}
+
+		switch event.Step {
+		case "error":
+			return nil, fmt.Errorf("issue process failed: %s", event.Error)
+		case "done":
+			return &event, nil
+		}
+	}
+
+	return nil, fmt.Errorf("worker closed stream before completion")
+}
+
+// doRequest performs an HTTP request, bounded by the client's timeout
 func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	return c.doRequestWith(ctx, c.client, method, path, body)
+}
+
+// doRequestWith sends the request through hc
+func (c *Client) doRequestWith(ctx context.Context, hc *http.Client, method, path string, body interface{}) (*http.Response, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
@@ -256,9 +678,16 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	}
 
 	req.Header.Set("X-Worker-Auth", c.token)
+	// The worker logs and forwards this ID to the gateway; without one it makes its own
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	if c.project != "" {
+		req.Header.Set("X-Worker-Project", c.project)
+	}
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	return c.client.Do(req)
+	return hc.Do(req)
 }
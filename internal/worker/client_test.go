@@ -0,0 +1,301 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vibe-git/internal/claude"
+)
+
+func TestProcessIssue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/issue/process" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("X-Worker-Auth") != "secret" {
+			t.Errorf("expected worker auth header")
+		}
+
+		var req IssueProcessRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		if req.Number != 42 || req.Branch != "vibe-git/issue-42" {
+			t.Errorf("unexpected request body: %+v", req)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"step":"branch","message":"Creating branch vibe-git/issue-42"}` + "\n"))
+		w.Write([]byte(`{"step":"generate","message":"Generating code with Claude..."}` + "\n"))
+		w.Write([]byte(`{"step":"done","branch":"vibe-git/issue-42","files":["main.go"]}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	var steps []string
+	result, err := client.ProcessIssue(context.Background(), IssueProcessRequest{
+		Number: 42,
+		Title:  "Fix bug",
+		Branch: "vibe-git/issue-42",
+	}, func(ev IssueProgressEvent) {
+		steps = append(steps, ev.Step)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(steps, ",") != "branch,generate,done" {
+		t.Errorf("unexpected progress steps: %v", steps)
+	}
+	if result.Branch != "vibe-git/issue-42" {
+		t.Errorf("expected branch vibe-git/issue-42, got %s", result.Branch)
+	}
+	if len(result.Files) != 1 || result.Files[0] != "main.go" {
+		t.Errorf("unexpected files: %v", result.Files)
+	}
+}
+
+func TestProcessIssueError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"step":"branch","message":"Creating branch"}` + "\n"))
+		w.Write([]byte(`{"step":"error","error":"generating code: model unavailable"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	_, err := client.ProcessIssue(context.Background(), IssueProcessRequest{Title: "x"}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "model unavailable") {
+		t.Errorf("expected error to contain cause, got %v", err)
+	}
+}
+
+func TestProcessIssueIncompleteStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"step":"branch","message":"Creating branch"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	if _, err := client.ProcessIssue(context.Background(), IssueProcessRequest{Title: "x"}, nil); err == nil {
+		t.Fatal("expected error when stream ends without done event")
+	}
+}
+
+func TestPlanIssueLargePlan(t *testing.T) {
+	// The done event of a plan holds every file in full, on one line
+	content := strings.Repeat("x", 2*1024*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		enc.Encode(IssueProgressEvent{Step: "generate", Message: "Generating code"})
+		enc.Encode(IssueProgressEvent{
+			Step:    "done",
+			Changes: []claude.FileChange{{Path: "big.txt", Operation: "create", Content: content}},
+			Diff:    "+" + content,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	done, err := client.PlanIssue(context.Background(), IssueProcessRequest{Title: "x"}, nil)
+	if err != nil {
+		t.Fatalf("PlanIssue: %v", err)
+	}
+	if len(done.Changes) != 1 || len(done.Changes[0].Content) != len(content) {
+		t.Errorf("expected the full planned change, got %d changes", len(done.Changes))
+	}
+}
+
+func TestParsePorcelainStatus(t *testing.T) {
+	output := " M cmd/root.go\n" +
+		"M  internal/git/client.go\n" +
+		"MM README.md\n" +
+		"A  new.go\n" +
+		" D removed.go\n" +
+		"R  old.go -> renamed.go\n" +
+		"?? scratch.txt\n" +
+		"UU conflicted.go\n" +
+		"AA both-added.go\n" +
+		"?? \"file with spaces.txt\"\n" +
+		"?? \"caf\\303\\251.txt\"\n" +
+		" M \"tab\\there.go\"\n" +
+		"A  \"say \\\"hi\\\".md\"\n" +
+		"R  \"old \\303\\244.go\" -> \"new\\\\name.go\"\n"
+
+	files := parsePorcelainStatus(output)
+
+	expected := []FileStatus{
+		{Path: "cmd/root.go", Staged: " ", Unstaged: "M", Status: "modified"},
+		{Path: "internal/git/client.go", Staged: "M", Unstaged: " ", Status: "modified"},
+		{Path: "README.md", Staged: "M", Unstaged: "M", Status: "modified"},
+		{Path: "new.go", Staged: "A", Unstaged: " ", Status: "added"},
+		{Path: "removed.go", Staged: " ", Unstaged: "D", Status: "deleted"},
+		{Path: "renamed.go", OrigPath: "old.go", Staged: "R", Unstaged: " ", Status: "renamed"},
+		{Path: "scratch.txt", Staged: "?", Unstaged: "?", Status: "untracked"},
+		{Path: "conflicted.go", Staged: "U", Unstaged: "U", Status: "conflict"},
+		{Path: "both-added.go", Staged: "A", Unstaged: "A", Status: "conflict"},
+		{Path: "file with spaces.txt", Staged: "?", Unstaged: "?", Status: "untracked"},
+		{Path: "café.txt", Staged: "?", Unstaged: "?", Status: "untracked"},
+		{Path: "tab\there.go", Staged: " ", Unstaged: "M", Status: "modified"},
+		{Path: `say "hi".md`, Staged: "A", Unstaged: " ", Status: "added"},
+		{Path: `new\name.go`, OrigPath: "old ä.go", Staged: "R", Unstaged: " ", Status: "renamed"},
+	}
+
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(files), files)
+	}
+	for i, want := range expected {
+		if files[i] != want {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want, files[i])
+		}
+	}
+}
+
+func TestGitStatusParsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/git/status" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"output":  " M main.go\n?? notes.txt\n",
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	files, err := client.GitStatusParsed(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(files))
+	}
+	if files[0].Path != "main.go" || files[0].Status != "modified" {
+		t.Errorf("unexpected first entry: %+v", files[0])
+	}
+	if files[1].Path != "notes.txt" || files[1].Status != "untracked" {
+		t.Errorf("unexpected second entry: %+v", files[1])
+	}
+}
+
+// stubWorker reports unhealthy for the first failures health checks
+func stubWorker(t *testing.T, failures int32, token string) *httptest.Server {
+	t.Helper()
+	var checks int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if atomic.AddInt32(&checks, 1) <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"status":"healthy"}`))
+		default:
+			if r.Header.Get("X-Worker-Auth") != token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+func TestWaitHealthyBecomesHealthy(t *testing.T) {
+	orig := healthPollInterval
+	healthPollInterval = 10 * time.Millisecond
+	defer func() { healthPollInterval = orig }()
+
+	server := stubWorker(t, 3, "secret")
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	if err := client.WaitHealthy(context.Background(), 2*time.Second); err != nil {
+		t.Fatalf("expected worker to become healthy, got %v", err)
+	}
+}
+
+func TestWaitHealthyTimeout(t *testing.T) {
+	orig := healthPollInterval
+	healthPollInterval = 10 * time.Millisecond
+	defer func() { healthPollInterval = orig }()
+
+	server := stubWorker(t, 1000, "secret")
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	err := client.WaitHealthy(context.Background(), 100*time.Millisecond)
+	if !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("expected ErrUnhealthy, got %v", err)
+	}
+}
+
+func TestWaitHealthyUnauthorized(t *testing.T) {
+	server := stubWorker(t, 0, "secret")
+	defer server.Close()
+
+	client := NewClient(server.URL, "wrong-token")
+	err := client.WaitHealthy(context.Background(), time.Second)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestWaitHealthyUnreachable(t *testing.T) {
+	orig := healthPollInterval
+	healthPollInterval = 10 * time.Millisecond
+	defer func() { healthPollInterval = orig }()
+
+	server := stubWorker(t, 0, "secret")
+	url := server.URL
+	server.Close()
+
+	client := NewClient(url, "secret")
+	err := client.WaitHealthy(context.Background(), 50*time.Millisecond)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("expected ErrUnreachable, got %v", err)
+	}
+}
+
+func TestNewClientWithHealthCheck(t *testing.T) {
+	server := stubWorker(t, 0, "secret")
+	defer server.Close()
+
+	if _, err := NewClientWithHealthCheck(context.Background(), server.URL, "secret", "", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewClientWithHealthCheck(context.Background(), server.URL, "bad", "", time.Second); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	projects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"status":"healthy"}`))
+			return
+		}
+		if r.Header.Get("X-Worker-Project") != "api" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer projects.Close()
+
+	client, err := NewClientWithHealthCheck(context.Background(), projects.URL, "", "api", time.Second)
+	if err != nil || client.project != "api" {
+		t.Fatalf("expected a client for project api, got %v, %v", client, err)
+	}
+	if _, err := NewClientWithHealthCheck(context.Background(), projects.URL, "", "web", time.Second); !errors.Is(err, ErrUnknownProject) {
+		t.Fatalf("expected ErrUnknownProject, got %v", err)
+	}
+}